@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/password"
 	"github.com/jinzhu/gorm"
 	"github.com/joho/godotenv"
-	"golang.org/x/crypto/bcrypt"
 )
 
 var DB *gorm.DB
@@ -28,6 +30,54 @@ func GetEnv(key string) string {
 	return value
 }
 
+// GetDurationEnv reads a duration such as "15m" or "720h" from the environment
+// and falls back to the provided default when it is not set or is invalid
+func GetDurationEnv(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration %q for environment variable %s, using %s", value, key, fallback)
+		return fallback
+	}
+	return duration
+}
+
+// GetIntEnv reads an integer from the environment and falls back
+// to the provided default when it is not set or is invalid
+func GetIntEnv(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid integer %q for environment variable %s, using %d", value, key, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// GetBoolEnv reads a boolean such as "true" or "0" from the environment
+// and falls back to the provided default when it is not set or is invalid
+func GetBoolEnv(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean %q for environment variable %s, using %t", value, key, fallback)
+		return fallback
+	}
+	return parsed
+}
+
 // ConnectDatabase initializes the database and creates an admin user
 // which will be the default user used to test the implementation
 func ConnectDatabase() {
@@ -50,14 +100,80 @@ func ConnectDatabase() {
 
 	log.Println("Database connection established.")
 
+	// accounts created before email verification existed count as verified,
+	// which is only known before AutoMigrate adds the column
+	backfillVerifiedAt := !DB.Dialect().HasColumn("users", "verified_at")
+
 	// Auto migrate the DB models (create tables and update schema if needed)
-	if err := DB.AutoMigrate(&model.User{}, &model.Order{}, &model.Product{}).Error; err != nil {
+	if err := DB.AutoMigrate(&model.User{}, &model.Order{}, &model.Product{}, &model.RefreshToken{},
+		&model.RevokedToken{}, &model.UserTokenRevocation{}, &model.OneTimeToken{},
+		&model.LoginAttempt{}, &model.Permission{}, &model.RoleDefinition{}, &model.APIKey{},
+		&model.TwoFactorAuth{}, &model.RecoveryCode{}, &model.OIDCLoginState{}, &model.UserIdentity{},
+		&model.Session{}, &model.Address{}, &model.AuthEvent{}, &model.Category{},
+		&model.ProductOption{}, &model.ProductVariant{}, &model.VariantOption{}, &model.ProductImage{},
+		&model.PriceChange{}, &model.PriceSchedule{}).Error; err != nil {
 		log.Fatalf("Error auto-migrating DB models: %v", err)
 	}
 
+	// AutoMigrate does not change existing columns, revocation times used to be stored in whole seconds
+	if err := DB.Model(&model.UserTokenRevocation{}).ModifyColumn("revoked_at", "datetime(3)").Error; err != nil {
+		log.Fatalf("Error migrating user token revocations: %v", err)
+	}
+
+	if backfillVerifiedAt {
+		err := DB.Model(&model.User{}).Where("verified_at IS NULL").UpdateColumn("verified_at", gorm.Expr("created_at")).Error
+		if err != nil {
+			log.Fatalf("Error marking existing users as verified: %v", err)
+		}
+	}
+
+	seedRolesAndPermissions()
 	createNewAdminUser(err)
 }
 
+// seedRolesAndPermissions creates the permissions known to the application and
+// the built-in roles. Roles that already exist keep their permissions, except
+// for the admin role which is always granted every permission
+func seedRolesAndPermissions() {
+	permissions := make(map[string]model.Permission, len(model.DefaultPermissions))
+	for name, description := range model.DefaultPermissions {
+		permission := model.Permission{Name: name, Description: description}
+		if err := DB.Where(model.Permission{Name: name}).FirstOrCreate(&permission).Error; err != nil {
+			log.Fatalf("Error creating permission %s: %v", name, err)
+		}
+		permissions[name] = permission
+	}
+
+	for name, permissionNames := range model.DefaultRolePermissions {
+		var role model.RoleDefinition
+		err := DB.Where("name = ?", name).First(&role).Error
+		if err != nil && !gorm.IsRecordNotFoundError(err) {
+			log.Fatalf("Error fetching role %s: %v", name, err)
+		}
+
+		if err == nil && name != model.AdminRole {
+			continue
+		}
+
+		role.Name = name
+		role.Permissions = nil
+		for _, permissionName := range permissionNames {
+			role.Permissions = append(role.Permissions, permissions[permissionName])
+		}
+
+		if name == model.AdminRole {
+			role.Permissions = nil
+			for _, permission := range permissions {
+				role.Permissions = append(role.Permissions, permission)
+			}
+		}
+
+		if err := DB.Save(&role).Error; err != nil {
+			log.Fatalf("Error saving role %s: %v", name, err)
+		}
+	}
+}
+
 // Helper function to create a new admin user
 func createNewAdminUser(err error) { // Check if the user "John Doe" exists
 	var user model.User
@@ -67,13 +183,13 @@ func createNewAdminUser(err error) { // Check if the user "John Doe" exists
 		// Some other error occurred while querying the database
 		log.Fatalf("Error fetching user from the database: %v", err)
 	} else if gorm.IsRecordNotFoundError(err) { // If user doesn't exist, create the user
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(GetEnv("ADMIN_PASSWORD")), bcrypt.DefaultCost)
+		hashedPassword, err := password.Hash(GetEnv("ADMIN_PASSWORD"))
 		if err != nil {
 			log.Fatalf("Error hashing user password: %v", err) // Return error if password hashing fails
 		}
 
 		// Create "John Doe" with admin role
-		user = createNewUserModel(string(hashedPassword))
+		user = createNewUserModel(hashedPassword)
 
 		// Create the user in the database
 		if err := DB.Create(&user).Error; err != nil {
@@ -87,12 +203,14 @@ func createNewAdminUser(err error) { // Check if the user "John Doe" exists
 }
 
 func createNewUserModel(hashedPassword string) model.User {
+	verifiedAt := time.Now() // the seeded admin does not need to confirm their email
 	return model.User{
-		FirstName: GetEnv("ADMIN_FIRST_NAME"),
-		LastName:  GetEnv("ADMIN_LAST_NAME"),
-		Email:     GetEnv("ADMIN_EMAIL"),
-		Password:  hashedPassword,
-		Currency:  "NGN",
-		Role:      model.AdminRole, // Assigning admin role
+		FirstName:  GetEnv("ADMIN_FIRST_NAME"),
+		LastName:   GetEnv("ADMIN_LAST_NAME"),
+		Email:      GetEnv("ADMIN_EMAIL"),
+		Password:   hashedPassword,
+		Currency:   "NGN",
+		Role:       model.AdminRole, // Assigning admin role
+		VerifiedAt: &verifiedAt,
 	}
 }
@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const INVALID_COUNTRY_ERROR = "Country must be an ISO 3166-1 alpha-2 code such as NG or GB"
+
+type AddressRequest struct {
+	Label             string `json:"label" binding:"max=64" example:"Home"`
+	FullName          string `json:"full_name" binding:"required,max=255" example:"Jane Doe"`
+	Line1             string `json:"line1" binding:"required,max=255" example:"12 Admiralty Way"`
+	Line2             string `json:"line2" binding:"max=255"`
+	City              string `json:"city" binding:"required,max=128" example:"Lagos"`
+	Region            string `json:"region" binding:"max=128" example:"Lagos"`
+	PostalCode        string `json:"postal_code" binding:"max=16" example:"106104"` // may be empty in countries without postal codes
+	Country           string `json:"country" binding:"required" example:"NG"`
+	Phone             string `json:"phone" binding:"max=32" example:"+2348012345678"`
+	IsDefaultShipping bool   `json:"is_default_shipping"`
+	IsDefaultBilling  bool   `json:"is_default_billing"`
+}
+
+type AddressResponse struct {
+	Address *model.Address `json:"address"`
+	Message string         `json:"message"`
+}
+
+type ListAddressesResponse struct {
+	Addresses []model.Address `json:"addresses"`
+}
+
+// bindAddressRequest reads and validates an address from the request body and
+// copies it onto address. It responds itself when the address is invalid
+func bindAddressRequest(ctx *gin.Context, address *model.Address) bool {
+	var addressRequest AddressRequest
+	if err := ctx.ShouldBindJSON(&addressRequest); err != nil {
+		validationError := util.ExtractValidationErrorMessage(err, addressRequest)
+		handleTokenError(ctx, http.StatusBadRequest, validationError[0])
+		return false
+	}
+
+	country := util.NormalizeCountryCode(addressRequest.Country)
+	if !util.IsValidCountryCode(country) {
+		handleTokenError(ctx, http.StatusBadRequest, INVALID_COUNTRY_ERROR)
+		return false
+	}
+
+	postalCode := util.NormalizePostalCode(addressRequest.PostalCode)
+	if err := util.ValidatePostalCode(country, postalCode); err != nil {
+		handleTokenError(ctx, http.StatusBadRequest, err.Error())
+		return false
+	}
+
+	address.Label = strings.TrimSpace(addressRequest.Label)
+	address.FullName = strings.TrimSpace(addressRequest.FullName)
+	address.Line1 = strings.TrimSpace(addressRequest.Line1)
+	address.Line2 = strings.TrimSpace(addressRequest.Line2)
+	address.City = strings.TrimSpace(addressRequest.City)
+	address.Region = strings.TrimSpace(addressRequest.Region)
+	address.PostalCode = postalCode
+	address.Country = country
+	address.Phone = strings.TrimSpace(addressRequest.Phone)
+	address.IsDefaultShipping = addressRequest.IsDefaultShipping
+	address.IsDefaultBilling = addressRequest.IsDefaultBilling
+	return true
+}
+
+// handleAddressLookupError responds to an address that could not be loaded or changed
+func handleAddressLookupError(ctx *gin.Context, err error, message string) {
+	if err.Error() == repository.ADDRESS_NOT_FOUND_ERROR {
+		handleTokenError(ctx, http.StatusNotFound, err.Error())
+		return
+	}
+	log.Println(err.Error())
+	handleTokenError(ctx, http.StatusInternalServerError, message)
+}
+
+// ListAddresses returns the address book of the authenticated user
+// @Summary List addresses
+// @Description Returns the shipping and billing addresses saved by the authenticated user
+// @Tags Addresses
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {object} handler.ListAddressesResponse "Address book"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Unauthorized access"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to load addresses"
+// @Router /api/v1/user/addresses [get]
+func ListAddresses(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, err := authenticatedUser(ctx, db)
+		if err != nil {
+			handleTokenError(ctx, http.StatusUnauthorized, "Unauthorized access")
+			return
+		}
+
+		addresses, err := repository.ListAddresses(db, user)
+		if err != nil {
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to load addresses")
+			return
+		}
+
+		if addresses == nil {
+			addresses = []model.Address{}
+		}
+		util.LogAndHandleResponse(ctx, http.StatusOK, ListAddressesResponse{Addresses: addresses})
+	}
+}
+
+// GetAddress returns an address of the authenticated user
+// @Summary Get an address
+// @Description Returns an address from the address book of the authenticated user
+// @Tags Addresses
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param address_id path string true "Address ID"
+// @Success 200 {object} model.Address "Address"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Unauthorized access"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Address not found"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to load address"
+// @Router /api/v1/user/addresses/{address_id} [get]
+func GetAddress(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, err := authenticatedUser(ctx, db)
+		if err != nil {
+			handleTokenError(ctx, http.StatusUnauthorized, "Unauthorized access")
+			return
+		}
+
+		address, err := repository.FindAddress(db, user, ctx.Param("address_id"))
+		if err != nil {
+			handleAddressLookupError(ctx, err, "Unable to load address")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, address)
+	}
+}
+
+// CreateAddress adds an address to the address book of the authenticated user
+// @Summary Add an address
+// @Description Saves a shipping or billing address. The country is an ISO 3166-1 alpha-2 code and the postal code must match the format of the country. The first address becomes the default shipping and billing address, marking another address as a default replaces the previous one
+// @Tags Addresses
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Accept		json
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param request body AddressRequest true "Address Request"
+// @Success 201 {object} handler.AddressResponse{address=model.Address, message=string} "Address added"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid address"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Unauthorized access"
+// @Failure 409 {object} util.ErrorResponse{error=bool, error_message=string} "The address book is full, delete an address first"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to save address"
+// @Router /api/v1/user/addresses [post]
+func CreateAddress(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var address model.Address
+		if !bindAddressRequest(ctx, &address) {
+			return
+		}
+
+		user, err := authenticatedUser(ctx, db)
+		if err != nil {
+			handleTokenError(ctx, http.StatusUnauthorized, "Unauthorized access")
+			return
+		}
+
+		if err := repository.CreateAddress(db, user, &address); err != nil {
+			if err.Error() == repository.ADDRESS_BOOK_FULL_ERROR {
+				handleTokenError(ctx, http.StatusConflict, err.Error())
+				return
+			}
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to save address")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusCreated, AddressResponse{Address: &address, Message: "Address added"})
+	}
+}
+
+// UpdateAddress replaces an address of the authenticated user
+// @Summary Update an address
+// @Description Replaces an address in the address book of the authenticated user. Orders that were already placed keep the address they were placed with
+// @Tags Addresses
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Accept		json
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param address_id path string true "Address ID"
+// @Param request body AddressRequest true "Address Request"
+// @Success 200 {object} handler.AddressResponse{address=model.Address, message=string} "Address updated"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid address"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Unauthorized access"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Address not found"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to save address"
+// @Router /api/v1/user/addresses/{address_id} [put]
+func UpdateAddress(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, err := authenticatedUser(ctx, db)
+		if err != nil {
+			handleTokenError(ctx, http.StatusUnauthorized, "Unauthorized access")
+			return
+		}
+
+		address, err := repository.FindAddress(db, user, ctx.Param("address_id"))
+		if err != nil {
+			handleAddressLookupError(ctx, err, "Unable to save address")
+			return
+		}
+
+		if !bindAddressRequest(ctx, address) {
+			return
+		}
+
+		if err := repository.UpdateAddress(db, user, address); err != nil {
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to save address")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, AddressResponse{Address: address, Message: "Address updated"})
+	}
+}
+
+// DeleteAddress removes an address of the authenticated user
+// @Summary Delete an address
+// @Description Removes an address from the address book of the authenticated user. Orders that were already placed keep the address they were placed with
+// @Tags Addresses
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param address_id path string true "Address ID"
+// @Success 200 {object} util.ErrorResponse{error=bool, error_message=string} "Address deleted"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Unauthorized access"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Address not found"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to delete address"
+// @Router /api/v1/user/addresses/{address_id} [delete]
+func DeleteAddress(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, err := authenticatedUser(ctx, db)
+		if err != nil {
+			handleTokenError(ctx, http.StatusUnauthorized, "Unauthorized access")
+			return
+		}
+
+		if err := repository.DeleteAddress(db, user, ctx.Param("address_id")); err != nil {
+			handleAddressLookupError(ctx, err, "Unable to delete address")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, util.ErrorResponse{Error: false, ErrorMessage: "Address deleted"})
+	}
+}
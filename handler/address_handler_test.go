@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+)
+
+const (
+	TEST_ADDRESS_ID                = "test_address_id"
+	ADDRESSES                      = "/user/addresses"
+	SELECT_ADDRESS_QUERY           = "SELECT * FROM `addresses` WHERE (address_guid = ? AND user_id = ?) ORDER BY `addresses`.`id` ASC LIMIT 1"
+	SELECT_DEFAULT_ADDRESSES_QUERY = "SELECT * FROM `addresses` WHERE (user_id = ? AND (is_default_shipping = ? OR is_default_billing = ?))"
+)
+
+func createAddressRequest() AddressRequest {
+	return AddressRequest{
+		Label:      "Home",
+		FullName:   "Jane Doe",
+		Line1:      "12 Admiralty Way",
+		City:       "Lagos",
+		PostalCode: "106104",
+		Country:    "ng",
+	}
+}
+
+func newAddressTestContext(method, addressID string, body any, t *testing.T) (*httptest.ResponseRecorder, *gin.Context) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, ADDRESSES, generateRequestBody(t, body))
+	c.Request.Header.Set(CONTEXT_TYPE, APPLICATION_JSON)
+	c.Params = gin.Params{{Key: "address_id", Value: addressID}}
+	c.Set("user_id", TEST_USER_ID)
+	return w, c
+}
+
+func expectAddressBookOwner(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_USER_QUERY)).
+		WithArgs(TEST_USER_ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_guid"}).AddRow(1, TEST_USER_ID))
+}
+
+// The first address of a user becomes the default shipping and billing address
+func TestCreateFirstAddress(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	expectAddressBookOwner(mock)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `addresses` WHERE (user_id = ?)")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `addresses`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	w, c := newAddressTestContext("POST", "", createAddressRequest(), t)
+	CreateAddress(gdb)(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response struct {
+		Address struct {
+			AddressID         string `json:"address_id"`
+			Country           string `json:"country"`
+			IsDefaultShipping bool   `json:"is_default_shipping"`
+			IsDefaultBilling  bool   `json:"is_default_billing"`
+		} `json:"address"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.NotEmpty(t, response.Address.AddressID)
+	assert.Equal(t, "NG", response.Address.Country, "The country code should be normalized")
+	assert.True(t, response.Address.IsDefaultShipping)
+	assert.True(t, response.Address.IsDefaultBilling)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// A new default address takes the default role away from the previous one
+func TestCreateDefaultAddressReplacesDefault(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	expectAddressBookOwner(mock)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `addresses` WHERE (user_id = ?)")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `addresses` SET `is_default_billing` = ? WHERE (user_id = ? AND id <> ? AND is_default_billing = ?)")).
+		WithArgs(false, 1, 0, true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `addresses`")).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectCommit()
+
+	addressRequest := createAddressRequest()
+	addressRequest.IsDefaultBilling = true
+	w, c := newAddressTestContext("POST", "", addressRequest, t)
+	CreateAddress(gdb)(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Countries and postal codes are validated before anything is stored
+func TestCreateAddressValidation(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	invalidCountry := createAddressRequest()
+	invalidCountry.Country = "UK"
+	w, c := newAddressTestContext("POST", "", invalidCountry, t)
+	CreateAddress(gdb)(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), INVALID_COUNTRY_ERROR)
+
+	invalidPostalCode := createAddressRequest()
+	invalidPostalCode.Country = "US"
+	invalidPostalCode.PostalCode = "1234"
+	w, c = newAddressTestContext("POST", "", invalidPostalCode, t)
+	CreateAddress(gdb)(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid postal code")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// The address book of another user is out of reach
+func TestUpdateAddressNotFound(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	expectAddressBookOwner(mock)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ADDRESS_QUERY)).
+		WithArgs("other_address_id", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	w, c := newAddressTestContext("PUT", "other_address_id", createAddressRequest(), t)
+	UpdateAddress(gdb)(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), repository.ADDRESS_NOT_FOUND_ERROR)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteAddress(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	expectAddressBookOwner(mock)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `addresses` WHERE (address_guid = ? AND user_id = ?)")).
+		WithArgs(TEST_ADDRESS_ID, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	w, c := newAddressTestContext("DELETE", TEST_ADDRESS_ID, nil, t)
+	DeleteAddress(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Address deleted")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Orders cannot be placed without an address to ship them to
+func TestPlaceOrderWithoutAddress(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ORDER_QUERY)).
+		WithArgs(TEST_ORDER_REF).
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_DEFAULT_ADDRESSES_QUERY)).
+		WithArgs(1, true, true).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	w, c := createTestContext(createOrderRequest(), ORDER_ENDPOINT, t)
+	withPrincipal(c, &model.User{ID: 1, UserID: TEST_USER_ID})
+	PlaceOrder(gdb)(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), MISSING_ADDRESS)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Addresses of other users cannot be shipped to
+func TestPlaceOrderUnknownAddress(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ORDER_QUERY)).
+		WithArgs(TEST_ORDER_REF).
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ADDRESS_QUERY)).
+		WithArgs("other_address_id", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	orderRequest := createOrderRequest()
+	orderRequest.ShippingAddressID = "other_address_id"
+	orderRequest.BillingAddressID = "other_address_id"
+	w, c := createTestContext(orderRequest, ORDER_ENDPOINT, t)
+	withPrincipal(c, &model.User{ID: 1, UserID: TEST_USER_ID})
+	PlaceOrder(gdb)(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), repository.ADDRESS_NOT_FOUND_ERROR)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,394 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+type UpdateUserRoleRequest struct {
+	Role string `json:"user_role" binding:"required"`
+}
+
+// AdminUser is a user as seen by an administrator
+type AdminUser struct {
+	*model.User
+	IsActive bool `json:"is_active"`
+}
+
+type ListUserResponse struct {
+	Users      []AdminUser `json:"users"`
+	Message    string      `json:"message"`
+	TotalUsers int         `json:"total_users"`
+	TotalPages int         `json:"total_pages"`
+	Page       int         `json:"page"`
+	Size       int         `json:"size"`
+}
+
+type AdminUserResponse struct {
+	User         AdminUser           `json:"user"`
+	OrderSummary *model.OrderSummary `json:"order_summary,omitempty"`
+	Message      string              `json:"message"`
+}
+
+// handleAdminUserError is a helper function to send a consistent error
+// response from the user management endpoints
+func handleAdminUserError(ctx *gin.Context, statusCode int, message string, err error) {
+	if err != nil {
+		log.Println(err.Error())
+	}
+	util.LogAndHandleResponse(ctx, statusCode, util.ErrorResponse{Error: true, ErrorMessage: message})
+}
+
+// UnlockUser lifts a login lockout on a user account
+// @Summary Unlock a user account
+// @Description Clears the failed login attempts of a user so that they can log in again immediately
+// @Tags Users
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param user_id path string true "User ID"
+// @Success 200 {object} util.ErrorResponse{error=bool, error_message=string} "User account unlocked"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "User not found"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to unlock user"
+// @Router /api/v1/admin/users/{user_id}/lock [delete]
+func UnlockUser(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, err := repository.FindUserBy(db, "user_guid", ctx.Param("user_id"))
+		if err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				handleAdminUserError(ctx, http.StatusNotFound, USER_NOT_FOUND_ERROR, nil)
+				return
+			}
+			handleAdminUserError(ctx, http.StatusInternalServerError, "Unable to unlock user", err)
+			return
+		}
+
+		if err := repository.UnlockAccount(db, user); err != nil {
+			handleAdminUserError(ctx, http.StatusInternalServerError, "Unable to unlock user", err)
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, util.ErrorResponse{Error: false, ErrorMessage: "User account unlocked"})
+	}
+}
+
+func newAdminUser(user *model.User) AdminUser {
+	return AdminUser{User: user, IsActive: !user.IsDeleted}
+}
+
+// findManagedUser loads the user named in the path, including deactivated
+// users, and responds with an error when the user cannot be loaded
+func findManagedUser(ctx *gin.Context, db *gorm.DB) (*model.User, bool) {
+	user, err := repository.FindAnyUser(db, ctx.Param("user_id"))
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			handleAdminUserError(ctx, http.StatusNotFound, USER_NOT_FOUND_ERROR, nil)
+			return nil, false
+		}
+		handleAdminUserError(ctx, http.StatusInternalServerError, "Unable to retrieve user", err)
+		return nil, false
+	}
+	return user, true
+}
+
+// isSelf reports whether the user is the administrator making the request,
+// who must not be able to lock themselves out
+func isSelf(ctx *gin.Context, user *model.User) bool {
+	adminID, _ := ctx.Get("user_id")
+	return adminID == user.UserID
+}
+
+// ListUsers lists and searches users
+// @Summary List users
+// @Description Lists users with an optional search on email and name, role and status filter
+// @Tags Users
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param search query string false "Search on email, first name and last name"
+// @Param user_role query string false "Role (admin, user)"
+// @Param status query string false "Status (active, deactivated, all) (Default active)"
+// @Param page query string false "Page (Default 1)"
+// @Param size query string false "Size (Default 10)"
+// @Success 200 {object} handler.ListUserResponse{users=[]handler.AdminUser, message=string, total_users=int, total_pages=int, page=int, size=int} "List of users"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Failed to retrieve users"
+// @Router /api/v1/admin/users [get]
+func ListUsers(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+		if err != nil || page <= 0 {
+			page = 1
+		}
+
+		limit, err := strconv.Atoi(ctx.DefaultQuery("size", "10"))
+		if err != nil || limit <= 0 {
+			limit = 10
+		}
+
+		filter := repository.UserFilter{
+			Search: ctx.Query("search"),
+			Role:   ctx.Query("user_role"),
+			Status: ctx.Query("status"),
+		}
+
+		users, totalUsers, err := repository.ListUsers(db, filter, page, limit)
+		if err != nil {
+			handleAdminUserError(ctx, http.StatusInternalServerError, "Failed to retrieve users", err)
+			return
+		}
+
+		adminUsers := make([]AdminUser, 0, len(users))
+		for _, user := range users {
+			adminUsers = append(adminUsers, newAdminUser(user))
+		}
+
+		message := "Users retrieved successfully"
+		if len(users) == 0 {
+			message = "No users found"
+		}
+
+		response := ListUserResponse{
+			Users:      adminUsers,
+			Message:    message,
+			TotalUsers: totalUsers,
+			TotalPages: int(math.Ceil(float64(totalUsers) / float64(limit))),
+			Page:       page,
+			Size:       limit,
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, response)
+	}
+}
+
+// GetUser returns a user together with a summary of their orders
+// @Summary Get a user
+// @Description Returns a user, including deactivated users, with a summary of their orders
+// @Tags Users
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param user_id path string true "User ID"
+// @Success 200 {object} handler.AdminUserResponse{user=handler.AdminUser, order_summary=model.OrderSummary, message=string} "User details"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "User not found"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to retrieve user"
+// @Router /api/v1/admin/users/{user_id} [get]
+func GetUser(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := findManagedUser(ctx, db)
+		if !ok {
+			return
+		}
+
+		summary, err := repository.GetUserOrderSummary(db, user)
+		if err != nil {
+			handleAdminUserError(ctx, http.StatusInternalServerError, "Unable to retrieve user", err)
+			return
+		}
+
+		response := AdminUserResponse{User: newAdminUser(user), OrderSummary: summary, Message: "User retrieved successfully"}
+		util.LogAndHandleResponse(ctx, http.StatusOK, response)
+	}
+}
+
+// UpdateUserRole promotes or demotes a user
+// @Summary Change the role of a user
+// @Description Assigns one of the roles defined in the database to a user. The user has to log in again for the change to apply
+// @Tags Users
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param user_id path string true "User ID"
+// @Param role body UpdateUserRoleRequest true "Update User Role Request"
+// @Success 200 {object} handler.AdminUserResponse{user=handler.AdminUser, message=string} "User role updated"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid input or unknown role"
+// @Failure 403 {object} util.ErrorResponse{error=bool, error_message=string} "Administrators cannot change their own role"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "User not found"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to update user role"
+// @Router /api/v1/admin/users/{user_id}/role [put]
+func UpdateUserRole(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var roleRequest UpdateUserRoleRequest
+		if err := ctx.ShouldBindJSON(&roleRequest); err != nil {
+			validationError := util.ExtractValidationErrorMessage(err, roleRequest)
+			handleAdminUserError(ctx, http.StatusBadRequest, validationError[0], nil)
+			return
+		}
+
+		user, ok := findManagedUser(ctx, db)
+		if !ok {
+			return
+		}
+
+		if isSelf(ctx, user) {
+			handleAdminUserError(ctx, http.StatusForbidden, "Administrators cannot change their own role", nil)
+			return
+		}
+
+		if _, err := repository.FindRole(db, roleRequest.Role); err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				handleAdminUserError(ctx, http.StatusBadRequest, "Unknown role", nil)
+				return
+			}
+			handleAdminUserError(ctx, http.StatusInternalServerError, "Unable to update user role", err)
+			return
+		}
+
+		previousRole := user.Role
+		if err := repository.SetUserRole(db, user, model.Role(roleRequest.Role)); err != nil {
+			handleAdminUserError(ctx, http.StatusInternalServerError, "Unable to update user role", err)
+			return
+		}
+
+		recordAuthEvent(ctx, db, model.RoleChangedEvent, user, fmt.Sprintf("%s -> %s", previousRole, roleRequest.Role))
+
+		user.Role = model.Role(roleRequest.Role)
+		util.LogAndHandleResponse(ctx, http.StatusOK, AdminUserResponse{User: newAdminUser(user), Message: "User role updated"})
+	}
+}
+
+// setUserActive is the shared implementation of DeactivateUser and ReactivateUser
+func setUserActive(db *gorm.DB, active bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := findManagedUser(ctx, db)
+		if !ok {
+			return
+		}
+
+		if isSelf(ctx, user) {
+			handleAdminUserError(ctx, http.StatusForbidden, "Administrators cannot change their own status", nil)
+			return
+		}
+
+		if err := repository.SetUserActive(db, user, active); err != nil {
+			if err.Error() == repository.USER_ERASED_ERROR {
+				handleAdminUserError(ctx, http.StatusConflict, err.Error(), nil)
+				return
+			}
+			handleAdminUserError(ctx, http.StatusInternalServerError, "Unable to update user status", err)
+			return
+		}
+
+		user.IsDeleted = !active
+		message := "User deactivated"
+		if active {
+			message = "User reactivated"
+		} else {
+			recordAuthEvent(ctx, db, model.TokensRevokedEvent, user, "deactivated")
+		}
+		util.LogAndHandleResponse(ctx, http.StatusOK, AdminUserResponse{User: newAdminUser(user), Message: message})
+	}
+}
+
+// DeactivateUser deactivates a user account
+// @Summary Deactivate a user
+// @Description Deactivates a user account and revokes all of its tokens. Orders of the user are kept
+// @Tags Users
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param user_id path string true "User ID"
+// @Success 200 {object} handler.AdminUserResponse{user=handler.AdminUser, message=string} "User deactivated"
+// @Failure 403 {object} util.ErrorResponse{error=bool, error_message=string} "Administrators cannot change their own status"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "User not found"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to update user status"
+// @Router /api/v1/admin/users/{user_id}/deactivate [post]
+func DeactivateUser(db *gorm.DB) gin.HandlerFunc {
+	return setUserActive(db, false)
+}
+
+// ReactivateUser reactivates a deactivated user account
+// @Summary Reactivate a user
+// @Description Reactivates a deactivated user account. Users whose personal data was erased cannot be reactivated
+// @Tags Users
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param user_id path string true "User ID"
+// @Success 200 {object} handler.AdminUserResponse{user=handler.AdminUser, message=string} "User reactivated"
+// @Failure 403 {object} util.ErrorResponse{error=bool, error_message=string} "Administrators cannot change their own status"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "User not found"
+// @Failure 409 {object} util.ErrorResponse{error=bool, error_message=string} "The personal data of this user has been erased"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to update user status"
+// @Router /api/v1/admin/users/{user_id}/reactivate [post]
+func ReactivateUser(db *gorm.DB) gin.HandlerFunc {
+	return setUserActive(db, true)
+}
+
+// ForcePasswordReset invalidates the password of a user and emails a reset link
+// @Summary Force a password reset
+// @Description Replaces the password of a user with an unusable one, revokes all of their tokens and emails them a password reset token
+// @Tags Users
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param user_id path string true "User ID"
+// @Success 200 {object} util.ErrorResponse{error=bool, error_message=string} "Password reset forced"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "User not found"
+// @Failure 409 {object} util.ErrorResponse{error=bool, error_message=string} "User account is deactivated"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to force password reset"
+// @Router /api/v1/admin/users/{user_id}/password/reset [post]
+func ForcePasswordReset(db *gorm.DB, mailer util.Mailer) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := findManagedUser(ctx, db)
+		if !ok {
+			return
+		}
+
+		// nobody knows the random password, so the account can only be used again after a reset
+		randomPassword, err := util.GenerateSecureToken(32)
+		if err != nil {
+			handleAdminUserError(ctx, http.StatusInternalServerError, "Unable to force password reset", err)
+			return
+		}
+
+		unusablePassword, err := hashPassword(randomPassword)
+		if err != nil {
+			handleAdminUserError(ctx, http.StatusInternalServerError, "Unable to force password reset", err)
+			return
+		}
+
+		rawToken, err := repository.ForcePasswordReset(db, user, unusablePassword)
+		if err != nil {
+			if err.Error() == repository.USER_DEACTIVATED_ERROR {
+				handleAdminUserError(ctx, http.StatusConflict, err.Error(), nil)
+				return
+			}
+			handleAdminUserError(ctx, http.StatusInternalServerError, "Unable to force password reset", err)
+			return
+		}
+
+		recordAuthEvent(ctx, db, model.PasswordChangedEvent, user, "forced reset")
+		sendMail(mailer, newPasswordResetMail(user, rawToken))
+		util.LogAndHandleResponse(ctx, http.StatusOK, util.ErrorResponse{Error: false, ErrorMessage: "Password reset forced"})
+	}
+}
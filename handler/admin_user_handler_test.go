@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	ADMIN_USERS            = "/api/v1/admin/users"
+	ADMIN_ID               = "admin_user_id"
+	SELECT_ANY_USER_QUERY  = "SELECT * FROM `users` WHERE (user_guid = ?) ORDER BY `users`.`id` ASC LIMIT 1"
+	COUNT_USERS_QUERY      = "SELECT count(*) FROM `users` WHERE (is_deleted = false) AND (email LIKE ? OR first_name LIKE ? OR last_name LIKE ?)"
+	SELECT_USERS_QUERY     = "SELECT * FROM `users` WHERE (is_deleted = false) AND (email LIKE ? OR first_name LIKE ? OR last_name LIKE ?) ORDER BY id ASC LIMIT 10 OFFSET 0"
+	SELECT_ORDER_SUMMARY   = "SELECT order_status, COUNT(*), COALESCE(SUM(total_price), 0), MAX(created_at) FROM `orders` WHERE (user_id = ? AND is_deleted = false) GROUP BY order_status"
+	SELECT_ROLE_QUERY      = "SELECT * FROM `roles` WHERE (name = ?) ORDER BY `roles`.`id` ASC LIMIT 1"
+	CANNOT_CHANGE_OWN_ROLE = "cannot change their own role"
+)
+
+func newAdminTestContext(method, target string, userID string) (*httptest.ResponseRecorder, *gin.Context) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, target, nil)
+	c.Params = gin.Params{{Key: "user_id", Value: userID}}
+	c.Set("user_id", ADMIN_ID)
+	return w, c
+}
+
+func newAdminUserTestDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf(MOCK_ERROR, err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	gdb, err := gorm.Open("mysql", db)
+	if err != nil {
+		t.Fatalf(OPEN_ERROR, err)
+	}
+	return gdb, mock
+}
+
+// Users are searched by email and name with pagination metadata
+func TestListUsers(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(COUNT_USERS_QUERY)).
+		WithArgs("%test%", "%test%", "%test%").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(11))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_USERS_QUERY)).
+		WithArgs("%test%", "%test%", "%test%").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "user_guid"}).AddRow(1, EMAIL, TEST_USER_ID))
+
+	w, c := newAdminTestContext("GET", ADMIN_USERS+"?search=test", "")
+	ListUsers(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total_pages":2`)
+	assert.Contains(t, w.Body.String(), `"is_active":true`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// A user is returned with a summary of their orders
+func TestGetUserWithOrderSummary(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ANY_USER_QUERY)).
+		WithArgs(TEST_USER_ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "user_guid", "is_deleted"}).AddRow(1, EMAIL, TEST_USER_ID, true))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ORDER_SUMMARY)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"order_status", "count", "sum", "max"}).
+			AddRow("Delivered", 2, "30.50", time.Now()).
+			AddRow("Cancelled", 1, "10.00", time.Now()))
+
+	w, c := newAdminTestContext("GET", ADMIN_USERS+"/"+TEST_USER_ID, TEST_USER_ID)
+	GetUser(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total_orders":3`)
+	assert.Contains(t, w.Body.String(), `"total_spent":"30.5"`)
+	assert.Contains(t, w.Body.String(), `"is_active":false`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// An unknown user returns 404
+func TestGetUserNotFound(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ANY_USER_QUERY)).
+		WithArgs(TEST_USER_ID).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	w, c := newAdminTestContext("GET", ADMIN_USERS+"/"+TEST_USER_ID, TEST_USER_ID)
+	GetUser(gdb)(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), USER_NOT_FOUND_ERROR)
+}
+
+// An administrator cannot demote themselves
+func TestUpdateUserRoleSelf(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ANY_USER_QUERY)).
+		WithArgs(ADMIN_ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "user_guid", "role"}).AddRow(1, EMAIL, ADMIN_ID, "admin"))
+
+	w, c := createTestContext(UpdateUserRoleRequest{Role: "user"}, ADMIN_USERS+"/"+ADMIN_ID+"/role", t)
+	c.Params = gin.Params{{Key: "user_id", Value: ADMIN_ID}}
+	c.Set("user_id", ADMIN_ID)
+	UpdateUserRole(gdb)(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), CANNOT_CHANGE_OWN_ROLE)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// A role that is not defined in the database is rejected
+func TestUpdateUserRoleUnknownRole(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ANY_USER_QUERY)).
+		WithArgs(TEST_USER_ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "user_guid", "role"}).AddRow(1, EMAIL, TEST_USER_ID, "user"))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ROLE_QUERY)).
+		WithArgs("superuser").
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	w, c := createTestContext(UpdateUserRoleRequest{Role: "superuser"}, ADMIN_USERS+"/"+TEST_USER_ID+"/role", t)
+	c.Params = gin.Params{{Key: "user_id", Value: TEST_USER_ID}}
+	c.Set("user_id", ADMIN_ID)
+	UpdateUserRole(gdb)(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Unknown role")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Deactivating a user logs them out everywhere, which goes to the audit log
+func TestDeactivateUserRecordsTokenRevocation(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ANY_USER_QUERY)).
+		WithArgs(TEST_USER_ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "user_guid", "role"}).AddRow(2, EMAIL, TEST_USER_ID, "user"))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `is_deleted` = ?")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `user_token_revocations`")).
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `user_token_revocations`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `refresh_tokens`")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `sessions`")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(INSERT_AUTH_EVENT_QUERY)).
+		WithArgs("tokens_revoked", TEST_USER_ID, ADMIN_ID, EMAIL, sqlmock.AnyArg(), sqlmock.AnyArg(), "deactivated", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	w, c := newAdminTestContext("POST", ADMIN_USERS+"/"+TEST_USER_ID+"/deactivate", TEST_USER_ID)
+	DeactivateUser(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const API_KEY_CREATED = "API key created, store it now as it will not be shown again"
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=255"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type APIKeyResponse struct {
+	APIKey  *model.APIKey `json:"api_key"`
+	Key     string        `json:"key,omitempty"`
+	Message string        `json:"message"`
+}
+
+// CreateAPIKey issues an API key for a machine client
+// @Summary Create an API key
+// @Description Issues an API key limited to the given scopes, which must be permissions the caller holds. The key is only returned in this response
+// @Tags API Keys
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param request body CreateAPIKeyRequest true "Create API Key Request"
+// @Success 201 {object} handler.APIKeyResponse{api_key=model.APIKey, key=string, message=string} "API key created"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid input"
+// @Failure 403 {object} util.ErrorResponse{error=bool, error_message=string} "Scope not held by the caller"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to create API key"
+// @Router /api/v1/admin/api-keys [post]
+func CreateAPIKey(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var keyRequest CreateAPIKeyRequest
+		if err := ctx.ShouldBindJSON(&keyRequest); err != nil {
+			validationError := util.ExtractValidationErrorMessage(err, keyRequest)
+			handleAdminUserError(ctx, http.StatusBadRequest, validationError[0], nil)
+			return
+		}
+
+		if keyRequest.ExpiresAt != nil && !keyRequest.ExpiresAt.After(time.Now()) {
+			handleAdminUserError(ctx, http.StatusBadRequest, "'expires_at' must be in the future", nil)
+			return
+		}
+
+		owner, err := authenticatedUser(ctx, db)
+		if err != nil {
+			handleAdminUserError(ctx, http.StatusUnauthorized, "Unauthorized access", nil)
+			return
+		}
+
+		// a key can never do more than the admin who created it
+		granted, err := repository.RolePermissions(db, string(owner.Role))
+		if err != nil {
+			handleAdminUserError(ctx, http.StatusInternalServerError, "Unable to create API key", err)
+			return
+		}
+
+		for _, scope := range keyRequest.Scopes {
+			if !slices.Contains(granted, scope) {
+				handleAdminUserError(ctx, http.StatusForbidden, "You cannot grant the scope "+scope, nil)
+				return
+			}
+		}
+
+		rawKey, apiKey, err := repository.CreateAPIKey(db, owner, keyRequest.Name, keyRequest.Scopes, keyRequest.ExpiresAt)
+		if err != nil {
+			handleAdminUserError(ctx, http.StatusInternalServerError, "Unable to create API key", err)
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusCreated, APIKeyResponse{APIKey: apiKey, Key: rawKey, Message: API_KEY_CREATED})
+	}
+}
+
+// ListAPIKeys lists the API keys
+// @Summary List API keys
+// @Description Lists every API key with its prefix, scopes, expiry and last use. The keys themselves are never returned
+// @Tags API Keys
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {array} model.APIKey "List of API keys"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Failed to retrieve API keys"
+// @Router /api/v1/admin/api-keys [get]
+func ListAPIKeys(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		apiKeys, err := repository.ListAPIKeys(db)
+		if err != nil {
+			handleAdminUserError(ctx, http.StatusInternalServerError, "Failed to retrieve API keys", err)
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, apiKeys)
+	}
+}
+
+// RevokeAPIKey revokes an API key
+// @Summary Revoke an API key
+// @Description Revokes an API key so that it is rejected from now on
+// @Tags API Keys
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param api_key_id path int true "API Key ID"
+// @Success 200 {object} handler.APIKeyResponse{api_key=model.APIKey, message=string} "API key revoked"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "API key not found"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to revoke API key"
+// @Router /api/v1/admin/api-keys/{api_key_id} [delete]
+func RevokeAPIKey(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := strconv.ParseUint(ctx.Param("api_key_id"), 10, 64)
+		if err != nil {
+			handleAdminUserError(ctx, http.StatusNotFound, "API key not found", nil)
+			return
+		}
+
+		apiKey, err := repository.RevokeAPIKey(db, uint(id))
+		if err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				handleAdminUserError(ctx, http.StatusNotFound, "API key not found", nil)
+				return
+			}
+			handleAdminUserError(ctx, http.StatusInternalServerError, "Unable to revoke API key", err)
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, APIKeyResponse{APIKey: apiKey, Message: "API key revoked"})
+	}
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	API_KEYS                = "/api/v1/admin/api-keys"
+	SELECT_ROLE_PERMISSIONS = "SELECT permissions.name FROM `permissions` INNER JOIN role_permissions ON role_permissions.permission_id = permissions.id INNER JOIN roles ON roles.id = role_permissions.role_id WHERE (roles.name = ?)"
+)
+
+// An API key cannot be granted a scope its creator does not hold
+func TestCreateAPIKeyScopeNotHeld(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	repository.InvalidateRolePermissions("support")
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_USER_QUERY)).
+		WithArgs(TEST_USER_ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_guid", "role"}).AddRow(1, TEST_USER_ID, "support"))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ROLE_PERMISSIONS)).
+		WithArgs("support").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("orders:read").AddRow("api_keys:manage"))
+
+	request := CreateAPIKeyRequest{Name: "warehouse", Scopes: []string{"orders:read", "products:write"}}
+	w, c := createTestContext(request, API_KEYS, t)
+	c.Set("user_id", TEST_USER_ID)
+	CreateAPIKey(gdb)(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "products:write")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Scopes are required
+func TestCreateAPIKeyWithoutScopes(t *testing.T) {
+	gdb, _ := newAdminUserTestDB(t)
+
+	w, c := createTestContext(CreateAPIKeyRequest{Name: "warehouse"}, API_KEYS, t)
+	CreateAPIKey(gdb)(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
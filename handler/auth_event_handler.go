@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const DATE_LAYOUT = "2006-01-02"
+
+type ListAuthEventsResponse struct {
+	Events      []model.AuthEvent `json:"events"`
+	Message     string            `json:"message"`
+	TotalEvents int               `json:"total_events"`
+	TotalPages  int               `json:"total_pages"`
+	Page        int               `json:"page"`
+	Size        int               `json:"size"`
+}
+
+// recordAuthEvent writes an event about user to the audit log. The actor is
+// the authenticated user of the request, or user when nobody is logged in.
+// The request goes on when the event cannot be written
+func recordAuthEvent(ctx *gin.Context, db *gorm.DB, eventType model.AuthEventType, user *model.User, details string) {
+	actorID, _ := ctx.Get("user_id")
+	event := model.AuthEvent{Type: eventType, UserID: user.UserID, ActorID: user.UserID, Email: user.Email, Details: details}
+	if actorID, ok := actorID.(string); ok && actorID != "" {
+		event.ActorID = actorID
+	}
+
+	if err := repository.RecordAuthEvent(db, event, requestClient(ctx)); err != nil {
+		log.Println("unable to record auth event:", err)
+	}
+}
+
+// recordLoginEvent writes a login event for an email that may not belong to any account
+func recordLoginEvent(ctx *gin.Context, db *gorm.DB, eventType model.AuthEventType, email, details string) {
+	event := model.AuthEvent{Type: eventType, Email: email, Details: details}
+	if err := repository.RecordAuthEvent(db, event, requestClient(ctx)); err != nil {
+		log.Println("unable to record auth event:", err)
+	}
+}
+
+// parseEventTime reads a query parameter given as RFC 3339 time or as a date.
+// Dates of the end of a range include the whole day
+func parseEventTime(value string, endOfRange bool) (*time.Time, bool) {
+	if value == "" {
+		return nil, true
+	}
+
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return &parsed, true
+	}
+
+	parsed, err := time.Parse(DATE_LAYOUT, value)
+	if err != nil {
+		return nil, false
+	}
+
+	if endOfRange {
+		parsed = parsed.AddDate(0, 0, 1)
+	}
+	return &parsed, true
+}
+
+// ListAuthEvents queries the authentication audit log
+// @Summary List authentication events
+// @Description Lists signups, logins, failed logins, lockouts, password changes, token revocations and role changes, newest first. Filtering by user returns the events about the user, the events caused by them and failed logins with their email address
+// @Tags Users
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param user_id query string false "User ID"
+// @Param event_type query string false "Event type (signup, login_succeeded, login_failed, login_locked_out, password_changed, tokens_revoked, role_changed, role_permissions_changed)"
+// @Param from query string false "Start of the range, RFC 3339 time or date (YYYY-MM-DD)"
+// @Param to query string false "End of the range, RFC 3339 time (exclusive) or date (inclusive)"
+// @Param page query string false "Page (Default 1)"
+// @Param size query string false "Size (Default 10)"
+// @Success 200 {object} handler.ListAuthEventsResponse{events=[]model.AuthEvent, message=string, total_events=int, total_pages=int, page=int, size=int} "List of authentication events"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid filter"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Failed to retrieve authentication events"
+// @Router /api/v1/admin/auth-events [get]
+func ListAuthEvents(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+		if err != nil || page <= 0 {
+			page = 1
+		}
+
+		limit, err := strconv.Atoi(ctx.DefaultQuery("size", "10"))
+		if err != nil || limit <= 0 {
+			limit = 10
+		}
+
+		filter := repository.AuthEventFilter{
+			UserID: ctx.Query("user_id"),
+			Type:   ctx.Query("event_type"),
+		}
+
+		if filter.Type != "" && !slices.Contains(model.AuthEvents, model.AuthEventType(filter.Type)) {
+			handleAdminUserError(ctx, http.StatusBadRequest, "Unknown event type", nil)
+			return
+		}
+
+		var ok bool
+		if filter.From, ok = parseEventTime(ctx.Query("from"), false); !ok {
+			handleAdminUserError(ctx, http.StatusBadRequest, "from must be a date (YYYY-MM-DD) or an RFC 3339 time", nil)
+			return
+		}
+
+		if filter.To, ok = parseEventTime(ctx.Query("to"), true); !ok {
+			handleAdminUserError(ctx, http.StatusBadRequest, "to must be a date (YYYY-MM-DD) or an RFC 3339 time", nil)
+			return
+		}
+
+		// failed logins are only linked to the user by their email address
+		if filter.UserID != "" {
+			user, err := repository.FindAnyUser(db, filter.UserID)
+			if isNotRecordNotFoundError(err) {
+				handleAdminUserError(ctx, http.StatusInternalServerError, "Failed to retrieve authentication events", err)
+				return
+			}
+			if user != nil {
+				filter.Email = user.Email
+			}
+		}
+
+		events, totalEvents, err := repository.ListAuthEvents(db, filter, page, limit)
+		if err != nil {
+			handleAdminUserError(ctx, http.StatusInternalServerError, "Failed to retrieve authentication events", err)
+			return
+		}
+
+		message := "Authentication events retrieved successfully"
+		if len(events) == 0 {
+			events = []model.AuthEvent{}
+			message = "No authentication events found"
+		}
+
+		response := ListAuthEventsResponse{
+			Events:      events,
+			Message:     message,
+			TotalEvents: totalEvents,
+			TotalPages:  int(math.Ceil(float64(totalEvents) / float64(limit))),
+			Page:        page,
+			Size:        limit,
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, response)
+	}
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hackdaemon2/instashop/model"
+)
+
+const (
+	ADMIN_AUTH_EVENTS         = "/api/v1/admin/auth-events"
+	INSERT_AUTH_EVENT_QUERY   = "INSERT INTO `auth_events` (`event_type`,`user_guid`,`actor_guid`,`email`,`ip_address`,`user_agent`,`details`,`created_at`) VALUES (?,?,?,?,?,?,?,?)"
+	COUNT_USER_EVENTS_QUERY   = "SELECT count(*) FROM `auth_events` WHERE (user_guid = ? OR actor_guid = ? OR (user_guid = '' AND email = ?)) AND (event_type = ?) AND (created_at >= ?) AND (created_at < ?)"
+	SELECT_USER_EVENTS_QUERY  = "SELECT * FROM `auth_events` WHERE (user_guid = ? OR actor_guid = ? OR (user_guid = '' AND email = ?)) AND (event_type = ?) AND (created_at >= ?) AND (created_at < ?) ORDER BY created_at DESC, id DESC LIMIT 10 OFFSET 0"
+	COUNT_ALL_EVENTS_QUERY    = "SELECT count(*) FROM `auth_events`"
+	SELECT_ALL_EVENTS_QUERY   = "SELECT * FROM `auth_events` ORDER BY created_at DESC, id DESC LIMIT 10 OFFSET 0"
+	AUTH_EVENT_USER_AGENT     = "audit-test/1.0"
+	AUTH_EVENT_REMOTE_ADDRESS = "192.0.2.1"
+)
+
+// expectAuthEvent expects an event of eventType to be written to the audit log
+func expectAuthEvent(mock sqlmock.Sqlmock, eventType model.AuthEventType) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(INSERT_AUTH_EVENT_QUERY)).
+		WithArgs(string(eventType), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+}
+
+// Events caused by an administrator name them as the actor and keep the device
+func TestRecordAuthEventByAdministrator(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(INSERT_AUTH_EVENT_QUERY)).
+		WithArgs("role_changed", TEST_USER_ID, ADMIN_ID, EMAIL, AUTH_EVENT_REMOTE_ADDRESS, AUTH_EVENT_USER_AGENT, "user -> support", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	_, c := newAdminTestContext("PUT", ADMIN_USERS+"/"+TEST_USER_ID+"/role", TEST_USER_ID)
+	c.Request.RemoteAddr = AUTH_EVENT_REMOTE_ADDRESS + ":1234"
+	c.Request.Header.Set("User-Agent", AUTH_EVENT_USER_AGENT)
+
+	user := &model.User{UserID: TEST_USER_ID, Email: EMAIL}
+	recordAuthEvent(c, gdb, model.RoleChangedEvent, user, "user -> support")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Failing to write the audit log does not fail the request
+func TestRecordAuthEventFailureIsIgnored(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(INSERT_AUTH_EVENT_QUERY)).
+		WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", LOGIN, nil)
+
+	recordLoginEvent(c, gdb, model.LoginFailedEvent, EMAIL, "password")
+
+	assert.False(t, c.IsAborted())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListAuthEventsForUser(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ANY_USER_QUERY)).
+		WithArgs(TEST_USER_ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "user_guid"}).AddRow(1, EMAIL, TEST_USER_ID))
+	mock.ExpectQuery(regexp.QuoteMeta(COUNT_USER_EVENTS_QUERY)).
+		WithArgs(TEST_USER_ID, TEST_USER_ID, EMAIL, "login_failed", from, to.AddDate(0, 0, 1)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_USER_EVENTS_QUERY)).
+		WithArgs(TEST_USER_ID, TEST_USER_ID, EMAIL, "login_failed", from, to.AddDate(0, 0, 1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "event_type", "user_guid", "email", "details"}).
+			AddRow(3, "login_failed", "", EMAIL, "password"))
+
+	target := ADMIN_AUTH_EVENTS + "?user_id=" + TEST_USER_ID + "&event_type=login_failed&from=2024-01-01&to=2024-01-31"
+	w, c := newAdminTestContext("GET", target, "")
+	ListAuthEvents(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"event_type":"login_failed"`)
+	assert.Contains(t, w.Body.String(), `"total_events":1`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListAuthEventsEmpty(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(COUNT_ALL_EVENTS_QUERY)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ALL_EVENTS_QUERY)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	w, c := newAdminTestContext("GET", ADMIN_AUTH_EVENTS, "")
+	ListAuthEvents(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"events":[]`)
+	assert.Contains(t, w.Body.String(), "No authentication events found")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListAuthEventsInvalidFilter(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	for query, message := range map[string]string{
+		"?event_type=logout":        "Unknown event type",
+		"?from=yesterday":           "from must be a date",
+		"?to=2024-13-01":            "to must be a date",
+		"?from=2024-01-01T00:00:00": "from must be a date",
+	} {
+		w, c := newAdminTestContext("GET", ADMIN_AUTH_EVENTS+query, "")
+		ListAuthEvents(gdb)(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.Contains(t, w.Body.String(), message, query)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
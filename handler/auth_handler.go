@@ -2,21 +2,26 @@ package handler
 
 import (
 	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/middleware"
 	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/password"
 	"github.com/hackdaemon2/instashop/repository"
 	"github.com/hackdaemon2/instashop/util"
 	"github.com/jinzhu/gorm"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type SignupRequest struct {
 	Email           string `json:"email" binding:"required,email"`
-	Password        string `json:"password" binding:"required,min=8"`
+	Password        string `json:"password" binding:"required"`
 	UserCurrency    string `json:"user_currency" binding:"required,min=3,max=3"`
-	ConfirmPassword string `json:"confirm_password" binding:"required,min=8"`
+	ConfirmPassword string `json:"confirm_password" binding:"required"`
 	FirstName       string `json:"first_name" binding:"required"`
 	LastName        string `json:"last_name" binding:"required"`
 }
@@ -26,13 +31,78 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-func hashPassword(password string) (string, error) {
-	// Hash the password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// authenticatedUser returns the user resolved by the authentication
+// middleware. Without a principal the user is looked up by the user_id in the
+// context, as when a handler is called on its own
+func authenticatedUser(ctx *gin.Context, db *gorm.DB) (*model.User, error) {
+	if principal, ok := middleware.CurrentPrincipal(ctx); ok {
+		return principal.User, nil
+	}
+
+	authUserID, _ := ctx.Get("user_id")
+	userID, ok := authUserID.(string)
+	if !ok || userID == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return repository.FindUserBy(db, "user_guid", userID)
+}
+
+// requestClient describes the device a login request came from
+func requestClient(ctx *gin.Context) repository.ClientInfo {
+	return repository.ClientInfo{UserAgent: ctx.Request.UserAgent(), IPAddress: ctx.ClientIP()}
+}
+
+// handleLoginThrottled responds to a login attempt made while the account or
+// the client IP is locked out or still inside its progressive delay
+func handleLoginThrottled(ctx *gin.Context, wait time.Duration, err error) {
+	if err.Error() != repository.TOO_MANY_LOGIN_ATTEMPTS_ERROR {
+		log.Println(err.Error())
+		util.LogAndHandleResponse(ctx, http.StatusInternalServerError, util.ErrorResponse{Error: true, ErrorMessage: "Unable to log in"})
+		return
+	}
+
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	util.LogAndHandleResponse(ctx, http.StatusTooManyRequests, util.ErrorResponse{Error: true, ErrorMessage: err.Error()})
+}
+
+// checkReauthenticationAllowed applies the login limits to the password or
+// code the authenticated user gives again before a sensitive change, so that a
+// stolen access token cannot be used to guess them. It responds itself when
+// the account or the client IP is locked out
+func checkReauthenticationAllowed(ctx *gin.Context, db *gorm.DB, user *model.User, details string) bool {
+	wait, err := repository.CheckLoginAllowed(db, user.Email, ctx.ClientIP())
 	if err != nil {
-		return "", err // Return error if password hashing fails
+		if err.Error() == repository.TOO_MANY_LOGIN_ATTEMPTS_ERROR {
+			recordAuthEvent(ctx, db, model.LoginLockedOutEvent, user, details)
+		}
+		handleLoginThrottled(ctx, wait, err)
+		return false
+	}
+	return true
+}
+
+// recordReauthenticationFailure counts a wrong password or code given before a
+// sensitive change like a failed login
+func recordReauthenticationFailure(ctx *gin.Context, db *gorm.DB, user *model.User, details string) {
+	if err := repository.RecordLoginFailure(db, user.Email, ctx.ClientIP()); err != nil {
+		log.Println("unable to record failed login:", err)
+	}
+	recordAuthEvent(ctx, db, model.LoginFailedEvent, user, details)
+}
+
+// hashPassword hashes a password with the configured algorithm, see the password package
+func hashPassword(plainPassword string) (string, error) {
+	return password.Hash(plainPassword)
+}
+
+// validateNewPassword checks a password that is about to be set against the
+// password policy and responds itself when the password is refused
+func validateNewPassword(ctx *gin.Context, newPassword string) bool {
+	if err := password.Validate(newPassword); err != nil {
+		util.LogAndHandleResponse(ctx, http.StatusBadRequest, util.ErrorResponse{Error: true, ErrorMessage: err.Error()})
+		return false
 	}
-	return string(hashedPassword), nil
+	return true
 }
 
 func newUserFromSignupRequest(signupRequest SignupRequest) (*model.User, error) {
@@ -56,7 +126,7 @@ func newUserFromSignupRequest(signupRequest SignupRequest) (*model.User, error)
 
 // Signup function for user registration
 // @Summary Register a new user
-// @Description Registers a user with the provided details
+// @Description Registers a user with the provided details and emails a link to verify their address
 // @Tags Authentication
 // @Produce		json
 // @Param signup body SignupRequest true "Signup Request"
@@ -64,7 +134,7 @@ func newUserFromSignupRequest(signupRequest SignupRequest) (*model.User, error)
 // @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid input"
 // @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Server error"
 // @Router /api/v1/user/signup [post]
-func Signup(db *gorm.DB) gin.HandlerFunc {
+func Signup(db *gorm.DB, mailer util.Mailer) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var signupRequest SignupRequest
 		if err := ctx.ShouldBindJSON(&signupRequest); err != nil { // Bind JSON and validate input
@@ -83,6 +153,10 @@ func Signup(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		if !validateNewPassword(ctx, signupRequest.Password) {
+			return
+		}
+
 		user, err := newUserFromSignupRequest(signupRequest)
 		if err != nil {
 			util.LogAndHandleResponse(ctx, http.StatusInternalServerError, util.ErrorResponse{Error: true, ErrorMessage: err.Error()})
@@ -96,6 +170,13 @@ func Signup(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		recordAuthEvent(ctx, db, model.SignupEvent, user, "")
+
+		// Send the verification link, the user can request a new one if this fails
+		if err := sendVerificationMail(db, mailer, user); err != nil {
+			log.Println("unable to send verification email:", err)
+		}
+
 		// Send successful response
 		util.LogAndHandleResponse(ctx, http.StatusCreated, UserResponse{User: user, Message: "User successfully registered"})
 	}
@@ -103,13 +184,15 @@ func Signup(db *gorm.DB) gin.HandlerFunc {
 
 // Login function for user authentication
 // @Summary Authenticate a user
-// @Description Authenticates a user using their email and password
+// @Description Authenticates a user using their email and password. Users with two-factor authentication receive a challenge token to complete the login at /api/v1/user/login/2fa
 // @Tags Authentication
 // @Produce		json
 // @Param login body LoginRequest true "Login Request"
 // @Success 200 {object} util.JwtData{token=string, issuer=string, issued=string, expires=string, user_id=string} "Successful authentication"
+// @Success 202 {object} handler.TwoFactorChallengeResponse{two_factor_required=bool, challenge_token=string, expires_in=int, message=string} "Second factor required"
 // @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid input"
 // @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid credentials"
+// @Failure 429 {object} util.ErrorResponse{error=bool, error_message=string} "Too many failed login attempts"
 // @Router /api/v1/user/login [post]
 func Login(db *gorm.DB) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -125,14 +208,56 @@ func Login(db *gorm.DB) gin.HandlerFunc {
 		// Log the request data
 		util.LogIncomingRequest(loginRequest)
 
+		// Reject the attempt while the account or the client IP is throttled
+		clientIP := ctx.ClientIP()
+		if wait, err := repository.CheckLoginAllowed(db, loginRequest.Email, clientIP); err != nil {
+			if err.Error() == repository.TOO_MANY_LOGIN_ATTEMPTS_ERROR {
+				recordLoginEvent(ctx, db, model.LoginLockedOutEvent, loginRequest.Email, "password")
+			}
+			handleLoginThrottled(ctx, wait, err)
+			return
+		}
+
 		// Authenticate user
-		response, err := repository.LoginUser(db, loginRequest.Email, loginRequest.Password)
+		user, err := repository.AuthenticateUser(db, loginRequest.Email, loginRequest.Password)
 		if err != nil {
+			if err.Error() == repository.INVALID_CREDENTIALS_ERROR {
+				if err := repository.RecordLoginFailure(db, loginRequest.Email, clientIP); err != nil {
+					log.Println("unable to record failed login:", err)
+				}
+				recordLoginEvent(ctx, db, model.LoginFailedEvent, loginRequest.Email, "password")
+			}
 			response := util.ErrorResponse{Error: true, ErrorMessage: "Invalid credentials"}
 			util.LogAndHandleResponse(ctx, http.StatusUnauthorized, response)
 			return
 		}
 
+		// Users with two-factor authentication continue with the second step. Their
+		// failed attempts are only cleared once the second factor has been checked
+		twoFactorEnabled, err := repository.IsTwoFactorEnabled(db, user)
+		if err != nil {
+			log.Println(err.Error())
+			util.LogAndHandleResponse(ctx, http.StatusInternalServerError, util.ErrorResponse{Error: true, ErrorMessage: "Unable to log in"})
+			return
+		}
+
+		if twoFactorEnabled {
+			respondWithTwoFactorChallenge(ctx, db, user)
+			return
+		}
+
+		response, err := repository.IssueTokens(db, user, false, requestClient(ctx))
+		if err != nil {
+			log.Println(err.Error())
+			util.LogAndHandleResponse(ctx, http.StatusInternalServerError, util.ErrorResponse{Error: true, ErrorMessage: "Unable to log in"})
+			return
+		}
+
+		if err := repository.ClearLoginFailures(db, loginRequest.Email); err != nil {
+			log.Println("unable to clear failed logins:", err)
+		}
+		recordAuthEvent(ctx, db, model.LoginSucceededEvent, user, "password")
+
 		// Send successful response
 		util.LogAndHandleResponse(ctx, http.StatusOK, response)
 	}
@@ -7,12 +7,18 @@ import (
 	"net/http/httptest"
 	"regexp"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/password"
+	"github.com/hackdaemon2/instashop/util"
 )
 
 const (
@@ -24,13 +30,29 @@ const (
 	APPLICATION_JSON = "application/json"
 	LOGIN            = "/login"
 	SELECT_QUERY     = "SELECT * FROM `users` WHERE (email = ? AND is_deleted = false)"
+	SELECT_ATTEMPTS  = "SELECT * FROM `login_attempts` WHERE (attempt_key IN (?,?))"
+
+	SELECT_TWO_FACTOR_QUERY = "SELECT * FROM `two_factor_auths` WHERE (user_id = ?) ORDER BY `two_factor_auths`.`id` ASC LIMIT 1"
 )
 
+// recordingMailer keeps sent messages in memory instead of delivering them
+type recordingMailer struct {
+	mutex    sync.Mutex
+	messages []util.MailMessage
+}
+
+func (mailer *recordingMailer) Send(message util.MailMessage) error {
+	mailer.mutex.Lock()
+	defer mailer.mutex.Unlock()
+	mailer.messages = append(mailer.messages, message)
+	return nil
+}
+
 func createSignupRequest() SignupRequest {
 	return SignupRequest{
 		Email:           EMAIL,
-		Password:        "password123",
-		ConfirmPassword: "password123",
+		Password:        "correct-horse-battery",
+		ConfirmPassword: "correct-horse-battery",
 		UserCurrency:    "NGN",
 		FirstName:       "John",
 		LastName:        "Doe",
@@ -79,23 +101,35 @@ func TestSignupHandlerSuccessfulRegistration(t *testing.T) {
 		WithArgs(EMAIL).
 		WillReturnError(gorm.ErrRecordNotFound)
 
-	sql := "INSERT INTO `users` (`email`,`password`,`first_name`,`last_name`,`user_currency`,`user_guid`,`role`,`created_at`,`updated_at`) VALUES (?,?,?,?,?,?,?,?,?)"
+	sql := "INSERT INTO `users` (`email`,`password`,`first_name`,`last_name`,`user_currency`,`user_guid`,`role`,`verified_at`,`created_at`,`updated_at`) VALUES (?,?,?,?,?,?,?,?,?,?)"
 	mock.ExpectBegin()
 	mock.ExpectExec(regexp.QuoteMeta(sql)).
-		WithArgs(EMAIL, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(EMAIL, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectQuery(regexp.QuoteMeta("SELECT `is_deleted` FROM `users` WHERE (id = ?)")).
 		WithArgs(sqlmock.AnyArg()).
 		WillReturnRows(sqlmock.NewRows([]string{"is_deleted"}).AddRow(0))
 	mock.ExpectCommit()
+	expectAuthEvent(mock, model.SignupEvent)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `one_time_tokens` SET `used_at` = ? WHERE (user_id = ? AND purpose = ? AND used_at IS NULL)")).
+		WithArgs(sqlmock.AnyArg(), 1, "email_verification").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `one_time_tokens`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	reqBody := createSignupRequest()
 
 	w, c := createTestContext(reqBody, SIGNUP, t)
-	Signup(gdb)(c)
+	Signup(gdb, &recordingMailer{})(c)
 
 	assert.Equal(t, http.StatusCreated, w.Code)
 	assert.Contains(t, w.Body.String(), "User successfully registered")
+	assert.NoError(t, mock.ExpectationsWereMet(), "Expected a verification token to be issued")
 }
 
 // Request JSON successfully binds to SignupRequest struct
@@ -167,7 +201,7 @@ func TestSignupHandlerInvalidJSON(t *testing.T) {
 	c.Request = httptest.NewRequest("POST", SIGNUP, strings.NewReader(invalidJSON))
 	c.Request.Header.Set(CONTEXT_TYPE, APPLICATION_JSON)
 
-	Signup(gdb)(c)
+	Signup(gdb, &recordingMailer{})(c)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
@@ -192,7 +226,7 @@ func TestLoginHandlerInvalidJSON(t *testing.T) {
 	c.Request = httptest.NewRequest("POST", LOGIN, strings.NewReader(invalidJSON))
 	c.Request.Header.Set(CONTEXT_TYPE, APPLICATION_JSON)
 
-	Signup(gdb)(c)
+	Signup(gdb, &recordingMailer{})(c)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
@@ -220,7 +254,7 @@ func TestSignupHandlerMissingRequiredFields(t *testing.T) {
 	}
 
 	w, c := createTestContext(reqBody, SIGNUP, t)
-	Signup(gdb)(c)
+	Signup(gdb, &recordingMailer{})(c)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 	assert.Contains(t, w.Body.String(), "is required")
@@ -274,7 +308,7 @@ func TestSignupHandlerPasswordMismatch(t *testing.T) {
 	}
 
 	w, c := createTestContext(reqBody, SIGNUP, t)
-	Signup(gdb)(c)
+	Signup(gdb, &recordingMailer{})(c)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 	assert.Contains(t, w.Body.String(), "do not match")
@@ -302,7 +336,7 @@ func TestSignupHandlerDuplicateEmail(t *testing.T) {
 	reqBody := createSignupRequest()
 
 	w, c := createTestContext(reqBody, SIGNUP, t)
-	Signup(gdb)(c)
+	Signup(gdb, &recordingMailer{})(c)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	assert.Regexp(t, `.*user with email test@test.com already exists.*`, w.Body.String())
@@ -321,11 +355,36 @@ func TestLoginSuccessful(t *testing.T) {
 		t.Fatalf(OPEN_ERROR, err)
 	}
 
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ATTEMPTS)).
+		WithArgs("account:"+EMAIL, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
 	mock.ExpectQuery(regexp.QuoteMeta(SELECT_QUERY)).
 		WithArgs(EMAIL).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password"}).
 			AddRow("1", EMAIL, "$2a$10$BvynkDL3zqY9wn8J6QFUD.XiSETqPtPPvs5VjH//EAJflvXNP3wRe"))
 
+	// the bcrypt hash is replaced by a hash of the configured algorithm
+	expectPasswordRehash(mock)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_TWO_FACTOR_QUERY)).
+		WithArgs(1).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `sessions`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `refresh_tokens`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `login_attempts` WHERE (attempt_key = ?)")).
+		WithArgs("account:" + EMAIL).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectAuthEvent(mock, model.LoginSucceededEvent)
+
 	reqBody := createLoginRequest()
 
 	w, c := createTestContext(reqBody, LOGIN, t)
@@ -333,4 +392,140 @@ func TestLoginSuccessful(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "token")
+	assert.Contains(t, w.Body.String(), "refresh_token")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func expectPasswordRehash(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `password` = ? WHERE `users`.`id` = ?")).
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+// A hash made with the current settings is kept as it is
+func TestLoginKeepsCurrentPasswordHash(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	hash, err := password.Hash("mayfay_2018@M1")
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ATTEMPTS)).
+		WithArgs("account:"+EMAIL, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_QUERY)).
+		WithArgs(EMAIL).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password"}).AddRow(1, EMAIL, hash))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_TWO_FACTOR_QUERY)).
+		WithArgs(1).
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `sessions`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `refresh_tokens`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `login_attempts` WHERE (attempt_key = ?)")).
+		WithArgs("account:" + EMAIL).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectAuthEvent(mock, model.LoginSucceededEvent)
+
+	w, c := createTestContext(createLoginRequest(), LOGIN, t)
+	Login(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Passwords from the common password list are refused
+func TestSignupHandlerCommonPassword(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	signupRequest := createSignupRequest()
+	signupRequest.Password = "Password123"
+	signupRequest.ConfirmPassword = "Password123"
+	w, c := createTestContext(signupRequest, SIGNUP, t)
+	Signup(gdb, &recordingMailer{})(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), password.COMMON_PASSWORD_ERROR)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Locked out account returns 429 without checking the password
+func TestLoginLockedOut(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf(MOCK_ERROR, err)
+	}
+	defer db.Close()
+
+	gdb, err := gorm.Open("mysql", db)
+	if err != nil {
+		t.Fatalf(OPEN_ERROR, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ATTEMPTS)).
+		WithArgs("account:"+EMAIL, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "attempt_key", "failures", "window_started_at", "last_failed_at", "locked_until"}).
+			AddRow(1, "account:"+EMAIL, 5, time.Now(), time.Now(), time.Now().Add(10*time.Minute)))
+	expectAuthEvent(mock, model.LoginLockedOutEvent)
+
+	reqBody := createLoginRequest()
+
+	w, c := createTestContext(reqBody, LOGIN, t)
+	Login(gdb)(c)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.NoError(t, mock.ExpectationsWereMet(), "The password should not be checked while locked out")
+}
+
+// expectLoginFailure makes the mock record a first failure for the account
+// and the client IP, and the failed login event
+func expectLoginFailure(mock sqlmock.Sqlmock) {
+	for range 2 {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `login_attempts` WHERE (attempt_key = ?)")).
+			WillReturnError(gorm.ErrRecordNotFound)
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `login_attempts`")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+	}
+	expectAuthEvent(mock, model.LoginFailedEvent)
+}
+
+// Unknown email is rejected like a wrong password and counted as a failure
+func TestLoginUnknownEmailRecordsFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf(MOCK_ERROR, err)
+	}
+	defer db.Close()
+
+	gdb, err := gorm.Open("mysql", db)
+	if err != nil {
+		t.Fatalf(OPEN_ERROR, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ATTEMPTS)).
+		WithArgs("account:"+EMAIL, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_QUERY)).
+		WithArgs(EMAIL).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	expectLoginFailure(mock)
+
+	reqBody := createLoginRequest()
+
+	w, c := createTestContext(reqBody, LOGIN, t)
+	Login(gdb)(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid credentials")
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
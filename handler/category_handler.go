@@ -0,0 +1,306 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/search"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	MAX_SLUG_LENGTH    = 64
+	INVALID_SLUG_ERROR = "slug must be at most 64 lowercase letters, digits and single hyphens, e.g. kitchen-appliances"
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type CategoryRequest struct {
+	Name        string `json:"name" binding:"required,max=128" example:"Kettles"`
+	Slug        string `json:"slug" binding:"max=64" example:"kettles"` // derived from the name when empty
+	Description string `json:"description" binding:"max=255"`
+	ParentSlug  string `json:"parent_slug" example:"kitchen"` // empty for a top-level category
+	SortOrder   int    `json:"sort_order" example:"1"`
+}
+
+type ProductCategoriesRequest struct {
+	Categories []string `json:"categories" example:"kettles,kitchen"` // slugs, replacing the current categories
+}
+
+type CategoryResponse struct {
+	Category *model.Category `json:"category"`
+	Message  string          `json:"message"`
+}
+
+type ListCategoriesResponse struct {
+	Categories []*model.Category `json:"categories"`
+}
+
+// slugify turns a name into a slug: accents are dropped and words are joined with hyphens
+func slugify(name string) string {
+	var words []string
+	for _, token := range search.Tokenize(name) {
+		words = append(words, token.Term)
+	}
+	return strings.Join(words, "-")
+}
+
+// bindCategoryRequest reads and validates a category from the request body and
+// copies it onto category. It responds itself when the category is invalid
+func bindCategoryRequest(ctx *gin.Context, category *model.Category) bool {
+	var categoryRequest CategoryRequest
+	if err := ctx.ShouldBindJSON(&categoryRequest); err != nil {
+		validationError := util.ExtractValidationErrorMessage(err, categoryRequest)
+		handleProductError(ctx, http.StatusBadRequest, validationError[0])
+		return false
+	}
+
+	slug := strings.TrimSpace(categoryRequest.Slug)
+	if slug == "" {
+		slug = slugify(categoryRequest.Name)
+	}
+
+	if len(slug) > MAX_SLUG_LENGTH || !slugPattern.MatchString(slug) {
+		handleProductError(ctx, http.StatusBadRequest, INVALID_SLUG_ERROR)
+		return false
+	}
+
+	category.Name = strings.TrimSpace(categoryRequest.Name)
+	category.Slug = slug
+	category.Description = strings.TrimSpace(categoryRequest.Description)
+	category.ParentSlug = strings.TrimSpace(categoryRequest.ParentSlug)
+	category.SortOrder = categoryRequest.SortOrder
+	return true
+}
+
+// handleCategoryError responds to a category that could not be loaded or changed
+func handleCategoryError(ctx *gin.Context, err error, message string) {
+	switch err.Error() {
+	case repository.CATEGORY_NOT_FOUND_ERROR:
+		handleProductError(ctx, http.StatusNotFound, err.Error())
+	case repository.PARENT_CATEGORY_NOT_FOUND_ERROR, repository.CATEGORY_CYCLE_ERROR, repository.UNKNOWN_CATEGORY_ERROR:
+		handleProductError(ctx, http.StatusBadRequest, err.Error())
+	case repository.CATEGORY_SLUG_TAKEN_ERROR, repository.CATEGORY_HAS_CHILDREN_ERROR:
+		handleProductError(ctx, http.StatusConflict, err.Error())
+	default:
+		log.Println(err.Error())
+		handleProductError(ctx, http.StatusInternalServerError, message)
+	}
+}
+
+// ListCategories returns the category tree
+// @Summary List categories
+// @Description Returns the top-level categories with their subcategories, siblings ordered by sort order and then name
+// @Tags Categories
+// @Produce		json
+// @Success 200 {object} handler.ListCategoriesResponse "Category tree"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Failed to retrieve categories"
+// @Router /api/v1/categories [get]
+func ListCategories(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		categories, err := repository.ListCategoryTree(db)
+		if err != nil {
+			log.Println(err.Error())
+			handleProductError(ctx, http.StatusInternalServerError, "Failed to retrieve categories")
+			return
+		}
+
+		if categories == nil {
+			categories = []*model.Category{}
+		}
+		util.LogAndHandleResponse(ctx, http.StatusOK, ListCategoriesResponse{Categories: categories})
+	}
+}
+
+// ListCategoryProducts lists the products of a category
+// @Summary List the products of a category
+// @Description Lists the products of a category and of all its subcategories. Accepts the filters, sorting and paging of the product listing
+// @Tags Categories
+// @Produce		json
+// @Param slug path string true "Category slug"
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param currency query string false "Currency code, e.g. NGN"
+// @Param in_stock query boolean false "Only list products that are in stock"
+// @Param sort query string false "Sort by price, name or created_at (Default created_at)"
+// @Param order query string false "Sort order, asc or desc (Default desc for created_at, asc otherwise)"
+// @Param page query string false "Page (Default 1)"
+// @Param size query string false "Size (Default 10, at most 100)"
+// @Success 200 {object} handler.ListProductResponse{products=[]model.Product, message=string, total_products=int, total_pages=int, page=int, size=int} "List of products"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid filter"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Category not found"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Failed to retrieve products"
+// @Router /api/v1/categories/{slug}/products [get]
+func ListCategoryProducts(db *gorm.DB, storage util.Storage) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		filter, message := parseProductFilter(ctx)
+		if message != "" {
+			handleProductError(ctx, http.StatusBadRequest, message)
+			return
+		}
+
+		category, err := repository.FindCategory(db, ctx.Param("slug"))
+		if err != nil {
+			handleCategoryError(ctx, err, "Failed to retrieve products")
+			return
+		}
+
+		respondWithProducts(ctx, db, storage, repository.CategoryProductFilter(category, filter))
+	}
+}
+
+// CreateCategory adds a category
+// @Summary Create a category
+// @Description Adds a category, below parent_slug or at the top level. The slug is derived from the name when it is left out
+// @Tags Categories
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Accept		json
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
+// @Param request body CategoryRequest true "Category Request"
+// @Success 201 {object} handler.CategoryResponse{category=model.Category, message=string} "Category created"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid category"
+// @Failure 409 {object} util.ErrorResponse{error=bool, error_message=string} "A category with this slug already exists"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to save category"
+// @Router /api/v1/admin/categories [post]
+func CreateCategory(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var category model.Category
+		if !bindCategoryRequest(ctx, &category) {
+			return
+		}
+
+		if err := repository.SaveCategory(db, &category); err != nil {
+			handleCategoryError(ctx, err, "Unable to save category")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusCreated, CategoryResponse{Category: &category, Message: "Category created"})
+	}
+}
+
+// UpdateCategory changes a category
+// @Summary Update a category
+// @Description Renames or moves a category. Its subcategories and products move along; a category cannot be moved below one of its own subcategories
+// @Tags Categories
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Accept		json
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
+// @Param slug path string true "Category slug"
+// @Param request body CategoryRequest true "Category Request"
+// @Success 200 {object} handler.CategoryResponse{category=model.Category, message=string} "Category updated"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid category"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Category not found"
+// @Failure 409 {object} util.ErrorResponse{error=bool, error_message=string} "A category with this slug already exists"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to save category"
+// @Router /api/v1/admin/categories/{slug} [put]
+func UpdateCategory(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		category, err := repository.FindCategory(db, ctx.Param("slug"))
+		if err != nil {
+			handleCategoryError(ctx, err, "Unable to save category")
+			return
+		}
+
+		if !bindCategoryRequest(ctx, category) {
+			return
+		}
+
+		if err := repository.SaveCategory(db, category); err != nil {
+			handleCategoryError(ctx, err, "Unable to save category")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, CategoryResponse{Category: category, Message: "Category updated"})
+	}
+}
+
+// DeleteCategory removes a category
+// @Summary Delete a category
+// @Description Removes a category without subcategories. Its products stay in the catalog
+// @Tags Categories
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
+// @Param slug path string true "Category slug"
+// @Success 200 {object} util.ErrorResponse{error=bool, error_message=string} "Category deleted"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Category not found"
+// @Failure 409 {object} util.ErrorResponse{error=bool, error_message=string} "Move or delete the subcategories first"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to delete category"
+// @Router /api/v1/admin/categories/{slug} [delete]
+func DeleteCategory(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		category, err := repository.FindCategory(db, ctx.Param("slug"))
+		if err != nil {
+			handleCategoryError(ctx, err, "Unable to delete category")
+			return
+		}
+
+		if err := repository.DeleteCategory(db, category); err != nil {
+			handleCategoryError(ctx, err, "Unable to delete category")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, util.ErrorResponse{Error: false, ErrorMessage: "Category deleted"})
+	}
+}
+
+// SetProductCategories assigns a product to categories
+// @Summary Set the categories of a product
+// @Description Replaces the categories of a product. An empty list takes the product out of every category
+// @Tags Categories
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Accept		json
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
+// @Param product_code path string true "Product Code"
+// @Param request body ProductCategoriesRequest true "Category slugs"
+// @Success 200 {object} handler.ProductResponse{product=model.Product, message=string} "Categories updated"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Unknown category"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Product not found"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to save categories"
+// @Router /api/v1/admin/product/{product_code}/categories [put]
+func SetProductCategories(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var categoriesRequest ProductCategoriesRequest
+		if err := ctx.ShouldBindJSON(&categoriesRequest); err != nil {
+			handleProductError(ctx, http.StatusBadRequest, INVALID_USER_INPUT)
+			return
+		}
+
+		product, ok := findProduct(ctx, db)
+		if !ok {
+			return
+		}
+
+		if err := repository.SetProductCategories(db, product, categoriesRequest.Categories); err != nil {
+			handleCategoryError(ctx, err, "Unable to save categories")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, ProductResponse{Product: product, Message: "Categories updated"})
+	}
+}
@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	CATEGORIES_ENDPOINT         = "/api/v1/admin/categories"
+	SELECT_CATEGORIES_QUERY     = "SELECT * FROM `categories` ORDER BY sort_order ASC, name ASC, id ASC"
+	INSERT_CATEGORY_QUERY       = "INSERT INTO `categories` (`name`,`slug`,`description`,`parent_id`,`sort_order`,`created_at`,`updated_at`) VALUES (?,?,?,?,?,?,?)"
+	DELETE_CATEGORY_LINKS_QUERY = "DELETE FROM product_categories WHERE category_id = ?"
+	DELETE_CATEGORY_QUERY       = "DELETE FROM `categories` WHERE `categories`.`id` = ?"
+	COUNT_CATEGORY_PRODUCTS     = "SELECT count(*) FROM `products` WHERE (is_deleted = false) AND (id IN (SELECT product_id FROM product_categories WHERE category_id IN (?,?,?)))"
+	SELECT_CATEGORY_PRODUCTS    = "SELECT * FROM `products` WHERE (is_deleted = false) AND (id IN (SELECT product_id FROM product_categories WHERE category_id IN (?,?,?))) ORDER BY created_at desc, id desc LIMIT 10 OFFSET 0"
+	SELECT_CATEGORIES_BY_SLUG   = "SELECT * FROM `categories` WHERE (slug IN (?,?)) ORDER BY sort_order ASC, name ASC, id ASC"
+	INSERT_CATEGORY_LINK        = "INSERT INTO `product_categories`" // gorm orders the columns at random
+	DELETE_OTHER_CATEGORY_LINKS = "DELETE FROM `product_categories`  WHERE (`category_id` NOT IN (?,?)) AND (`product_id` IN (?))"
+	SELECT_PRODUCT_BY_CODE      = "SELECT * FROM `products` WHERE (product_code = ? AND is_deleted = false)"
+)
+
+var categoryColumns = []string{"id", "name", "slug", "parent_id", "sort_order"}
+
+// expectCategoryTree returns kitchen > kettles > electric-kettles and garden
+func expectCategoryTree(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_CATEGORIES_QUERY)).
+		WillReturnRows(sqlmock.NewRows(categoryColumns).
+			AddRow(4, "Garden", "garden", nil, 0).
+			AddRow(1, "Kitchen", "kitchen", nil, 1).
+			AddRow(2, "Kettles", "kettles", 1, 0).
+			AddRow(3, "Electric Kettles", "electric-kettles", 2, 0))
+}
+
+func newCategoryTestContext(t *testing.T, method, slug string, body any) (*httptest.ResponseRecorder, *gin.Context) {
+	w, c := createTestContext(body, CATEGORIES_ENDPOINT, t)
+	c.Request.Method = method
+	c.Params = gin.Params{{Key: "slug", Value: slug}}
+	return w, c
+}
+
+func TestListCategories(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	expectCategoryTree(mock)
+
+	w, c := newProductListContext("")
+	ListCategories(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Regexp(t, `"slug":"garden".*"slug":"kitchen"`, body, "Top-level categories should follow their sort order")
+	assert.Contains(t, body, `"slug":"kettles","description":"","parent_slug":"kitchen","sort_order":0,"children":[{"name":"Electric Kettles"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// The slug is derived from the name and the parent is resolved by its slug
+func TestCreateCategory(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	expectCategoryTree(mock)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(INSERT_CATEGORY_QUERY)).
+		WithArgs("Stove-top Kettles", "stove-top-kettles", "", 2, 3, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectCommit()
+
+	w, c := newCategoryTestContext(t, "POST", "", map[string]any{"name": "Stove-top Kettles", "parent_slug": "kettles", "sort_order": 3})
+	CreateCategory(gdb)(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"slug":"stove-top-kettles"`)
+	assert.Contains(t, w.Body.String(), `"parent_slug":"kettles"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateCategoryRejectsInvalidCategories(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	for _, test := range []struct {
+		body      map[string]any
+		loadsTree bool
+		status    int
+		message   string
+	}{
+		{map[string]any{"name": "Kitchen"}, true, http.StatusConflict, "slug already exists"},
+		{map[string]any{"name": "Toasters", "parent_slug": "bathroom"}, true, http.StatusBadRequest, "Parent category not found"},
+		{map[string]any{"name": "Toasters", "slug": "Toasters & Grills"}, false, http.StatusBadRequest, "slug must be"},
+	} {
+		if test.loadsTree {
+			expectCategoryTree(mock)
+		}
+
+		w, c := newCategoryTestContext(t, "POST", "", test.body)
+		CreateCategory(gdb)(c)
+
+		assert.Equal(t, test.status, w.Code, test.message)
+		assert.Contains(t, w.Body.String(), test.message)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// A category cannot become a subcategory of its own subcategory
+func TestUpdateCategoryRejectsCycle(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	expectCategoryTree(mock)
+	expectCategoryTree(mock)
+
+	w, c := newCategoryTestContext(t, "PUT", "kitchen", map[string]any{"name": "Kitchen", "parent_slug": "electric-kettles"})
+	UpdateCategory(gdb)(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "cannot be moved below itself")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteCategory(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	expectCategoryTree(mock)
+	w, c := newCategoryTestContext(t, "DELETE", "kettles", nil)
+	DeleteCategory(gdb)(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code, "Categories with subcategories should be kept")
+
+	expectCategoryTree(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(DELETE_CATEGORY_LINKS_QUERY)).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(DELETE_CATEGORY_QUERY)).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	w, c = newCategoryTestContext(t, "DELETE", "electric-kettles", nil)
+	DeleteCategory(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Category deleted")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Products of subcategories are listed with their parent category
+func TestListCategoryProductsIncludesDescendants(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	expectCategoryTree(mock)
+
+	mock.ExpectQuery(regexp.QuoteMeta(COUNT_CATEGORY_PRODUCTS)).
+		WithArgs(1, 2, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_CATEGORY_PRODUCTS)).
+		WithArgs(1, 2, 3).
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(1, "Electric Kettle", "Boils water fast", "kettle", "20.00", 4, "NGN"))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_PRODUCT_IMAGES)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(imageColumns))
+
+	w, c := newProductListContext("")
+	c.Params = gin.Params{{Key: "slug", Value: "kitchen"}}
+	ListCategoryProducts(gdb, newTestStorage(t))(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"product_code":"kettle"`)
+	assert.Contains(t, w.Body.String(), `"total_products":1`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListCategoryProductsUnknownCategory(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	expectCategoryTree(mock)
+
+	w, c := newProductListContext("")
+	c.Params = gin.Params{{Key: "slug", Value: "bathroom"}}
+	ListCategoryProducts(gdb, newTestStorage(t))(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetProductCategoriesRejectsUnknownCategory(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_PRODUCT_BY_CODE)).
+		WithArgs("kettle").
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(1, "Electric Kettle", "Boils water fast", "kettle", "20.00", 4, "NGN"))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_CATEGORIES_BY_SLUG)).
+		WithArgs("kettles", "bathroom").
+		WillReturnRows(sqlmock.NewRows(categoryColumns).AddRow(2, "Kettles", "kettles", 1, 0))
+
+	w, c := createTestContext(map[string]any{"categories": []string{"kettles", "bathroom"}}, "/api/v1/admin/product/kettle/categories", t)
+	c.Request.Method = "PUT"
+	c.Params = gin.Params{{Key: "product_code", Value: "kettle"}}
+	SetProductCategories(gdb)(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Unknown category")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetProductCategories(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_PRODUCT_BY_CODE)).
+		WithArgs("kettle").
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(1, "Electric Kettle", "Boils water fast", "kettle", "20.00", 4, "NGN"))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_CATEGORIES_BY_SLUG)).
+		WithArgs("kettles", "kitchen").
+		WillReturnRows(sqlmock.NewRows(categoryColumns).
+			AddRow(2, "Kettles", "kettles", 1, 0).
+			AddRow(1, "Kitchen", "kitchen", nil, 1))
+	mock.ExpectBegin()
+	for range 2 {
+		mock.ExpectExec(regexp.QuoteMeta(INSERT_CATEGORY_LINK)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec(regexp.QuoteMeta(DELETE_OTHER_CATEGORY_LINKS)).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	w, c := createTestContext(map[string]any{"categories": []string{"kettles", "kitchen"}}, "/api/v1/admin/product/kettle/categories", t)
+	c.Request.Method = "PUT"
+	c.Params = gin.Params{{Key: "product_code", Value: "kettle"}}
+	SetProductCategories(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"categories":[{"name":"Kettles"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
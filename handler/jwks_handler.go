@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/util"
+)
+
+// JWKS publishes the public keys that verify Instashop access tokens
+// @Summary JSON Web Key Set
+// @Description Returns the public keys other services can use to verify access tokens. Keys retired by a rotation are listed until their grace period ends
+// @Tags Authentication
+// @Produce		json
+// @Success 200 {object} util.JSONWebKeySet "Public signing keys"
+// @Router /.well-known/jwks.json [get]
+func JWKS() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header("Cache-Control", "public, max-age=300")
+		ctx.JSON(http.StatusOK, util.Keys().JWKS())
+	}
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	OIDC_STATE_COOKIE         = "instashop_oidc_state"
+	OIDC_COOKIE_PATH          = "/api/v1/user/oidc"
+	OIDC_NOT_CONFIGURED_ERROR = "Single sign-on is not configured"
+	OIDC_PROVIDER_ERROR       = "Unable to reach the identity provider"
+	OIDC_LOGIN_FAILED_ERROR   = "Unable to log in with the identity provider"
+)
+
+// OIDCLogin starts a login at the OpenID Connect provider
+// @Summary Log in with the identity provider
+// @Description Redirects the browser to the OpenID Connect provider using the authorization code flow with PKCE. The provider redirects back to /api/v1/user/oidc/callback
+// @Tags Authentication
+// @Produce		json
+// @Success 302 "Redirect to the identity provider"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Single sign-on is not configured"
+// @Failure 502 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to reach the identity provider"
+// @Router /api/v1/user/oidc/login [get]
+func OIDCLogin(db *gorm.DB, provider *util.OIDCProvider) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if provider == nil {
+			handleTokenError(ctx, http.StatusNotFound, OIDC_NOT_CONFIGURED_ERROR)
+			return
+		}
+
+		rawState, loginState, err := repository.CreateOIDCLoginState(db)
+		if err != nil {
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to start login")
+			return
+		}
+
+		authorizationURL, err := provider.AuthorizationURL(rawState, loginState.Nonce, loginState.CodeVerifier)
+		if err != nil {
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusBadGateway, OIDC_PROVIDER_ERROR)
+			return
+		}
+
+		// the cookie ties the state to this browser so that nobody can complete
+		// a login they started in the victim's browser
+		ctx.SetSameSite(http.SameSiteLaxMode)
+		ctx.SetCookie(OIDC_STATE_COOKIE, rawState, int(repository.OIDCLoginStateTTL().Seconds()),
+			OIDC_COOKIE_PATH, "", ctx.Request.TLS != nil, true)
+		ctx.Redirect(http.StatusFound, authorizationURL)
+	}
+}
+
+// OIDCCallback completes a login at the OpenID Connect provider
+// @Summary Complete a login with the identity provider
+// @Description Exchanges the authorization code for an ID token, links the provider account to the user with the same verified email address or creates a new user, and issues a token pair. Users with two-factor authentication receive a challenge token to complete the login at /api/v1/user/login/2fa
+// @Tags Authentication
+// @Produce		json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State sent with the authorization request"
+// @Success 200 {object} util.JwtData{token=string, issuer=string, issued=string, expires=string, user_id=string} "Successful authentication"
+// @Success 202 {object} handler.TwoFactorChallengeResponse{two_factor_required=bool, challenge_token=string, expires_in=int, message=string} "Second factor required"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid or expired login state"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to log in with the identity provider"
+// @Failure 403 {object} util.ErrorResponse{error=bool, error_message=string} "User account is deactivated or has no verified email address"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Single sign-on is not configured"
+// @Router /api/v1/user/oidc/callback [get]
+func OIDCCallback(db *gorm.DB, provider *util.OIDCProvider) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if provider == nil {
+			handleTokenError(ctx, http.StatusNotFound, OIDC_NOT_CONFIGURED_ERROR)
+			return
+		}
+
+		rawState := ctx.Query("state")
+		cookieState, _ := ctx.Cookie(OIDC_STATE_COOKIE)
+		ctx.SetCookie(OIDC_STATE_COOKIE, "", -1, OIDC_COOKIE_PATH, "", ctx.Request.TLS != nil, true)
+		if rawState == "" || rawState != cookieState {
+			handleTokenError(ctx, http.StatusBadRequest, repository.INVALID_OIDC_STATE_ERROR)
+			return
+		}
+
+		loginState, err := repository.ConsumeOIDCLoginState(db, rawState)
+		if err != nil {
+			if err.Error() == repository.INVALID_OIDC_STATE_ERROR {
+				handleTokenError(ctx, http.StatusBadRequest, err.Error())
+				return
+			}
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to log in")
+			return
+		}
+
+		// the provider redirects back with an error when the user cancels the login
+		code := ctx.Query("code")
+		if providerError := ctx.Query("error"); providerError != "" || code == "" {
+			log.Println("identity provider returned an error:", providerError, ctx.Query("error_description"))
+			handleTokenError(ctx, http.StatusUnauthorized, OIDC_LOGIN_FAILED_ERROR)
+			return
+		}
+
+		rawIDToken, err := provider.Exchange(code, loginState.CodeVerifier)
+		if err != nil {
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusUnauthorized, OIDC_LOGIN_FAILED_ERROR)
+			return
+		}
+
+		claims, err := provider.VerifyIDToken(rawIDToken, loginState.Nonce)
+		if err != nil {
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusUnauthorized, OIDC_LOGIN_FAILED_ERROR)
+			return
+		}
+
+		// users created here can only log in through the provider until they reset their password
+		randomPassword, err := util.GenerateSecureToken(32)
+		if err != nil {
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to log in")
+			return
+		}
+
+		unusablePassword, err := hashPassword(randomPassword)
+		if err != nil {
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to log in")
+			return
+		}
+
+		user, err := repository.FindOrCreateOIDCUser(db, claims, unusablePassword)
+		if err != nil {
+			if err.Error() == repository.USER_DEACTIVATED_ERROR || err.Error() == repository.OIDC_EMAIL_NOT_VERIFIED_ERROR {
+				handleTokenError(ctx, http.StatusForbidden, err.Error())
+				return
+			}
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to log in")
+			return
+		}
+
+		twoFactorEnabled, err := repository.IsTwoFactorEnabled(db, user)
+		if err != nil {
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to log in")
+			return
+		}
+
+		if twoFactorEnabled {
+			respondWithTwoFactorChallenge(ctx, db, user)
+			return
+		}
+
+		response, err := repository.IssueTokens(db, user, false, requestClient(ctx))
+		if err != nil {
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to log in")
+			return
+		}
+		recordAuthEvent(ctx, db, model.LoginSucceededEvent, user, "oidc")
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, response)
+	}
+}
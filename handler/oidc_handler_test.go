@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/util"
+)
+
+const (
+	OIDC_CLIENT_ID     = "instashop"
+	OIDC_CODE          = "authorization-code"
+	OIDC_NONCE         = "nonce"
+	OIDC_CODE_VERIFIER = "verifier"
+	OIDC_STATE         = "state"
+
+	SELECT_OIDC_STATE_QUERY    = "SELECT * FROM `oidc_login_states` WHERE (state_hash = ?) ORDER BY `oidc_login_states`.`id` ASC LIMIT 1"
+	DELETE_OIDC_STATE_QUERY    = "DELETE FROM `oidc_login_states` WHERE (id = ?)"
+	SELECT_USER_IDENTITY_QUERY = "SELECT * FROM `user_identities` WHERE (issuer = ? AND subject = ?) ORDER BY `user_identities`.`id` ASC LIMIT 1"
+	SELECT_ANY_EMAIL_QUERY     = "SELECT * FROM `users` WHERE (email = ?) ORDER BY `users`.`id` ASC LIMIT 1"
+)
+
+// newTestOIDCProvider starts a local stand-in for the identity provider that
+// redeems OIDC_CODE with OIDC_CODE_VERIFIER for an ID token with the claims
+func newTestOIDCProvider(t *testing.T, claims jwt.MapClaims) *util.OIDCProvider {
+	key, err := util.GenerateSigningKey("provider-key", util.RS256_ALGORITHM)
+	if err != nil {
+		t.Fatalf("Error generating provider key: %v", err)
+	}
+
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc(util.OIDC_DISCOVERY_PATH, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(util.OIDCDiscovery{
+			Issuer:                server.URL,
+			AuthorizationEndpoint: server.URL + "/authorize",
+			TokenEndpoint:         server.URL + "/token",
+			JWKSURI:               server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(util.NewKeyring(key, time.Hour).JWKS())
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("code") != OIDC_CODE || r.PostFormValue("code_verifier") != OIDC_CODE_VERIFIER {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		claims["iss"] = server.URL
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = key.ID
+		idToken, _ := token.SignedString(key.PrivateKey)
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idToken})
+	})
+
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return util.NewOIDCProvider(util.OIDCConfig{
+		Issuer:      server.URL,
+		ClientID:    OIDC_CLIENT_ID,
+		RedirectURL: "http://localhost:3000/api/v1/user/oidc/callback",
+	})
+}
+
+func newTestIDTokenClaims(emailVerified bool) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":            "subject-1",
+		"aud":            OIDC_CLIENT_ID,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"nonce":          OIDC_NONCE,
+		"email":          EMAIL,
+		"email_verified": emailVerified,
+		"given_name":     "Jane",
+		"family_name":    "Doe",
+	}
+}
+
+func newOIDCCallbackContext(query, cookieState string) (*httptest.ResponseRecorder, *gin.Context) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/user/oidc/callback?"+query, nil)
+	if cookieState != "" {
+		c.Request.AddCookie(&http.Cookie{Name: OIDC_STATE_COOKIE, Value: cookieState})
+	}
+	return w, c
+}
+
+func expectOIDCStateConsumed(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_OIDC_STATE_QUERY)).
+		WithArgs(util.HashToken(OIDC_STATE)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "state_hash", "nonce", "code_verifier", "expires_at"}).
+			AddRow(1, util.HashToken(OIDC_STATE), OIDC_NONCE, OIDC_CODE_VERIFIER, time.Now().Add(time.Minute)))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(DELETE_OIDC_STATE_QUERY)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func TestOIDCLoginNotConfigured(t *testing.T) {
+	gdb, _ := newAdminUserTestDB(t)
+
+	w, c := newOIDCCallbackContext("", "")
+	OIDCLogin(gdb, nil)(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), OIDC_NOT_CONFIGURED_ERROR)
+}
+
+func TestOIDCLoginRedirectsToProvider(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	provider := newTestOIDCProvider(t, newTestIDTokenClaims(true))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `oidc_login_states` WHERE (expires_at < ?)")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `oidc_login_states`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	w, c := newOIDCCallbackContext("", "")
+	OIDCLogin(gdb, provider)(c)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, "/authorize", location.Path)
+	assert.Equal(t, util.PKCE_CHALLENGE_METHOD, location.Query().Get("code_challenge_method"))
+	assert.NotEmpty(t, location.Query().Get("code_challenge"))
+
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, OIDC_STATE_COOKIE, cookies[0].Name)
+	assert.Equal(t, location.Query().Get("state"), cookies[0].Value, "The state should be bound to the browser")
+	assert.True(t, cookies[0].HttpOnly)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOIDCCallbackRejectsStateFromAnotherBrowser(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	provider := newTestOIDCProvider(t, newTestIDTokenClaims(true))
+
+	w, c := newOIDCCallbackContext("code="+OIDC_CODE+"&state="+OIDC_STATE, "another-state")
+	OIDCCallback(gdb, provider)(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), repository.INVALID_OIDC_STATE_ERROR)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOIDCCallbackCreatesUser(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	provider := newTestOIDCProvider(t, newTestIDTokenClaims(true))
+	discovery, err := provider.Discovery()
+	assert.NoError(t, err)
+
+	expectOIDCStateConsumed(mock)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_USER_IDENTITY_QUERY)).
+		WithArgs(discovery.Issuer, "subject-1").
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ANY_EMAIL_QUERY)).
+		WithArgs(EMAIL).
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `users`")).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `user_identities`")).
+		WithArgs(7, discovery.Issuer, "subject-1", EMAIL, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_TWO_FACTOR_QUERY)).
+		WithArgs(7).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `sessions`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `refresh_tokens`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	expectAuthEvent(mock, model.LoginSucceededEvent)
+
+	w, c := newOIDCCallbackContext("code="+OIDC_CODE+"&state="+OIDC_STATE, OIDC_STATE)
+	OIDCCallback(gdb, provider)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "refresh_token")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOIDCCallbackRejectsUnverifiedEmail(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	provider := newTestOIDCProvider(t, newTestIDTokenClaims(false))
+	discovery, err := provider.Discovery()
+	assert.NoError(t, err)
+
+	expectOIDCStateConsumed(mock)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_USER_IDENTITY_QUERY)).
+		WithArgs(discovery.Issuer, "subject-1").
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	w, c := newOIDCCallbackContext("code="+OIDC_CODE+"&state="+OIDC_STATE, OIDC_STATE)
+	OIDCCallback(gdb, provider)(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), repository.OIDC_EMAIL_NOT_VERIFIED_ERROR)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOIDCCallbackRejectsWrongCode(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	provider := newTestOIDCProvider(t, newTestIDTokenClaims(true))
+
+	expectOIDCStateConsumed(mock)
+
+	w, c := newOIDCCallbackContext("code=another-code&state="+OIDC_STATE, OIDC_STATE)
+	OIDCCallback(gdb, provider)(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), OIDC_LOGIN_FAILED_ERROR)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
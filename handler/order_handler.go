@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -8,6 +9,7 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/config"
 	"github.com/hackdaemon2/instashop/model"
 	"github.com/hackdaemon2/instashop/repository"
 	"github.com/hackdaemon2/instashop/util"
@@ -19,17 +21,24 @@ const (
 	ZERO                 = 0
 	USER_NOT_FOUND_ERROR = "User not found"
 	PLACE_ORDER_ERROR    = "error occured in placing order"
+	UNVERIFIED_EMAIL     = "Email address must be verified before placing an order"
+	MISSING_ADDRESS      = "A shipping address is required, add one to the address book first"
+	FOREIGN_ORDER_ERROR  = "Orders can only be placed for the authenticated user"
+	FOREIGN_ORDERS_ERROR = "Only the orders of the authenticated user can be listed"
 )
 
 type ProductDTO struct {
-	Code     string `json:"product_code" binding:"required"`
-	Quantity uint   `json:"product_quantity" binding:"required"`
+	Code       string `json:"product_code" binding:"required"`
+	VariantSKU string `json:"variant_sku" example:"SHIRT-RED-M"` // required for products with variants
+	Quantity   uint   `json:"product_quantity" binding:"required"`
 }
 
 type OrderRequest struct {
-	UserID         string       `json:"user_id" binding:"required"`
-	OrderReference string       `json:"order_reference" binding:"required"`
-	Products       []ProductDTO `json:"products" binding:"required"`
+	UserID            string       `json:"user_id"` // optional, must be the authenticated user when given
+	OrderReference    string       `json:"order_reference" binding:"required"`
+	Products          []ProductDTO `json:"products" binding:"required"`
+	ShippingAddressID string       `json:"shipping_address_id"` // defaults to the default shipping address of the user
+	BillingAddressID  string       `json:"billing_address_id"`  // defaults to the default billing address, then to the shipping address
 }
 
 type UpdateOrderRequest struct {
@@ -65,13 +74,14 @@ func isNotRecordNotFoundError(err error) bool {
 }
 
 // newOrder is a helper function to create a new order model
-func newOrder(userID uint, products []model.Product, orderReference string, totalPrice decimal.Decimal) model.Order {
+func newOrder(userID uint, products []model.Product, variants []model.ProductVariant, orderReference string, totalPrice decimal.Decimal) model.Order {
 	return model.Order{
 		UserID:         userID,
 		OrderReference: orderReference,
 		Status:         model.Pending,
 		TotalPrice:     totalPrice,
 		Products:       products,
+		Variants:       variants,
 	}
 }
 
@@ -116,9 +126,44 @@ func validateUser(db *gorm.DB, userID string) (*model.User, error) {
 	return repository.FindUserBy(db, "user_guid", userID)
 }
 
-// Validate products and calculate total price
-func validateProducts(db *gorm.DB, productsDTO []ProductDTO, user *model.User) ([]model.Product, decimal.Decimal, error) {
+// resolveOrderAddresses picks the shipping and billing addresses of an order
+// from the address book of the user
+func resolveOrderAddresses(db *gorm.DB, orderRequest OrderRequest, user *model.User) (shipping, billing *model.Address, err error) {
+	if orderRequest.ShippingAddressID == "" || orderRequest.BillingAddressID == "" {
+		if shipping, billing, err = repository.FindDefaultAddresses(db, user); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if orderRequest.ShippingAddressID != "" {
+		if shipping, err = repository.FindAddress(db, user, orderRequest.ShippingAddressID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if orderRequest.BillingAddressID != "" {
+		if billing, err = repository.FindAddress(db, user, orderRequest.BillingAddressID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if shipping == nil {
+		return nil, nil, errors.New(MISSING_ADDRESS)
+	}
+
+	if billing == nil {
+		billing = shipping
+	}
+	return shipping, billing, nil
+}
+
+// Validate products and calculate total price. Products with variants are
+// priced and stocked by variant. The stock is only taken when the order is
+// created, from the returned reservations
+func validateProducts(db *gorm.DB, productsDTO []ProductDTO, user *model.User) ([]model.Product, []model.ProductVariant, []repository.StockReservation, decimal.Decimal, error) {
 	var products []model.Product
+	var variants []model.ProductVariant
+	var reservations []repository.StockReservation
 
 	totalPrice := decimal.NewFromFloat(0)
 	zero := decimal.NewFromFloat(0)
@@ -126,40 +171,58 @@ func validateProducts(db *gorm.DB, productsDTO []ProductDTO, user *model.User) (
 	for _, productDTO := range productsDTO {
 		product, err := repository.GetProduct(db, productDTO.Code)
 		if err != nil {
-			return nil, zero, fmt.Errorf("Product with code %s is not found", productDTO.Code)
+			return nil, nil, nil, zero, fmt.Errorf("Product with code %s is not found", productDTO.Code)
 		}
 
 		// check if the quantity is a valid value
 		if productDTO.Quantity <= ZERO {
-			return nil, zero, fmt.Errorf("Invalid quantity for product %s (code: %s)", product.Name, product.ProductCode)
+			return nil, nil, nil, zero, fmt.Errorf("Invalid quantity for product %s (code: %s)", product.Name, product.ProductCode)
+		}
+
+		variant, err := orderedVariant(db, product, productDTO)
+		if err != nil {
+			return nil, nil, nil, zero, err
+		}
+
+		quantity := decimal.NewFromInt(int64(productDTO.Quantity))
+
+		if variant != nil {
+			if variant.Stock == ZERO {
+				return nil, nil, nil, zero, fmt.Errorf("Variant %s of product %s is out of stock", variant.SKU, productDTO.Code)
+			}
+
+			if variant.Stock < productDTO.Quantity {
+				return nil, nil, nil, zero, fmt.Errorf("Variant %s of product %s is not enough in stock. There are only %d left", variant.SKU, product.Name, variant.Stock)
+			}
+
+			totalPrice = totalPrice.Add(quantity.Mul(variant.EffectivePrice(product.Price)))
+			reservations = append(reservations, repository.StockReservation{ProductID: product.ID, VariantID: variant.ID, Quantity: productDTO.Quantity})
+
+			products = append(products, *product)
+			variants = append(variants, *variant)
+			continue
 		}
 
 		// check if the product
 		if product.Stock == ZERO {
-			return nil, zero, fmt.Errorf("Product %s is out of stock", productDTO.Code)
+			return nil, nil, nil, zero, fmt.Errorf("Product %s is out of stock", productDTO.Code)
 		}
 
 		if product.Stock < productDTO.Quantity {
-			return nil, zero, fmt.Errorf("Product: %s is not enough in stock. There are only %d left", product.Name, product.Stock)
+			return nil, nil, nil, zero, fmt.Errorf("Product: %s is not enough in stock. There are only %d left", product.Name, product.Stock)
 		}
 
-		quantity := decimal.NewFromInt(int64(productDTO.Quantity))
 		totalPrice = totalPrice.Add(quantity.Mul(product.Price))
-		product.Stock = product.Stock - productDTO.Quantity
-
-		_, err = repository.UpdateProduct(db, product) // update product stock
-		if err != nil {
-			return nil, zero, err
-		}
+		reservations = append(reservations, repository.StockReservation{ProductID: product.ID, Quantity: productDTO.Quantity})
 
 		products = append(products, *product)
 	}
 
 	if isCurrencyMismatch(products, user.Currency) {
-		return nil, zero, fmt.Errorf("Product currency mismatch with user's currency: %s", user.Currency)
+		return nil, nil, nil, zero, fmt.Errorf("Product currency mismatch with user's currency: %s", user.Currency)
 	}
 
-	return products, totalPrice, nil
+	return products, variants, reservations, totalPrice, nil
 }
 
 // CancelUserOrder cancels the specific order placed by a user
@@ -183,19 +246,18 @@ func validateProducts(db *gorm.DB, productsDTO []ProductDTO, user *model.User) (
 func CancelUserOrder(db *gorm.DB) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		orderReference := ctx.Param("order_reference")
-		authUserID, exists := ctx.Get("user_id")
-		if !exists {
+		if _, exists := ctx.Get("user_id"); !exists {
 			handleOrderError(ctx, http.StatusUnauthorized, "Unauthorized access", nil)
 			return
 		}
 
-		user, err := validateUser(db, authUserID.(string))
-		if err != nil || user == nil {
-			if err != nil {
-				handleOrderError(ctx, http.StatusInternalServerError, "Error in order", err)
+		user, err := authenticatedUser(ctx, db)
+		if err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				handleOrderError(ctx, http.StatusNotFound, USER_NOT_FOUND_ERROR, err)
 				return
 			}
-			handleOrderError(ctx, http.StatusNotFound, USER_NOT_FOUND_ERROR, err)
+			handleOrderError(ctx, http.StatusInternalServerError, "Error in order", err)
 			return
 		}
 
@@ -234,7 +296,7 @@ func CancelUserOrder(db *gorm.DB) gin.HandlerFunc {
 
 // PlaceOrder godoc
 // @Summary Place a new order
-// @Description Creates a new order for a user with a list of products
+// @Description Creates a new order for a user with a list of products. The shipping and billing addresses are copied from the address book of the user: the addresses given by id, otherwise the default shipping and billing addresses. The billing address falls back to the shipping address
 // @Tags Orders
 // @SecurityDefinitions.apiKey Bearer
 // @in header
@@ -244,7 +306,8 @@ func CancelUserOrder(db *gorm.DB) gin.HandlerFunc {
 // @Param Authorization header string true "Bearer Token"
 // @Param order body OrderRequest true "Order Request"
 // @Success 201 {object} handler.OrderResponse{message=string, order=model.Order} "Order placed successfully"
-// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid input"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid input, unknown address or no shipping address"
+// @Failure 403 {object} util.ErrorResponse{error=bool, error_message=string} "Email address must be verified before placing an order"
 // @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "User not found"
 // @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Failed to place order"
 // @Router /api/v1/user/order [post]
@@ -268,7 +331,7 @@ func PlaceOrder(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		user, err := validateUser(db, orderRequest.UserID)
+		user, err := authenticatedUser(ctx, db)
 		if isNotRecordNotFoundError(err) {
 			handleOrderError(ctx, http.StatusInternalServerError, PLACE_ORDER_ERROR, err)
 			return
@@ -279,16 +342,42 @@ func PlaceOrder(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		products, totalPrice, err := validateProducts(db, orderRequest.Products, user)
+		if orderRequest.UserID != "" && orderRequest.UserID != user.UserID {
+			handleOrderError(ctx, http.StatusForbidden, FOREIGN_ORDER_ERROR, nil)
+			return
+		}
+
+		if config.GetBoolEnv("REQUIRE_VERIFIED_EMAIL", false) && !user.IsVerified() {
+			handleOrderError(ctx, http.StatusForbidden, UNVERIFIED_EMAIL, nil)
+			return
+		}
+
+		shippingAddress, billingAddress, err := resolveOrderAddresses(db, orderRequest, user)
+		if err != nil {
+			if err.Error() == MISSING_ADDRESS || err.Error() == repository.ADDRESS_NOT_FOUND_ERROR {
+				handleOrderError(ctx, http.StatusBadRequest, err.Error(), nil)
+				return
+			}
+			handleOrderError(ctx, http.StatusInternalServerError, PLACE_ORDER_ERROR, err)
+			return
+		}
+
+		products, variants, reservations, totalPrice, err := validateProducts(db, orderRequest.Products, user)
 		if err != nil {
 			handleOrderError(ctx, http.StatusBadRequest, err.Error(), err)
 			return
 		}
 
-		order := newOrder(user.ID, products, orderRequest.OrderReference, totalPrice)
+		order := newOrder(user.ID, products, variants, orderRequest.OrderReference, totalPrice)
+		order.ShippingAddress = shippingAddress.Snapshot()
+		order.BillingAddress = billingAddress.Snapshot()
 
-		savedOrder, err := repository.CreateOrder(db, order)
+		savedOrder, err := repository.CreateOrder(db, order, reservations)
 		if err != nil {
+			if err.Error() == repository.OUT_OF_STOCK_ERROR {
+				handleOrderError(ctx, http.StatusBadRequest, err.Error(), err)
+				return
+			}
 			handleOrderError(ctx, http.StatusInternalServerError, "Failed to place order", err)
 			return
 		}
@@ -304,7 +393,7 @@ func PlaceOrder(db *gorm.DB) gin.HandlerFunc {
 
 // GetUserOrders godoc
 // @Summary Get user orders
-// @Description Retrieves all orders for a given user with an optional status filter
+// @Description Retrieves the orders of the authenticated user with an optional status filter
 // @Tags Orders
 // @SecurityDefinitions.apiKey Bearer
 // @in header
@@ -312,65 +401,110 @@ func PlaceOrder(db *gorm.DB) gin.HandlerFunc {
 // @Produce		json
 // @Security BearerAuth
 // @Param Authorization header string true "Bearer Token"
-// @Param user_id query string true "User ID"
+// @Param user_id query string false "User ID, must be the authenticated user when given"
 // @Param order_status query string false "Order Status (Pending, Shipped, Delivered, Cancelled)"
 // @Param page query string false "Page (Default 1)"
 // @Param size query string false "Size (Default 10)"
 // @Success 200 {object} handler.ListOrderResponse{orders=[]model.Order, message=string, total_orders=int, total_pages=int, page=int, size=int} "List of user orders"
+// @Failure 403 {object} util.ErrorResponse{error=bool, error_message=string} "Orders of another user"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "User not found"
 // @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Failed to retrieve orders"
 // @Router /api/v1/user/order [get]
 func GetUserOrders(db *gorm.DB) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		userID := ctx.Query("user_id")
-		orderStatus := ctx.Query("order_status")
-
-		page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-		if err != nil || page <= 0 {
-			page = 1
-		}
-
-		limit, err := strconv.Atoi(ctx.DefaultQuery("size", "10"))
-		if err != nil || limit <= 0 {
-			limit = 10
-		}
-
-		user, err := validateUser(db, userID)
+		user, err := authenticatedUser(ctx, db)
 		if err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				handleOrderError(ctx, http.StatusNotFound, USER_NOT_FOUND_ERROR, err)
+				return
+			}
 			handleOrderError(ctx, http.StatusInternalServerError, "error in retrieving order", err)
 			return
 		}
 
-		if user == nil {
-			handleOrderError(ctx, http.StatusNotFound, USER_NOT_FOUND_ERROR, err)
+		if userID := ctx.Query("user_id"); userID != "" && userID != user.UserID {
+			handleOrderError(ctx, http.StatusForbidden, FOREIGN_ORDERS_ERROR, nil)
 			return
 		}
 
-		id := strconv.Itoa(int(user.ID))
+		listOrders(ctx, db, user)
+	}
+}
 
-		orders, totalOrders, err := repository.GetUserOrders(db, id, orderStatus, page, limit)
+// GetOrders godoc
+// @Summary Get the orders of a user
+// @Description Retrieves all orders for a given user with an optional status filter
+// @Tags Orders
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
+// @Param user_id query string true "User ID"
+// @Param order_status query string false "Order Status (Pending, Shipped, Delivered, Cancelled)"
+// @Param page query string false "Page (Default 1)"
+// @Param size query string false "Size (Default 10)"
+// @Success 200 {object} handler.ListOrderResponse{orders=[]model.Order, message=string, total_orders=int, total_pages=int, page=int, size=int} "List of user orders"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "User not found"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Failed to retrieve orders"
+// @Router /api/v1/admin/order [get]
+func GetOrders(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, err := validateUser(db, ctx.Query("user_id"))
 		if err != nil {
-			handleOrderError(ctx, http.StatusInternalServerError, "Failed to retrieve orders", err)
+			if gorm.IsRecordNotFoundError(err) {
+				handleOrderError(ctx, http.StatusNotFound, USER_NOT_FOUND_ERROR, err)
+				return
+			}
+			handleOrderError(ctx, http.StatusInternalServerError, "error in retrieving order", err)
 			return
 		}
 
-		message := "Order retrieved successfully"
-		if len(orders) == 0 {
-			message = "No orders found"
-		}
+		listOrders(ctx, db, user)
+	}
+}
 
-		totalPages := int(math.Ceil(float64(totalOrders) / float64(limit)))
+// listOrders responds with a page of the orders of user
+func listOrders(ctx *gin.Context, db *gorm.DB, user *model.User) {
+	orderStatus := ctx.Query("order_status")
 
-		response := ListOrderResponse{
-			TotalOrders: totalOrders,
-			Page:        page,
-			TotalPages:  totalPages,
-			Size:        limit,
-			Message:     message,
-			Orders:      orders,
-		}
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
 
-		util.LogAndHandleResponse(ctx, http.StatusOK, response)
+	limit, err := strconv.Atoi(ctx.DefaultQuery("size", "10"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+
+	id := strconv.Itoa(int(user.ID))
+
+	orders, totalOrders, err := repository.GetUserOrders(db, id, orderStatus, page, limit)
+	if err != nil {
+		handleOrderError(ctx, http.StatusInternalServerError, "Failed to retrieve orders", err)
+		return
+	}
+
+	message := "Order retrieved successfully"
+	if len(orders) == 0 {
+		message = "No orders found"
+	}
+
+	totalPages := int(math.Ceil(float64(totalOrders) / float64(limit)))
+
+	response := ListOrderResponse{
+		TotalOrders: totalOrders,
+		Page:        page,
+		TotalPages:  totalPages,
+		Size:        limit,
+		Message:     message,
+		Orders:      orders,
 	}
+
+	util.LogAndHandleResponse(ctx, http.StatusOK, response)
 }
 
 // UpdateOrderStatus Update order status
@@ -382,7 +516,8 @@ func GetUserOrders(db *gorm.DB) gin.HandlerFunc {
 // @name Authorization
 // @Produce		json
 // @Security BearerAuth
-// @Param Authorization header string true "Bearer Token"
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
 // @Param order_reference path string true "Order Reference"
 //
 //	@Param updateOrder body UpdateOrderRequest true "Update Status Request"
@@ -14,7 +14,9 @@ import (
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/hackdaemon2/instashop/middleware"
 	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
 )
 
 const (
@@ -26,6 +28,7 @@ const (
 	SELECT_ORDER_QUERY   = "SELECT * FROM `orders` WHERE (order_reference = ? AND is_deleted = false)"
 	SELECT_USER_QUERY    = "SELECT * FROM `users` WHERE (user_guid = ? AND is_deleted = false) ORDER BY `users`.`id` ASC LIMIT 1"
 	SELECT_PRODUCT_QUERY = "SELECT * FROM `products` INNER JOIN `order_products` ON `order_products`.`product_id` = `products`.`id` WHERE (`order_products`.`order_id` IN (?)) AND (is_deleted = ?)"
+	RESERVE_STOCK_QUERY  = "UPDATE `products` SET `stock` = stock - ? WHERE (id = ? AND stock >= ?)"
 )
 
 func createOrderRequest() OrderRequest {
@@ -64,6 +67,13 @@ func createOrderTestContext(reqBody any, endpoint string, t *testing.T) (*httpte
 	return w, c
 }
 
+// withPrincipal authenticates the request the way the middleware does, so
+// handlers find the user without querying it
+func withPrincipal(c *gin.Context, user *model.User) {
+	c.Set(middleware.PRINCIPAL_KEY, &model.Principal{User: user, Role: user.Role})
+	c.Set("user_id", user.UserID)
+}
+
 // Helper function to create a mock user
 func createMockUser() *model.User {
 	user := &model.User{
@@ -149,9 +159,10 @@ func TestPlaceOrderProductNotFound(t *testing.T) {
 		WithArgs(TEST_ORDER_REF).
 		WillReturnError(gorm.ErrRecordNotFound)
 
-	mock.ExpectQuery(regexp.QuoteMeta(SELECT_USER_QUERY)).
-		WithArgs(sqlmock.AnyArg()).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "user_guid", "currency"}).AddRow(1, TEST_USER_ID, TEST_CURRENCY))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_DEFAULT_ADDRESSES_QUERY)).
+		WithArgs(1, true, true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "address_guid", "user_id", "country", "is_default_shipping", "is_default_billing"}).
+			AddRow(1, TEST_ADDRESS_ID, 1, "NG", true, true))
 
 	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `products` WHERE (product_code = ? AND is_deleted = false) ORDER BY `products`.`id` ASC LIMIT 1")).
 		WithArgs(sqlmock.AnyArg()).
@@ -163,9 +174,62 @@ func TestPlaceOrderProductNotFound(t *testing.T) {
 
 	orderRequest := createOrderRequest()
 	w, c := createTestContext(orderRequest, ORDER_ENDPOINT, t)
+	withPrincipal(c, createMockUser())
+	PlaceOrder(gdb)(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// The stock is taken with the order, so an order fails when the product
+// sold out after it was checked
+func TestPlaceOrderSoldOutInTheMeantime(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ORDER_QUERY)).
+		WithArgs(TEST_ORDER_REF).
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_DEFAULT_ADDRESSES_QUERY)).
+		WithArgs(1, true, true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "address_guid", "user_id", "country", "is_default_shipping", "is_default_billing"}).
+			AddRow(1, TEST_ADDRESS_ID, 1, "NG", true, true))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `products` WHERE (product_code = ? AND is_deleted = false) ORDER BY `products`.`id` ASC LIMIT 1")).
+		WithArgs(TEST_PRODUCT_CODE).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_code", "price", "stock", "currency"}).AddRow(1, TEST_PRODUCT_CODE, "10.00", 1, TEST_CURRENCY))
+	mock.ExpectQuery(regexp.QuoteMeta(COUNT_PRODUCT_VARIANTS)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(RESERVE_STOCK_QUERY)).
+		WithArgs(1, 1, 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	w, c := createTestContext(createOrderRequest(), ORDER_ENDPOINT, t)
+	withPrincipal(c, createMockUser())
 	PlaceOrder(gdb)(c)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), repository.OUT_OF_STOCK_ERROR)
+	assert.NoError(t, mock.ExpectationsWereMet(), "No order should be created")
+}
+
+// Orders cannot be placed on behalf of another user
+func TestPlaceOrderForAnotherUser(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ORDER_QUERY)).
+		WithArgs(TEST_ORDER_REF).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	orderRequest := createOrderRequest()
+	orderRequest.UserID = "another_user_id"
+	w, c := createTestContext(orderRequest, ORDER_ENDPOINT, t)
+	withPrincipal(c, createMockUser())
+	PlaceOrder(gdb)(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), FOREIGN_ORDER_ERROR)
+	assert.NoError(t, mock.ExpectationsWereMet(), "The user should come from the principal")
 }
 
 // PlaceOrder: Order Already Exists
@@ -201,3 +265,44 @@ func placeOrderTest(t *testing.T, mock sqlmock.Sqlmock, gdb *gorm.DB) *httptest.
 	PlaceOrder(gdb)(c)
 	return w
 }
+
+// Customers only list their own orders, whatever user_id they ask for
+func TestGetUserOrdersOfAnotherUser(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", ORDER_ENDPOINT+"?user_id=another_user_id", nil)
+	withPrincipal(c, createMockUser())
+	GetUserOrders(gdb)(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), FOREIGN_ORDERS_ERROR)
+	assert.NoError(t, mock.ExpectationsWereMet(), "No orders should be read")
+}
+
+// Operations staff list the orders of the user they name
+func TestGetOrdersOfUser(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_USER_QUERY)).
+		WithArgs(TEST_USER_ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_guid"}).AddRow(7, TEST_USER_ID))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `orders` WHERE (user_id = ? AND is_deleted = false)")).
+		WithArgs("7").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `orders` WHERE (user_id = ? AND is_deleted = false)")).
+		WithArgs("7").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "order_reference"}).AddRow(1, 7, TEST_ORDER_REF))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `products` INNER JOIN `order_products`")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `product_variants` INNER JOIN `order_variants`")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	w, c := newAdminTestContext("GET", "/api/v1/admin/order?user_id="+TEST_USER_ID, "")
+	GetOrders(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), TEST_ORDER_REF)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
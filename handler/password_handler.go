@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const PASSWORD_RESET_REQUESTED = "If an account exists for this email, a password reset link has been sent"
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token           string `json:"token" binding:"required"`
+	Password        string `json:"password" binding:"required"`
+	ConfirmPassword string `json:"confirm_password" binding:"required"`
+}
+
+// newPasswordResetMail builds the email that carries a password reset token.
+// When PASSWORD_RESET_URL is set the token is appended to it to form a link
+func newPasswordResetMail(user *model.User, rawToken string) util.MailMessage {
+	body := fmt.Sprintf("Hello %s,\n\nUse the following token to reset your Instashop password: %s\n", user.FirstName, rawToken)
+	if resetURL := os.Getenv("PASSWORD_RESET_URL"); resetURL != "" {
+		body += fmt.Sprintf("\nOr open this link: %s%s\n", resetURL, rawToken)
+	}
+	body += fmt.Sprintf("\nThe token expires in %s. If you did not request a password reset you can ignore this email.\n", repository.PasswordResetTTL())
+
+	return util.MailMessage{To: user.Email, Subject: "Reset your Instashop password", Body: body}
+}
+
+// sendMail delivers a message in the background so that the response
+// time does not reveal whether an email was sent
+func sendMail(mailer util.Mailer, message util.MailMessage) {
+	go func() {
+		if err := mailer.Send(message); err != nil {
+			log.Printf("unable to send %q email: %v", message.Subject, err)
+		}
+	}()
+}
+
+// ForgotPassword sends a password reset token to the user
+// @Summary Request a password reset
+// @Description Emails a single-use password reset token. The response is the same whether or not the email is registered
+// @Tags Authentication
+// @Produce		json
+// @Param forgot body ForgotPasswordRequest true "Forgot Password Request"
+// @Success 200 {object} util.ErrorResponse{error=bool, error_message=string} "Password reset requested"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid input"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Server error"
+// @Router /api/v1/user/password/forgot [post]
+func ForgotPassword(db *gorm.DB, mailer util.Mailer) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var forgotRequest ForgotPasswordRequest
+		if err := ctx.ShouldBindJSON(&forgotRequest); err != nil {
+			validationError := util.ExtractValidationErrorMessage(err, forgotRequest)
+			handleTokenError(ctx, http.StatusBadRequest, validationError[0])
+			return
+		}
+
+		util.LogIncomingRequest(forgotRequest)
+
+		user, rawToken, err := repository.CreatePasswordResetToken(db, forgotRequest.Email)
+		if err != nil {
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to process password reset")
+			return
+		}
+
+		if user != nil {
+			sendMail(mailer, newPasswordResetMail(user, rawToken))
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, util.ErrorResponse{Error: false, ErrorMessage: PASSWORD_RESET_REQUESTED})
+	}
+}
+
+// ResetPassword sets a new password using a password reset token
+// @Summary Reset a password
+// @Description Sets a new password using a token from the password reset email. Every existing token of the user is revoked
+// @Tags Authentication
+// @Produce		json
+// @Param reset body ResetPasswordRequest true "Reset Password Request"
+// @Success 200 {object} util.ErrorResponse{error=bool, error_message=string} "Password successfully reset"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid input or invalid token"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Server error"
+// @Router /api/v1/user/password/reset [post]
+func ResetPassword(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var resetRequest ResetPasswordRequest
+		if err := ctx.ShouldBindJSON(&resetRequest); err != nil {
+			validationError := util.ExtractValidationErrorMessage(err, resetRequest)
+			handleTokenError(ctx, http.StatusBadRequest, validationError[0])
+			return
+		}
+
+		if resetRequest.ConfirmPassword != resetRequest.Password {
+			handleTokenError(ctx, http.StatusBadRequest, "'password' and 'confirm_password' do not match")
+			return
+		}
+
+		if !validateNewPassword(ctx, resetRequest.Password) {
+			return
+		}
+
+		hashedPassword, err := hashPassword(resetRequest.Password)
+		if err != nil {
+			handleTokenError(ctx, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		user, err := repository.ResetPassword(db, resetRequest.Token, hashedPassword)
+		if err != nil {
+			if err.Error() == repository.INVALID_ONE_TIME_TOKEN_ERROR {
+				handleTokenError(ctx, http.StatusBadRequest, err.Error())
+				return
+			}
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to reset password")
+			return
+		}
+
+		recordAuthEvent(ctx, db, model.PasswordChangedEvent, user, "reset")
+		util.LogAndHandleResponse(ctx, http.StatusOK, util.ErrorResponse{Error: false, ErrorMessage: "Password successfully reset"})
+	}
+}
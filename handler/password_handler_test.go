@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	FORGOT_PASSWORD               = "/password/forgot"
+	RESET_PASSWORD                = "/password/reset"
+	SELECT_ONE_TIME_TOKEN_QUERY   = "SELECT * FROM `one_time_tokens` WHERE (token_hash = ? AND purpose = ?) ORDER BY `one_time_tokens`.`id` ASC LIMIT 1"
+	INVALID_OR_EXPIRED_TOKEN_TEXT = "Invalid or expired token"
+)
+
+// Unknown email gets the same response as a registered one
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf(MOCK_ERROR, err)
+	}
+	defer db.Close()
+
+	gdb, err := gorm.Open("mysql", db)
+	if err != nil {
+		t.Fatalf(OPEN_ERROR, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_QUERY)).
+		WithArgs(EMAIL).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	mailer := &recordingMailer{}
+	w, c := createTestContext(ForgotPasswordRequest{Email: EMAIL}, FORGOT_PASSWORD, t)
+	ForgotPassword(gdb, mailer)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), PASSWORD_RESET_REQUESTED)
+	assert.Empty(t, mailer.messages, "No email should be sent for an unknown account")
+}
+
+// Invalid email returns validation error
+func TestForgotPasswordInvalidEmail(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf(MOCK_ERROR, err)
+	}
+	defer db.Close()
+
+	gdb, err := gorm.Open("mysql", db)
+	if err != nil {
+		t.Fatalf(OPEN_ERROR, err)
+	}
+
+	w, c := createTestContext(ForgotPasswordRequest{Email: "not-an-email"}, FORGOT_PASSWORD, t)
+	ForgotPassword(gdb, &recordingMailer{})(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "is not a valid email")
+}
+
+// A reset token that was already used is rejected
+func TestResetPasswordUsedToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf(MOCK_ERROR, err)
+	}
+	defer db.Close()
+
+	gdb, err := gorm.Open("mysql", db)
+	if err != nil {
+		t.Fatalf(OPEN_ERROR, err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ONE_TIME_TOKEN_QUERY)).
+		WithArgs(sqlmock.AnyArg(), "password_reset").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "purpose", "expires_at", "used_at"}).
+			AddRow(1, 1, "password_reset", time.Now().Add(time.Hour), time.Now()))
+	mock.ExpectRollback()
+
+	reqBody := ResetPasswordRequest{Token: "used", Password: "correct-horse-battery", ConfirmPassword: "correct-horse-battery"}
+	w, c := createTestContext(reqBody, RESET_PASSWORD, t)
+	ResetPassword(gdb)(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), INVALID_OR_EXPIRED_TOKEN_TEXT)
+}
+
+// Password and confirm password mismatch returns 400
+func TestResetPasswordMismatch(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf(MOCK_ERROR, err)
+	}
+	defer db.Close()
+
+	gdb, err := gorm.Open("mysql", db)
+	if err != nil {
+		t.Fatalf(OPEN_ERROR, err)
+	}
+
+	reqBody := ResetPasswordRequest{Token: "token", Password: "password123", ConfirmPassword: "password456"}
+	w, c := createTestContext(reqBody, RESET_PASSWORD, t)
+	ResetPassword(gdb)(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "do not match")
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const PERSONAL_DATA_ERASED = "Personal data erased"
+
+type EraseAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code"` // required when two-factor authentication is enabled
+}
+
+// erasePersonalData anonymizes a user with a password nobody knows
+func erasePersonalData(db *gorm.DB, user *model.User) error {
+	randomPassword, err := util.GenerateSecureToken(32)
+	if err != nil {
+		return err
+	}
+
+	unusablePassword, err := hashPassword(randomPassword)
+	if err != nil {
+		return err
+	}
+	return repository.ErasePersonalData(db, user, unusablePassword)
+}
+
+// ExportPersonalData returns a copy of the data stored about the authenticated user
+// @Summary Export personal data
+// @Description Returns a JSON archive with the profile, orders, addresses, sessions, linked identities, API keys and two-factor enrollment of the authenticated user
+// @Tags User
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {object} model.PersonalDataExport "Personal data archive"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Unauthorized access"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to export personal data"
+// @Router /api/v1/user/me/export [get]
+func ExportPersonalData(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, err := authenticatedUser(ctx, db)
+		if err != nil {
+			handleTokenError(ctx, http.StatusUnauthorized, "Unauthorized access")
+			return
+		}
+
+		export, err := repository.ExportPersonalData(db, user)
+		if err != nil {
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to export personal data")
+			return
+		}
+
+		// the archive is not logged, it is made of personal data
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="instashop-%s.json"`, user.UserID))
+		ctx.IndentedJSON(http.StatusOK, export)
+	}
+}
+
+// EraseAccount erases the personal data of the authenticated user
+// @Summary Erase account
+// @Description Anonymizes the profile of the authenticated user, deletes their addresses, sessions, tokens, linked identities, API keys and two-factor enrollment and deactivates the account. Orders are kept for accounting with their addresses cleared. Users with two-factor authentication must also send a current code. Wrong passwords and codes count as failed logins
+// @Tags User
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param request body EraseAccountRequest true "Erase Account Request"
+// @Success 200 {object} util.ErrorResponse{error=bool, error_message=string} "Personal data erased"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid input"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Wrong password or code"
+// @Failure 429 {object} util.ErrorResponse{error=bool, error_message=string} "Too many failed attempts"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to erase personal data"
+// @Router /api/v1/user/me [delete]
+func EraseAccount(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var eraseRequest EraseAccountRequest
+		if err := ctx.ShouldBindJSON(&eraseRequest); err != nil {
+			validationError := util.ExtractValidationErrorMessage(err, eraseRequest)
+			handleTokenError(ctx, http.StatusBadRequest, validationError[0])
+			return
+		}
+
+		user, err := authenticatedUser(ctx, db)
+		if err != nil {
+			handleTokenError(ctx, http.StatusUnauthorized, "Unauthorized access")
+			return
+		}
+
+		if !checkReauthenticationAllowed(ctx, db, user, "erase_account") {
+			return
+		}
+
+		if !repository.PasswordMatches(user, eraseRequest.Password) {
+			recordReauthenticationFailure(ctx, db, user, "erase_account")
+			handleTokenError(ctx, http.StatusUnauthorized, "Password is incorrect")
+			return
+		}
+
+		twoFactorEnabled, err := repository.IsTwoFactorEnabled(db, user)
+		if err != nil {
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to erase personal data")
+			return
+		}
+
+		if twoFactorEnabled {
+			if err := repository.VerifyTwoFactorCode(db, user, eraseRequest.Code); err != nil {
+				if err.Error() == repository.INVALID_TWO_FACTOR_CODE_ERROR {
+					recordReauthenticationFailure(ctx, db, user, "erase_account")
+				}
+				handleTwoFactorError(ctx, err, "Unable to erase personal data")
+				return
+			}
+		}
+
+		if err := erasePersonalData(db, user); err != nil {
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to erase personal data")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, util.ErrorResponse{Error: false, ErrorMessage: PERSONAL_DATA_ERASED})
+	}
+}
+
+// EraseUser erases the personal data of a user on their behalf
+// @Summary Erase a user
+// @Description Anonymizes a user on their behalf, e.g. after an erasure request received by email, and deactivates the account. Orders are kept for accounting with their addresses cleared
+// @Tags Users
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param user_id path string true "User ID"
+// @Success 200 {object} util.ErrorResponse{error=bool, error_message=string} "Personal data erased"
+// @Failure 403 {object} util.ErrorResponse{error=bool, error_message=string} "Administrators cannot erase themselves"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "User not found"
+// @Failure 409 {object} util.ErrorResponse{error=bool, error_message=string} "The personal data of this user has been erased"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to erase personal data"
+// @Router /api/v1/admin/users/{user_id}/erase [post]
+func EraseUser(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := findManagedUser(ctx, db)
+		if !ok {
+			return
+		}
+
+		if isSelf(ctx, user) {
+			handleAdminUserError(ctx, http.StatusForbidden, "Administrators cannot erase themselves", nil)
+			return
+		}
+
+		if err := erasePersonalData(db, user); err != nil {
+			if err.Error() == repository.USER_ERASED_ERROR {
+				handleAdminUserError(ctx, http.StatusConflict, err.Error(), nil)
+				return
+			}
+			handleAdminUserError(ctx, http.StatusInternalServerError, "Unable to erase personal data", err)
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, util.ErrorResponse{Error: false, ErrorMessage: PERSONAL_DATA_ERASED})
+	}
+}
@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+)
+
+const (
+	ERASED_USER_ID = "erased_user_id"
+	EXPORT         = "/user/me/export"
+)
+
+// The export contains the profile and related records but no secrets
+func TestExportPersonalData(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_USER_QUERY)).
+		WithArgs(ADMIN_ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_guid", "email", "password", "first_name"}).
+			AddRow(1, ADMIN_ID, EMAIL, "$2a$10$hash", "Jane"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `orders` WHERE (user_id = ?) ORDER BY id ASC")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_reference", "total_price"}).AddRow(5, "order123", "10.50"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `products` INNER JOIN `order_products`")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_code", "order_id", "product_id"}).AddRow(3, "product123", 5, 3))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `product_variants` INNER JOIN `order_variants`")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sku", "order_id", "product_variant_id"}).AddRow(7, "SHIRT-RED-M", 5, 7))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `variant_options`")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "variant_id", "name", "value"}).AddRow(1, 7, "color", "red"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `addresses` WHERE (user_id = ?) ORDER BY id ASC")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "address_guid", "full_name", "country"}).AddRow(1, "address-1", "Jane Doe", "NG"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `sessions` WHERE (user_id = ?) ORDER BY id ASC")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "session_id", "user_agent"}).AddRow(1, "session-1", "curl/8.0"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `user_identities` WHERE (user_id = ?) ORDER BY id ASC")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `api_keys` WHERE (owner_id = ?) ORDER BY id ASC")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "key_hash"}).AddRow(2, "warehouse", "secret-hash"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `auth_events` WHERE (user_guid = ?) ORDER BY id ASC")).
+		WithArgs(ADMIN_ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "event_type", "user_guid", "ip_address"}).AddRow(4, "login_succeeded", ADMIN_ID, "192.0.2.1"))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_TWO_FACTOR_QUERY)).
+		WithArgs(1).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	w, c := newAdminTestContext("GET", EXPORT, "")
+	ExportPersonalData(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+	assert.NotContains(t, w.Body.String(), "$2a$10$hash")
+	assert.NotContains(t, w.Body.String(), "secret-hash")
+
+	var export struct {
+		User struct {
+			Email string `json:"Email"`
+		} `json:"user"`
+		Orders []struct {
+			OrderReference string `json:"order_reference"`
+			Products       []struct {
+				ProductCode string `json:"product_code"`
+			} `json:"products"`
+			Variants []struct {
+				SKU string `json:"sku"`
+			} `json:"variants"`
+		} `json:"orders"`
+		Addresses  []map[string]any `json:"addresses"`
+		Sessions   []map[string]any `json:"sessions"`
+		Identities []map[string]any `json:"linked_identities"`
+		APIKeys    []map[string]any `json:"api_keys"`
+		AuthEvents []map[string]any `json:"auth_events"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &export))
+	assert.Equal(t, EMAIL, export.User.Email)
+	assert.Equal(t, "order123", export.Orders[0].OrderReference)
+	assert.Equal(t, "product123", export.Orders[0].Products[0].ProductCode)
+	assert.Equal(t, "SHIRT-RED-M", export.Orders[0].Variants[0].SKU, "Variant line items should be exported")
+	assert.Len(t, export.Addresses, 1)
+	assert.Len(t, export.Sessions, 1)
+	assert.NotNil(t, export.Identities, "Empty collections should be exported as empty lists")
+	assert.Len(t, export.APIKeys, 1)
+	assert.Len(t, export.AuthEvents, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Erasing an account needs the current password
+func TestEraseAccountWrongPassword(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_USER_QUERY)).
+		WithArgs(TEST_USER_ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_guid", "email", "password"}).
+			AddRow(1, TEST_USER_ID, EMAIL, "$2a$10$BvynkDL3zqY9wn8J6QFUD.XiSETqPtPPvs5VjH//EAJflvXNP3wRe"))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ATTEMPTS)).
+		WithArgs("account:"+EMAIL, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	expectLoginFailure(mock)
+
+	w, c := createTestContext(EraseAccountRequest{Password: "wrong-password"}, "/user/me", t)
+	c.Set("user_id", TEST_USER_ID)
+	EraseAccount(gdb)(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Password is incorrect")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Once the login limit is reached the account cannot be erased, even with the right password
+func TestEraseAccountLockedOut(t *testing.T) {
+	gdb, mock := newProfileTestDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ATTEMPTS)).
+		WithArgs("account:"+EMAIL, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "attempt_key", "failures", "window_started_at", "last_failed_at", "locked_until"}).
+			AddRow(1, "account:"+EMAIL, 5, time.Now(), time.Now(), time.Now().Add(10*time.Minute)))
+	expectAuthEvent(mock, model.LoginLockedOutEvent)
+
+	w, c := createTestContext(EraseAccountRequest{Password: CURRENT_PASSWORD}, "/user/me", t)
+	c.Set("user_id", TEST_USER_ID)
+	EraseAccount(gdb)(c)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Erasure anonymizes the user, deletes their records and keeps their orders
+func TestEraseUser(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ANY_USER_QUERY)).
+		WithArgs(ERASED_USER_ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_guid", "email", "first_name", "last_name"}).
+			AddRow(2, ERASED_USER_ID, EMAIL, "Jane", "Doe"))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `email` = ?, `first_name` = ?, `is_deleted` = ?, `last_name` = ?, `password` = ?, `updated_at` = ?, `verified_at` = ? WHERE `users`.`id` = ?")).
+		WithArgs(ERASED_USER_ID+"@erased.invalid", "", true, "", sqlmock.AnyArg(), sqlmock.AnyArg(), nil, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// orders keep their countries but lose the rest of their addresses
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `orders` SET `billing_city` = ?, `billing_full_name` = ?, `billing_line1` = ?, `billing_line2` = ?, `billing_phone` = ?, `billing_postal_code` = ?, `billing_region` = ?, `shipping_city` = ?, `shipping_full_name` = ?, `shipping_line1` = ?, `shipping_line2` = ?, `shipping_phone` = ?, `shipping_postal_code` = ?, `shipping_region` = ? WHERE (user_id = ?)")).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	for _, table := range []string{"sessions", "refresh_tokens", "one_time_tokens", "two_factor_auths", "recovery_codes", "user_identities", "addresses"} {
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `" + table + "` WHERE (user_id = ?)")).
+			WithArgs(2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `api_keys` WHERE (owner_id = ?)")).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `login_attempts` WHERE (attempt_key = ?)")).
+		WithArgs("account:" + EMAIL).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	// audit events stay but lose the email, address and device
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `auth_events` SET `email` = ?, `ip_address` = ?, `user_agent` = ? WHERE (user_guid = ? OR (user_guid = '' AND email = ?))")).
+		WithArgs("", "", "", ERASED_USER_ID, EMAIL).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectCommit()
+
+	// outstanding access tokens are revoked once the data is gone
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `user_token_revocations`")).
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `user_token_revocations`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `refresh_tokens`")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `sessions`")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	w, c := newAdminTestContext("POST", ADMIN_USERS+"/"+ERASED_USER_ID+"/erase", ERASED_USER_ID)
+	EraseUser(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), PERSONAL_DATA_ERASED)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Erased users cannot be erased again or reactivated
+func TestEraseUserAlreadyErased(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	for range 2 {
+		mock.ExpectQuery(regexp.QuoteMeta(SELECT_ANY_USER_QUERY)).
+			WithArgs(ERASED_USER_ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_guid", "email", "is_deleted"}).
+				AddRow(2, ERASED_USER_ID, ERASED_USER_ID+"@erased.invalid", true))
+	}
+
+	w, c := newAdminTestContext("POST", ADMIN_USERS+"/"+ERASED_USER_ID+"/erase", ERASED_USER_ID)
+	EraseUser(gdb)(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), repository.USER_ERASED_ERROR)
+
+	w, c = newAdminTestContext("POST", ADMIN_USERS+"/"+ERASED_USER_ID+"/reactivate", ERASED_USER_ID)
+	ReactivateUser(gdb)(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
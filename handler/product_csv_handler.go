@@ -0,0 +1,490 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/config"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	DEFAULT_PRODUCT_IMPORT_MAX_MB   = 10
+	DEFAULT_PRODUCT_IMPORT_MAX_ROWS = 10000
+	PRODUCT_EXPORT_BATCH_SIZE       = 500
+	MAX_PRODUCT_FIELD_LENGTH        = 255
+	CSV_FORMULA_PREFIXES            = "=+-@\t\r" // spreadsheets treat cells starting with these as formulas
+)
+
+// productCSVColumns are the columns of product CSV files, in the order they are exported
+var productCSVColumns = []string{"product_code", "product_name", "product_description", "price", "stock", "currency"}
+
+// maxProductPrice is the largest price a decimal(10,2) column holds
+var maxProductPrice = decimal.New(1, 8)
+
+type ImportRowError struct {
+	Row         int    `json:"row" example:"7"` // line of the file, the header being line 1
+	ProductCode string `json:"product_code,omitempty" example:"KETTLE-1"`
+	Error       string `json:"error" example:"price must be a non-negative number"`
+}
+
+type ImportProductsResponse struct {
+	Message   string           `json:"message"`
+	DryRun    bool             `json:"dry_run"`
+	Created   int              `json:"created"`
+	Updated   int              `json:"updated"`
+	Unchanged int              `json:"unchanged"`
+	Failed    int              `json:"failed"`
+	Errors    []ImportRowError `json:"errors"`
+}
+
+// importRow is a line of an import file, with the cells of the columns the file has
+type importRow struct {
+	line  int
+	cells map[string]string
+}
+
+// escapeCSVCell keeps spreadsheets from running text that looks like a
+// formula by quoting it with an apostrophe, which unescapeCSVCell removes
+func escapeCSVCell(value string) string {
+	if value != "" && strings.ContainsRune(CSV_FORMULA_PREFIXES, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func unescapeCSVCell(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(CSV_FORMULA_PREFIXES, rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+// productCSVRecord is the line of a product in an export
+func productCSVRecord(product *model.Product) []string {
+	return []string{
+		escapeCSVCell(product.ProductCode),
+		escapeCSVCell(product.Name),
+		escapeCSVCell(product.Description),
+		product.Price.StringFixed(2),
+		strconv.FormatUint(uint64(product.Stock), 10),
+		product.Currency,
+	}
+}
+
+// openImportFile returns the CSV file of an import request, either the file
+// field of a multipart form or the request body itself. It responds itself
+// when there is no file
+func openImportFile(ctx *gin.Context) (io.ReadCloser, bool) {
+	maxSize := int64(config.GetIntEnv("PRODUCT_IMPORT_MAX_MB", DEFAULT_PRODUCT_IMPORT_MAX_MB)) << 20
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSize)
+
+	if !strings.HasPrefix(ctx.ContentType(), "multipart/") {
+		return ctx.Request.Body, true
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			handleProductError(ctx, http.StatusRequestEntityTooLarge, fmt.Sprintf("Import files may be at most %d MB", maxSize>>20))
+			return nil, false
+		}
+		handleProductError(ctx, http.StatusBadRequest, "file is required, send the CSV as a multipart form file or as the request body")
+		return nil, false
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		handleProductError(ctx, http.StatusBadRequest, INVALID_USER_INPUT)
+		return nil, false
+	}
+	return file, true
+}
+
+// readImportRows reads the header and the lines of an import file. Lines with
+// the wrong number of cells are reported in rowErrors, problems with the file
+// as a whole in the returned error
+func readImportRows(file io.Reader) (rows []importRow, rowErrors []ImportRowError, err error) {
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return nil, nil, err
+		}
+		if err == io.EOF {
+			return nil, nil, errors.New("the file is empty, it needs a header line")
+		}
+		return nil, nil, fmt.Errorf("the header line cannot be read: %v", err)
+	}
+
+	columns := make([]string, 0, len(header))
+	for i, column := range header {
+		if i == 0 {
+			column = strings.TrimPrefix(column, "\ufeff") // spreadsheets often start UTF-8 files with a byte order mark
+		}
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !slices.Contains(productCSVColumns, column) {
+			return nil, nil, fmt.Errorf("unknown column %q, columns are %s", column, strings.Join(productCSVColumns, ", "))
+		}
+		if slices.Contains(columns, column) {
+			return nil, nil, fmt.Errorf("column %q appears more than once", column)
+		}
+		columns = append(columns, column)
+	}
+
+	if !slices.Contains(columns, "product_code") {
+		return nil, nil, errors.New("the product_code column is required")
+	}
+
+	maxRows := config.GetIntEnv("PRODUCT_IMPORT_MAX_ROWS", DEFAULT_PRODUCT_IMPORT_MAX_ROWS)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, rowErrors, nil
+		}
+
+		var parseError *csv.ParseError
+		if err != nil && (!errors.As(err, &parseError) || !errors.Is(parseError.Err, csv.ErrFieldCount)) {
+			return nil, nil, err
+		}
+
+		if len(rows)+len(rowErrors) >= maxRows {
+			return nil, nil, fmt.Errorf("import files may have at most %d products", maxRows)
+		}
+
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			rowErrors = append(rowErrors, ImportRowError{Row: line, Error: fmt.Sprintf("expected %d cells, found %d", len(columns), len(record))})
+			continue
+		}
+
+		cells := make(map[string]string, len(columns))
+		for i, column := range columns {
+			cells[column] = unescapeCSVCell(strings.TrimSpace(record[i]))
+		}
+		rows = append(rows, importRow{line: line, cells: cells})
+	}
+}
+
+// applyImportRow copies the cells of a line onto a product and returns the
+// columns it set, so that updates leave the other columns alone. On error it
+// returns what is wrong with the first invalid cell
+func applyImportRow(row importRow, product *model.Product) (map[string]any, string) {
+	columns := make(map[string]any, len(row.cells))
+
+	if name, ok := row.cells["product_name"]; ok {
+		if len(name) < 3 || len(name) > MAX_PRODUCT_FIELD_LENGTH {
+			return nil, "product_name must be between 3 and 255 characters long"
+		}
+		product.Name = name
+		columns["product_name"] = product.Name
+	}
+
+	if description, ok := row.cells["product_description"]; ok {
+		if len(description) > MAX_PRODUCT_FIELD_LENGTH {
+			return nil, "product_description must be at most 255 characters long"
+		}
+		product.Description = description
+		columns["product_description"] = product.Description
+	}
+
+	if value, ok := row.cells["price"]; ok {
+		price, err := decimal.NewFromString(value)
+		if err != nil || price.IsNegative() || !price.LessThan(maxProductPrice) {
+			return nil, "price must be a non-negative number below 100000000"
+		}
+		if !price.Equal(price.Round(2)) {
+			return nil, "price may have at most 2 decimal places"
+		}
+		product.Price = price
+		columns["price"] = product.Price
+	}
+
+	if value, ok := row.cells["stock"]; ok {
+		stock, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, "stock must be a whole number of at least 0"
+		}
+		product.Stock = uint(stock)
+		columns["stock"] = product.Stock
+	}
+
+	if currency, ok := row.cells["currency"]; ok {
+		if len(currency) != 3 {
+			return nil, "currency must be a three-letter currency code"
+		}
+		product.Currency = strings.ToUpper(currency)
+		columns["currency"] = product.Currency
+	}
+	return columns, ""
+}
+
+// sameProductFields reports whether an import leaves a product as it was
+func sameProductFields(a, b *model.Product) bool {
+	return a.Name == b.Name && a.Description == b.Description && a.Price.Equal(b.Price) &&
+		a.Stock == b.Stock && a.Currency == b.Currency
+}
+
+// ImportProducts creates and updates products from a CSV file
+// @Summary Import products from CSV
+// @Description Creates or updates a product for each line of a CSV file, matched by product_code. The header names the columns: product_code (required), product_name, product_description, price, stock and currency. Updates only change the columns in the file; new products need product_name, price and currency. Lines with errors are reported and skipped, unless all_or_nothing is set, in which case any error stops the whole import. With dry_run nothing is saved. Send the file as the file field of a multipart form or as a text/csv body
+// @Tags Products
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Accept		multipart/form-data
+// @Accept		text/csv
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
+// @Param file formData file false "CSV file"
+// @Param dry_run query boolean false "Only report what the import would do (Default false)"
+// @Param all_or_nothing query boolean false "Import nothing when any line has an error (Default false)"
+// @Success 200 {object} handler.ImportProductsResponse "Import report"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid file"
+// @Failure 413 {object} util.ErrorResponse{error=bool, error_message=string} "File too large"
+// @Failure 422 {object} handler.ImportProductsResponse "Nothing imported, as some lines have errors"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to import products"
+// @Router /api/v1/admin/products/import [post]
+func ImportProducts(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		dryRun, err := strconv.ParseBool(ctx.DefaultQuery("dry_run", "false"))
+		if err != nil {
+			handleProductError(ctx, http.StatusBadRequest, "dry_run must be true or false")
+			return
+		}
+
+		allOrNothing, err := strconv.ParseBool(ctx.DefaultQuery("all_or_nothing", "false"))
+		if err != nil {
+			handleProductError(ctx, http.StatusBadRequest, "all_or_nothing must be true or false")
+			return
+		}
+
+		user, err := authenticatedUser(ctx, db)
+		if err != nil || user == nil {
+			handleProductError(ctx, http.StatusBadRequest, INVALID_USER_INPUT)
+			return
+		}
+
+		file, ok := openImportFile(ctx)
+		if !ok {
+			return
+		}
+		defer file.Close()
+
+		rows, rowErrors, err := readImportRows(file)
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				handleProductError(ctx, http.StatusRequestEntityTooLarge, fmt.Sprintf("Import files may be at most %d MB", maxBytesError.Limit>>20))
+				return
+			}
+			handleProductError(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		codes := make([]string, 0, len(rows))
+		listed := make(map[string]struct{}, len(rows))
+		for _, row := range rows {
+			code := row.cells["product_code"]
+			if _, ok := listed[code]; code == "" || ok {
+				continue
+			}
+			listed[code] = struct{}{}
+			codes = append(codes, code)
+		}
+
+		existing, err := repository.FindProductsByCode(db, codes)
+		if err != nil {
+			log.Println(err.Error())
+			handleProductError(ctx, http.StatusInternalServerError, "Unable to import products")
+			return
+		}
+
+		response := ImportProductsResponse{DryRun: dryRun}
+		var created []*model.Product
+		var updated []repository.ProductUpdate
+		var priceChanges []*model.PriceChange
+		seen := make(map[string]bool, len(rows))
+
+		for _, row := range rows {
+			code := row.cells["product_code"]
+			rowError := ImportRowError{Row: row.line, ProductCode: code}
+
+			switch {
+			case code == "" || len(code) > MAX_PRODUCT_FIELD_LENGTH:
+				rowError.Error = "product_code must be between 1 and 255 characters long"
+			case seen[code]:
+				rowError.Error = "product_code appears on an earlier line"
+			case existing[code] != nil && existing[code].IsDeleted:
+				rowError.Error = "product_code belongs to a deleted product"
+			}
+			seen[code] = true
+
+			product := &model.Product{ProductCode: code, UserID: user.ID}
+			if current := existing[code]; current != nil {
+				copied := *current
+				product = &copied
+			} else if rowError.Error == "" {
+				for _, column := range []string{"product_name", "price", "currency"} {
+					if _, ok := row.cells[column]; !ok {
+						rowError.Error = column + " is required for new products"
+						break
+					}
+				}
+			}
+
+			var columns map[string]any
+			if rowError.Error == "" {
+				columns, rowError.Error = applyImportRow(row, product)
+			}
+
+			if rowError.Error != "" {
+				rowErrors = append(rowErrors, rowError)
+				continue
+			}
+
+			switch current := existing[code]; {
+			case current == nil:
+				created = append(created, product)
+			case sameProductFields(current, product):
+				response.Unchanged++
+			default:
+				updated = append(updated, repository.ProductUpdate{Product: product, Columns: columns})
+				if change := repository.NewPriceChange(product, current.Price, model.ImportPriceChange, user.UserID); change != nil {
+					priceChanges = append(priceChanges, change)
+				}
+			}
+		}
+
+		slices.SortFunc(rowErrors, func(a, b ImportRowError) int { return a.Row - b.Row })
+		response.Created = len(created)
+		response.Updated = len(updated)
+		response.Failed = len(rowErrors)
+		response.Errors = rowErrors
+		if response.Errors == nil {
+			response.Errors = []ImportRowError{}
+		}
+
+		if allOrNothing && len(rowErrors) > 0 {
+			response.Message = "Nothing was imported, fix the lines with errors first"
+			util.LogAndHandleResponse(ctx, http.StatusUnprocessableEntity, response)
+			return
+		}
+
+		if dryRun {
+			response.Message = "Dry run, nothing was saved"
+			util.LogAndHandleResponse(ctx, http.StatusOK, response)
+			return
+		}
+
+		if err := repository.ImportProducts(db, created, updated, priceChanges); err != nil {
+			log.Println(err.Error())
+			handleProductError(ctx, http.StatusInternalServerError, "Unable to import products")
+			return
+		}
+
+		response.Message = "Products imported"
+		util.LogAndHandleResponse(ctx, http.StatusOK, response)
+	}
+}
+
+// ExportProducts streams the product catalog as CSV
+// @Summary Export products to CSV
+// @Description Streams the products matching the filters as a CSV file in the format ImportProducts reads, in the order they were created. Cells that spreadsheets would run as formulas start with an apostrophe, which imports remove again
+// @Tags Products
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		text/csv
+// @Security BearerAuth
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param currency query string false "Currency code, e.g. NGN"
+// @Param in_stock query boolean false "Only export products that are in stock"
+// @Param category query string false "Only export products of this category, given by slug, and of its subcategories"
+// @Success 200 {file} file "CSV file"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid filter"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Category not found"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to export products"
+// @Router /api/v1/admin/products/export [get]
+func ExportProducts(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		filter, message := parseProductFilter(ctx)
+		if message != "" {
+			handleProductError(ctx, http.StatusBadRequest, message)
+			return
+		}
+
+		if slug := ctx.Query("category"); slug != "" {
+			category, err := repository.FindCategory(db, slug)
+			if err != nil {
+				handleCategoryError(ctx, err, "Unable to export products")
+				return
+			}
+			filter = repository.CategoryProductFilter(category, filter)
+		}
+
+		writer := csv.NewWriter(ctx.Writer)
+		started := false
+		start := func() {
+			// the response only starts with the first batch, so that a failing
+			// first query can still be answered with an error
+			ctx.Header("Content-Type", "text/csv; charset=utf-8")
+			ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="products-%s.csv"`, time.Now().Format("20060102")))
+			ctx.Status(http.StatusOK)
+			writer.Write(productCSVColumns)
+			started = true
+		}
+
+		err := repository.EachProduct(db, filter, PRODUCT_EXPORT_BATCH_SIZE, func(products []*model.Product) error {
+			if !started {
+				start()
+			}
+
+			for _, product := range products {
+				writer.Write(productCSVRecord(product))
+			}
+			writer.Flush()
+			ctx.Writer.Flush()
+			return writer.Error()
+		})
+
+		if err != nil && !started {
+			log.Println(err.Error())
+			handleProductError(ctx, http.StatusInternalServerError, "Unable to export products")
+			return
+		}
+
+		if err != nil {
+			// the client notices the cut off file, the status has already been sent
+			log.Println("Product export stopped:", err)
+			return
+		}
+
+		if !started {
+			start()
+			writer.Flush()
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	IMPORT_ENDPOINT               = "/api/v1/admin/products/import"
+	EXPORT_ENDPOINT               = "/api/v1/admin/products/export"
+	SELECT_PRODUCTS_BY_CODE       = "SELECT * FROM `products` WHERE (product_code IN (?,?,?,?,?))"
+	INSERT_PRODUCT_QUERY          = "INSERT INTO `products` (`product_name`,`product_description`,`product_code`,`price`,`stock`,`currency`,`user_id`,`created_at`,`updated_at`) VALUES (?,?,?,?,?,?,?,?,?)"
+	SELECT_PRODUCT_DELETED_QUERY  = "SELECT `is_deleted` FROM `products` WHERE (id = ?)"
+	UPDATE_PRODUCT_QUERY          = "UPDATE `products` SET `currency` = ?, `price` = ?, `product_name` = ?, `stock` = ?, `updated_at` = ? WHERE (id = ?)"
+	EXPORT_FIRST_PRODUCTS_QUERY   = "SELECT * FROM `products` WHERE (is_deleted = false) AND (currency = ?) AND (id > ?) ORDER BY id ASC LIMIT 500"
+	IMPORT_TEST_CSV_HEADER        = "product_code,product_name,price,stock,currency\n"
+	IMPORT_TEST_EXISTING_PRODUCTS = "cups,Tea Cups,8.00,10,NGN\n"
+)
+
+var importProductColumns = []string{"id", "product_name", "product_description", "product_code", "price", "stock", "currency", "is_deleted"}
+
+func newImportContext(query, csvBody string) (*httptest.ResponseRecorder, *gin.Context) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", IMPORT_ENDPOINT+query, strings.NewReader(csvBody))
+	c.Request.Header.Set(CONTEXT_TYPE, "text/csv")
+	withPrincipal(c, createMockUser())
+	return w, c
+}
+
+// expectImportLookup returns the cups, which the import changes, the blender,
+// which it leaves as it is, and a deleted product
+func expectImportLookup(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_PRODUCTS_BY_CODE)).
+		WithArgs("KETTLE-1", "cups", "blender", "old", "broken").
+		WillReturnRows(sqlmock.NewRows(importProductColumns).
+			AddRow(2, "Tea Cups", "Set of cups", "cups", "8.00", 10, "NGN", false).
+			AddRow(3, "Blender", "", "blender", "45.00", 2, "NGN", false).
+			AddRow(4, "Old Kettle", "", "old", "5.00", 0, "NGN", true))
+}
+
+const importTestCSV = IMPORT_TEST_CSV_HEADER +
+	"KETTLE-1,Electric Kettle,20.5,4,ngn\n" +
+	"cups,Tea Cups,9.00,10,NGN\n" +
+	"blender,Blender,45,2,NGN\n" +
+	"old,Old Kettle,5.00,1,NGN\n" +
+	"broken,Broken,cheap,1,NGN\n" +
+	"cups,Tea Cups,9.00,10\n" +
+	"cups,Tea Cups,9.00,11,NGN\n"
+
+func TestImportProductsReportsEachLine(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	expectImportLookup(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(INSERT_PRODUCT_QUERY)).
+		WithArgs("Electric Kettle", "", "KETTLE-1", "20.5", 4, "NGN", 1, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_PRODUCT_DELETED_QUERY)).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"is_deleted"}).AddRow(false))
+	mock.ExpectExec(regexp.QuoteMeta(UPDATE_PRODUCT_QUERY)).
+		WithArgs("NGN", "9", "Tea Cups", 10, sqlmock.AnyArg(), 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(INSERT_PRICE_CHANGE_QUERY)).
+		WithArgs(2, "8", "9", "NGN", "import", "test_user_id", "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	w, c := newImportContext("", importTestCSV)
+	ImportProducts(gdb)(c)
+
+	body := w.Body.String()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, body, `"created":1,"updated":1,"unchanged":1,"failed":4`)
+	assert.Contains(t, body, `{"row":5,"product_code":"old","error":"product_code belongs to a deleted product"}`)
+	assert.Contains(t, body, `{"row":6,"product_code":"broken","error":"price must be a non-negative number below 100000000"}`)
+	assert.Contains(t, body, `{"row":7,"error":"expected 5 cells, found 4"}`)
+	assert.Contains(t, body, `{"row":8,"product_code":"cups","error":"product_code appears on an earlier line"}`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Dry runs and imports with errors in all_or_nothing mode save nothing
+func TestImportProductsWithoutSaving(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	expectImportLookup(mock)
+	w, c := newImportContext("?dry_run=true", importTestCSV)
+	ImportProducts(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"dry_run":true,"created":1,"updated":1,"unchanged":1,"failed":4`)
+
+	expectImportLookup(mock)
+	w, c = newImportContext("?all_or_nothing=true", importTestCSV)
+	ImportProducts(gdb)(c)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "Nothing was imported")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportProductsRejectsInvalidFiles(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	for body, message := range map[string]string{
+		"":                                "the file is empty",
+		"product_code,colour\n":           `unknown column \"colour\"`,
+		"product_name,price\n":            "the product_code column is required",
+		"product_code,price,PRICE\n":      `column \"price\" appears more than once`,
+		"product_code\n\"unterminated\n":  "extraneous or missing",
+		"\ufeffProduct_Code,Stock\nx,1\n": "",
+	} {
+		if message == "" {
+			// a byte order mark and upper case column names are fine
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `products` WHERE (product_code IN (?))")).
+				WithArgs("x").
+				WillReturnRows(sqlmock.NewRows(importProductColumns))
+		}
+
+		w, c := newImportContext("?dry_run=true", body)
+		ImportProducts(gdb)(c)
+
+		if message == "" {
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), "product_name is required for new products")
+			continue
+		}
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		assert.Contains(t, w.Body.String(), message, body)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Updates only write the columns of the file, so stock taken by orders and
+// prices set since the products were read are kept
+func TestImportProductsUpdatesOnlyTheColumnsInTheFile(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `products` WHERE (product_code IN (?))")).
+		WithArgs("cups").
+		WillReturnRows(sqlmock.NewRows(importProductColumns).AddRow(2, "Tea Cups", "", "cups", "8.00", 10, "NGN", false))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `products` SET `product_description` = ?, `updated_at` = ? WHERE (id = ?)")).
+		WithArgs("Set of six cups", sqlmock.AnyArg(), 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	w, c := newImportContext("", "product_code,product_description\ncups,Set of six cups\n")
+	ImportProducts(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"updated":1`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Files can also be sent as a multipart form, as browsers do
+func TestImportProductsFromMultipartForm(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "products.csv")
+	assert.NoError(t, err)
+	_, err = part.Write([]byte(IMPORT_TEST_CSV_HEADER + IMPORT_TEST_EXISTING_PRODUCTS))
+	assert.NoError(t, err)
+	assert.NoError(t, form.Close())
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `products` WHERE (product_code IN (?))")).
+		WithArgs("cups").
+		WillReturnRows(sqlmock.NewRows(importProductColumns).AddRow(2, "Tea Cups", "", "cups", "8.00", 10, "NGN", false))
+
+	w, c := newImportContext("", "")
+	c.Request = httptest.NewRequest("POST", IMPORT_ENDPOINT, &body)
+	c.Request.Header.Set(CONTEXT_TYPE, form.FormDataContentType())
+	ImportProducts(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"unchanged":1`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportProducts(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(EXPORT_FIRST_PRODUCTS_QUERY)).
+		WithArgs("NGN", 0).
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(1, "Electric Kettle", "Boils water, fast", "kettle", "20.50", 4, "NGN").
+			AddRow(2, "=HYPERLINK(\"http://evil\")", "", "cups", "8", 10, "NGN"))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", EXPORT_ENDPOINT+"?currency=ngn", nil)
+	ExportProducts(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+	assert.Equal(t, "product_code,product_name,product_description,price,stock,currency\n"+
+		"kettle,Electric Kettle,\"Boils water, fast\",20.50,4,NGN\n"+
+		"cups,\"'=HYPERLINK(\"\"http://evil\"\")\",,8.00,10,NGN\n", w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEscapeCSVCellRoundTrip(t *testing.T) {
+	for _, value := range []string{"=1+1", "+234", "-", "@SUM(A1)", "plain", "'quoted", ""} {
+		assert.Equal(t, value, unescapeCSVCell(escapeCSVCell(value)), value)
+	}
+	assert.Equal(t, "'=1+1", escapeCSVCell("=1+1"))
+}
@@ -4,6 +4,8 @@ import (
 	"log"
 	"math"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -17,8 +19,18 @@ import (
 const (
 	PRODUCT_RETRIEVAL_ERROR = "Failed to retrieve product"
 	INVALID_USER_INPUT      = "Invalid input"
+	MAX_PRODUCT_PAGE_SIZE   = 100
 )
 
+type ListProductResponse struct {
+	Products      []*model.Product `json:"products"`
+	Message       string           `json:"message"`
+	TotalProducts int              `json:"total_products"`
+	TotalPages    int              `json:"total_pages"`
+	Page          int              `json:"page"`
+	Size          int              `json:"size"`
+}
+
 type ProductCommonData struct {
 	Description string          `json:"product_description"`
 	Name        string          `json:"product_name" binding:"required,min=3"`
@@ -60,7 +72,7 @@ func handleProductError(ctx *gin.Context, statusCode int, message string) {
 
 // GetProduct retrieves a product by its product code
 // @Summary Get a product by its product code
-// @Description Retrieve product details using the product code
+// @Description Retrieve product details using the product code, with the options, variants and images of the product
 // @Tags Products
 // @SecurityDefinitions.apiKey Bearer
 // @in header
@@ -73,7 +85,7 @@ func handleProductError(ctx *gin.Context, statusCode int, message string) {
 // @Failure 404 {object} handler.ProductResponse{product=model.Product, message=string} "No product found"
 // @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Failed to retrieve product"
 // @Router /api/v1/product/{product_code} [get]
-func GetProduct(db *gorm.DB) gin.HandlerFunc {
+func GetProduct(db *gorm.DB, storage util.Storage) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		productCode := ctx.Param("product_code")
 
@@ -87,6 +99,18 @@ func GetProduct(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		if err := repository.LoadProductVariants(db, product); err != nil {
+			log.Println(err.Error())
+			handleProductError(ctx, http.StatusInternalServerError, PRODUCT_RETRIEVAL_ERROR)
+			return
+		}
+
+		if err := loadProductImages(db, storage, []*model.Product{product}); err != nil {
+			log.Println(err.Error())
+			handleProductError(ctx, http.StatusInternalServerError, PRODUCT_RETRIEVAL_ERROR)
+			return
+		}
+
 		message := "Product successfully retrieved"
 		status := http.StatusOK
 
@@ -104,16 +128,153 @@ func GetProduct(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
+// parsePriceQuery reads an optional non-negative price from the query
+func parsePriceQuery(ctx *gin.Context, name string) (*decimal.Decimal, bool) {
+	value := ctx.Query(name)
+	if value == "" {
+		return nil, true
+	}
+
+	price, err := decimal.NewFromString(value)
+	if err != nil || price.IsNegative() {
+		return nil, false
+	}
+	return &price, true
+}
+
+// parseProductFilter reads the catalog filters from the query. The returned
+// message describes the first invalid filter
+func parseProductFilter(ctx *gin.Context) (repository.ProductFilter, string) {
+	var filter repository.ProductFilter
+	var ok bool
+
+	if filter.MinPrice, ok = parsePriceQuery(ctx, "min_price"); !ok {
+		return filter, "min_price must be a non-negative number"
+	}
+
+	if filter.MaxPrice, ok = parsePriceQuery(ctx, "max_price"); !ok {
+		return filter, "max_price must be a non-negative number"
+	}
+
+	if filter.MinPrice != nil && filter.MaxPrice != nil && filter.MinPrice.GreaterThan(*filter.MaxPrice) {
+		return filter, "min_price cannot be greater than max_price"
+	}
+
+	if currency := ctx.Query("currency"); currency != "" {
+		if len(currency) != 3 {
+			return filter, "currency must be a three-letter currency code"
+		}
+		filter.Currency = strings.ToUpper(currency)
+	}
+
+	if inStock := ctx.Query("in_stock"); inStock != "" {
+		value, err := strconv.ParseBool(inStock)
+		if err != nil {
+			return filter, "in_stock must be true or false"
+		}
+		filter.InStock = value
+	}
+
+	filter.Sort = ctx.DefaultQuery("sort", "created_at")
+	if !repository.IsProductSort(filter.Sort) {
+		return filter, "sort must be one of price, name or created_at"
+	}
+
+	filter.Order = strings.ToLower(ctx.Query("order"))
+	if filter.Order != "" && filter.Order != repository.SORT_ASCENDING && filter.Order != repository.SORT_DESCENDING {
+		return filter, "order must be asc or desc"
+	}
+	return filter, ""
+}
+
+// ListProducts lists the product catalog
+// @Summary List products
+// @Description Lists the products of the catalog with their images. Prices are compared in the currency of each product, so price ranges are best combined with a currency. Without a sort the newest products come first
+// @Tags Products
+// @Produce		json
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param currency query string false "Currency code, e.g. NGN"
+// @Param in_stock query boolean false "Only list products that are in stock"
+// @Param sort query string false "Sort by price, name or created_at (Default created_at)"
+// @Param order query string false "Sort order, asc or desc (Default desc for created_at, asc otherwise)"
+// @Param page query string false "Page (Default 1)"
+// @Param size query string false "Size (Default 10, at most 100)"
+// @Success 200 {object} handler.ListProductResponse{products=[]model.Product, message=string, total_products=int, total_pages=int, page=int, size=int} "List of products"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid filter"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Failed to retrieve products"
+// @Router /api/v1/products [get]
+func ListProducts(db *gorm.DB, storage util.Storage) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		filter, message := parseProductFilter(ctx)
+		if message != "" {
+			handleProductError(ctx, http.StatusBadRequest, message)
+			return
+		}
+
+		respondWithProducts(ctx, db, storage, filter)
+	}
+}
+
+// productPage reads the page and page size of a product listing
+func productPage(ctx *gin.Context) (page, limit int) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	limit, err = strconv.Atoi(ctx.DefaultQuery("size", "10"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	return page, min(limit, MAX_PRODUCT_PAGE_SIZE)
+}
+
+// respondWithProducts responds with the page of the catalog asked for by the
+// request, each product with its images
+func respondWithProducts(ctx *gin.Context, db *gorm.DB, storage util.Storage, filter repository.ProductFilter) {
+	page, limit := productPage(ctx)
+
+	products, totalProducts, err := repository.ListProducts(db, filter, page, limit)
+	if err == nil {
+		err = loadProductImages(db, storage, products)
+	}
+
+	if err != nil {
+		log.Println(err.Error())
+		handleProductError(ctx, http.StatusInternalServerError, "Failed to retrieve products")
+		return
+	}
+
+	message := "Products retrieved successfully"
+	if len(products) == 0 {
+		products = []*model.Product{}
+		message = "No products found"
+	}
+
+	response := ListProductResponse{
+		Products:      products,
+		Message:       message,
+		TotalProducts: totalProducts,
+		TotalPages:    int(math.Ceil(float64(totalProducts) / float64(limit))),
+		Page:          page,
+		Size:          limit,
+	}
+
+	util.LogAndHandleResponse(ctx, http.StatusOK, response)
+}
+
 // UpdateProduct updates an existing product
 // @Summary Update an existing product
-// @Description Update product details
+// @Description Update product details. A change of the price is recorded in the price history of the product
 // @Tags Products
 // @SecurityDefinitions.apiKey Bearer
 // @in header
 // @name Authorization
 // @Produce		json
 // @Security BearerAuth
-// @Param Authorization header string true "Bearer Token"
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
 // @Param product_code path string true "Product Code"
 // @Param product body UpdateProductRequest true "Product Data"
 // @Success 200 {object} handler.ProductResponse{product=model.Product, message=string}
@@ -144,14 +305,22 @@ func UpdateProduct(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		user, err := authenticatedUser(ctx, db)
+		if err != nil || user == nil {
+			handleProductError(ctx, http.StatusBadRequest, INVALID_USER_INPUT)
+			return
+		}
+
 		// Update product fields
+		oldPrice := product.Price
 		product.Description = updateProduct.Description
 		product.Name = updateProduct.Name
 		product.Price = updateProduct.Price
 		product.Currency = updateProduct.Currency
 		product.Stock = updateProduct.Stock
 
-		updatedProduct, err := repository.UpdateProduct(db, product)
+		priceChange := repository.NewPriceChange(product, oldPrice, model.ManualPriceChange, user.UserID)
+		updatedProduct, err := repository.UpdateProduct(db, product, priceChange)
 		if err != nil {
 			handleProductError(ctx, http.StatusInternalServerError, "Failed to update product")
 			return
@@ -175,7 +344,8 @@ func UpdateProduct(db *gorm.DB) gin.HandlerFunc {
 // @name Authorization
 // @Produce		json
 // @Security BearerAuth
-// @Param Authorization header string true "Bearer Token"
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
 // @Param product_code path string true "Product Code"
 // @Success 200 {object} util.ErrorResponse{error=bool, error_message=string} "Product has been successfully deleted"
 // @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Product not found"
@@ -219,7 +389,8 @@ func DeleteProduct(db *gorm.DB) gin.HandlerFunc {
 // @name Authorization
 // @Produce		json
 // @Security BearerAuth
-// @Param Authorization header string true "Bearer Token"
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
 // @Param product body CreateProductRequest true "Product Data"
 // @Success 201 {object} handler.ProductResponse{product=model.Product, message=string}
 // @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string}
@@ -236,11 +407,8 @@ func CreateProduct(db *gorm.DB) gin.HandlerFunc {
 
 		util.LogIncomingRequest(createProductRequest)
 
-		var err error
-		var user *model.User
-
-		if authenticatedUserID, exists := ctx.Get("user_id"); exists {
-			user, err = repository.FindUserBy(db, "user_guid", authenticatedUserID.(string))
+		if _, exists := ctx.Get("user_id"); exists {
+			user, err := authenticatedUser(ctx, db)
 			if err != nil || user == nil {
 				log.Printf("error => %v\nUser is null => %v", err, user == nil)
 				handleProductError(ctx, http.StatusBadRequest, INVALID_USER_INPUT)
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	PRODUCTS_ENDPOINT        = "/api/v1/products"
+	ACTIVE_VARIANTS          = "SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id AND product_variants.is_deleted = false"
+	PRODUCT_FILTERS_QUERY    = "((NOT EXISTS (" + ACTIVE_VARIANTS + ") AND products.price >= ? AND products.price <= ?) OR EXISTS (" + ACTIVE_VARIANTS + " AND COALESCE(product_variants.price, products.price) >= ? AND COALESCE(product_variants.price, products.price) <= ?)) AND (currency = ?) AND ((NOT EXISTS (" + ACTIVE_VARIANTS + ") AND products.stock > 0) OR EXISTS (" + ACTIVE_VARIANTS + " AND product_variants.stock > 0))"
+	COUNT_FILTERED_PRODUCTS  = "SELECT count(*) FROM `products` WHERE (is_deleted = false) AND " + PRODUCT_FILTERS_QUERY
+	SELECT_FILTERED_PRODUCTS = "SELECT * FROM `products` WHERE (is_deleted = false) AND " + PRODUCT_FILTERS_QUERY + " ORDER BY price desc, id desc LIMIT 2 OFFSET 2"
+	COUNT_ALL_PRODUCTS_QUERY = "SELECT count(*) FROM `products` WHERE (is_deleted = false)"
+	SELECT_NEWEST_PRODUCTS   = "SELECT * FROM `products` WHERE (is_deleted = false) ORDER BY created_at desc, id desc LIMIT 100 OFFSET 0"
+)
+
+func newProductListContext(query string) (*httptest.ResponseRecorder, *gin.Context) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", PRODUCTS_ENDPOINT+query, nil)
+	return w, c
+}
+
+func TestListProductsWithFilters(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(COUNT_FILTERED_PRODUCTS)).
+		WithArgs("10", "99.5", "10", "99.5", "NGN").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_FILTERED_PRODUCTS)).
+		WithArgs("10", "99.5", "10", "99.5", "NGN").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_name", "product_code", "price", "stock", "currency"}).
+			AddRow(1, "Kettle", "product-1", "20.00", 4, "NGN"))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_PRODUCT_IMAGES)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(imageColumns).AddRow(4, TEST_IMAGE_ID, 1, 0, true, "image/jpeg", 1200, 800))
+
+	w, c := newProductListContext("?min_price=10&max_price=99.5&currency=ngn&in_stock=true&sort=price&order=DESC&page=2&size=2")
+	ListProducts(gdb, newTestStorage(t))(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"product_code":"product-1"`)
+	assert.Contains(t, w.Body.String(), `"total_products":3`)
+	assert.Contains(t, w.Body.String(), `"total_pages":2`)
+	assert.Contains(t, w.Body.String(), `"page":2`)
+	assert.Contains(t, w.Body.String(), `"small":"/uploads/products/`+TEST_IMAGE_ID+`/small.jpg"`, "Listings should show the images")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Without filters the newest products come first and pages are capped
+func TestListProductsDefaults(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(COUNT_ALL_PRODUCTS_QUERY)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_NEWEST_PRODUCTS)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	w, c := newProductListContext("?size=500")
+	ListProducts(gdb, newTestStorage(t))(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"products":[]`)
+	assert.Contains(t, w.Body.String(), `"size":100`)
+	assert.Contains(t, w.Body.String(), "No products found")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListProductsInvalidFilter(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	for query, message := range map[string]string{
+		"?min_price=cheap":             "min_price must be",
+		"?max_price=-1":                "max_price must be",
+		"?min_price=20&max_price=10":   "min_price cannot be greater",
+		"?currency=naira":              "currency must be",
+		"?in_stock=maybe":              "in_stock must be",
+		"?sort=popularity":             "sort must be",
+		"?sort=price&order=increasing": "order must be",
+	} {
+		w, c := newProductListContext(query)
+		ListProducts(gdb, newTestStorage(t))(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.Contains(t, w.Body.String(), message, query)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,354 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hackdaemon2/instashop/config"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	DEFAULT_MAX_IMAGE_UPLOAD_MB = 10
+	IMAGE_REQUIRED_ERROR        = "image is required, send it as a multipart form file"
+	IMAGE_UPLOAD_ERROR          = "Unable to save image"
+)
+
+type ImageOrderRequest struct {
+	ImageIDs []string `json:"image_ids" binding:"required"` // every image of the product, in the new order
+}
+
+type ProductImageResponse struct {
+	Image   *model.ProductImage `json:"image"`
+	Message string              `json:"message"`
+}
+
+type ProductImagesResponse struct {
+	Images  []model.ProductImage `json:"images"`
+	Message string               `json:"message"`
+}
+
+// maxImageUploadSize returns how many bytes an uploaded image may have
+func maxImageUploadSize() int64 {
+	return int64(config.GetIntEnv("IMAGE_MAX_UPLOAD_MB", DEFAULT_MAX_IMAGE_UPLOAD_MB)) << 20
+}
+
+// withImageURLs fills in where the images and their thumbnails can be downloaded from
+func withImageURLs(storage util.Storage, images []model.ProductImage) {
+	for i := range images {
+		images[i].URL = storage.URL(images[i].StorageKey())
+		images[i].Thumbnails = make(map[string]string, len(util.ThumbnailSizes))
+		for _, size := range util.ThumbnailSizes {
+			images[i].Thumbnails[size.Name] = storage.URL(images[i].ThumbnailKey(size.Name))
+		}
+	}
+}
+
+// loadProductImages fills in the images of the products along with their URLs
+func loadProductImages(db *gorm.DB, storage util.Storage, products []*model.Product) error {
+	if err := repository.LoadProductImages(db, products); err != nil {
+		return err
+	}
+
+	for _, product := range products {
+		withImageURLs(storage, product.Images)
+	}
+	return nil
+}
+
+// storeImageFiles saves an uploaded image and its thumbnails. Nothing is left
+// behind in the storage when one of them cannot be saved
+func storeImageFiles(storage util.Storage, image *model.ProductImage, content []byte, processed *util.ProcessedImage) error {
+	if err := storage.Put(image.StorageKey(), content, image.ContentType); err != nil {
+		return err
+	}
+
+	for _, size := range util.ThumbnailSizes {
+		key := image.ThumbnailKey(size.Name)
+		if err := storage.Put(key, processed.Thumbnails[size.Name], util.ThumbnailContentType(image.ContentType)); err != nil {
+			deleteImageFiles(storage, image)
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteImageFiles removes an image and its thumbnails from the storage. A
+// file that cannot be removed is only logged, as the image is gone either way
+func deleteImageFiles(storage util.Storage, image *model.ProductImage) {
+	keys := []string{image.StorageKey()}
+	for _, size := range util.ThumbnailSizes {
+		keys = append(keys, image.ThumbnailKey(size.Name))
+	}
+
+	for _, key := range keys {
+		if err := storage.Delete(key); err != nil {
+			log.Println("Unable to delete", key, err)
+		}
+	}
+}
+
+// findProductImage loads the image of the image_id path parameter. It
+// responds itself when the image cannot be loaded
+func findProductImage(ctx *gin.Context, db *gorm.DB, product *model.Product) (*model.ProductImage, bool) {
+	image, err := repository.FindProductImage(db, product, ctx.Param("image_id"))
+	if err != nil {
+		handleImageError(ctx, err, "Unable to retrieve image")
+		return nil, false
+	}
+	return image, true
+}
+
+// handleImageError responds to an image that could not be loaded or changed
+func handleImageError(ctx *gin.Context, err error, message string) {
+	switch err.Error() {
+	case repository.IMAGE_NOT_FOUND_ERROR:
+		handleProductError(ctx, http.StatusNotFound, err.Error())
+	case repository.INVALID_IMAGE_ORDER_ERROR:
+		handleProductError(ctx, http.StatusBadRequest, err.Error())
+	case repository.PRODUCT_IMAGES_FULL_ERROR:
+		handleProductError(ctx, http.StatusConflict, err.Error())
+	default:
+		log.Println(err.Error())
+		handleProductError(ctx, http.StatusInternalServerError, message)
+	}
+}
+
+// respondWithProductImages responds with all images of a product, in their display order
+func respondWithProductImages(ctx *gin.Context, db *gorm.DB, storage util.Storage, product *model.Product, message string) {
+	if err := loadProductImages(db, storage, []*model.Product{product}); err != nil {
+		log.Println(err.Error())
+		handleProductError(ctx, http.StatusInternalServerError, "Unable to retrieve images")
+		return
+	}
+
+	images := product.Images
+	if images == nil {
+		images = []model.ProductImage{}
+	}
+	util.LogAndHandleResponse(ctx, http.StatusOK, ProductImagesResponse{Images: images, Message: message})
+}
+
+// UploadProductImage adds an image to a product
+// @Summary Upload a product image
+// @Description Adds a JPEG, PNG or GIF image after the other images of a product and renders small (160px), medium (480px) and large (1024px) thumbnails of it. The first image of a product becomes its primary image
+// @Tags Products
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Accept		multipart/form-data
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
+// @Param product_code path string true "Product Code"
+// @Param image formData file true "Image file, at most IMAGE_MAX_UPLOAD_MB megabytes"
+// @Success 201 {object} handler.ProductImageResponse{image=model.ProductImage, message=string} "Image uploaded"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Missing or unsupported image"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Product not found"
+// @Failure 409 {object} util.ErrorResponse{error=bool, error_message=string} "The product has the most images allowed"
+// @Failure 413 {object} util.ErrorResponse{error=bool, error_message=string} "Image too large"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to save image"
+// @Router /api/v1/admin/product/{product_code}/images [post]
+func UploadProductImage(db *gorm.DB, storage util.Storage) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		product, ok := findProduct(ctx, db)
+		if !ok {
+			return
+		}
+
+		maxSize := maxImageUploadSize()
+		tooLarge := fmt.Sprintf("Images may be at most %d MB", maxSize>>20)
+
+		// leave room for the rest of the multipart form around the file
+		if ctx.Request.ContentLength > maxSize+1<<20 {
+			handleProductError(ctx, http.StatusRequestEntityTooLarge, tooLarge)
+			return
+		}
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSize+1<<20)
+		fileHeader, err := ctx.FormFile("image")
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				handleProductError(ctx, http.StatusRequestEntityTooLarge, tooLarge)
+				return
+			}
+			handleProductError(ctx, http.StatusBadRequest, IMAGE_REQUIRED_ERROR)
+			return
+		}
+
+		if fileHeader.Size > maxSize {
+			handleProductError(ctx, http.StatusRequestEntityTooLarge, tooLarge)
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			handleProductError(ctx, http.StatusBadRequest, IMAGE_REQUIRED_ERROR)
+			return
+		}
+		defer file.Close()
+
+		content, err := io.ReadAll(file)
+		if err != nil {
+			handleProductError(ctx, http.StatusBadRequest, IMAGE_REQUIRED_ERROR)
+			return
+		}
+
+		processed, err := util.ProcessImage(content)
+		if err != nil {
+			handleProductError(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		image := model.ProductImage{
+			ImageID:     uuid.New().String(),
+			ContentType: processed.ContentType,
+			Width:       processed.Width,
+			Height:      processed.Height,
+		}
+
+		if err := storeImageFiles(storage, &image, content, processed); err != nil {
+			log.Println(err.Error())
+			handleProductError(ctx, http.StatusInternalServerError, IMAGE_UPLOAD_ERROR)
+			return
+		}
+
+		if err := repository.AddProductImage(db, product, &image); err != nil {
+			deleteImageFiles(storage, &image)
+			handleImageError(ctx, err, IMAGE_UPLOAD_ERROR)
+			return
+		}
+
+		images := []model.ProductImage{image}
+		withImageURLs(storage, images)
+		util.LogAndHandleResponse(ctx, http.StatusCreated, ProductImageResponse{Image: &images[0], Message: "Image uploaded"})
+	}
+}
+
+// ReorderProductImages changes the order of the images of a product
+// @Summary Reorder product images
+// @Description Puts the images of a product in the given order, which must list every image of the product once
+// @Tags Products
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Accept		json
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
+// @Param product_code path string true "Product Code"
+// @Param request body ImageOrderRequest true "Image ids in the new order"
+// @Success 200 {object} handler.ProductImagesResponse{images=[]model.ProductImage, message=string} "Images reordered"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid order"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Product not found"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to reorder images"
+// @Router /api/v1/admin/product/{product_code}/images [put]
+func ReorderProductImages(db *gorm.DB, storage util.Storage) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var orderRequest ImageOrderRequest
+		if err := ctx.ShouldBindJSON(&orderRequest); err != nil {
+			handleProductError(ctx, http.StatusBadRequest, INVALID_USER_INPUT)
+			return
+		}
+
+		product, ok := findProduct(ctx, db)
+		if !ok {
+			return
+		}
+
+		if err := repository.ReorderProductImages(db, product, orderRequest.ImageIDs); err != nil {
+			handleImageError(ctx, err, "Unable to reorder images")
+			return
+		}
+
+		withImageURLs(storage, product.Images)
+		util.LogAndHandleResponse(ctx, http.StatusOK, ProductImagesResponse{Images: product.Images, Message: "Images reordered"})
+	}
+}
+
+// SetPrimaryProductImage makes an image the primary image of its product
+// @Summary Set the primary product image
+// @Description Makes an image the one shown for the product in listings, in place of the previous primary image
+// @Tags Products
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
+// @Param product_code path string true "Product Code"
+// @Param image_id path string true "Image ID"
+// @Success 200 {object} handler.ProductImagesResponse{images=[]model.ProductImage, message=string} "Primary image updated"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Product or image not found"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to update image"
+// @Router /api/v1/admin/product/{product_code}/images/{image_id}/primary [put]
+func SetPrimaryProductImage(db *gorm.DB, storage util.Storage) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		product, ok := findProduct(ctx, db)
+		if !ok {
+			return
+		}
+
+		image, ok := findProductImage(ctx, db, product)
+		if !ok {
+			return
+		}
+
+		if err := repository.SetPrimaryImage(db, product, image); err != nil {
+			handleImageError(ctx, err, "Unable to update image")
+			return
+		}
+
+		respondWithProductImages(ctx, db, storage, product, "Primary image updated")
+	}
+}
+
+// DeleteProductImage removes an image of a product
+// @Summary Delete a product image
+// @Description Removes an image and its thumbnails. When it was the primary image, the first remaining image takes its place
+// @Tags Products
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
+// @Param product_code path string true "Product Code"
+// @Param image_id path string true "Image ID"
+// @Success 200 {object} handler.ProductImagesResponse{images=[]model.ProductImage, message=string} "Image deleted"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Product or image not found"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to delete image"
+// @Router /api/v1/admin/product/{product_code}/images/{image_id} [delete]
+func DeleteProductImage(db *gorm.DB, storage util.Storage) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		product, ok := findProduct(ctx, db)
+		if !ok {
+			return
+		}
+
+		image, ok := findProductImage(ctx, db, product)
+		if !ok {
+			return
+		}
+
+		if err := repository.DeleteProductImage(db, product, image); err != nil {
+			handleImageError(ctx, err, "Unable to delete image")
+			return
+		}
+
+		deleteImageFiles(storage, image)
+		respondWithProductImages(ctx, db, storage, product, "Image deleted")
+	}
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	IMAGES_ENDPOINT              = "/api/v1/admin/product/shirt/images"
+	TEST_IMAGE_ID                = "7d1f2a4e-0b7c-4c4e-9a51-3f0a9e6b2c11"
+	OTHER_IMAGE_ID               = "c2a4b6d8-1e3f-4a5b-8c7d-9e0f1a2b3c4d"
+	SELECT_PRODUCT_IMAGES        = "SELECT * FROM `product_images` WHERE (product_id IN (?)) ORDER BY position ASC, id ASC"
+	SELECT_TWO_PRODUCT_IMAGES    = "SELECT * FROM `product_images` WHERE (product_id IN (?,?)) ORDER BY position ASC, id ASC"
+	SELECT_REMAINING_IMAGES      = "SELECT * FROM `product_images` WHERE (product_id = ?) ORDER BY position ASC, id ASC"
+	SELECT_IMAGE_BY_ID           = "SELECT * FROM `product_images` WHERE (image_guid = ? AND product_id = ?)"
+	COUNT_PRODUCT_IMAGES         = "SELECT count(*) FROM `product_images` WHERE (product_id = ?)"
+	INSERT_PRODUCT_IMAGE_QUERY   = "INSERT INTO `product_images` (`image_guid`,`product_id`,`position`,`is_primary`,`content_type`,`width`,`height`,`created_at`) VALUES (?,?,?,?,?,?,?,?)"
+	DELETE_PRODUCT_IMAGE_QUERY   = "DELETE FROM `product_images` WHERE `product_images`.`id` = ?"
+	UPDATE_IMAGE_POSITION_QUERY  = "UPDATE `product_images` SET `position` = ? WHERE `product_images`.`id` = ?"
+	UPDATE_PRIMARY_IMAGE_QUERY   = "UPDATE `product_images` SET `is_primary` = ? WHERE `product_images`.`id` = ?"
+	TEST_IMAGE_CONTENT_TYPE_JPEG = "image/jpeg"
+)
+
+var imageColumns = []string{"id", "image_guid", "product_id", "position", "is_primary", "content_type", "width", "height"}
+
+func newTestStorage(t *testing.T) *util.LocalStorage {
+	return &util.LocalStorage{Directory: t.TempDir(), BaseURL: "/uploads"}
+}
+
+// newImageUploadContext posts content as the image file of a multipart form
+func newImageUploadContext(t *testing.T, content []byte) (*httptest.ResponseRecorder, *gin.Context) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("image", "shirt.png")
+	assert.NoError(t, err)
+	_, err = part.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, form.Close())
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", IMAGES_ENDPOINT, &body)
+	c.Request.Header.Set(CONTEXT_TYPE, form.FormDataContentType())
+	c.Params = gin.Params{{Key: "product_code", Value: "shirt"}}
+	return w, c
+}
+
+func encodeTestImage(t *testing.T) []byte {
+	var buffer bytes.Buffer
+	assert.NoError(t, png.Encode(&buffer, image.NewNRGBA(image.Rect(0, 0, 600, 300))))
+	return buffer.Bytes()
+}
+
+func expectShirt(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_SHIRT_QUERY)).
+		WithArgs("shirt").
+		WillReturnRows(sqlmock.NewRows(productColumns).AddRow(1, "Shirt", "Cotton shirt", "shirt", "20.00", 0, TEST_CURRENCY))
+}
+
+// The first image becomes the primary image and gets its thumbnails
+func TestUploadProductImage(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	storage := newTestStorage(t)
+
+	expectShirt(mock)
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(COUNT_PRODUCT_IMAGES)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(regexp.QuoteMeta(INSERT_PRODUCT_IMAGE_QUERY)).
+		WithArgs(sqlmock.AnyArg(), 1, 0, true, "image/png", 600, 300, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(4, 1))
+	mock.ExpectCommit()
+
+	w, c := newImageUploadContext(t, encodeTestImage(t))
+	UploadProductImage(gdb, storage)(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"is_primary":true`)
+	assert.Regexp(t, `"url":"/uploads/products/[0-9a-f-]{36}/original.png"`, w.Body.String())
+	assert.Regexp(t, `"medium":"/uploads/products/[0-9a-f-]{36}/medium.png"`, w.Body.String())
+
+	files, err := filepath.Glob(filepath.Join(storage.Directory, "products", "*", "*.png"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 4, "The original and three thumbnails should be stored")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Only images are accepted, whatever the file is called
+func TestUploadProductImageRejectsOtherFiles(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	storage := newTestStorage(t)
+
+	expectShirt(mock)
+
+	w, c := newImageUploadContext(t, []byte("<html><script>alert(1)</script></html>"))
+	UploadProductImage(gdb, storage)(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), util.UNSUPPORTED_IMAGE_ERROR)
+
+	entries, err := os.ReadDir(storage.Directory)
+	assert.NoError(t, err)
+	assert.Empty(t, entries, "Nothing should be stored")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Files of images that cannot be added are removed again
+func TestUploadProductImageWhenFull(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	storage := newTestStorage(t)
+
+	expectShirt(mock)
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(COUNT_PRODUCT_IMAGES)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(repository.DEFAULT_MAX_IMAGES_PER_PRODUCT))
+	mock.ExpectRollback()
+
+	w, c := newImageUploadContext(t, encodeTestImage(t))
+	UploadProductImage(gdb, storage)(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), repository.PRODUCT_IMAGES_FULL_ERROR)
+
+	files, err := filepath.Glob(filepath.Join(storage.Directory, "products", "*", "*"))
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func expectShirtImages(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_PRODUCT_IMAGES)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(imageColumns).
+			AddRow(4, TEST_IMAGE_ID, 1, 0, true, TEST_IMAGE_CONTENT_TYPE_JPEG, 1200, 800).
+			AddRow(5, OTHER_IMAGE_ID, 1, 1, false, TEST_IMAGE_CONTENT_TYPE_JPEG, 800, 800))
+}
+
+func TestReorderProductImages(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	for _, order := range [][]string{{TEST_IMAGE_ID}, {TEST_IMAGE_ID, TEST_IMAGE_ID}, {TEST_IMAGE_ID, "unknown"}} {
+		expectShirt(mock)
+		expectShirtImages(mock)
+
+		w, c := newVariantTestContext(t, "PUT", map[string]any{"image_ids": order})
+		ReorderProductImages(gdb, newTestStorage(t))(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, order)
+		assert.Contains(t, w.Body.String(), repository.INVALID_IMAGE_ORDER_ERROR)
+	}
+
+	expectShirt(mock)
+	expectShirtImages(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(UPDATE_IMAGE_POSITION_QUERY)).
+		WithArgs(0, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(UPDATE_IMAGE_POSITION_QUERY)).
+		WithArgs(1, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	w, c := newVariantTestContext(t, "PUT", map[string]any{"image_ids": []string{OTHER_IMAGE_ID, TEST_IMAGE_ID}})
+	ReorderProductImages(gdb, newTestStorage(t))(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Less(t, regexp.MustCompile(OTHER_IMAGE_ID).FindStringIndex(w.Body.String())[0],
+		regexp.MustCompile(TEST_IMAGE_ID).FindStringIndex(w.Body.String())[0], "The images should be in the new order")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Deleting the primary image promotes the next one and removes the files
+func TestDeleteProductImage(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	storage := newTestStorage(t)
+	assert.NoError(t, storage.Put("products/"+TEST_IMAGE_ID+"/small.jpg", []byte("jpeg"), TEST_IMAGE_CONTENT_TYPE_JPEG))
+
+	expectShirt(mock)
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_IMAGE_BY_ID)).
+		WithArgs(TEST_IMAGE_ID, 1).
+		WillReturnRows(sqlmock.NewRows(imageColumns).AddRow(4, TEST_IMAGE_ID, 1, 0, true, TEST_IMAGE_CONTENT_TYPE_JPEG, 1200, 800))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(DELETE_PRODUCT_IMAGE_QUERY)).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_REMAINING_IMAGES)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(imageColumns).AddRow(5, OTHER_IMAGE_ID, 1, 1, false, TEST_IMAGE_CONTENT_TYPE_JPEG, 800, 800))
+	mock.ExpectExec(regexp.QuoteMeta(UPDATE_IMAGE_POSITION_QUERY)).
+		WithArgs(0, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(UPDATE_PRIMARY_IMAGE_QUERY)).
+		WithArgs(true, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_PRODUCT_IMAGES)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(imageColumns).AddRow(5, OTHER_IMAGE_ID, 1, 0, true, TEST_IMAGE_CONTENT_TYPE_JPEG, 800, 800))
+
+	w, c := newVariantTestContext(t, "DELETE", nil)
+	c.Params = append(c.Params, gin.Param{Key: "image_id", Value: TEST_IMAGE_ID})
+	DeleteProductImage(gdb, storage)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"image_id":"`+OTHER_IMAGE_ID+`","position":0,"is_primary":true`)
+	assert.NotContains(t, w.Body.String(), TEST_IMAGE_ID)
+
+	_, err := os.Stat(filepath.Join(storage.Directory, "products", TEST_IMAGE_ID, "small.jpg"))
+	assert.True(t, os.IsNotExist(err), "The thumbnails should be deleted")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,280 @@
+package handler
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+const INVALID_SCHEDULED_PRICE = "price must be a non-negative number below 100000000 with at most 2 decimal places"
+
+type PriceScheduleRequest struct {
+	Price    decimal.Decimal `json:"price" binding:"required" example:"19.99"`
+	StartsAt time.Time       `json:"starts_at" binding:"required" example:"2026-11-27T00:00:00Z"`
+	EndsAt   *time.Time      `json:"ends_at" example:"2026-11-30T23:59:59Z"` // leave out to change the price for good
+}
+
+type PriceScheduleResponse struct {
+	Schedule *model.PriceSchedule `json:"schedule"`
+	Message  string               `json:"message"`
+}
+
+type ListPriceChangesResponse struct {
+	PriceChanges []model.PriceChange `json:"price_changes"`
+	Message      string              `json:"message"`
+	TotalChanges int                 `json:"total_changes"`
+	TotalPages   int                 `json:"total_pages"`
+	Page         int                 `json:"page"`
+	Size         int                 `json:"size"`
+}
+
+type ListPriceSchedulesResponse struct {
+	Schedules      []model.PriceSchedule `json:"schedules"`
+	Message        string                `json:"message"`
+	TotalSchedules int                   `json:"total_schedules"`
+	TotalPages     int                   `json:"total_pages"`
+	Page           int                   `json:"page"`
+	Size           int                   `json:"size"`
+}
+
+// handlePriceScheduleError responds to a price schedule that could not be loaded or changed
+func handlePriceScheduleError(ctx *gin.Context, err error, message string) {
+	switch err.Error() {
+	case repository.PRICE_SCHEDULE_NOT_FOUND_ERROR:
+		handleProductError(ctx, http.StatusNotFound, err.Error())
+	case repository.PRICE_SCHEDULE_OVERLAP_ERROR, repository.PRICE_SCHEDULE_CLOSED_ERROR:
+		handleProductError(ctx, http.StatusConflict, err.Error())
+	default:
+		log.Println(err.Error())
+		handleProductError(ctx, http.StatusInternalServerError, message)
+	}
+}
+
+// ListPriceChanges returns the price history of a product
+// @Summary List the price history of a product
+// @Description Lists every change of the price of a product, newest first, with who made it and whether it came from an update, an import or a price schedule. Changes made by the scheduler have no changed_by
+// @Tags Products
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
+// @Param product_code path string true "Product Code"
+// @Param page query string false "Page (Default 1)"
+// @Param size query string false "Size (Default 10)"
+// @Success 200 {object} handler.ListPriceChangesResponse{price_changes=[]model.PriceChange, message=string, total_changes=int, total_pages=int, page=int, size=int} "Price history"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Product not found"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Failed to retrieve price history"
+// @Router /api/v1/admin/product/{product_code}/prices [get]
+func ListPriceChanges(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		product, ok := findProduct(ctx, db)
+		if !ok {
+			return
+		}
+
+		page, limit := productPage(ctx)
+		changes, totalChanges, err := repository.ListPriceChanges(db, product, page, limit)
+		if err != nil {
+			log.Println(err.Error())
+			handleProductError(ctx, http.StatusInternalServerError, "Failed to retrieve price history")
+			return
+		}
+
+		message := "Price history retrieved successfully"
+		if len(changes) == 0 {
+			changes = []model.PriceChange{}
+			message = "The price has not changed"
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, ListPriceChangesResponse{
+			PriceChanges: changes,
+			Message:      message,
+			TotalChanges: totalChanges,
+			TotalPages:   int(math.Ceil(float64(totalChanges) / float64(limit))),
+			Page:         page,
+			Size:         limit,
+		})
+	}
+}
+
+// ListPriceSchedules returns the price schedules of a product
+// @Summary List the price schedules of a product
+// @Description Lists the scheduled prices of a product, the latest start first, whatever their status: pending, active, completed, cancelled or expired
+// @Tags Products
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
+// @Param product_code path string true "Product Code"
+// @Param page query string false "Page (Default 1)"
+// @Param size query string false "Size (Default 10)"
+// @Success 200 {object} handler.ListPriceSchedulesResponse{schedules=[]model.PriceSchedule, message=string, total_schedules=int, total_pages=int, page=int, size=int} "Price schedules"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Product not found"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Failed to retrieve price schedules"
+// @Router /api/v1/admin/product/{product_code}/price-schedules [get]
+func ListPriceSchedules(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		product, ok := findProduct(ctx, db)
+		if !ok {
+			return
+		}
+
+		page, limit := productPage(ctx)
+		schedules, totalSchedules, err := repository.ListPriceSchedules(db, product, page, limit)
+		if err != nil {
+			log.Println(err.Error())
+			handleProductError(ctx, http.StatusInternalServerError, "Failed to retrieve price schedules")
+			return
+		}
+
+		message := "Price schedules retrieved successfully"
+		if len(schedules) == 0 {
+			schedules = []model.PriceSchedule{}
+			message = "No price schedules found"
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, ListPriceSchedulesResponse{
+			Schedules:      schedules,
+			Message:        message,
+			TotalSchedules: totalSchedules,
+			TotalPages:     int(math.Ceil(float64(totalSchedules) / float64(limit))),
+			Page:           page,
+			Size:           limit,
+		})
+	}
+}
+
+// CreatePriceSchedule schedules a price for a product
+// @Summary Schedule a price
+// @Description Schedules a price that the product takes from starts_at, e.g. for a sale. With ends_at the regular price comes back at the end, unless the price was changed in the meantime. Without ends_at the price changes for good. Schedules with an end may not overlap. The scheduler runs every PRICE_SCHEDULER_INTERVAL, so prices change within that time of the start and end
+// @Tags Products
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Accept		json
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
+// @Param product_code path string true "Product Code"
+// @Param request body PriceScheduleRequest true "Scheduled price, RFC 3339 times"
+// @Success 201 {object} handler.PriceScheduleResponse{schedule=model.PriceSchedule, message=string} "Price scheduled"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid price or times"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Product not found"
+// @Failure 409 {object} util.ErrorResponse{error=bool, error_message=string} "Overlaps another price schedule"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to schedule price"
+// @Router /api/v1/admin/product/{product_code}/price-schedules [post]
+func CreatePriceSchedule(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var scheduleRequest PriceScheduleRequest
+		if err := ctx.ShouldBindJSON(&scheduleRequest); err != nil {
+			validationError := util.ExtractValidationErrorMessage(err, scheduleRequest)
+			handleProductError(ctx, http.StatusBadRequest, validationError[0])
+			return
+		}
+
+		price := scheduleRequest.Price
+		if price.IsNegative() || !price.LessThan(maxProductPrice) || !price.Equal(price.Round(2)) {
+			handleProductError(ctx, http.StatusBadRequest, INVALID_SCHEDULED_PRICE)
+			return
+		}
+
+		if !scheduleRequest.StartsAt.After(time.Now()) {
+			handleProductError(ctx, http.StatusBadRequest, "starts_at must be in the future")
+			return
+		}
+
+		if scheduleRequest.EndsAt != nil && !scheduleRequest.EndsAt.After(scheduleRequest.StartsAt) {
+			handleProductError(ctx, http.StatusBadRequest, "ends_at must be after starts_at")
+			return
+		}
+
+		user, err := authenticatedUser(ctx, db)
+		if err != nil || user == nil {
+			handleProductError(ctx, http.StatusBadRequest, INVALID_USER_INPUT)
+			return
+		}
+
+		product, ok := findProduct(ctx, db)
+		if !ok {
+			return
+		}
+
+		schedule := model.PriceSchedule{
+			Price:     price,
+			StartsAt:  scheduleRequest.StartsAt.UTC(),
+			CreatedBy: user.UserID,
+		}
+		if scheduleRequest.EndsAt != nil {
+			endsAt := scheduleRequest.EndsAt.UTC()
+			schedule.EndsAt = &endsAt
+		}
+
+		if err := repository.CreatePriceSchedule(db, product, &schedule); err != nil {
+			handlePriceScheduleError(ctx, err, "Unable to schedule price")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusCreated, PriceScheduleResponse{Schedule: &schedule, Message: "Price scheduled"})
+	}
+}
+
+// CancelPriceSchedule cancels a price schedule
+// @Summary Cancel a price schedule
+// @Description Cancels a pending price schedule, or ends an active one at once and brings back the regular price, unless the price was changed in the meantime
+// @Tags Products
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
+// @Param product_code path string true "Product Code"
+// @Param schedule_id path string true "Schedule ID"
+// @Success 200 {object} handler.PriceScheduleResponse{schedule=model.PriceSchedule, message=string} "Price schedule cancelled"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Product or price schedule not found"
+// @Failure 409 {object} util.ErrorResponse{error=bool, error_message=string} "The price schedule has already ended or been cancelled"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to cancel price schedule"
+// @Router /api/v1/admin/product/{product_code}/price-schedules/{schedule_id} [delete]
+func CancelPriceSchedule(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, err := authenticatedUser(ctx, db)
+		if err != nil || user == nil {
+			handleProductError(ctx, http.StatusBadRequest, INVALID_USER_INPUT)
+			return
+		}
+
+		product, ok := findProduct(ctx, db)
+		if !ok {
+			return
+		}
+
+		schedule, err := repository.FindPriceSchedule(db, product, ctx.Param("schedule_id"))
+		if err != nil {
+			handlePriceScheduleError(ctx, err, "Unable to retrieve price schedule")
+			return
+		}
+
+		if err := repository.CancelPriceSchedule(db, schedule, user.UserID); err != nil {
+			handlePriceScheduleError(ctx, err, "Unable to cancel price schedule")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, PriceScheduleResponse{Schedule: schedule, Message: "Price schedule cancelled"})
+	}
+}
@@ -0,0 +1,328 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	PRICE_SCHEDULES_ENDPOINT     = "/api/v1/admin/product/shirt/price-schedules"
+	TEST_SCHEDULE_ID             = "5b0e8f3c-2d4a-4e6b-9c1d-7a8f0e2b4c6d"
+	COUNT_PRICE_CHANGES          = "SELECT count(*) FROM `price_changes` WHERE (product_id = ?)"
+	SELECT_PRICE_CHANGES         = "SELECT * FROM `price_changes` WHERE (product_id = ?) ORDER BY created_at DESC, id DESC LIMIT 10 OFFSET 0"
+	INSERT_PRICE_CHANGE_QUERY    = "INSERT INTO `price_changes` (`product_id`,`old_price`,`new_price`,`currency`,`source`,`changed_by_guid`,`schedule_guid`,`created_at`) VALUES (?,?,?,?,?,?,?,?)"
+	COUNT_OVERLAPPING_SCHEDULES  = "SELECT count(*) FROM `price_schedules` WHERE (product_id = ? AND status IN (?,?)) AND (ends_at IS NOT NULL AND ends_at > ? AND starts_at < ?)"
+	INSERT_PRICE_SCHEDULE_QUERY  = "INSERT INTO `price_schedules` (`schedule_guid`,`product_id`,`price`,`regular_price`,`starts_at`,`ends_at`,`status`,`created_by_guid`,`created_at`,`updated_at`) VALUES (?,?,?,?,?,?,?,?,?,?)"
+	SELECT_PRICE_SCHEDULE_BY_ID  = "SELECT * FROM `price_schedules` WHERE (schedule_guid = ? AND product_id = ?)"
+	SELECT_PRODUCT_BY_ID_QUERY   = "SELECT * FROM `products` WHERE (id = ?)"
+	UPDATE_PRICE_SCHEDULE_STATUS = "UPDATE `price_schedules` SET `status` = ?, `updated_at` = ? WHERE (id = ? AND status = ?)"
+	UPDATE_PRODUCT_PRICE_QUERY   = "UPDATE `products` SET `price` = ?, `updated_at` = ? WHERE `products`.`id` = ?"
+	SELECT_DUE_PRICE_SCHEDULES   = "SELECT * FROM `price_schedules` WHERE ((status = ? AND starts_at <= ?) OR (status = ? AND ends_at <= ?))"
+	START_PRICE_SCHEDULE_QUERY   = "UPDATE `price_schedules` SET `regular_price` = ?, `status` = ?, `updated_at` = ? WHERE (id = ? AND status = ?)"
+	OTHER_SCHEDULE_ID            = "9e4d2c1b-6a8f-4b3e-8d5c-1f0a2b3c4d5e"
+)
+
+var priceScheduleColumns = []string{"id", "schedule_guid", "product_id", "price", "regular_price", "starts_at", "ends_at", "status"}
+
+func newPriceScheduleContext(t *testing.T, method string, body any) (*httptest.ResponseRecorder, *gin.Context) {
+	w, c := createTestContext(body, PRICE_SCHEDULES_ENDPOINT, t)
+	c.Request.Method = method
+	c.Params = gin.Params{{Key: "product_code", Value: "shirt"}}
+	withPrincipal(c, createMockUser())
+	return w, c
+}
+
+func TestListPriceChanges(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	expectShirt(mock)
+	mock.ExpectQuery(regexp.QuoteMeta(COUNT_PRICE_CHANGES)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_PRICE_CHANGES)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "old_price", "new_price", "currency", "source", "changed_by_guid", "schedule_guid"}).
+			AddRow(2, 1, "15.00", "20.00", TEST_CURRENCY, "schedule_ended", "", TEST_SCHEDULE_ID).
+			AddRow(1, 1, "20.00", "15.00", TEST_CURRENCY, "manual", "test_user_id", ""))
+
+	w, c := newPriceScheduleContext(t, "GET", nil)
+	ListPriceChanges(gdb)(c)
+
+	body := w.Body.String()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, body, `"old_price":"15","new_price":"20","currency":"`+TEST_CURRENCY+`","source":"schedule_ended","schedule_id":"`+TEST_SCHEDULE_ID+`"`)
+	assert.Contains(t, body, `"source":"manual","changed_by":"test_user_id"`)
+	assert.Contains(t, body, `"total_changes":2,"total_pages":1`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreatePriceSchedule(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	startsAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	endsAt := startsAt.Add(72 * time.Hour)
+
+	expectShirt(mock)
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(COUNT_OVERLAPPING_SCHEDULES)).
+		WithArgs(1, "pending", "active", startsAt, endsAt).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(regexp.QuoteMeta(INSERT_PRICE_SCHEDULE_QUERY)).
+		WithArgs(sqlmock.AnyArg(), 1, "15.5", nil, startsAt, endsAt, "pending", "test_user_id", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	w, c := newPriceScheduleContext(t, "POST", gin.H{"price": "15.50", "starts_at": startsAt, "ends_at": endsAt})
+	CreatePriceSchedule(gdb)(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"price":"15.5"`)
+	assert.Contains(t, w.Body.String(), `"status":"pending"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Schedules with an end may not overlap, as each brings back the price it started from
+func TestCreateOverlappingPriceSchedule(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	startsAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	endsAt := startsAt.Add(time.Hour)
+
+	expectShirt(mock)
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(COUNT_OVERLAPPING_SCHEDULES)).
+		WithArgs(1, "pending", "active", startsAt, endsAt).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+
+	w, c := newPriceScheduleContext(t, "POST", gin.H{"price": "15", "starts_at": startsAt, "ends_at": endsAt})
+	CreatePriceSchedule(gdb)(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "already has a price scheduled")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreatePriceScheduleRejectsInvalidInput(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	future := time.Now().Add(time.Hour)
+	for message, body := range map[string]gin.H{
+		"starts_at must be in the future": {"price": "15", "starts_at": time.Now().Add(-time.Hour)},
+		"ends_at must be after starts_at": {"price": "15", "starts_at": future, "ends_at": future},
+		"price must be a non-negative":    {"price": "-1", "starts_at": future},
+		"with at most 2 decimal places":   {"price": "9.999", "starts_at": future},
+		"starts_at is required":           {"price": "15"},
+		"below 100000000":                 {"price": "100000000", "starts_at": future},
+	} {
+		w, c := newPriceScheduleContext(t, "POST", body)
+		CreatePriceSchedule(gdb)(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, message)
+		assert.Contains(t, w.Body.String(), message)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Cancelling a schedule in effect brings back the regular price right away
+func TestCancelActivePriceSchedule(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	startsAt := time.Now().Add(-time.Hour)
+	expectShirt(mock)
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_PRICE_SCHEDULE_BY_ID)).
+		WithArgs(TEST_SCHEDULE_ID, 1).
+		WillReturnRows(sqlmock.NewRows(priceScheduleColumns).
+			AddRow(3, TEST_SCHEDULE_ID, 1, "15.00", "20.00", startsAt, startsAt.Add(24*time.Hour), "active"))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_PRODUCT_BY_ID_QUERY)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(productColumns).AddRow(1, "Shirt", "Cotton shirt", "shirt", "15.00", 0, TEST_CURRENCY))
+	mock.ExpectExec(regexp.QuoteMeta(UPDATE_PRICE_SCHEDULE_STATUS)).
+		WithArgs("cancelled", sqlmock.AnyArg(), 3, "active").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(UPDATE_PRODUCT_PRICE_QUERY)).
+		WithArgs("20", sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(INSERT_PRICE_CHANGE_QUERY)).
+		WithArgs(1, "15", "20", TEST_CURRENCY, "schedule_ended", "test_user_id", TEST_SCHEDULE_ID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	w, c := newPriceScheduleContext(t, "DELETE", nil)
+	c.Params = append(c.Params, gin.Param{Key: "schedule_id", Value: TEST_SCHEDULE_ID})
+	CancelPriceSchedule(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"cancelled"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// A schedule that another request or the scheduler has just moved on is left alone
+func TestCancelFinishedPriceSchedule(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	startsAt := time.Now().Add(time.Hour)
+	for _, status := range []string{"completed", "pending"} {
+		expectShirt(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(SELECT_PRICE_SCHEDULE_BY_ID)).
+			WithArgs(TEST_SCHEDULE_ID, 1).
+			WillReturnRows(sqlmock.NewRows(priceScheduleColumns).
+				AddRow(3, TEST_SCHEDULE_ID, 1, "15.00", nil, startsAt, nil, status))
+
+		if status == "pending" {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(SELECT_PRODUCT_BY_ID_QUERY)).
+				WithArgs(1).
+				WillReturnRows(sqlmock.NewRows(productColumns).AddRow(1, "Shirt", "Cotton shirt", "shirt", "20.00", 0, TEST_CURRENCY))
+			mock.ExpectExec(regexp.QuoteMeta(UPDATE_PRICE_SCHEDULE_STATUS)).
+				WithArgs("cancelled", sqlmock.AnyArg(), 3, "pending").
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectRollback()
+		}
+
+		w, c := newPriceScheduleContext(t, "DELETE", nil)
+		c.Params = append(c.Params, gin.Param{Key: "schedule_id", Value: TEST_SCHEDULE_ID})
+		CancelPriceSchedule(gdb)(c)
+
+		assert.Equal(t, http.StatusConflict, w.Code, status)
+		assert.Contains(t, w.Body.String(), "already ended or been cancelled")
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// expectDueSchedules returns rows of price_schedules as the schedules due at now
+func expectDueSchedules(mock sqlmock.Sqlmock, now time.Time, rows *sqlmock.Rows) {
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_DUE_PRICE_SCHEDULES)).
+		WithArgs("pending", now, "active", now).
+		WillReturnRows(rows)
+}
+
+// expectScheduledShirt starts the transaction of a schedule step, which loads the shirt at price
+func expectScheduledShirt(mock sqlmock.Sqlmock, price string) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_PRODUCT_BY_ID_QUERY)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(productColumns).AddRow(1, "Shirt", "Cotton shirt", "shirt", price, 0, TEST_CURRENCY))
+}
+
+// expectPriceChange expects the price of the shirt to change from oldPrice to newPrice because of a schedule
+func expectPriceChange(mock sqlmock.Sqlmock, scheduleID, oldPrice, newPrice, source string) {
+	mock.ExpectExec(regexp.QuoteMeta(UPDATE_PRODUCT_PRICE_QUERY)).
+		WithArgs(newPrice, sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(INSERT_PRICE_CHANGE_QUERY)).
+		WithArgs(1, oldPrice, newPrice, TEST_CURRENCY, source, "", scheduleID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+// A due schedule puts its price in effect and remembers the regular price
+func TestApplyPriceSchedulesStartsSchedule(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	now := time.Now()
+	expectDueSchedules(mock, now, sqlmock.NewRows(priceScheduleColumns).
+		AddRow(3, TEST_SCHEDULE_ID, 1, "15.00", nil, now.Add(-time.Minute), now.Add(time.Hour), "pending"))
+	expectScheduledShirt(mock, "20.00")
+	mock.ExpectExec(regexp.QuoteMeta(START_PRICE_SCHEDULE_QUERY)).
+		WithArgs("20", "active", sqlmock.AnyArg(), 3, "pending").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectPriceChange(mock, TEST_SCHEDULE_ID, "20", "15", "schedule_started")
+	mock.ExpectCommit()
+
+	assert.NoError(t, repository.ApplyPriceSchedules(gdb, now))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// At the end the regular price comes back, unless the price was changed in the meantime
+func TestApplyPriceSchedulesEndsSchedule(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	now := time.Now()
+	for _, currentPrice := range []string{"15.00", "18.00"} {
+		expectDueSchedules(mock, now, sqlmock.NewRows(priceScheduleColumns).
+			AddRow(3, TEST_SCHEDULE_ID, 1, "15.00", "20.00", now.Add(-time.Hour), now.Add(-time.Minute), "active"))
+		expectScheduledShirt(mock, currentPrice)
+		mock.ExpectExec(regexp.QuoteMeta(UPDATE_PRICE_SCHEDULE_STATUS)).
+			WithArgs("completed", sqlmock.AnyArg(), 3, "active").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		if currentPrice == "15.00" {
+			expectPriceChange(mock, TEST_SCHEDULE_ID, "15", "20", "schedule_ended")
+		}
+		mock.ExpectCommit()
+
+		assert.NoError(t, repository.ApplyPriceSchedules(gdb, now), currentPrice)
+		assert.NoError(t, mock.ExpectationsWereMet(), currentPrice)
+	}
+}
+
+// A schedule whose whole window passed before the scheduler got to it changes nothing
+func TestApplyPriceSchedulesExpiresMissedSchedule(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	now := time.Now()
+	expectDueSchedules(mock, now, sqlmock.NewRows(priceScheduleColumns).
+		AddRow(3, TEST_SCHEDULE_ID, 1, "15.00", nil, now.Add(-2*time.Hour), now.Add(-time.Hour), "pending"))
+	expectScheduledShirt(mock, "20.00")
+	mock.ExpectExec(regexp.QuoteMeta(UPDATE_PRICE_SCHEDULE_STATUS)).
+		WithArgs("expired", sqlmock.AnyArg(), 3, "pending").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repository.ApplyPriceSchedules(gdb, now))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Another instance of the scheduler claimed the schedule first, so this one leaves the price alone
+func TestApplyPriceSchedulesSkipsClaimedSchedule(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	now := time.Now()
+	expectDueSchedules(mock, now, sqlmock.NewRows(priceScheduleColumns).
+		AddRow(3, TEST_SCHEDULE_ID, 1, "15.00", nil, now.Add(-time.Minute), now.Add(time.Hour), "pending"))
+	expectScheduledShirt(mock, "20.00")
+	mock.ExpectExec(regexp.QuoteMeta(START_PRICE_SCHEDULE_QUERY)).
+		WithArgs("20", "active", sqlmock.AnyArg(), 3, "pending").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	assert.NoError(t, repository.ApplyPriceSchedules(gdb, now))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// When one sale ends as the next starts, the end goes first even when the
+// next sale was created earlier, so that it remembers the regular price
+func TestApplyPriceSchedulesEndsBeforeStarting(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	now := time.Now()
+	changeover := now.Add(-time.Minute)
+	expectDueSchedules(mock, now, sqlmock.NewRows(priceScheduleColumns).
+		AddRow(1, OTHER_SCHEDULE_ID, 1, "12.00", nil, changeover, changeover.Add(24*time.Hour), "pending").
+		AddRow(2, TEST_SCHEDULE_ID, 1, "15.00", "20.00", changeover.Add(-24*time.Hour), changeover, "active"))
+
+	expectScheduledShirt(mock, "15.00")
+	mock.ExpectExec(regexp.QuoteMeta(UPDATE_PRICE_SCHEDULE_STATUS)).
+		WithArgs("completed", sqlmock.AnyArg(), 2, "active").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectPriceChange(mock, TEST_SCHEDULE_ID, "15", "20", "schedule_ended")
+	mock.ExpectCommit()
+
+	expectScheduledShirt(mock, "20.00")
+	mock.ExpectExec(regexp.QuoteMeta(START_PRICE_SCHEDULE_QUERY)).
+		WithArgs("20", "active", sqlmock.AnyArg(), 1, "pending").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectPriceChange(mock, OTHER_SCHEDULE_ID, "20", "12", "schedule_started")
+	mock.ExpectCommit()
+
+	assert.NoError(t, repository.ApplyPriceSchedules(gdb, now))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const MAX_SEARCH_QUERY_LENGTH = 200
+
+type ProductSearchHit struct {
+	Product    *model.Product    `json:"product"`
+	Score      float64           `json:"score" example:"3.12"`
+	Highlights map[string]string `json:"highlights"` // the indexed fields as HTML, matching words in <mark> tags
+}
+
+type SearchProductsResponse struct {
+	Results      []ProductSearchHit `json:"results"`
+	Message      string             `json:"message"`
+	Query        string             `json:"query"`
+	TotalResults int                `json:"total_results"`
+	TotalPages   int                `json:"total_pages"`
+	Page         int                `json:"page"`
+	Size         int                `json:"size"`
+}
+
+// SearchProducts searches the product catalog
+// @Summary Search products
+// @Description Full-text search over the names and descriptions of the products, the most relevant first. Every word of the query has to match; accents and case are ignored, the last word may be a prefix and longer words tolerate typos (one from 5 letters, two from 9). Highlights are HTML with the matching words in <mark> tags
+// @Tags Products
+// @Produce		json
+// @Param q query string true "Search query"
+// @Param page query string false "Page (Default 1)"
+// @Param size query string false "Size (Default 10, at most 100)"
+// @Success 200 {object} handler.SearchProductsResponse{results=[]handler.ProductSearchHit, message=string, query=string, total_results=int, total_pages=int, page=int, size=int} "Matching products"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid query"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Failed to search products"
+// @Router /api/v1/products/search [get]
+func SearchProducts(db *gorm.DB, storage util.Storage) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		query := strings.TrimSpace(ctx.Query("q"))
+		if query == "" {
+			handleProductError(ctx, http.StatusBadRequest, "q is required")
+			return
+		}
+
+		if utf8.RuneCountInString(query) > MAX_SEARCH_QUERY_LENGTH {
+			handleProductError(ctx, http.StatusBadRequest, "q must be at most 200 characters long")
+			return
+		}
+
+		page, limit := productPage(ctx)
+		matches, totalResults, err := repository.SearchProducts(db, query, page, limit)
+		if err == nil {
+			products := make([]*model.Product, 0, len(matches))
+			for _, match := range matches {
+				products = append(products, match.Product)
+			}
+			err = loadProductImages(db, storage, products)
+		}
+
+		if err != nil {
+			log.Println(err.Error())
+			handleProductError(ctx, http.StatusInternalServerError, "Failed to search products")
+			return
+		}
+
+		results := make([]ProductSearchHit, 0, len(matches))
+		for _, match := range matches {
+			results = append(results, ProductSearchHit{Product: match.Product, Score: match.Score, Highlights: match.Highlights})
+		}
+
+		message := "Products retrieved successfully"
+		if len(results) == 0 {
+			message = "No products found"
+		}
+
+		response := SearchProductsResponse{
+			Results:      results,
+			Message:      message,
+			Query:        query,
+			TotalResults: totalResults,
+			TotalPages:   int(math.Ceil(float64(totalResults) / float64(limit))),
+			Page:         page,
+			Size:         limit,
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, response)
+	}
+}
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	PRODUCT_SEARCH_ENDPOINT  = "/api/v1/products/search"
+	INDEX_PRODUCTS_QUERY     = "SELECT * FROM `products` WHERE (id > ? AND is_deleted = false) ORDER BY id ASC LIMIT 1000"
+	SEARCH_PRODUCTS_QUERY    = "SELECT * FROM `products` WHERE (product_code IN (?,?) AND is_deleted = false)"
+	SEARCH_ONE_PRODUCT_QUERY = "SELECT * FROM `products` WHERE (product_code IN (?) AND is_deleted = false)"
+	DELETE_PRODUCT_QUERY     = "UPDATE `products` SET `is_deleted` = ?, `updated_at` = ? WHERE `products`.`id` = ?"
+)
+
+var productColumns = []string{"id", "product_name", "product_description", "product_code", "price", "stock", "currency"}
+
+// indexTestProducts rebuilds the search index from a small catalog
+func indexTestProducts(t *testing.T, gdb *gorm.DB, mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(regexp.QuoteMeta(INDEX_PRODUCTS_QUERY)).
+		WithArgs(0).
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(1, "Electric Kettle", "Boils water fast", "kettle", "20.00", 4, "NGN").
+			AddRow(2, "Tea Cups", "Set of cups, goes well with a kettle", "cups", "8.00", 10, "NGN").
+			AddRow(3, "Blender", "Crushes ice", "blender", "45.00", 2, "NGN"))
+
+	assert.NoError(t, repository.RebuildProductIndex(gdb))
+}
+
+func newProductSearchContext(query string) (*httptest.ResponseRecorder, *gin.Context) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", PRODUCT_SEARCH_ENDPOINT+query, nil)
+	return w, c
+}
+
+// Matches in the name rank above matches in the description, typos are tolerated
+func TestSearchProductsRanksAndHighlights(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	indexTestProducts(t, gdb, mock)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SEARCH_PRODUCTS_QUERY)).
+		WithArgs("kettle", "cups").
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(2, "Tea Cups", "Set of cups, goes well with a kettle", "cups", "8.00", 10, "NGN").
+			AddRow(1, "Electric Kettle", "Boils water fast", "kettle", "20.00", 4, "NGN"))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_TWO_PRODUCT_IMAGES)).
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows(imageColumns))
+
+	w, c := newProductSearchContext("?q=ketle")
+	SearchProducts(gdb, newTestStorage(t))(c)
+
+	body := w.Body.String()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, body, `"total_results":2`)
+	assert.Contains(t, body, `"query":"ketle"`)
+	assert.Less(t, regexp.MustCompile(`"product_code":"kettle"`).FindStringIndex(body)[0],
+		regexp.MustCompile(`"product_code":"cups"`).FindStringIndex(body)[0], "The name match should come first")
+	assert.Contains(t, body, `"product_name":"Electric \u003cmark\u003eKettle\u003c/mark\u003e"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchProductsInvalidQuery(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	w, c := newProductSearchContext("?q=++")
+	SearchProducts(gdb, newTestStorage(t))(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "q is required")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Deleted products leave the index right away
+func TestSearchProductsAfterDelete(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	indexTestProducts(t, gdb, mock)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(DELETE_PRODUCT_QUERY)).
+		WithArgs(true, sqlmock.AnyArg(), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repository.DeleteProduct(gdb, &model.Product{ID: 3, ProductCode: "blender"}))
+
+	w, c := newProductSearchContext("?q=blender")
+	SearchProducts(gdb, newTestStorage(t))(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"results":[]`)
+	assert.Contains(t, w.Body.String(), "No products found")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Prefixes of the last word match while typing
+func TestSearchProductsPrefix(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	indexTestProducts(t, gdb, mock)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SEARCH_ONE_PRODUCT_QUERY)).
+		WithArgs("blender").
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(3, "Blender", "Crushes ice", "blender", "45.00", 2, "NGN"))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_PRODUCT_IMAGES)).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(imageColumns))
+
+	w, c := newProductSearchContext("?q=crushes+ic")
+	SearchProducts(gdb, newTestStorage(t))(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"product_code":"blender"`)
+	assert.Contains(t, w.Body.String(), `"total_results":1`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Products deleted by another instance since the last rebuild are left out of
+// the page, only the database is queried for it
+func TestSearchProductsLeavesOutStaleProducts(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	indexTestProducts(t, gdb, mock)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SEARCH_PRODUCTS_QUERY)).
+		WithArgs("kettle", "cups").
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(2, "Tea Cups", "Set of cups, goes well with a kettle", "cups", "8.00", 10, "NGN"))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_PRODUCT_IMAGES)).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(imageColumns))
+
+	w, c := newProductSearchContext("?q=kettle")
+	SearchProducts(gdb, newTestStorage(t))(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"product_code":"cups"`)
+	assert.NotContains(t, w.Body.String(), `"product_code":"kettle"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// A product deleted while the index is rebuilt stays out of the new index
+func TestSearchProductsDeletedDuringRebuild(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(INDEX_PRODUCTS_QUERY)).
+		WithArgs(0).
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(3, "Blender", "Crushes ice", "blender", "45.00", 2, "NGN"))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(DELETE_PRODUCT_QUERY)).
+		WithArgs(true, sqlmock.AnyArg(), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// the rebuild waits after reading the blender until it has been deleted
+	read, deleted := make(chan struct{}), make(chan struct{})
+	gdb.Callback().Query().After("gorm:after_query").Register("test:pause_rebuild", func(scope *gorm.Scope) {
+		close(read)
+		<-deleted
+	})
+
+	rebuilt := make(chan error)
+	go func() { rebuilt <- repository.RebuildProductIndex(gdb) }()
+
+	<-read
+	assert.NoError(t, repository.DeleteProduct(gdb, &model.Product{ID: 3, ProductCode: "blender"}))
+	close(deleted)
+	assert.NoError(t, <-rebuilt)
+
+	w, c := newProductSearchContext("?q=blender")
+	SearchProducts(gdb, newTestStorage(t))(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"results":[]`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,313 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	MAX_PRODUCT_OPTIONS        = 3
+	MAX_OPTION_LENGTH          = 64
+	INVALID_OPTIONS_ERROR      = "options must be at most 3 distinct names of at most 64 characters"
+	INVALID_VARIANT_PRICE      = "price must be a non-negative number"
+	INVALID_OPTION_VALUE_ERROR = "option values must be between 1 and 64 characters long"
+)
+
+type ProductOptionsRequest struct {
+	Options []string `json:"options" example:"size,colour"` // in display order, an empty list removes the options
+}
+
+type VariantRequest struct {
+	SKU     string            `json:"sku" binding:"required,max=64" example:"SHIRT-RED-M"`
+	Price   *decimal.Decimal  `json:"price" example:"25.00"` // leave out to use the price of the product
+	Stock   uint              `json:"stock" example:"10"`
+	Options map[string]string `json:"options" binding:"required"` // a value for each option of the product, e.g. {"size": "M", "colour": "red"}
+}
+
+type VariantResponse struct {
+	Variant *model.ProductVariant `json:"variant"`
+	Message string                `json:"message"`
+}
+
+// findProduct loads the product of the product_code path parameter. It
+// responds itself when the product cannot be loaded
+func findProduct(ctx *gin.Context, db *gorm.DB) (*model.Product, bool) {
+	product, err := repository.GetProduct(db, ctx.Param("product_code"))
+	if err != nil {
+		if err.Error() == repository.PRODUCT_NOT_FOUND_ERROR {
+			handleProductError(ctx, http.StatusNotFound, repository.PRODUCT_NOT_FOUND_ERROR)
+			return nil, false
+		}
+		log.Println(err.Error())
+		handleProductError(ctx, http.StatusInternalServerError, PRODUCT_RETRIEVAL_ERROR)
+		return nil, false
+	}
+	return product, true
+}
+
+// bindVariantRequest reads and validates a variant from the request body and
+// copies it onto variant. It responds itself when the variant is invalid
+func bindVariantRequest(ctx *gin.Context, variant *model.ProductVariant) bool {
+	var variantRequest VariantRequest
+	if err := ctx.ShouldBindJSON(&variantRequest); err != nil {
+		validationError := util.ExtractValidationErrorMessage(err, variantRequest)
+		handleProductError(ctx, http.StatusBadRequest, validationError[0])
+		return false
+	}
+
+	if variantRequest.Price != nil && variantRequest.Price.IsNegative() {
+		handleProductError(ctx, http.StatusBadRequest, INVALID_VARIANT_PRICE)
+		return false
+	}
+
+	options := make([]model.VariantOption, 0, len(variantRequest.Options))
+	for name, value := range variantRequest.Options {
+		value = strings.TrimSpace(value)
+		if value == "" || len(value) > MAX_OPTION_LENGTH {
+			handleProductError(ctx, http.StatusBadRequest, INVALID_OPTION_VALUE_ERROR)
+			return false
+		}
+		options = append(options, model.VariantOption{Name: strings.TrimSpace(name), Value: value})
+	}
+	slices.SortFunc(options, func(a, b model.VariantOption) int { return strings.Compare(a.Name, b.Name) })
+
+	variant.SKU = strings.TrimSpace(variantRequest.SKU)
+	variant.Price = variantRequest.Price
+	variant.Stock = variantRequest.Stock
+	variant.Options = options
+	return true
+}
+
+// handleVariantError responds to a variant that could not be loaded or changed
+func handleVariantError(ctx *gin.Context, err error, message string) {
+	switch err.Error() {
+	case repository.VARIANT_NOT_FOUND_ERROR:
+		handleProductError(ctx, http.StatusNotFound, err.Error())
+	case repository.VARIANT_OPTIONS_ERROR:
+		handleProductError(ctx, http.StatusBadRequest, err.Error())
+	case repository.SKU_TAKEN_ERROR, repository.DUPLICATE_VARIANT_ERROR, repository.PRODUCT_HAS_VARIANTS_ERROR:
+		handleProductError(ctx, http.StatusConflict, err.Error())
+	default:
+		log.Println(err.Error())
+		handleProductError(ctx, http.StatusInternalServerError, message)
+	}
+}
+
+// SetProductOptions sets the options of a product
+// @Summary Set the options of a product
+// @Description Sets the ways in which the variants of a product differ, such as size and colour, in display order. Options can only change while the product has no variants
+// @Tags Products
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Accept		json
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
+// @Param product_code path string true "Product Code"
+// @Param request body ProductOptionsRequest true "Option names"
+// @Success 200 {object} handler.ProductResponse{product=model.Product, message=string} "Options updated"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid options"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Product not found"
+// @Failure 409 {object} util.ErrorResponse{error=bool, error_message=string} "Delete the variants of the product before changing its options"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to save options"
+// @Router /api/v1/admin/product/{product_code}/options [put]
+func SetProductOptions(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var optionsRequest ProductOptionsRequest
+		if err := ctx.ShouldBindJSON(&optionsRequest); err != nil {
+			handleProductError(ctx, http.StatusBadRequest, INVALID_USER_INPUT)
+			return
+		}
+
+		names := make([]string, 0, len(optionsRequest.Options))
+		for _, name := range optionsRequest.Options {
+			name = strings.TrimSpace(name)
+			if name == "" || len(name) > MAX_OPTION_LENGTH || slices.Contains(names, name) {
+				handleProductError(ctx, http.StatusBadRequest, INVALID_OPTIONS_ERROR)
+				return
+			}
+			names = append(names, name)
+		}
+
+		if len(names) > MAX_PRODUCT_OPTIONS {
+			handleProductError(ctx, http.StatusBadRequest, INVALID_OPTIONS_ERROR)
+			return
+		}
+
+		product, ok := findProduct(ctx, db)
+		if !ok {
+			return
+		}
+
+		if err := repository.SetProductOptions(db, product, names); err != nil {
+			handleVariantError(ctx, err, "Unable to save options")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, ProductResponse{Product: product, Message: "Options updated"})
+	}
+}
+
+// CreateVariant adds a variant to a product
+// @Summary Create a product variant
+// @Description Adds a variant with its own SKU and stock and a value for each option of the product. The price of the product applies unless the variant has its own. Once a product has variants, orders must name the variant
+// @Tags Products
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Accept		json
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
+// @Param product_code path string true "Product Code"
+// @Param request body VariantRequest true "Variant Request"
+// @Success 201 {object} handler.VariantResponse{variant=model.ProductVariant, message=string} "Variant created"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid variant"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Product not found"
+// @Failure 409 {object} util.ErrorResponse{error=bool, error_message=string} "The SKU or the options are taken by another variant"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to save variant"
+// @Router /api/v1/admin/product/{product_code}/variants [post]
+func CreateVariant(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var variant model.ProductVariant
+		if !bindVariantRequest(ctx, &variant) {
+			return
+		}
+
+		product, ok := findProduct(ctx, db)
+		if !ok {
+			return
+		}
+
+		if err := repository.SaveVariant(db, product, &variant); err != nil {
+			handleVariantError(ctx, err, "Unable to save variant")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusCreated, VariantResponse{Variant: &variant, Message: "Variant created"})
+	}
+}
+
+// UpdateVariant changes a variant of a product
+// @Summary Update a product variant
+// @Description Replaces the SKU, price, stock and options of a variant
+// @Tags Products
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Accept		json
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
+// @Param product_code path string true "Product Code"
+// @Param sku path string true "Variant SKU"
+// @Param request body VariantRequest true "Variant Request"
+// @Success 200 {object} handler.VariantResponse{variant=model.ProductVariant, message=string} "Variant updated"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid variant"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Product or variant not found"
+// @Failure 409 {object} util.ErrorResponse{error=bool, error_message=string} "The SKU or the options are taken by another variant"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to save variant"
+// @Router /api/v1/admin/product/{product_code}/variants/{sku} [put]
+func UpdateVariant(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		product, ok := findProduct(ctx, db)
+		if !ok {
+			return
+		}
+
+		variant, err := repository.FindVariant(db, product, ctx.Param("sku"))
+		if err != nil {
+			handleVariantError(ctx, err, "Unable to save variant")
+			return
+		}
+
+		if !bindVariantRequest(ctx, variant) {
+			return
+		}
+
+		if err := repository.SaveVariant(db, product, variant); err != nil {
+			handleVariantError(ctx, err, "Unable to save variant")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, VariantResponse{Variant: variant, Message: "Variant updated"})
+	}
+}
+
+// DeleteVariant removes a variant of a product
+// @Summary Delete a product variant
+// @Description Stops a variant from being sold. Orders keep showing the variant and its SKU stays taken
+// @Tags Products
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string false "Bearer Token"
+// @Param X-API-Key header string false "API Key, instead of a Bearer Token"
+// @Param product_code path string true "Product Code"
+// @Param sku path string true "Variant SKU"
+// @Success 200 {object} util.ErrorResponse{error=bool, error_message=string} "Variant deleted"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Product or variant not found"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to delete variant"
+// @Router /api/v1/admin/product/{product_code}/variants/{sku} [delete]
+func DeleteVariant(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		product, ok := findProduct(ctx, db)
+		if !ok {
+			return
+		}
+
+		variant, err := repository.FindVariant(db, product, ctx.Param("sku"))
+		if err != nil {
+			handleVariantError(ctx, err, "Unable to delete variant")
+			return
+		}
+
+		if err := repository.DeleteVariant(db, variant); err != nil {
+			handleVariantError(ctx, err, "Unable to delete variant")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, util.ErrorResponse{Error: false, ErrorMessage: "Variant deleted"})
+	}
+}
+
+// orderedVariant returns the variant named by an order line. Products with
+// variants are only sold by variant, products without by themselves
+func orderedVariant(db *gorm.DB, product *model.Product, productDTO ProductDTO) (*model.ProductVariant, error) {
+	if productDTO.VariantSKU == "" {
+		count, err := repository.CountVariants(db, product)
+		if err != nil {
+			return nil, err
+		}
+
+		if count > 0 {
+			return nil, fmt.Errorf("Product %s comes in several variants, choose one with variant_sku", product.ProductCode)
+		}
+		return nil, nil
+	}
+
+	variant, err := repository.FindVariant(db, product, productDTO.VariantSKU)
+	if err != nil {
+		if err.Error() == repository.VARIANT_NOT_FOUND_ERROR {
+			return nil, fmt.Errorf("Product %s has no variant %s", product.ProductCode, productDTO.VariantSKU)
+		}
+		return nil, err
+	}
+	return variant, nil
+}
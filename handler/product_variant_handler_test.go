@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	VARIANTS_ENDPOINT            = "/api/v1/admin/product/shirt/variants"
+	SELECT_SHIRT_QUERY           = "SELECT * FROM `products` WHERE (product_code = ? AND is_deleted = false)"
+	SELECT_PRODUCT_OPTIONS       = "SELECT * FROM `product_options` WHERE (product_id = ?) ORDER BY position ASC"
+	SELECT_PRODUCT_VARIANTS      = "SELECT * FROM `product_variants` WHERE (product_id = ? AND is_deleted = false) ORDER BY id ASC"
+	SELECT_VARIANT_OPTIONS       = "SELECT * FROM `variant_options` WHERE (`variant_id` IN (?))"
+	SELECT_VARIANT_BY_SKU        = "SELECT * FROM `product_variants` WHERE (sku = ? AND product_id = ? AND is_deleted = false)"
+	COUNT_VARIANT_SKU            = "SELECT count(*) FROM `product_variants` WHERE (sku = ? AND id <> ?)"
+	COUNT_PRODUCT_VARIANTS       = "SELECT count(*) FROM `product_variants` WHERE (product_id = ? AND is_deleted = false)"
+	INSERT_VARIANT_QUERY         = "INSERT INTO `product_variants` (`product_id`,`sku`,`price`,`stock`,`is_deleted`,`created_at`,`updated_at`) VALUES (?,?,?,?,?,?,?)"
+	DELETE_VARIANT_OPTIONS_QUERY = "DELETE FROM `variant_options` WHERE (variant_id = ?)"
+	INSERT_VARIANT_OPTION_QUERY  = "INSERT INTO `variant_options` (`variant_id`,`name`,`value`) VALUES (?,?,?)"
+)
+
+// expectShirtVariants returns the options of a shirt, size and colour, and its red medium variant
+func expectShirtVariants(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_PRODUCT_OPTIONS)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "name", "position"}).
+			AddRow(1, 1, "size", 0).
+			AddRow(2, 1, "colour", 1))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_PRODUCT_VARIANTS)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "sku", "stock"}).AddRow(7, 1, "SHIRT-RED-M", 3))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_VARIANT_OPTIONS)).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "variant_id", "name", "value"}).
+			AddRow(1, 7, "colour", "red").
+			AddRow(2, 7, "size", "M"))
+}
+
+func newVariantTestContext(t *testing.T, method string, body any) (*httptest.ResponseRecorder, *gin.Context) {
+	w, c := createTestContext(body, VARIANTS_ENDPOINT, t)
+	c.Request.Method = method
+	c.Params = gin.Params{{Key: "product_code", Value: "shirt"}}
+	return w, c
+}
+
+func TestCreateVariant(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_SHIRT_QUERY)).
+		WithArgs("shirt").
+		WillReturnRows(sqlmock.NewRows(productColumns).AddRow(1, "Shirt", "Cotton shirt", "shirt", "20.00", 0, TEST_CURRENCY))
+	expectShirtVariants(mock)
+	mock.ExpectQuery(regexp.QuoteMeta(COUNT_VARIANT_SKU)).
+		WithArgs("SHIRT-BLUE-L", 0).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(INSERT_VARIANT_QUERY)).
+		WithArgs(1, "SHIRT-BLUE-L", "25", 5, false, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(8, 1))
+	mock.ExpectExec(regexp.QuoteMeta(DELETE_VARIANT_OPTIONS_QUERY)).
+		WithArgs(8).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(INSERT_VARIANT_OPTION_QUERY)).
+		WithArgs(8, "colour", "blue").
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectExec(regexp.QuoteMeta(INSERT_VARIANT_OPTION_QUERY)).
+		WithArgs(8, "size", "L").
+		WillReturnResult(sqlmock.NewResult(4, 1))
+	mock.ExpectCommit()
+
+	w, c := newVariantTestContext(t, "POST", map[string]any{
+		"sku": "SHIRT-BLUE-L", "price": "25.00", "stock": 5, "options": map[string]string{"size": "L", "colour": "blue"},
+	})
+	CreateVariant(gdb)(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"sku":"SHIRT-BLUE-L","price":"25"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Variants need one value per option and a combination of their own
+func TestCreateVariantRejectsInvalidOptions(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	for _, test := range []struct {
+		options map[string]string
+		status  int
+		message string
+	}{
+		{map[string]string{"size": "L"}, http.StatusBadRequest, "exactly one value for each option"},
+		{map[string]string{"size": "L", "fit": "slim"}, http.StatusBadRequest, "exactly one value for each option"},
+		{map[string]string{"size": "m", "colour": "Red"}, http.StatusConflict, "same options"},
+	} {
+		mock.ExpectQuery(regexp.QuoteMeta(SELECT_SHIRT_QUERY)).
+			WithArgs("shirt").
+			WillReturnRows(sqlmock.NewRows(productColumns).AddRow(1, "Shirt", "Cotton shirt", "shirt", "20.00", 0, TEST_CURRENCY))
+		expectShirtVariants(mock)
+
+		w, c := newVariantTestContext(t, "POST", map[string]any{"sku": "SHIRT-NEW", "options": test.options})
+		CreateVariant(gdb)(c)
+
+		assert.Equal(t, test.status, w.Code, test.message)
+		assert.Contains(t, w.Body.String(), test.message)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetProductOptionsWithVariants(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_SHIRT_QUERY)).
+		WithArgs("shirt").
+		WillReturnRows(sqlmock.NewRows(productColumns).AddRow(1, "Shirt", "Cotton shirt", "shirt", "20.00", 0, TEST_CURRENCY))
+	mock.ExpectQuery(regexp.QuoteMeta(COUNT_PRODUCT_VARIANTS)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	w, c := newVariantTestContext(t, "PUT", map[string]any{"options": []string{"size"}})
+	SetProductOptions(gdb)(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), repository.PRODUCT_HAS_VARIANTS_ERROR)
+
+	w, c = newVariantTestContext(t, "PUT", map[string]any{"options": []string{"size", "size"}})
+	SetProductOptions(gdb)(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code, "Options should be distinct")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Products with variants are priced and stocked by variant. The stock is
+// only taken with the order
+func TestValidateProductsByVariant(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_SHIRT_QUERY)).
+		WithArgs("shirt").
+		WillReturnRows(sqlmock.NewRows(productColumns).AddRow(1, "Shirt", "Cotton shirt", "shirt", "20.00", 0, TEST_CURRENCY))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_VARIANT_BY_SKU)).
+		WithArgs("SHIRT-RED-M", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "sku", "price", "stock"}).AddRow(7, 1, "SHIRT-RED-M", "22.50", 3))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_VARIANT_OPTIONS)).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "variant_id", "name", "value"}))
+
+	products, variants, reservations, totalPrice, err := validateProducts(gdb, []ProductDTO{{Code: "shirt", VariantSKU: "SHIRT-RED-M", Quantity: 2}}, createMockUser())
+
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, "SHIRT-RED-M", variants[0].SKU)
+	assert.Equal(t, []repository.StockReservation{{ProductID: 1, VariantID: 7, Quantity: 2}}, reservations)
+	assert.Equal(t, "45", totalPrice.String(), "The price of the variant should apply")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestValidateProductsRequiresVariant(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_SHIRT_QUERY)).
+		WithArgs("shirt").
+		WillReturnRows(sqlmock.NewRows(productColumns).AddRow(1, "Shirt", "Cotton shirt", "shirt", "20.00", 10, TEST_CURRENCY))
+	mock.ExpectQuery(regexp.QuoteMeta(COUNT_PRODUCT_VARIANTS)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	_, _, _, _, err := validateProducts(gdb, []ProductDTO{{Code: "shirt", Quantity: 1}}, createMockUser())
+
+	assert.ErrorContains(t, err, "choose one with variant_sku")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
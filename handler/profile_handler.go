@@ -0,0 +1,294 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const EMAIL_CHANGE_REQUESTED = "A confirmation link has been sent to the new email address"
+
+type UpdateProfileRequest struct {
+	FirstName    string `json:"first_name" binding:"required"`
+	LastName     string `json:"last_name" binding:"required"`
+	UserCurrency string `json:"user_currency" binding:"required,min=3,max=3"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	Password        string `json:"password" binding:"required"`
+	ConfirmPassword string `json:"confirm_password" binding:"required"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// newEmailChangeMail builds the email sent to the new address to confirm an email change
+func newEmailChangeMail(user *model.User, newEmail, rawToken string) util.MailMessage {
+	link := fmt.Sprintf("%s/api/v1/user/email/confirm?token=%s", appBaseURL(), url.QueryEscape(rawToken))
+	body := fmt.Sprintf("Hello %s,\n\nPlease confirm your new Instashop email address by opening this link: %s\n", user.FirstName, link)
+	body += fmt.Sprintf("\nThe link expires in %s. Your current address stays active until the change is confirmed.\n", repository.EmailVerificationTTL())
+
+	return util.MailMessage{To: newEmail, Subject: "Confirm your new Instashop email address", Body: body}
+}
+
+// newEmailChangeNotice builds the email that warns the current address about a requested change
+func newEmailChangeNotice(user *model.User) util.MailMessage {
+	body := fmt.Sprintf("Hello %s,\n\nA change of the email address of your Instashop account was requested.\n", user.FirstName)
+	body += "\nIf you did not request this change, please change your password.\n"
+
+	return util.MailMessage{To: user.Email, Subject: "Your Instashop email address is being changed", Body: body}
+}
+
+// GetProfile returns the profile of the authenticated user
+// @Summary Get own profile
+// @Description Returns the profile of the authenticated user
+// @Tags User
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {object} model.User "User profile"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Unauthorized access"
+// @Router /api/v1/user/me [get]
+func GetProfile(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, err := authenticatedUser(ctx, db)
+		if err != nil {
+			handleTokenError(ctx, http.StatusUnauthorized, "Unauthorized access")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, user)
+	}
+}
+
+// UpdateProfile changes the name and currency of the authenticated user
+// @Summary Update own profile
+// @Description Updates the first name, last name and currency of the authenticated user
+// @Tags User
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param profile body UpdateProfileRequest true "Update Profile Request"
+// @Success 200 {object} handler.UserResponse{message=string, user=model.User} "Profile successfully updated"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid input"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Unauthorized access"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Server error"
+// @Router /api/v1/user/me [put]
+func UpdateProfile(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var profileRequest UpdateProfileRequest
+		if err := ctx.ShouldBindJSON(&profileRequest); err != nil {
+			validationError := util.ExtractValidationErrorMessage(err, profileRequest)
+			handleTokenError(ctx, http.StatusBadRequest, validationError[0])
+			return
+		}
+
+		util.LogIncomingRequest(profileRequest)
+
+		user, err := authenticatedUser(ctx, db)
+		if err != nil {
+			handleTokenError(ctx, http.StatusUnauthorized, "Unauthorized access")
+			return
+		}
+
+		currency := strings.ToUpper(profileRequest.UserCurrency)
+		user, err = repository.UpdateUserProfile(db, user, profileRequest.FirstName, profileRequest.LastName, currency)
+		if err != nil {
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to update profile")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, UserResponse{User: user, Message: "Profile successfully updated"})
+	}
+}
+
+// ChangePassword changes the password of the authenticated user
+// @Summary Change own password
+// @Description Changes the password of the authenticated user after checking the current one. Wrong passwords count as failed logins. Every existing token of the user is revoked
+// @Tags User
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param password body ChangePasswordRequest true "Change Password Request"
+// @Success 200 {object} util.ErrorResponse{error=bool, error_message=string} "Password successfully changed"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid input"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Unauthorized access or wrong current password"
+// @Failure 429 {object} util.ErrorResponse{error=bool, error_message=string} "Too many failed attempts"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Server error"
+// @Router /api/v1/user/me/password [post]
+func ChangePassword(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var passwordRequest ChangePasswordRequest
+		if err := ctx.ShouldBindJSON(&passwordRequest); err != nil {
+			validationError := util.ExtractValidationErrorMessage(err, passwordRequest)
+			handleTokenError(ctx, http.StatusBadRequest, validationError[0])
+			return
+		}
+
+		if passwordRequest.ConfirmPassword != passwordRequest.Password {
+			handleTokenError(ctx, http.StatusBadRequest, "'password' and 'confirm_password' do not match")
+			return
+		}
+
+		if !validateNewPassword(ctx, passwordRequest.Password) {
+			return
+		}
+
+		user, err := authenticatedUser(ctx, db)
+		if err != nil {
+			handleTokenError(ctx, http.StatusUnauthorized, "Unauthorized access")
+			return
+		}
+
+		if !checkReauthenticationAllowed(ctx, db, user, "change_password") {
+			return
+		}
+
+		if !repository.PasswordMatches(user, passwordRequest.CurrentPassword) {
+			recordReauthenticationFailure(ctx, db, user, "change_password")
+			handleTokenError(ctx, http.StatusUnauthorized, "Current password is incorrect")
+			return
+		}
+
+		hashedPassword, err := hashPassword(passwordRequest.Password)
+		if err != nil {
+			handleTokenError(ctx, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if err := repository.ChangeUserPassword(db, user, hashedPassword); err != nil {
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to change password")
+			return
+		}
+
+		recordAuthEvent(ctx, db, model.PasswordChangedEvent, user, "changed")
+
+		message := "Password successfully changed, please log in again"
+		util.LogAndHandleResponse(ctx, http.StatusOK, util.ErrorResponse{Error: false, ErrorMessage: message})
+	}
+}
+
+// RequestEmailChange starts a change of the authenticated user's email address
+// @Summary Change own email address
+// @Description Emails a confirmation link to the new address. The email address is only changed once the link is opened
+// @Tags User
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param email body ChangeEmailRequest true "Change Email Request"
+// @Success 200 {object} util.ErrorResponse{error=bool, error_message=string} "Confirmation link sent"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid input"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Unauthorized access or wrong password"
+// @Failure 409 {object} util.ErrorResponse{error=bool, error_message=string} "Email address already in use"
+// @Failure 429 {object} util.ErrorResponse{error=bool, error_message=string} "Too many failed attempts"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Server error"
+// @Router /api/v1/user/me/email [post]
+func RequestEmailChange(db *gorm.DB, mailer util.Mailer) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var emailRequest ChangeEmailRequest
+		if err := ctx.ShouldBindJSON(&emailRequest); err != nil {
+			validationError := util.ExtractValidationErrorMessage(err, emailRequest)
+			handleTokenError(ctx, http.StatusBadRequest, validationError[0])
+			return
+		}
+
+		user, err := authenticatedUser(ctx, db)
+		if err != nil {
+			handleTokenError(ctx, http.StatusUnauthorized, "Unauthorized access")
+			return
+		}
+
+		if !checkReauthenticationAllowed(ctx, db, user, "change_email") {
+			return
+		}
+
+		if !repository.PasswordMatches(user, emailRequest.Password) {
+			recordReauthenticationFailure(ctx, db, user, "change_email")
+			handleTokenError(ctx, http.StatusUnauthorized, "Password is incorrect")
+			return
+		}
+
+		if strings.EqualFold(emailRequest.NewEmail, user.Email) {
+			handleTokenError(ctx, http.StatusBadRequest, "'new_email' is the current email address")
+			return
+		}
+
+		rawToken, err := repository.RequestEmailChange(db, user, emailRequest.NewEmail)
+		if err != nil {
+			if err.Error() == repository.EMAIL_ALREADY_IN_USE_ERROR {
+				handleTokenError(ctx, http.StatusConflict, err.Error())
+				return
+			}
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to change email address")
+			return
+		}
+
+		sendMail(mailer, newEmailChangeMail(user, emailRequest.NewEmail, rawToken))
+		sendMail(mailer, newEmailChangeNotice(user))
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, util.ErrorResponse{Error: false, ErrorMessage: EMAIL_CHANGE_REQUESTED})
+	}
+}
+
+// ConfirmEmailChange switches a user's email address to a confirmed new address
+// @Summary Confirm an email address change
+// @Description Switches the email address of a user to the new address with the token sent to that address
+// @Tags User
+// @Produce		json
+// @Param token query string true "Email Change Token"
+// @Success 200 {object} handler.UserResponse{message=string, user=model.User} "Email address successfully changed"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid or expired token"
+// @Failure 409 {object} util.ErrorResponse{error=bool, error_message=string} "Email address already in use"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Server error"
+// @Router /api/v1/user/email/confirm [get]
+func ConfirmEmailChange(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		rawToken := ctx.Query("token")
+		if rawToken == "" {
+			handleTokenError(ctx, http.StatusBadRequest, "token is required")
+			return
+		}
+
+		user, err := repository.ConfirmEmailChange(db, rawToken)
+		if err != nil {
+			switch err.Error() {
+			case repository.INVALID_ONE_TIME_TOKEN_ERROR:
+				handleTokenError(ctx, http.StatusBadRequest, err.Error())
+			case repository.EMAIL_ALREADY_IN_USE_ERROR:
+				handleTokenError(ctx, http.StatusConflict, err.Error())
+			default:
+				log.Println(err.Error())
+				handleTokenError(ctx, http.StatusInternalServerError, "Unable to change email address")
+			}
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, UserResponse{User: user, Message: "Email address successfully changed"})
+	}
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	PROFILE             = "/api/v1/user/me"
+	CURRENT_PASSWORD    = "password123"
+	COUNT_EMAIL_QUERY   = "SELECT count(*) FROM `users` WHERE (email = ?)"
+	NEW_EMAIL           = "new@test.com"
+	WRONG_PASSWORD_TEXT = "incorrect"
+)
+
+func newProfileTestDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf(MOCK_ERROR, err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	gdb, err := gorm.Open("mysql", db)
+	if err != nil {
+		t.Fatalf(OPEN_ERROR, err)
+	}
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(CURRENT_PASSWORD), bcrypt.MinCost)
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_USER_QUERY)).
+		WithArgs(TEST_USER_ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password", "first_name", "user_guid"}).
+			AddRow(1, EMAIL, string(hashedPassword), "Test", TEST_USER_ID))
+	return gdb, mock
+}
+
+// The profile of the authenticated user is returned
+func TestGetProfile(t *testing.T) {
+	gdb, mock := newProfileTestDB(t)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", PROFILE, nil)
+	c.Set("user_id", TEST_USER_ID)
+	GetProfile(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), EMAIL)
+	assert.NotContains(t, w.Body.String(), "password")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// A wrong current password does not change the password and counts as a failed login
+func TestChangePasswordWrongCurrentPassword(t *testing.T) {
+	gdb, mock := newProfileTestDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ATTEMPTS)).
+		WithArgs("account:"+EMAIL, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	expectLoginFailure(mock)
+
+	request := ChangePasswordRequest{CurrentPassword: "wrong-password", Password: "newpassword", ConfirmPassword: "newpassword"}
+	w, c := createTestContext(request, PROFILE+"/password", t)
+	c.Set("user_id", TEST_USER_ID)
+	ChangePassword(gdb)(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), WRONG_PASSWORD_TEXT)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// An address that belongs to another account cannot be requested
+func TestRequestEmailChangeEmailTaken(t *testing.T) {
+	gdb, mock := newProfileTestDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ATTEMPTS)).
+		WithArgs("account:"+EMAIL, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(COUNT_EMAIL_QUERY)).
+		WithArgs(NEW_EMAIL).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	mailer := &recordingMailer{}
+	w, c := createTestContext(ChangeEmailRequest{NewEmail: NEW_EMAIL, Password: CURRENT_PASSWORD}, PROFILE+"/email", t)
+	c.Set("user_id", TEST_USER_ID)
+	RequestEmailChange(gdb, mailer)(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Empty(t, mailer.messages)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// A missing email change token returns 400
+func TestConfirmEmailChangeMissingToken(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf(MOCK_ERROR, err)
+	}
+	defer db.Close()
+
+	gdb, err := gorm.Open("mysql", db)
+	if err != nil {
+		t.Fatalf(OPEN_ERROR, err)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/email/confirm", nil)
+	ConfirmEmailChange(gdb)(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// A locked out account cannot request an email change, even with the right password
+func TestRequestEmailChangeLockedOut(t *testing.T) {
+	gdb, mock := newProfileTestDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ATTEMPTS)).
+		WithArgs("account:"+EMAIL, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "attempt_key", "failures", "window_started_at", "last_failed_at", "locked_until"}).
+			AddRow(1, "account:"+EMAIL, 5, time.Now(), time.Now(), time.Now().Add(10*time.Minute)))
+	expectAuthEvent(mock, model.LoginLockedOutEvent)
+
+	mailer := &recordingMailer{}
+	w, c := createTestContext(ChangeEmailRequest{NewEmail: NEW_EMAIL, Password: CURRENT_PASSWORD}, PROFILE+"/email", t)
+	c.Set("user_id", TEST_USER_ID)
+	RequestEmailChange(gdb, mailer)(c)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Empty(t, mailer.messages)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+type SaveRoleRequest struct {
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+type RoleResponse struct {
+	Role    *model.RoleDefinition `json:"role"`
+	Message string                `json:"message"`
+}
+
+// ListRoles lists the roles and their permissions
+// @Summary List roles
+// @Description Lists every role together with the permissions it grants
+// @Tags Roles
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {array} model.RoleDefinition "List of roles"
+// @Failure 403 {object} util.ErrorResponse{error=bool, error_message=string} "Forbidden"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Failed to retrieve roles"
+// @Router /api/v1/admin/roles [get]
+func ListRoles(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		roles, err := repository.ListRoles(db)
+		if err != nil {
+			handleAdminUserError(ctx, http.StatusInternalServerError, "Failed to retrieve roles", err)
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, roles)
+	}
+}
+
+// ListPermissions lists the permissions that can be granted to roles
+// @Summary List permissions
+// @Description Lists every permission that can be granted to a role
+// @Tags Roles
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {array} model.Permission "List of permissions"
+// @Failure 403 {object} util.ErrorResponse{error=bool, error_message=string} "Forbidden"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Failed to retrieve permissions"
+// @Router /api/v1/admin/permissions [get]
+func ListPermissions(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		permissions, err := repository.ListPermissions(db)
+		if err != nil {
+			handleAdminUserError(ctx, http.StatusInternalServerError, "Failed to retrieve permissions", err)
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, permissions)
+	}
+}
+
+// SaveRole creates a role or replaces its permissions
+// @Summary Create or update a role
+// @Description Creates a role or replaces the permissions of an existing role. The admin role always holds every permission and cannot be changed
+// @Tags Roles
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param role path string true "Role name"
+// @Param request body SaveRoleRequest true "Save Role Request"
+// @Success 200 {object} handler.RoleResponse{role=model.RoleDefinition, message=string} "Role saved"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid input or unknown permission"
+// @Failure 403 {object} util.ErrorResponse{error=bool, error_message=string} "The admin role cannot be changed"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to save role"
+// @Router /api/v1/admin/roles/{role} [put]
+func SaveRole(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var roleRequest SaveRoleRequest
+		if err := ctx.ShouldBindJSON(&roleRequest); err != nil {
+			validationError := util.ExtractValidationErrorMessage(err, roleRequest)
+			handleAdminUserError(ctx, http.StatusBadRequest, validationError[0], nil)
+			return
+		}
+
+		name := ctx.Param("role")
+		if name == model.AdminRole {
+			handleAdminUserError(ctx, http.StatusForbidden, "The admin role cannot be changed", nil)
+			return
+		}
+
+		role, err := repository.SaveRole(db, name, roleRequest.Description, roleRequest.Permissions)
+		if err != nil {
+			if err.Error() == repository.UNKNOWN_PERMISSION_ERROR {
+				handleAdminUserError(ctx, http.StatusBadRequest, err.Error(), nil)
+				return
+			}
+			handleAdminUserError(ctx, http.StatusInternalServerError, "Unable to save role", err)
+			return
+		}
+
+		// the event is about the administrator, there is no account the role belongs to
+		if administrator, err := authenticatedUser(ctx, db); err == nil {
+			recordAuthEvent(ctx, db, model.RolePermissionsChangedEvent, administrator, name+": "+strings.Join(roleRequest.Permissions, ","))
+		} else {
+			log.Println("unable to record auth event:", err)
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, RoleResponse{Role: role, Message: "Role saved"})
+	}
+}
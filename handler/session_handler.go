@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+// UserSession is a session as shown to its owner
+type UserSession struct {
+	*model.Session
+	Current bool `json:"current"` // the session of the token used for the request
+}
+
+type ListSessionsResponse struct {
+	Sessions []UserSession `json:"sessions"`
+}
+
+// ListSessions returns the devices the authenticated user is logged in on
+// @Summary List sessions
+// @Description Returns the active sessions of the authenticated user with the device, IP address, login time and last activity of each
+// @Tags Authentication
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {object} handler.ListSessionsResponse "Active sessions"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Unauthorized access"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to load sessions"
+// @Router /api/v1/user/sessions [get]
+func ListSessions(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, err := authenticatedUser(ctx, db)
+		if err != nil {
+			handleTokenError(ctx, http.StatusUnauthorized, "Unauthorized access")
+			return
+		}
+
+		sessions, err := repository.ListSessions(db, user)
+		if err != nil {
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to load sessions")
+			return
+		}
+
+		currentSessionID, _ := ctx.Get("session_id")
+		response := ListSessionsResponse{Sessions: make([]UserSession, 0, len(sessions))}
+		for i := range sessions {
+			response.Sessions = append(response.Sessions, UserSession{
+				Session: &sessions[i],
+				Current: sessions[i].SessionID == currentSessionID,
+			})
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, response)
+	}
+}
+
+// RevokeSession logs the authenticated user out of one of their sessions
+// @Summary Revoke a session
+// @Description Ends a session of the authenticated user. Its access and refresh tokens are rejected from then on
+// @Tags Authentication
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param session_id path string true "Session ID"
+// @Success 200 {object} util.ErrorResponse{error=bool, error_message=string} "Session revoked"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Unauthorized access"
+// @Failure 404 {object} util.ErrorResponse{error=bool, error_message=string} "Session not found"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to revoke session"
+// @Router /api/v1/user/sessions/{session_id} [delete]
+func RevokeSession(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, err := authenticatedUser(ctx, db)
+		if err != nil {
+			handleTokenError(ctx, http.StatusUnauthorized, "Unauthorized access")
+			return
+		}
+
+		if err := repository.RevokeSession(db, user, ctx.Param("session_id")); err != nil {
+			if err.Error() == repository.SESSION_NOT_FOUND_ERROR {
+				handleTokenError(ctx, http.StatusNotFound, err.Error())
+				return
+			}
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to revoke session")
+			return
+		}
+
+		recordAuthEvent(ctx, db, model.TokensRevokedEvent, user, "session "+ctx.Param("session_id"))
+		util.LogAndHandleResponse(ctx, http.StatusOK, util.ErrorResponse{Error: false, ErrorMessage: "Session revoked"})
+	}
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+)
+
+const (
+	SESSION_USER_ID       = "session-user"
+	SELECT_SESSIONS_QUERY = "SELECT * FROM `sessions` WHERE (user_id = ? AND revoked_at IS NULL AND expires_at > ?) ORDER BY last_seen_at DESC"
+	SELECT_SESSION_QUERY  = "SELECT * FROM `sessions` WHERE (session_id = ? AND user_id = ?) ORDER BY `sessions`.`id` ASC LIMIT 1"
+)
+
+func newSessionTestContext(method, sessionID string) (*httptest.ResponseRecorder, *gin.Context) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, "/user/sessions", nil)
+	c.Params = gin.Params{{Key: "session_id", Value: sessionID}}
+	c.Set("user_id", SESSION_USER_ID)
+	c.Set("session_id", "session-1")
+	return w, c
+}
+
+func expectSessionUser(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_USER_QUERY)).
+		WithArgs(SESSION_USER_ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_guid"}).AddRow(1, SESSION_USER_ID))
+}
+
+// Active sessions are listed with the session of the request marked as current
+func TestListSessions(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	expectSessionUser(mock)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_SESSIONS_QUERY)).
+		WithArgs(1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "session_id", "user_id", "user_agent", "ip_address", "last_seen_at"}).
+			AddRow(1, "session-2", 1, "curl/8.0", "10.0.0.2", time.Now()).
+			AddRow(2, "session-1", 1, "Mozilla/5.0", "10.0.0.1", time.Now().Add(-time.Hour)))
+
+	w, c := newSessionTestContext("GET", "")
+	ListSessions(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Sessions []struct {
+			SessionID string `json:"session_id"`
+			UserAgent string `json:"user_agent"`
+			IPAddress string `json:"ip_address"`
+			Current   bool   `json:"current"`
+		} `json:"sessions"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Sessions, 2)
+	assert.Equal(t, "session-2", response.Sessions[0].SessionID)
+	assert.Equal(t, "curl/8.0", response.Sessions[0].UserAgent)
+	assert.False(t, response.Sessions[0].Current)
+	assert.Equal(t, "10.0.0.1", response.Sessions[1].IPAddress)
+	assert.True(t, response.Sessions[1].Current)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Revoking a session revokes its refresh tokens and the session itself
+func TestRevokeSession(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	expectSessionUser(mock)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_SESSION_QUERY)).
+		WithArgs("session-2", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "session_id", "user_id", "expires_at"}).
+			AddRow(1, "session-2", 1, time.Now().Add(time.Hour)))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `refresh_tokens` SET `revoked_at` = ? WHERE (family_id = ? AND revoked_at IS NULL)")).
+		WithArgs(sqlmock.AnyArg(), "session-2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `sessions` SET `revoked_at` = ? WHERE (session_id = ? AND revoked_at IS NULL)")).
+		WithArgs(sqlmock.AnyArg(), "session-2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectAuthEvent(mock, model.TokensRevokedEvent)
+
+	w, c := newSessionTestContext("DELETE", "session-2")
+	RevokeSession(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Session revoked")
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	revoked, err := repository.IsSessionRevoked(gdb, "session-2")
+	assert.NoError(t, err)
+	assert.True(t, revoked, "The revocation should be visible without another lookup")
+}
+
+// Sessions of other users cannot be revoked
+func TestRevokeSessionNotFound(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	expectSessionUser(mock)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_SESSION_QUERY)).
+		WithArgs("other-session", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	w, c := newSessionTestContext("DELETE", "other-session")
+	RevokeSession(gdb)(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), repository.SESSION_NOT_FOUND_ERROR)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
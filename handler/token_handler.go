@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// handleTokenError is a helper function to send a consistent error
+// response from the token endpoints
+func handleTokenError(ctx *gin.Context, statusCode int, message string) {
+	util.LogAndHandleResponse(ctx, statusCode, util.ErrorResponse{Error: true, ErrorMessage: message})
+}
+
+// RefreshToken exchanges a refresh token for a new token pair
+// @Summary Refresh an access token
+// @Description Exchanges a refresh token for a new access token and a rotated refresh token. Reusing an old refresh token revokes every token issued from the same login
+// @Tags Authentication
+// @Produce		json
+// @Param refresh body RefreshTokenRequest true "Refresh Token Request"
+// @Success 200 {object} util.JwtData{token=string, issuer=string, issued=string, expires=string, user_id=string, refresh_token=string, refresh_expires=string} "Tokens successfully refreshed"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid input"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid, expired or reused refresh token"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Server error"
+// @Router /api/v1/user/token/refresh [post]
+func RefreshToken(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var refreshRequest RefreshTokenRequest
+		if err := ctx.ShouldBindJSON(&refreshRequest); err != nil {
+			validationError := util.ExtractValidationErrorMessage(err, refreshRequest)
+			handleTokenError(ctx, http.StatusBadRequest, validationError[0])
+			return
+		}
+
+		response, err := repository.RefreshTokens(db, refreshRequest.RefreshToken, requestClient(ctx))
+		if err != nil {
+			switch err.Error() {
+			case repository.INVALID_REFRESH_TOKEN_ERROR, repository.REFRESH_TOKEN_REUSED_ERROR:
+				handleTokenError(ctx, http.StatusUnauthorized, err.Error())
+			default:
+				log.Println(err.Error())
+				handleTokenError(ctx, http.StatusInternalServerError, "Unable to refresh token")
+			}
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, response)
+	}
+}
+
+// Logout revokes the access token used for the request
+// @Summary Log out
+// @Description Revokes the current access token and ends its session together with its refresh tokens
+// @Tags Authentication
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param logout body LogoutRequest false "Logout Request"
+// @Success 200 {object} util.ErrorResponse{error=bool, error_message=string} "Successfully logged out"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid input"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Unauthorized access"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to log out"
+// @Router /api/v1/user/logout [post]
+func Logout(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var logoutRequest LogoutRequest
+		if err := ctx.ShouldBindJSON(&logoutRequest); err != nil && !errors.Is(err, io.EOF) {
+			handleTokenError(ctx, http.StatusBadRequest, INVALID_USER_INPUT)
+			return
+		}
+
+		user, err := authenticatedUser(ctx, db)
+		if err != nil {
+			handleTokenError(ctx, http.StatusUnauthorized, "Unauthorized access")
+			return
+		}
+
+		if logoutRequest.RefreshToken != "" {
+			if err := repository.RevokeRefreshToken(db, logoutRequest.RefreshToken, user.ID); err != nil {
+				log.Println(err.Error())
+				handleTokenError(ctx, http.StatusInternalServerError, "Unable to log out")
+				return
+			}
+		}
+
+		if sessionID, ok := ctx.Get("session_id"); ok {
+			if sessionID, ok := sessionID.(string); ok && sessionID != "" {
+				if err := repository.RevokeTokenFamily(db, sessionID); err != nil {
+					log.Println(err.Error())
+					handleTokenError(ctx, http.StatusInternalServerError, "Unable to log out")
+					return
+				}
+			}
+		}
+
+		jti, _ := ctx.Get("jti")
+		tokenExpiry, _ := ctx.Get("token_expiry")
+		if jti, ok := jti.(string); ok && jti != "" {
+			expiresAt, _ := tokenExpiry.(time.Time)
+			if err := repository.RevokeAccessToken(db, jti, user.UserID, expiresAt); err != nil {
+				log.Println(err.Error())
+				handleTokenError(ctx, http.StatusInternalServerError, "Unable to log out")
+				return
+			}
+		}
+
+		recordAuthEvent(ctx, db, model.TokensRevokedEvent, user, "logout")
+		util.LogAndHandleResponse(ctx, http.StatusOK, util.ErrorResponse{Error: false, ErrorMessage: "Successfully logged out"})
+	}
+}
+
+// LogoutEverywhere revokes every token issued to the authenticated user
+// @Summary Log out everywhere
+// @Description Revokes every access token and refresh token issued to the authenticated user
+// @Tags Authentication
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {object} util.ErrorResponse{error=bool, error_message=string} "Successfully logged out of all sessions"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Unauthorized access"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Unable to log out"
+// @Router /api/v1/user/logout/all [post]
+func LogoutEverywhere(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, err := authenticatedUser(ctx, db)
+		if err != nil {
+			handleTokenError(ctx, http.StatusUnauthorized, "Unauthorized access")
+			return
+		}
+
+		if err := repository.RevokeAllUserTokens(db, user); err != nil {
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to log out")
+			return
+		}
+
+		recordAuthEvent(ctx, db, model.TokensRevokedEvent, user, "all sessions")
+		util.LogAndHandleResponse(ctx, http.StatusOK, util.ErrorResponse{Error: false, ErrorMessage: "Successfully logged out of all sessions"})
+	}
+}
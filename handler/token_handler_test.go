@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	REFRESH                    = "/token/refresh"
+	SELECT_REFRESH_TOKEN_QUERY = "SELECT * FROM `refresh_tokens` WHERE (token_hash = ?) ORDER BY `refresh_tokens`.`id` ASC LIMIT 1"
+)
+
+// Missing refresh token returns validation error
+func TestRefreshTokenMissingToken(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf(MOCK_ERROR, err)
+	}
+	defer db.Close()
+
+	gdb, err := gorm.Open("mysql", db)
+	if err != nil {
+		t.Fatalf(OPEN_ERROR, err)
+	}
+
+	w, c := createTestContext(RefreshTokenRequest{}, REFRESH, t)
+	RefreshToken(gdb)(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "refresh_token is required")
+}
+
+// Unknown refresh token returns 401
+func TestRefreshTokenUnknownToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf(MOCK_ERROR, err)
+	}
+	defer db.Close()
+
+	gdb, err := gorm.Open("mysql", db)
+	if err != nil {
+		t.Fatalf(OPEN_ERROR, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_REFRESH_TOKEN_QUERY)).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	w, c := createTestContext(RefreshTokenRequest{RefreshToken: "unknown"}, REFRESH, t)
+	RefreshToken(gdb)(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid or expired refresh token")
+}
+
+// Reusing a rotated refresh token revokes the whole family and records the
+// revocation for the owner of the token
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf(MOCK_ERROR, err)
+	}
+	defer db.Close()
+
+	gdb, err := gorm.Open("mysql", db)
+	if err != nil {
+		t.Fatalf(OPEN_ERROR, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_REFRESH_TOKEN_QUERY)).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "family_id", "expires_at", "revoked_at"}).
+			AddRow(1, 1, "family-1", time.Now().Add(time.Hour), time.Now().Add(-time.Minute)))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `refresh_tokens` SET `revoked_at` = ? WHERE (family_id = ? AND revoked_at IS NULL)")).
+		WithArgs(sqlmock.AnyArg(), "family-1").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `sessions` SET `revoked_at` = ? WHERE (session_id = ? AND revoked_at IS NULL)")).
+		WithArgs(sqlmock.AnyArg(), "family-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE (id = ?) ORDER BY `users`.`id` ASC LIMIT 1")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_guid", "email"}).AddRow(1, TEST_USER_ID, EMAIL))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(INSERT_AUTH_EVENT_QUERY)).
+		WithArgs("tokens_revoked", TEST_USER_ID, TEST_USER_ID, EMAIL, sqlmock.AnyArg(), sqlmock.AnyArg(), "refresh token reuse", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	w, c := createTestContext(RefreshTokenRequest{RefreshToken: "reused"}, REFRESH, t)
+	RefreshToken(gdb)(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Refresh token has already been used")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,341 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	TWO_FACTOR_REQUIRED      = "Enter the code from your authenticator app or a recovery code"
+	RECOVERY_CODES_GENERATED = "Store these recovery codes somewhere safe, they will not be shown again"
+)
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorReauthRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int64  `json:"expires_in"` // seconds
+	Message           string `json:"message"`
+}
+
+type TwoFactorEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+	Message         string `json:"message"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+	Message       string   `json:"message"`
+}
+
+// respondWithTwoFactorChallenge ends the password step of a login for a user
+// with two-factor authentication
+func respondWithTwoFactorChallenge(ctx *gin.Context, db *gorm.DB, user *model.User) {
+	challengeToken, err := repository.CreateTwoFactorChallenge(db, user)
+	if err != nil {
+		log.Println(err.Error())
+		handleTokenError(ctx, http.StatusInternalServerError, "Unable to log in")
+		return
+	}
+
+	response := TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    challengeToken,
+		ExpiresIn:         int64(repository.TwoFactorChallengeTTL().Seconds()),
+		Message:           TWO_FACTOR_REQUIRED,
+	}
+	util.LogAndHandleResponse(ctx, http.StatusAccepted, response)
+}
+
+// handleTwoFactorError maps the errors of the two-factor repository functions to responses
+func handleTwoFactorError(ctx *gin.Context, err error, message string) {
+	switch err.Error() {
+	case repository.INVALID_TWO_FACTOR_CODE_ERROR:
+		handleTokenError(ctx, http.StatusUnauthorized, err.Error())
+	case repository.TWO_FACTOR_ALREADY_ENABLED_ERROR, repository.TWO_FACTOR_NOT_ENROLLED_ERROR:
+		handleTokenError(ctx, http.StatusConflict, err.Error())
+	default:
+		log.Println(err.Error())
+		handleTokenError(ctx, http.StatusInternalServerError, message)
+	}
+}
+
+// LoginTwoFactor completes a login with a second factor
+// @Summary Complete a two-factor login
+// @Description Exchanges the challenge token from the password step and a TOTP or recovery code for a token pair
+// @Tags Authentication
+// @Produce		json
+// @Param login body TwoFactorLoginRequest true "Two-Factor Login Request"
+// @Success 200 {object} util.JwtData{token=string, issuer=string, issued=string, expires=string, user_id=string, refresh_token=string, refresh_expires=string} "Successful authentication"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid input"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid challenge or code"
+// @Failure 429 {object} util.ErrorResponse{error=bool, error_message=string} "Too many failed login attempts"
+// @Router /api/v1/user/login/2fa [post]
+func LoginTwoFactor(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var loginRequest TwoFactorLoginRequest
+		if err := ctx.ShouldBindJSON(&loginRequest); err != nil {
+			validationError := util.ExtractValidationErrorMessage(err, loginRequest)
+			handleTokenError(ctx, http.StatusBadRequest, validationError[0])
+			return
+		}
+
+		challenge, user, err := repository.FindTwoFactorChallenge(db, loginRequest.ChallengeToken)
+		if err != nil {
+			if err.Error() != repository.INVALID_ONE_TIME_TOKEN_ERROR {
+				log.Println(err.Error())
+			}
+			handleTokenError(ctx, http.StatusUnauthorized, repository.INVALID_ONE_TIME_TOKEN_ERROR)
+			return
+		}
+
+		// wrong codes count against the same limits as wrong passwords
+		clientIP := ctx.ClientIP()
+		if wait, err := repository.CheckLoginAllowed(db, user.Email, clientIP); err != nil {
+			if err.Error() == repository.TOO_MANY_LOGIN_ATTEMPTS_ERROR {
+				recordAuthEvent(ctx, db, model.LoginLockedOutEvent, user, "two_factor")
+			}
+			handleLoginThrottled(ctx, wait, err)
+			return
+		}
+
+		// the challenge is used up before the code is checked, so that parallel
+		// requests cannot try several codes against the same challenge
+		if _, err := repository.RedeemOneTimeToken(db, loginRequest.ChallengeToken, challenge.Purpose); err != nil {
+			handleTokenError(ctx, http.StatusUnauthorized, repository.INVALID_ONE_TIME_TOKEN_ERROR)
+			return
+		}
+
+		if err := repository.VerifyTwoFactorCode(db, user, loginRequest.Code); err != nil {
+			if err.Error() == repository.INVALID_TWO_FACTOR_CODE_ERROR {
+				if err := repository.RecordLoginFailure(db, user.Email, clientIP); err != nil {
+					log.Println("unable to record failed login:", err)
+				}
+				recordAuthEvent(ctx, db, model.LoginFailedEvent, user, "two_factor")
+			}
+			handleTwoFactorError(ctx, err, "Unable to log in")
+			return
+		}
+
+		response, err := repository.IssueTokens(db, user, true, requestClient(ctx))
+		if err != nil {
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to log in")
+			return
+		}
+
+		if err := repository.ClearLoginFailures(db, user.Email); err != nil {
+			log.Println("unable to clear failed logins:", err)
+		}
+		recordAuthEvent(ctx, db, model.LoginSucceededEvent, user, "two_factor")
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, response)
+	}
+}
+
+// EnrollTwoFactor starts the TOTP enrollment of the authenticated user
+// @Summary Start two-factor enrollment
+// @Description Generates a TOTP secret and its otpauth:// provisioning URI to show as a QR code. Two-factor authentication is enabled once a code is confirmed
+// @Tags User
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {object} handler.TwoFactorEnrollmentResponse{secret=string, provisioning_uri=string, message=string} "Enrollment started"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Unauthorized access"
+// @Failure 409 {object} util.ErrorResponse{error=bool, error_message=string} "Two-factor authentication is already enabled"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Server error"
+// @Router /api/v1/user/me/2fa [post]
+func EnrollTwoFactor(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, err := authenticatedUser(ctx, db)
+		if err != nil {
+			handleTokenError(ctx, http.StatusUnauthorized, "Unauthorized access")
+			return
+		}
+
+		secret, provisioningURI, err := repository.StartTwoFactorEnrollment(db, user)
+		if err != nil {
+			handleTwoFactorError(ctx, err, "Unable to start two-factor enrollment")
+			return
+		}
+
+		response := TwoFactorEnrollmentResponse{
+			Secret:          secret,
+			ProvisioningURI: provisioningURI,
+			Message:         "Scan the QR code with your authenticator app and confirm with a code",
+		}
+		util.LogAndHandleResponse(ctx, http.StatusOK, response)
+	}
+}
+
+// ConfirmTwoFactor enables two-factor authentication with a code from the authenticator
+// @Summary Confirm two-factor enrollment
+// @Description Enables two-factor authentication once a code from the authenticator app is valid and returns single-use recovery codes
+// @Tags User
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param request body TwoFactorCodeRequest true "Two-Factor Code Request"
+// @Success 200 {object} handler.RecoveryCodesResponse{recovery_codes=[]string, message=string} "Two-factor authentication enabled"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid input"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Unauthorized access or invalid code"
+// @Failure 409 {object} util.ErrorResponse{error=bool, error_message=string} "Enrollment not started or already enabled"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Server error"
+// @Router /api/v1/user/me/2fa/confirm [post]
+func ConfirmTwoFactor(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var codeRequest TwoFactorCodeRequest
+		if err := ctx.ShouldBindJSON(&codeRequest); err != nil {
+			validationError := util.ExtractValidationErrorMessage(err, codeRequest)
+			handleTokenError(ctx, http.StatusBadRequest, validationError[0])
+			return
+		}
+
+		user, err := authenticatedUser(ctx, db)
+		if err != nil {
+			handleTokenError(ctx, http.StatusUnauthorized, "Unauthorized access")
+			return
+		}
+
+		recoveryCodes, err := repository.ConfirmTwoFactorEnrollment(db, user, codeRequest.Code)
+		if err != nil {
+			handleTwoFactorError(ctx, err, "Unable to enable two-factor authentication")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: recoveryCodes, Message: RECOVERY_CODES_GENERATED})
+	}
+}
+
+// reauthenticateTwoFactor checks the password and a second factor of the
+// authenticated user before a sensitive two-factor change. Wrong answers
+// count against the same limits as failed logins
+func reauthenticateTwoFactor(ctx *gin.Context, db *gorm.DB, details string) (*model.User, bool) {
+	var reauthRequest TwoFactorReauthRequest
+	if err := ctx.ShouldBindJSON(&reauthRequest); err != nil {
+		validationError := util.ExtractValidationErrorMessage(err, reauthRequest)
+		handleTokenError(ctx, http.StatusBadRequest, validationError[0])
+		return nil, false
+	}
+
+	user, err := authenticatedUser(ctx, db)
+	if err != nil {
+		handleTokenError(ctx, http.StatusUnauthorized, "Unauthorized access")
+		return nil, false
+	}
+
+	if !checkReauthenticationAllowed(ctx, db, user, details) {
+		return nil, false
+	}
+
+	if !repository.PasswordMatches(user, reauthRequest.Password) {
+		recordReauthenticationFailure(ctx, db, user, details)
+		handleTokenError(ctx, http.StatusUnauthorized, "Password is incorrect")
+		return nil, false
+	}
+
+	if err := repository.VerifyTwoFactorCode(db, user, reauthRequest.Code); err != nil {
+		if err.Error() == repository.INVALID_TWO_FACTOR_CODE_ERROR {
+			recordReauthenticationFailure(ctx, db, user, details)
+		}
+		handleTwoFactorError(ctx, err, "Unable to verify two-factor authentication code")
+		return nil, false
+	}
+
+	return user, true
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the authenticated user
+// @Summary Regenerate recovery codes
+// @Description Replaces every recovery code after checking the password and a current code
+// @Tags User
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param request body TwoFactorReauthRequest true "Two-Factor Reauthentication Request"
+// @Success 200 {object} handler.RecoveryCodesResponse{recovery_codes=[]string, message=string} "Recovery codes regenerated"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid input"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Wrong password or code"
+// @Failure 409 {object} util.ErrorResponse{error=bool, error_message=string} "Two-factor authentication is not enabled"
+// @Failure 429 {object} util.ErrorResponse{error=bool, error_message=string} "Too many failed attempts"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Server error"
+// @Router /api/v1/user/me/2fa/recovery-codes [post]
+func RegenerateRecoveryCodes(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := reauthenticateTwoFactor(ctx, db, "regenerate_recovery_codes")
+		if !ok {
+			return
+		}
+
+		recoveryCodes, err := repository.RegenerateRecoveryCodes(db, user)
+		if err != nil {
+			handleTwoFactorError(ctx, err, "Unable to regenerate recovery codes")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: recoveryCodes, Message: RECOVERY_CODES_GENERATED})
+	}
+}
+
+// DisableTwoFactor turns off two-factor authentication for the authenticated user
+// @Summary Disable two-factor authentication
+// @Description Disables two-factor authentication after checking the password and a current TOTP or recovery code
+// @Tags User
+// @SecurityDefinitions.apiKey Bearer
+// @in header
+// @name Authorization
+// @Produce		json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer Token"
+// @Param request body TwoFactorReauthRequest true "Two-Factor Reauthentication Request"
+// @Success 200 {object} util.ErrorResponse{error=bool, error_message=string} "Two-factor authentication disabled"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid input"
+// @Failure 401 {object} util.ErrorResponse{error=bool, error_message=string} "Wrong password or code"
+// @Failure 409 {object} util.ErrorResponse{error=bool, error_message=string} "Two-factor authentication is not enabled"
+// @Failure 429 {object} util.ErrorResponse{error=bool, error_message=string} "Too many failed attempts"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Server error"
+// @Router /api/v1/user/me/2fa [delete]
+func DisableTwoFactor(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := reauthenticateTwoFactor(ctx, db, "disable_two_factor")
+		if !ok {
+			return
+		}
+
+		if err := repository.DisableTwoFactor(db, user); err != nil {
+			handleTwoFactorError(ctx, err, "Unable to disable two-factor authentication")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, util.ErrorResponse{Error: false, ErrorMessage: "Two-factor authentication disabled"})
+	}
+}
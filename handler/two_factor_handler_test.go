@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	LOGIN_TWO_FACTOR   = "/login/2fa"
+	TOTP_SECRET        = "JBSWY3DPEHPK3PXP"
+	CHALLENGE_TOKEN    = "challenge-token"
+	PASSWORD_HASH      = "$2a$10$BvynkDL3zqY9wn8J6QFUD.XiSETqPtPPvs5VjH//EAJflvXNP3wRe"
+	SELECT_USER_BY_ID  = "SELECT * FROM `users` WHERE (id = ? AND is_deleted = false) ORDER BY `users`.`id` ASC LIMIT 1"
+	UPDATE_LAST_STEP   = "UPDATE `two_factor_auths` SET `last_used_step` = ? WHERE (id = ? AND last_used_step < ?)"
+	UPDATE_RECOVERY    = "UPDATE `recovery_codes` SET `used_at` = ? WHERE (user_id = ? AND code_hash = ? AND used_at IS NULL)"
+	TWO_FACTOR_ENABLED = "two_factor_required"
+)
+
+// A user with two-factor authentication gets a challenge instead of tokens
+func TestLoginWithTwoFactorReturnsChallenge(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ATTEMPTS)).
+		WithArgs("account:"+EMAIL, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_QUERY)).
+		WithArgs(EMAIL).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password"}).AddRow(1, EMAIL, PASSWORD_HASH))
+	expectPasswordRehash(mock)
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_TWO_FACTOR_QUERY)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "secret", "enabled_at"}).AddRow(1, 1, TOTP_SECRET, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `one_time_tokens` SET `used_at` = ?")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `one_time_tokens`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	w, c := createTestContext(createLoginRequest(), LOGIN, t)
+	Login(gdb)(c)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Contains(t, w.Body.String(), TWO_FACTOR_ENABLED)
+	assert.Contains(t, w.Body.String(), "challenge_token")
+	assert.NotContains(t, w.Body.String(), "refresh_token")
+	assert.NoError(t, mock.ExpectationsWereMet(), "Failed logins must not be cleared before the second step")
+}
+
+// expectTwoFactorChallenge makes the mock return a valid challenge for user 1
+// and redeem it
+func expectTwoFactorChallenge(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ONE_TIME_TOKEN_QUERY)).
+		WithArgs(util.HashToken(CHALLENGE_TOKEN), "two_factor_challenge").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "purpose", "expires_at"}).
+			AddRow(1, 1, "two_factor_challenge", time.Now().Add(time.Minute)))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_USER_BY_ID)).
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "user_guid"}).AddRow(1, EMAIL, TEST_USER_ID))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ATTEMPTS)).
+		WithArgs("account:"+EMAIL, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ONE_TIME_TOKEN_QUERY)).
+		WithArgs(util.HashToken(CHALLENGE_TOKEN), "two_factor_challenge").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "purpose", "expires_at"}).
+			AddRow(1, 1, "two_factor_challenge", time.Now().Add(time.Minute)))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `one_time_tokens` SET `used_at` = ?")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectTwoFactorSecret(mock)
+}
+
+// expectTwoFactorSecret makes the mock return the enabled TOTP secret of user 1
+func expectTwoFactorSecret(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_TWO_FACTOR_QUERY)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "secret", "enabled_at", "last_used_step"}).
+			AddRow(1, 1, TOTP_SECRET, time.Now(), 0))
+}
+
+// A valid TOTP code completes the login with an mfa token
+func TestLoginTwoFactorWithValidCode(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	expectTwoFactorChallenge(mock)
+
+	code, _ := util.TOTPCode(TOTP_SECRET, util.TOTPStep(time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(UPDATE_LAST_STEP)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `sessions`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `refresh_tokens`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `login_attempts` WHERE (attempt_key = ?)")).
+		WithArgs("account:" + EMAIL).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectAuthEvent(mock, model.LoginSucceededEvent)
+
+	w, c := createTestContext(TwoFactorLoginRequest{ChallengeToken: CHALLENGE_TOKEN, Code: code}, LOGIN_TWO_FACTOR, t)
+	LoginTwoFactor(gdb)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "refresh_token")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// A wrong code is counted as a failed login
+func TestLoginTwoFactorWithWrongCode(t *testing.T) {
+	gdb, mock := newAdminUserTestDB(t)
+	expectTwoFactorChallenge(mock)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(UPDATE_RECOVERY)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	expectLoginFailure(mock)
+
+	w, c := createTestContext(TwoFactorLoginRequest{ChallengeToken: CHALLENGE_TOKEN, Code: "not-a-code"}, LOGIN_TWO_FACTOR, t)
+	LoginTwoFactor(gdb)(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// A wrong password given to disable two-factor authentication is counted as a failed login
+func TestDisableTwoFactorWrongPasswordRecordsFailure(t *testing.T) {
+	gdb, mock := newProfileTestDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ATTEMPTS)).
+		WithArgs("account:"+EMAIL, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	expectLoginFailure(mock)
+
+	w, c := createTestContext(TwoFactorReauthRequest{Password: "wrong-password", Code: "123456"}, PROFILE+"/2fa", t)
+	c.Set("user_id", TEST_USER_ID)
+	DisableTwoFactor(gdb)(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// A wrong code given to regenerate the recovery codes is counted as a failed login
+func TestRegenerateRecoveryCodesWrongCodeRecordsFailure(t *testing.T) {
+	gdb, mock := newProfileTestDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ATTEMPTS)).
+		WithArgs("account:"+EMAIL, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	expectTwoFactorSecret(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(UPDATE_RECOVERY)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	expectLoginFailure(mock)
+
+	w, c := createTestContext(TwoFactorReauthRequest{Password: CURRENT_PASSWORD, Code: "not-a-code"}, PROFILE+"/2fa/recovery-codes", t)
+	c.Set("user_id", TEST_USER_ID)
+	RegenerateRecoveryCodes(gdb)(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// A locked out account cannot disable two-factor authentication, whatever it sends
+func TestDisableTwoFactorLockedOut(t *testing.T) {
+	gdb, mock := newProfileTestDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ATTEMPTS)).
+		WithArgs("account:"+EMAIL, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "attempt_key", "failures", "window_started_at", "last_failed_at", "locked_until"}).
+			AddRow(1, "account:"+EMAIL, 5, time.Now(), time.Now(), time.Now().Add(10*time.Minute)))
+	expectAuthEvent(mock, model.LoginLockedOutEvent)
+
+	w, c := createTestContext(TwoFactorReauthRequest{Password: CURRENT_PASSWORD, Code: "123456"}, PROFILE+"/2fa", t)
+	c.Set("user_id", TEST_USER_ID)
+	DisableTwoFactor(gdb)(c)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.NoError(t, mock.ExpectationsWereMet(), "The password should not be checked while locked out")
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const VERIFICATION_EMAIL_REQUESTED = "If an unverified account exists for this email, a verification link has been sent"
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// appBaseURL returns the public address of the API used to build links in emails
+func appBaseURL() string {
+	if baseURL := os.Getenv("APP_BASE_URL"); baseURL != "" {
+		return baseURL
+	}
+	return fmt.Sprintf("http://localhost:%s", os.Getenv("PORT"))
+}
+
+// newVerificationMail builds the email that carries an email verification link
+func newVerificationMail(user *model.User, rawToken string) util.MailMessage {
+	link := fmt.Sprintf("%s/api/v1/user/verify?token=%s", appBaseURL(), url.QueryEscape(rawToken))
+	body := fmt.Sprintf("Hello %s,\n\nPlease confirm your email address by opening this link: %s\n", user.FirstName, link)
+	body += fmt.Sprintf("\nThe link expires in %s.\n", repository.EmailVerificationTTL())
+
+	return util.MailMessage{To: user.Email, Subject: "Confirm your Instashop email address", Body: body}
+}
+
+// sendVerificationMail issues a new verification token and emails it to the user
+func sendVerificationMail(db *gorm.DB, mailer util.Mailer, user *model.User) error {
+	rawToken, err := repository.CreateEmailVerificationToken(db, user)
+	if err != nil {
+		return err
+	}
+
+	sendMail(mailer, newVerificationMail(user, rawToken))
+	return nil
+}
+
+// VerifyEmail confirms a user's email address
+// @Summary Verify an email address
+// @Description Confirms the email address of a user with the token sent at signup
+// @Tags Authentication
+// @Produce		json
+// @Param token query string true "Verification Token"
+// @Success 200 {object} handler.UserResponse{message=string, user=model.User} "Email successfully verified"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid or expired token"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Server error"
+// @Router /api/v1/user/verify [get]
+func VerifyEmail(db *gorm.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		rawToken := ctx.Query("token")
+		if rawToken == "" {
+			handleTokenError(ctx, http.StatusBadRequest, "token is required")
+			return
+		}
+
+		user, err := repository.VerifyEmail(db, rawToken)
+		if err != nil {
+			if err.Error() == repository.INVALID_ONE_TIME_TOKEN_ERROR {
+				handleTokenError(ctx, http.StatusBadRequest, err.Error())
+				return
+			}
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to verify email")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, UserResponse{User: user, Message: "Email successfully verified"})
+	}
+}
+
+// ResendVerificationEmail sends a new verification link
+// @Summary Resend the verification email
+// @Description Sends a new email verification link. The response is the same whether or not the email is registered
+// @Tags Authentication
+// @Produce		json
+// @Param resend body ResendVerificationRequest true "Resend Verification Request"
+// @Success 200 {object} util.ErrorResponse{error=bool, error_message=string} "Verification email requested"
+// @Failure 400 {object} util.ErrorResponse{error=bool, error_message=string} "Invalid input"
+// @Failure 500 {object} util.ErrorResponse{error=bool, error_message=string} "Server error"
+// @Router /api/v1/user/verify/resend [post]
+func ResendVerificationEmail(db *gorm.DB, mailer util.Mailer) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var resendRequest ResendVerificationRequest
+		if err := ctx.ShouldBindJSON(&resendRequest); err != nil {
+			validationError := util.ExtractValidationErrorMessage(err, resendRequest)
+			handleTokenError(ctx, http.StatusBadRequest, validationError[0])
+			return
+		}
+
+		util.LogIncomingRequest(resendRequest)
+
+		user, err := repository.FindUnverifiedUser(db, resendRequest.Email)
+		if err == nil && user != nil {
+			err = sendVerificationMail(db, mailer, user)
+		}
+
+		if err != nil {
+			log.Println(err.Error())
+			handleTokenError(ctx, http.StatusInternalServerError, "Unable to send verification email")
+			return
+		}
+
+		util.LogAndHandleResponse(ctx, http.StatusOK, util.ErrorResponse{Error: false, ErrorMessage: VERIFICATION_EMAIL_REQUESTED})
+	}
+}
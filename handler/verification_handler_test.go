@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+const VERIFY = "/verify"
+
+// Missing verification token returns 400
+func TestVerifyEmailMissingToken(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf(MOCK_ERROR, err)
+	}
+	defer db.Close()
+
+	gdb, err := gorm.Open("mysql", db)
+	if err != nil {
+		t.Fatalf(OPEN_ERROR, err)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", VERIFY, nil)
+	VerifyEmail(gdb)(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "token is required")
+}
+
+// Unknown verification token returns 400
+func TestVerifyEmailUnknownToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf(MOCK_ERROR, err)
+	}
+	defer db.Close()
+
+	gdb, err := gorm.Open("mysql", db)
+	if err != nil {
+		t.Fatalf(OPEN_ERROR, err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ONE_TIME_TOKEN_QUERY)).
+		WithArgs(sqlmock.AnyArg(), "email_verification").
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectRollback()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", VERIFY+"?token=unknown", nil)
+	VerifyEmail(gdb)(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), INVALID_OR_EXPIRED_TOKEN_TEXT)
+}
+
+// An already verified account does not get another email
+func TestResendVerificationEmailAlreadyVerified(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf(MOCK_ERROR, err)
+	}
+	defer db.Close()
+
+	gdb, err := gorm.Open("mysql", db)
+	if err != nil {
+		t.Fatalf(OPEN_ERROR, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_QUERY)).
+		WithArgs(EMAIL).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "verified_at"}).AddRow(1, EMAIL, time.Now()))
+
+	mailer := &recordingMailer{}
+	w, c := createTestContext(ResendVerificationRequest{Email: EMAIL}, VERIFY+"/resend", t)
+	ResendVerificationEmail(gdb, mailer)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), VERIFICATION_EMAIL_REQUESTED)
+	assert.Empty(t, mailer.messages)
+}
@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/hackdaemon2/instashop/config"
 	_ "github.com/hackdaemon2/instashop/docs"
 	"github.com/hackdaemon2/instashop/handler"
 	"github.com/hackdaemon2/instashop/middleware"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
 	"github.com/hackdaemon2/instashop/util"
 	"github.com/jinzhu/gorm"
 	swaggerFiles "github.com/swaggo/files"
@@ -25,7 +28,7 @@ func noRouteOrMethod(status int, message string) gin.HandlerFunc {
 }
 
 // SetupRouter configures the routes that this application uses
-func setupRouter(db *gorm.DB) *gin.Engine {
+func setupRouter(db *gorm.DB, mailer util.Mailer, oidcProvider *util.OIDCProvider, storage util.Storage) *gin.Engine {
 	router := gin.Default()
 
 	router.NoRoute(noRouteOrMethod(http.StatusNotFound, "route not found"))
@@ -33,25 +36,109 @@ func setupRouter(db *gorm.DB) *gin.Engine {
 
 	// Serve the Swagger UI and Doc JSON
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	router.GET("/.well-known/jwks.json", handler.JWKS())
+
+	// uploaded files on the local disk are served by the application itself
+	// unless they are published under another host
+	if local, ok := storage.(*util.LocalStorage); ok && strings.HasPrefix(local.BaseURL, "/") {
+		router.Static(local.BaseURL, local.Directory)
+	}
 
 	apiV1 := router.Group("/api/v1")
-	apiV1.POST("/user/signup", handler.Signup(db))
+	apiV1.POST("/user/signup", handler.Signup(db, mailer))
 	apiV1.POST("/user/login", handler.Login(db))
+	apiV1.POST("/user/login/2fa", handler.LoginTwoFactor(db))
+	apiV1.GET("/user/oidc/login", handler.OIDCLogin(db, oidcProvider))
+	apiV1.GET("/user/oidc/callback", handler.OIDCCallback(db, oidcProvider))
+	apiV1.POST("/user/token/refresh", handler.RefreshToken(db))
+	apiV1.POST("/user/password/forgot", handler.ForgotPassword(db, mailer))
+	apiV1.POST("/user/password/reset", handler.ResetPassword(db))
+	apiV1.GET("/user/verify", handler.VerifyEmail(db))
+	apiV1.POST("/user/verify/resend", handler.ResendVerificationEmail(db, mailer))
+	apiV1.GET("/user/email/confirm", handler.ConfirmEmailChange(db))
+	apiV1.GET("/products", handler.ListProducts(db, storage))
+	apiV1.GET("/products/search", handler.SearchProducts(db, storage))
+	apiV1.GET("/categories", handler.ListCategories(db))
+	apiV1.GET("/categories/:slug/products", handler.ListCategoryProducts(db, storage))
 
 	authenticated := apiV1.Group("/")
-	authenticated.Use(middleware.Authenticate())
+	authenticated.Use(middleware.Authenticate(db))
+	authenticated.POST("/user/logout", handler.Logout(db))
+	authenticated.POST("/user/logout/all", handler.LogoutEverywhere(db))
+	authenticated.GET("/user/sessions", handler.ListSessions(db))
+	authenticated.DELETE("/user/sessions/:session_id", handler.RevokeSession(db))
+	authenticated.GET("/user/me", handler.GetProfile(db))
+	authenticated.PUT("/user/me", handler.UpdateProfile(db))
+	authenticated.DELETE("/user/me", handler.EraseAccount(db))
+	authenticated.GET("/user/me/export", handler.ExportPersonalData(db))
+	authenticated.POST("/user/me/password", handler.ChangePassword(db))
+	authenticated.POST("/user/me/email", handler.RequestEmailChange(db, mailer))
+	authenticated.POST("/user/me/2fa", handler.EnrollTwoFactor(db))
+	authenticated.POST("/user/me/2fa/confirm", handler.ConfirmTwoFactor(db))
+	authenticated.POST("/user/me/2fa/recovery-codes", handler.RegenerateRecoveryCodes(db))
+	authenticated.DELETE("/user/me/2fa", handler.DisableTwoFactor(db))
+	authenticated.GET("/user/addresses", handler.ListAddresses(db))
+	authenticated.POST("/user/addresses", handler.CreateAddress(db))
+	authenticated.GET("/user/addresses/:address_id", handler.GetAddress(db))
+	authenticated.PUT("/user/addresses/:address_id", handler.UpdateAddress(db))
+	authenticated.DELETE("/user/addresses/:address_id", handler.DeleteAddress(db))
 	authenticated.POST("/user/order", handler.PlaceOrder(db))
 	authenticated.GET("/user/order", handler.GetUserOrders(db))
 	authenticated.PUT("/user/order/:order_reference/cancel", handler.CancelUserOrder(db))
-	authenticated.GET("/user/product/:product_code", handler.GetProduct(db))
+	authenticated.GET("/user/product/:product_code", handler.GetProduct(db, storage))
+
+	// admin routes are authorized per permission, see model.DefaultPermissions.
+	// Order and product routes also accept API keys for machine clients
+	operations := apiV1.Group("/admin")
+	operations.Use(middleware.AuthenticateAPIKey(db))
+
+	writeProducts := middleware.RequirePermission(db, model.ProductsWritePermission)
+
+	operations.GET("/order", middleware.RequirePermission(db, model.OrdersReadPermission), handler.GetOrders(db))
+	operations.PUT("/order/:order_reference/status", middleware.RequirePermission(db, model.OrdersUpdateStatusPermission), handler.UpdateOrderStatus(db))
+	operations.POST("/product", writeProducts, handler.CreateProduct(db))
+	operations.PUT("/product/:product_code", writeProducts, handler.UpdateProduct(db))
+	operations.DELETE("/product/:product_code", writeProducts, handler.DeleteProduct(db))
+	operations.POST("/products/import", writeProducts, handler.ImportProducts(db))
+	operations.GET("/products/export", writeProducts, handler.ExportProducts(db))
+	operations.PUT("/product/:product_code/categories", writeProducts, handler.SetProductCategories(db))
+	operations.PUT("/product/:product_code/options", writeProducts, handler.SetProductOptions(db))
+	operations.POST("/product/:product_code/variants", writeProducts, handler.CreateVariant(db))
+	operations.PUT("/product/:product_code/variants/:sku", writeProducts, handler.UpdateVariant(db))
+	operations.DELETE("/product/:product_code/variants/:sku", writeProducts, handler.DeleteVariant(db))
+	operations.POST("/product/:product_code/images", writeProducts, handler.UploadProductImage(db, storage))
+	operations.PUT("/product/:product_code/images", writeProducts, handler.ReorderProductImages(db, storage))
+	operations.PUT("/product/:product_code/images/:image_id/primary", writeProducts, handler.SetPrimaryProductImage(db, storage))
+	operations.DELETE("/product/:product_code/images/:image_id", writeProducts, handler.DeleteProductImage(db, storage))
+	operations.GET("/product/:product_code/prices", writeProducts, handler.ListPriceChanges(db))
+	operations.GET("/product/:product_code/price-schedules", writeProducts, handler.ListPriceSchedules(db))
+	operations.POST("/product/:product_code/price-schedules", writeProducts, handler.CreatePriceSchedule(db))
+	operations.DELETE("/product/:product_code/price-schedules/:schedule_id", writeProducts, handler.CancelPriceSchedule(db))
+	operations.POST("/categories", writeProducts, handler.CreateCategory(db))
+	operations.PUT("/categories/:slug", writeProducts, handler.UpdateCategory(db))
+	operations.DELETE("/categories/:slug", writeProducts, handler.DeleteCategory(db))
 
 	admin := apiV1.Group("/admin")
-	admin.Use(middleware.Authenticate())
-	admin.Use(middleware.IsAdmin())
-	admin.PUT("/order/:order_reference/status", handler.UpdateOrderStatus(db))
-	admin.POST("/product", handler.CreateProduct(db))
-	admin.PUT("/product/:product_code", handler.UpdateProduct(db))
-	admin.DELETE("/product/:product_code", handler.DeleteProduct(db))
+	admin.Use(middleware.Authenticate(db))
+
+	manageUsers := middleware.RequirePermission(db, model.UsersManagePermission)
+	manageAPIKeys := middleware.RequirePermission(db, model.APIKeysManagePermission)
+
+	admin.GET("/users", manageUsers, handler.ListUsers(db))
+	admin.GET("/users/:user_id", manageUsers, handler.GetUser(db))
+	admin.PUT("/users/:user_id/role", manageUsers, handler.UpdateUserRole(db))
+	admin.POST("/users/:user_id/deactivate", manageUsers, handler.DeactivateUser(db))
+	admin.POST("/users/:user_id/reactivate", manageUsers, handler.ReactivateUser(db))
+	admin.POST("/users/:user_id/password/reset", manageUsers, handler.ForcePasswordReset(db, mailer))
+	admin.DELETE("/users/:user_id/lock", manageUsers, handler.UnlockUser(db))
+	admin.POST("/users/:user_id/erase", manageUsers, handler.EraseUser(db))
+	admin.GET("/roles", manageUsers, handler.ListRoles(db))
+	admin.PUT("/roles/:role", manageUsers, handler.SaveRole(db))
+	admin.GET("/permissions", manageUsers, handler.ListPermissions(db))
+	admin.POST("/api-keys", manageAPIKeys, handler.CreateAPIKey(db))
+	admin.GET("/api-keys", manageAPIKeys, handler.ListAPIKeys(db))
+	admin.DELETE("/api-keys/:api_key_id", manageAPIKeys, handler.RevokeAPIKey(db))
+	admin.GET("/auth-events", middleware.RequirePermission(db, model.AuditReadPermission), handler.ListAuthEvents(db))
 
 	return router
 }
@@ -65,7 +152,14 @@ func main() {
 	config.LoadEnv()
 	config.ConnectDatabase()
 
-	route := setupRouter(config.DB)
+	if err := repository.RebuildProductIndex(config.DB); err != nil {
+		log.Println("Unable to build the product search index:", err)
+	}
+	repository.StartProductIndexRefresh(config.DB)
+	repository.StartPriceScheduler(config.DB)
+	util.Keys() // load the JWT signing keys up front so that a bad key setup fails at startup
+
+	route := setupRouter(config.DB, util.NewMailer(), util.NewOIDCProviderFromEnv(), util.NewStorage())
 
 	if err := route.Run(fmt.Sprintf(":%s", config.GetEnv("PORT"))); err != nil {
 		log.Fatal("Unable to start server:", err)
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"log"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/jinzhu/gorm"
+)
+
+const API_KEY_HEADER = "X-API-Key"
+
+// AuthenticateAPIKey authenticates machine clients that send an API key in the
+// X-API-Key header. Requests without the header fall back to Authenticate, so
+// routes using this middleware accept both API keys and user tokens.
+// A key is granted the scopes that its owner's role still holds
+func AuthenticateAPIKey(db *gorm.DB) gin.HandlerFunc {
+	authenticate := Authenticate(db)
+
+	return func(ctx *gin.Context) {
+		rawKey := ctx.GetHeader(API_KEY_HEADER)
+		if rawKey == "" {
+			authenticate(ctx)
+			return
+		}
+
+		apiKey, err := repository.FindActiveAPIKey(db, rawKey)
+		if err != nil {
+			if err.Error() != repository.INVALID_API_KEY_ERROR {
+				log.Println("unable to look up API key:", err)
+			}
+			respondUnauthorized(ctx, repository.INVALID_API_KEY_ERROR)
+			return
+		}
+
+		rolePermissions, err := repository.RolePermissions(db, string(apiKey.Owner.Role))
+		if err != nil {
+			log.Println("unable to load permissions:", err)
+			respondAuthorizationFailed(ctx)
+			return
+		}
+
+		permissions := []string{}
+		for _, scope := range apiKey.ScopeList() {
+			if slices.Contains(rolePermissions, scope) {
+				permissions = append(permissions, scope)
+			}
+		}
+
+		if err := repository.TouchAPIKey(db, apiKey); err != nil {
+			log.Println("unable to record API key usage:", err)
+		}
+
+		setPrincipal(ctx, &model.Principal{
+			User:        &apiKey.Owner,
+			Role:        apiKey.Owner.Role,
+			Permissions: permissions,
+			APIKeyID:    apiKey.ID,
+		})
+		ctx.Next()
+	}
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	API_KEY              = "isk_abcdefgh.secret"
+	SELECT_API_KEY_QUERY = "SELECT * FROM `api_keys` WHERE (key_hash = ?) ORDER BY `api_keys`.`id` ASC LIMIT 1"
+	SELECT_OWNER_QUERY   = "SELECT * FROM `users` WHERE (`id` IN (?)) ORDER BY `users`.`id` ASC"
+)
+
+func newAPIKeyRouter(gdb *gorm.DB, permissions ...string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(AuthenticateAPIKey(gdb), RequirePermission(gdb, permissions...))
+	r.GET(ADMIN, func(ctx *gin.Context) {
+		userID, _ := ctx.Get("user_id")
+		ctx.JSON(http.StatusOK, gin.H{"user_id": userID})
+	})
+	return r
+}
+
+func expectAPIKey(mock sqlmock.Sqlmock, scopes string, revokedAt any) {
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_API_KEY_QUERY)).
+		WithArgs(util.HashToken(API_KEY)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "prefix", "scopes", "owner_id", "revoked_at"}).
+			AddRow(7, "isk_abcdefgh", scopes, 1, revokedAt))
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_OWNER_QUERY)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_guid", "role"}).AddRow(1, "owner-guid", "support"))
+}
+
+func TestAuthenticateAPIKeyWithScope(t *testing.T) {
+	gdb, mock := newMockDB(t)
+	expectAPIKey(mock, "orders:read products:write", nil)
+	expectRolePermissions(mock, "support", "orders:read", "orders:update_status")
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `api_keys` SET `last_used_at`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	r := newAPIKeyRouter(gdb, "orders:read")
+
+	req := httptest.NewRequest(http.MethodGet, ADMIN, nil)
+	req.Header.Set(API_KEY_HEADER, API_KEY)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id": "owner-guid"}`, w.Body.String())
+}
+
+// A scope the owner's role no longer holds is not granted
+func TestAuthenticateAPIKeyScopeNotHeldByOwner(t *testing.T) {
+	gdb, mock := newMockDB(t)
+	expectAPIKey(mock, "orders:read products:write", nil)
+	expectRolePermissions(mock, "support", "orders:read", "orders:update_status")
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `api_keys` SET `last_used_at`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	r := newAPIKeyRouter(gdb, "products:write")
+
+	req := httptest.NewRequest(http.MethodGet, ADMIN, nil)
+	req.Header.Set(API_KEY_HEADER, API_KEY)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAuthenticateAPIKeyRevoked(t *testing.T) {
+	gdb, mock := newMockDB(t)
+	expectAPIKey(mock, "orders:read", time.Now())
+	r := newAPIKeyRouter(gdb, "orders:read")
+
+	req := httptest.NewRequest(http.MethodGet, ADMIN, nil)
+	req.Header.Set(API_KEY_HEADER, API_KEY)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "revoked API key")
+}
+
+// Without an API key the bearer token is used
+func TestAuthenticateAPIKeyFallsBackToToken(t *testing.T) {
+	gdb, mock := newMockDB(t)
+	expectActiveUser(mock, "123", "admin")
+	expectRolePermissions(mock, "admin", "orders:read")
+	r := newAPIKeyRouter(gdb, "orders:read")
+
+	req := httptest.NewRequest(http.MethodGet, ADMIN, nil)
+	req.Header.Set("Authorization", "Bearer "+generateToken("admin", "123"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id": "123"}`, w.Body.String())
+}
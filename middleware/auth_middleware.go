@@ -1,20 +1,30 @@
 package middleware
 
 import (
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"github.com/hackdaemon2/instashop/config"
 	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
 	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
 )
 
 const (
 	AUTHORIZATION_HEADER_ERROR = "Authorization header missing"
 	INVALID_TOKEN_ERROR        = "Invalid token"
+	REVOKED_TOKEN_ERROR        = "Token has been revoked"
+	REVOKED_SESSION_ERROR      = "Session has been revoked"
 	FORBIDDEN_ACCESS_ERROR     = "You do not have the right to access this resource"
+	TWO_FACTOR_REQUIRED_ERROR  = "Two-factor authentication is required to access this resource"
+	INACTIVE_USER_ERROR        = "User no longer exists or has been deactivated"
+
+	PRINCIPAL_KEY = "principal"
 )
 
 func parseToken(ctx *gin.Context) (*jwt.Token, error) {
@@ -24,9 +34,7 @@ func parseToken(ctx *gin.Context) (*jwt.Token, error) {
 	}
 
 	tokenStr := authHeader[7:]
-	return jwt.Parse(tokenStr, func(token *jwt.Token) (any, error) {
-		return []byte(config.GetEnv("SECRET_KEY")), nil
-	})
+	return util.ParseJWT(tokenStr)
 }
 
 func respondUnauthorized(ctx *gin.Context, message string) {
@@ -34,48 +42,209 @@ func respondUnauthorized(ctx *gin.Context, message string) {
 	ctx.Abort()
 }
 
-func IsAdmin() gin.HandlerFunc {
+// claimTime converts a numeric date claim such as exp or iat into a time
+func claimTime(claims jwt.MapClaims, name string) time.Time {
+	if value, ok := claims[name].(float64); ok {
+		return time.Unix(int64(value), 0)
+	}
+	return time.Time{}
+}
+
+// issuedAt returns the issue time of a token in milliseconds, or in seconds
+// for tokens issued before the millisecond claim was added
+func issuedAt(claims jwt.MapClaims) time.Time {
+	if value, ok := claims[util.ISSUED_AT_MILLIS_CLAIM].(float64); ok {
+		return time.UnixMilli(int64(value))
+	}
+	return claimTime(claims, "iat")
+}
+
+// validateToken parses the bearer token and checks it against the revocation
+// store. When the token cannot be used the request is aborted and false is returned
+func validateToken(ctx *gin.Context, db *gorm.DB) (jwt.MapClaims, bool) {
+	token, err := parseToken(ctx)
+	if err == http.ErrNoLocation {
+		respondUnauthorized(ctx, AUTHORIZATION_HEADER_ERROR)
+		return nil, false
+	}
+
+	if err != nil || !token.Valid {
+		respondUnauthorized(ctx, INVALID_TOKEN_ERROR)
+		return nil, false
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		respondUnauthorized(ctx, INVALID_TOKEN_ERROR)
+		return nil, false
+	}
+
+	jti, _ := claims["jti"].(string)
+	userID, _ := claims["user_id"].(string)
+	revoked, err := repository.IsAccessTokenRevoked(db, jti, userID, issuedAt(claims))
+	if err != nil {
+		log.Println("unable to check token revocation:", err)
+		respondUnauthorized(ctx, INVALID_TOKEN_ERROR)
+		return nil, false
+	}
+
+	if revoked {
+		respondUnauthorized(ctx, REVOKED_TOKEN_ERROR)
+		return nil, false
+	}
+
+	// tokens issued before sessions were tracked have no sid claim
+	if sessionID, _ := claims["sid"].(string); sessionID != "" {
+		return claims, validateSession(ctx, db, sessionID)
+	}
+
+	return claims, true
+}
+
+// validateSession rejects tokens of revoked sessions and records the
+// activity of the others
+func validateSession(ctx *gin.Context, db *gorm.DB, sessionID string) bool {
+	revoked, err := repository.IsSessionRevoked(db, sessionID)
+	if err != nil {
+		log.Println("unable to check session revocation:", err)
+		respondUnauthorized(ctx, INVALID_TOKEN_ERROR)
+		return false
+	}
+
+	if revoked {
+		respondUnauthorized(ctx, REVOKED_SESSION_ERROR)
+		return false
+	}
+
+	if err := repository.TouchSession(db, sessionID, ctx.ClientIP()); err != nil {
+		log.Println("unable to record session activity:", err)
+	}
+	return true
+}
+
+// usedMultiFactor reports whether the amr claim of a token lists a second factor
+func usedMultiFactor(claims jwt.MapClaims) bool {
+	methods, _ := claims["amr"].([]any)
+	for _, method := range methods {
+		if method == util.MULTI_FACTOR_AUTH_METHOD {
+			return true
+		}
+	}
+	return false
+}
+
+// requiresTwoFactor reports whether the principal has to log in with a second
+// factor before using a permission. Only user tokens are affected, API keys
+// authenticate machine clients
+func requiresTwoFactor(principal *model.Principal) bool {
+	if !config.GetBoolEnv("REQUIRE_ADMIN_2FA", false) {
+		return false
+	}
+	return principal.APIKeyID == 0 && !principal.MultiFactor
+}
+
+// CurrentPrincipal returns the principal resolved by Authenticate or
+// AuthenticateAPIKey, false when the request was not authenticated
+func CurrentPrincipal(ctx *gin.Context) (*model.Principal, bool) {
+	value, _ := ctx.Get(PRINCIPAL_KEY)
+	principal, ok := value.(*model.Principal)
+	return principal, ok && principal != nil
+}
+
+// setPrincipal stores the principal of the request. The guid of the user is
+// also kept under user_id, which is what most handlers look for
+func setPrincipal(ctx *gin.Context, principal *model.Principal) {
+	ctx.Set(PRINCIPAL_KEY, principal)
+	ctx.Set("user_id", principal.User.UserID)
+}
+
+func respondAuthorizationFailed(ctx *gin.Context) {
+	ctx.JSON(http.StatusInternalServerError, util.ErrorResponse{Error: true, ErrorMessage: "Unable to authorize request"})
+	ctx.Abort()
+}
+
+// loadPrincipal resolves the user a token was issued to, with the permissions
+// of their current role. Tokens of deactivated or erased users are rejected.
+// When the principal cannot be loaded the request is aborted and false is returned
+func loadPrincipal(ctx *gin.Context, db *gorm.DB, claims jwt.MapClaims) (*model.Principal, bool) {
+	userID, _ := claims["user_id"].(string)
+	if userID == "" {
+		respondUnauthorized(ctx, INVALID_TOKEN_ERROR)
+		return nil, false
+	}
+
+	user, err := repository.FindActiveUser(db, userID)
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			respondUnauthorized(ctx, INACTIVE_USER_ERROR)
+			return nil, false
+		}
+		log.Println("unable to load user:", err)
+		respondAuthorizationFailed(ctx)
+		return nil, false
+	}
+
+	permissions, err := repository.RolePermissions(db, string(user.Role))
+	if err != nil {
+		log.Println("unable to load permissions:", err)
+		respondAuthorizationFailed(ctx)
+		return nil, false
+	}
+
+	principal := &model.Principal{
+		User:        user,
+		Role:        user.Role,
+		Permissions: permissions,
+		MultiFactor: usedMultiFactor(claims),
+	}
+	return principal, true
+}
+
+// RequirePermission only lets a request through when the authenticated
+// principal holds every one of the permissions. It must run after Authenticate.
+// With REQUIRE_ADMIN_2FA set, users must also have logged in with a second factor
+func RequirePermission(db *gorm.DB, permissions ...string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		token, err := parseToken(ctx)
-		if err == http.ErrNoLocation {
+		principal, ok := CurrentPrincipal(ctx)
+		if !ok {
 			respondUnauthorized(ctx, AUTHORIZATION_HEADER_ERROR)
 			return
 		}
-		if err != nil || !token.Valid {
-			respondUnauthorized(ctx, INVALID_TOKEN_ERROR)
+
+		if requiresTwoFactor(principal) {
+			ctx.JSON(http.StatusForbidden, util.ErrorResponse{Error: true, ErrorMessage: TWO_FACTOR_REQUIRED_ERROR})
+			ctx.Abort()
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			if role, ok := claims["role"].(string); ok && role == string(model.AdminRole) {
-				ctx.Next()
-				return
-			}
+		if repository.HasPermissions(principal.Permissions, permissions...) {
+			ctx.Next()
+			return
 		}
+
 		ctx.JSON(http.StatusForbidden, util.ErrorResponse{Error: true, ErrorMessage: FORBIDDEN_ACCESS_ERROR})
 		ctx.Abort()
 	}
 }
 
-func Authenticate() gin.HandlerFunc {
+// Authenticate validates the bearer token and resolves its principal once
+// per request, see CurrentPrincipal
+func Authenticate(db *gorm.DB) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		token, err := parseToken(ctx)
-		if err == http.ErrNoLocation {
-			respondUnauthorized(ctx, AUTHORIZATION_HEADER_ERROR)
-			return
-		}
-
-		if err != nil || !token.Valid {
-			respondUnauthorized(ctx, INVALID_TOKEN_ERROR)
+		claims, ok := validateToken(ctx, db)
+		if !ok {
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			ctx.Set("user_id", claims["user_id"])
-			ctx.Next()
+		principal, ok := loadPrincipal(ctx, db, claims)
+		if !ok {
 			return
 		}
 
-		respondUnauthorized(ctx, INVALID_TOKEN_ERROR)
+		setPrincipal(ctx, principal)
+		ctx.Set("jti", claims["jti"])
+		ctx.Set("session_id", claims["sid"])
+		ctx.Set("token_expiry", claimTime(claims, "exp"))
+		ctx.Next()
 	}
 }
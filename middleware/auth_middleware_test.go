@@ -3,18 +3,51 @@ package middleware
 import (
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/repository"
+	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
 )
 
 const (
-	ADMIN = "/admin"
-	USER  = "/user"
+	ADMIN                   = "/admin"
+	USER                    = "/user"
+	SELECT_REVOKED_TOKEN    = "SELECT * FROM `revoked_tokens` WHERE (jti = ?) ORDER BY `revoked_tokens`.`id` ASC LIMIT 1"
+	SELECT_USER_REVOCATION  = "SELECT * FROM `user_token_revocations` WHERE (user_guid = ?) ORDER BY `user_token_revocations`.`id` ASC LIMIT 1"
+	SELECT_ACTIVE_USER      = "SELECT * FROM `users` WHERE (user_guid = ? AND is_deleted = false) ORDER BY `users`.`id` ASC LIMIT 1"
+	SELECT_ROLE_PERMISSIONS = "SELECT permissions.name FROM `permissions` INNER JOIN role_permissions ON role_permissions.permission_id = permissions.id INNER JOIN roles ON roles.id = role_permissions.role_id WHERE (roles.name = ?)"
+	OPEN_ERROR              = "Error opening gorm connection: %v"
+	MOCK_ERROR              = "Error creating mock db: %v"
 )
 
+// newMockDB creates a gorm connection backed by sqlmock where the user has never
+// logged out everywhere, so tokens are only rejected because of their jti
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf(MOCK_ERROR, err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	gdb, err := gorm.Open("mysql", db)
+	if err != nil {
+		t.Fatalf(OPEN_ERROR, err)
+	}
+
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_USER_REVOCATION)).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnError(gorm.ErrRecordNotFound)
+	return gdb, mock
+}
+
 func generateToken(role string, userID string) string {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"role":    role,
@@ -24,13 +57,39 @@ func generateToken(role string, userID string) string {
 	return tokenString
 }
 
-func TestIsAdminWithValidAdminToken(t *testing.T) {
+// expectRolePermissions makes the mock return the permissions of a role
+func expectRolePermissions(mock sqlmock.Sqlmock, role string, permissions ...string) {
+	repository.InvalidateRolePermissions(role)
+	rows := sqlmock.NewRows([]string{"name"})
+	for _, permission := range permissions {
+		rows.AddRow(permission)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ROLE_PERMISSIONS)).WithArgs(role).WillReturnRows(rows)
+}
+
+// expectActiveUser makes the mock return the user behind a token
+func expectActiveUser(mock sqlmock.Sqlmock, userID, role string) {
+	repository.InvalidateUser(&model.User{UserID: userID})
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ACTIVE_USER)).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_guid", "role"}).AddRow(1, userID, role))
+}
+
+func newPermissionRouter(gdb *gorm.DB, permissions ...string) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
-	r.Use(IsAdmin())
+	r.Use(Authenticate(gdb), RequirePermission(gdb, permissions...))
 	r.GET(ADMIN, func(ctx *gin.Context) {
 		ctx.JSON(http.StatusOK, gin.H{"message": "success"})
 	})
+	return r
+}
+
+func TestRequirePermissionWithGrantedPermission(t *testing.T) {
+	gdb, mock := newMockDB(t)
+	expectActiveUser(mock, "123", "admin")
+	expectRolePermissions(mock, "admin", "products:write", "users:manage")
+	r := newPermissionRouter(gdb, "products:write")
 
 	token := generateToken("admin", "123")
 	req := httptest.NewRequest(http.MethodGet, ADMIN, nil)
@@ -43,15 +102,28 @@ func TestIsAdminWithValidAdminToken(t *testing.T) {
 	assert.JSONEq(t, `{"message": "success"}`, w.Body.String())
 }
 
-func TestIsAdminWithInvalidToken(t *testing.T) {
-	gin.SetMode(gin.TestMode)
-	r := gin.New()
-	r.Use(IsAdmin())
-	r.GET(ADMIN, func(ctx *gin.Context) {
-		ctx.JSON(http.StatusOK, gin.H{"message": "success"})
-	})
+func TestRequirePermissionWithMissingPermission(t *testing.T) {
+	gdb, mock := newMockDB(t)
+	expectActiveUser(mock, "123", "support")
+	expectRolePermissions(mock, "support", "orders:read", "orders:update_status")
+	r := newPermissionRouter(gdb, "products:write")
+
+	token := generateToken("support", "123")
+	req := httptest.NewRequest(http.MethodGet, ADMIN, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), FORBIDDEN_ACCESS_ERROR)
+}
+
+func TestRequirePermissionWithInvalidToken(t *testing.T) {
+	gdb, _ := newMockDB(t)
+	r := newPermissionRouter(gdb, "products:write")
 
-	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req := httptest.NewRequest(http.MethodGet, ADMIN, nil)
 	req.Header.Set("Authorization", "Bearer invalid_token")
 
 	w := httptest.NewRecorder()
@@ -62,8 +134,11 @@ func TestIsAdminWithInvalidToken(t *testing.T) {
 
 func TestAuthenticateWithValidToken(t *testing.T) {
 	gin.SetMode(gin.TestMode)
+	gdb, mock := newMockDB(t)
+	expectActiveUser(mock, "123", "user")
+	expectRolePermissions(mock, "user")
 	r := gin.New()
-	r.Use(Authenticate())
+	r.Use(Authenticate(gdb))
 	r.GET(USER, func(ctx *gin.Context) {
 		userID, _ := ctx.Get("user_id")
 		ctx.JSON(http.StatusOK, gin.H{"user_id": userID})
@@ -82,8 +157,9 @@ func TestAuthenticateWithValidToken(t *testing.T) {
 
 func TestAuthenticateWithMissingAuthorizationHeader(t *testing.T) {
 	gin.SetMode(gin.TestMode)
+	gdb, _ := newMockDB(t)
 	r := gin.New()
-	r.Use(Authenticate())
+	r.Use(Authenticate(gdb))
 	r.GET(USER, func(ctx *gin.Context) {
 		ctx.JSON(http.StatusOK, gin.H{"message": "success"})
 	})
@@ -98,8 +174,9 @@ func TestAuthenticateWithMissingAuthorizationHeader(t *testing.T) {
 
 func TestAuthenticateWithInvalidToken(t *testing.T) {
 	gin.SetMode(gin.TestMode)
+	gdb, _ := newMockDB(t)
 	r := gin.New()
-	r.Use(Authenticate())
+	r.Use(Authenticate(gdb))
 	r.GET(USER, func(ctx *gin.Context) {
 		ctx.JSON(http.StatusOK, gin.H{"message": "success"})
 	})
@@ -112,3 +189,263 @@ func TestAuthenticateWithInvalidToken(t *testing.T) {
 
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
 }
+
+func TestAuthenticateWithRevokedToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	gdb, mock := newMockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_REVOKED_TOKEN)).
+		WithArgs("revoked-jti").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "jti", "user_guid", "expires_at"}).
+			AddRow(1, "revoked-jti", "456", time.Now().Add(time.Hour)))
+
+	r := gin.New()
+	r.Use(Authenticate(gdb))
+	r.GET(USER, func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"role":    "user",
+		"user_id": "456",
+		"jti":     "revoked-jti",
+	})
+	tokenString, _ := token.SignedString([]byte("secret_key"))
+	req := httptest.NewRequest(http.MethodGet, USER, nil)
+	req.Header.Set("Authorization", "Bearer "+tokenString)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), REVOKED_TOKEN_ERROR)
+}
+
+func TestAuthenticateWithTokenIssuedBeforeLogoutEverywhere(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf(MOCK_ERROR, err)
+	}
+	defer db.Close()
+
+	gdb, err := gorm.Open("mysql", db)
+	if err != nil {
+		t.Fatalf(OPEN_ERROR, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_REVOKED_TOKEN)).
+		WithArgs("old-jti").
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_USER_REVOCATION)).
+		WithArgs("789").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_guid", "revoked_at"}).AddRow(1, "789", time.Now()))
+
+	r := gin.New()
+	r.Use(Authenticate(gdb))
+	r.GET(USER, func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"role":    "user",
+		"user_id": "789",
+		"jti":     "old-jti",
+		"iat":     time.Now().Add(-time.Hour).Unix(),
+	})
+	tokenString, _ := token.SignedString([]byte("secret_key"))
+	req := httptest.NewRequest(http.MethodGet, USER, nil)
+	req.Header.Set("Authorization", "Bearer "+tokenString)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), REVOKED_TOKEN_ERROR)
+}
+
+// A token issued in the same second as a logout everywhere, but after it,
+// stays valid, one issued earlier in that second does not
+func TestAuthenticateWithTokenIssuedInTheSecondOfLogoutEverywhere(t *testing.T) {
+	revokedAt := time.Now().Truncate(time.Second).Add(400 * time.Millisecond)
+
+	for userID, issuedAt := range map[string]time.Time{
+		"relogin-user":    revokedAt.Add(300 * time.Millisecond),
+		"logged-out-user": revokedAt.Add(-300 * time.Millisecond),
+	} {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf(MOCK_ERROR, err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		gdb, err := gorm.Open("mysql", db)
+		if err != nil {
+			t.Fatalf(OPEN_ERROR, err)
+		}
+
+		mock.MatchExpectationsInOrder(false)
+		mock.ExpectQuery(regexp.QuoteMeta(SELECT_REVOKED_TOKEN)).
+			WithArgs(userID + "-jti").
+			WillReturnError(gorm.ErrRecordNotFound)
+		mock.ExpectQuery(regexp.QuoteMeta(SELECT_USER_REVOCATION)).
+			WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_guid", "revoked_at"}).AddRow(1, userID, revokedAt))
+		expected := http.StatusUnauthorized
+		if issuedAt.After(revokedAt) {
+			expected = http.StatusOK
+			expectActiveUser(mock, userID, "user")
+			expectRolePermissions(mock, "user")
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"role":    "user",
+			"user_id": userID,
+			"jti":     userID + "-jti",
+			"iat":     issuedAt.Unix(),
+			"iat_ms":  issuedAt.UnixMilli(),
+		})
+		tokenString, _ := token.SignedString([]byte("secret_key"))
+
+		w := serveAuthenticated(gdb, tokenString)
+
+		assert.Equal(t, expected, w.Code, userID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestRequirePermissionEnforcesTwoFactor(t *testing.T) {
+	t.Setenv("REQUIRE_ADMIN_2FA", "true")
+
+	for amr, expected := range map[string]int{"pwd": http.StatusForbidden, "mfa": http.StatusOK} {
+		gdb, mock := newMockDB(t)
+		expectActiveUser(mock, "123", "admin")
+		expectRolePermissions(mock, "admin", "products:write")
+		r := newPermissionRouter(gdb, "products:write")
+
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"role":    "admin",
+			"user_id": "123",
+			"amr":     []string{"pwd", amr},
+		})
+		tokenString, _ := token.SignedString([]byte("secret_key"))
+
+		req := httptest.NewRequest(http.MethodGet, ADMIN, nil)
+		req.Header.Set("Authorization", "Bearer "+tokenString)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, expected, w.Code, "amr %s", amr)
+	}
+}
+
+const SELECT_SESSION = "SELECT * FROM `sessions` WHERE (session_id = ?) ORDER BY `sessions`.`id` ASC LIMIT 1"
+
+func generateSessionToken(userID, sessionID string) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"role":    "user",
+		"user_id": userID,
+		"sid":     sessionID,
+	})
+	tokenString, _ := token.SignedString([]byte("secret_key"))
+	return tokenString
+}
+
+func serveAuthenticated(gdb *gorm.DB, token string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Authenticate(gdb))
+	r.GET(USER, func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"session_id": ctx.GetString("session_id")})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, USER, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthenticateRejectsRevokedSession(t *testing.T) {
+	gdb, mock := newMockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_SESSION)).
+		WithArgs("revoked-session").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "session_id", "revoked_at"}).AddRow(1, "revoked-session", time.Now()))
+
+	w := serveAuthenticated(gdb, generateSessionToken("session-user-1", "revoked-session"))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), REVOKED_SESSION_ERROR)
+}
+
+func TestAuthenticateRecordsSessionActivity(t *testing.T) {
+	gdb, mock := newMockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_SESSION)).
+		WithArgs("active-session").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "session_id", "revoked_at"}).AddRow(1, "active-session", nil))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `sessions` SET `ip_address` = ?, `last_seen_at` = ? WHERE (session_id = ?)")).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "active-session").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectActiveUser(mock, "session-user-2", "user")
+	expectRolePermissions(mock, "user")
+
+	w := serveAuthenticated(gdb, generateSessionToken("session-user-2", "active-session"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"session_id": "active-session"}`, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// The principal is loaded from the database: the role of the user counts,
+// not the role the token was issued with
+func TestAuthenticateUsesCurrentRole(t *testing.T) {
+	gdb, mock := newMockDB(t)
+	expectActiveUser(mock, "promoted-user", "admin")
+	expectRolePermissions(mock, "admin", "products:write")
+	r := newPermissionRouter(gdb, "products:write")
+
+	req := httptest.NewRequest(http.MethodGet, ADMIN, nil)
+	req.Header.Set("Authorization", "Bearer "+generateToken("user", "promoted-user"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// Tokens of deactivated or erased users stop working before they expire
+func TestAuthenticateRejectsInactiveUser(t *testing.T) {
+	gdb, mock := newMockDB(t)
+	repository.InvalidateUser(&model.User{UserID: "deleted-user"})
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ACTIVE_USER)).
+		WithArgs("deleted-user").
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	w := serveAuthenticated(gdb, generateToken("user", "deleted-user"))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), INACTIVE_USER_ERROR)
+}
+
+// The user is cached between requests until it changes
+func TestAuthenticateCachesPrincipal(t *testing.T) {
+	gdb, mock := newMockDB(t)
+	expectActiveUser(mock, "cached-user", "user")
+	expectRolePermissions(mock, "user")
+	token := generateToken("user", "cached-user")
+
+	for range 2 {
+		assert.Equal(t, http.StatusOK, serveAuthenticated(gdb, token).Code)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	repository.InvalidateUser(&model.User{UserID: "cached-user"})
+	mock.ExpectQuery(regexp.QuoteMeta(SELECT_ACTIVE_USER)).
+		WithArgs("cached-user").
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	assert.Equal(t, http.StatusUnauthorized, serveAuthenticated(gdb, token).Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// Address is an entry of the address book of a user. A user has at most one
+// default shipping and one default billing address
+type Address struct {
+	ID                uint      `json:"-" gorm:"primary_key"`
+	AddressID         string    `json:"address_id" gorm:"column:address_guid;not null;unique;size:36"`
+	UserID            uint      `json:"-" gorm:"column:user_id;index"`
+	User              User      `json:"-" gorm:"foreignKey:UserID"`
+	Label             string    `json:"label" gorm:"column:label;size:64" example:"Home"`
+	FullName          string    `json:"full_name" gorm:"column:full_name;not null;size:255" example:"Jane Doe"`
+	Line1             string    `json:"line1" gorm:"column:line1;not null;size:255" example:"12 Admiralty Way"`
+	Line2             string    `json:"line2" gorm:"column:line2;size:255" example:"Flat 3"`
+	City              string    `json:"city" gorm:"column:city;not null;size:128" example:"Lagos"`
+	Region            string    `json:"region" gorm:"column:region;size:128" example:"Lagos"` // state, province or county
+	PostalCode        string    `json:"postal_code" gorm:"column:postal_code;size:16" example:"106104"`
+	Country           string    `json:"country" gorm:"column:country;not null;size:2" example:"NG"` // ISO 3166-1 alpha-2 code
+	Phone             string    `json:"phone" gorm:"column:phone;size:32" example:"+2348012345678"`
+	IsDefaultShipping bool      `json:"is_default_shipping" gorm:"column:is_default_shipping;default:false"`
+	IsDefaultBilling  bool      `json:"is_default_billing" gorm:"column:is_default_billing;default:false"`
+	CreatedAt         time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt         time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+func (address *Address) BeforeCreate(tx *gorm.DB) (err error) {
+	now := time.Now()
+	address.CreatedAt = now
+	address.UpdatedAt = now
+	address.AddressID = uuid.New().String()
+	return nil
+}
+
+func (address *Address) BeforeUpdate(tx *gorm.DB) (err error) {
+	address.UpdatedAt = time.Now()
+	return nil
+}
+
+// Snapshot copies the address as it is when an order is placed, so that later
+// changes to the address book do not change where an order is shipped
+func (address *Address) Snapshot() AddressSnapshot {
+	return AddressSnapshot{
+		FullName:   address.FullName,
+		Line1:      address.Line1,
+		Line2:      address.Line2,
+		City:       address.City,
+		Region:     address.Region,
+		PostalCode: address.PostalCode,
+		Country:    address.Country,
+		Phone:      address.Phone,
+	}
+}
+
+// AddressSnapshot is an address stored on an order. It is embedded with a
+// column prefix, once for shipping and once for billing
+type AddressSnapshot struct {
+	FullName   string `json:"full_name" gorm:"column:full_name;size:255"`
+	Line1      string `json:"line1" gorm:"column:line1;size:255"`
+	Line2      string `json:"line2" gorm:"column:line2;size:255"`
+	City       string `json:"city" gorm:"column:city;size:128"`
+	Region     string `json:"region" gorm:"column:region;size:128"`
+	PostalCode string `json:"postal_code" gorm:"column:postal_code;size:16"`
+	Country    string `json:"country" gorm:"column:country;size:2"`
+	Phone      string `json:"phone" gorm:"column:phone;size:32"`
+}
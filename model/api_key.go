@@ -0,0 +1,44 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// APIKey lets a machine client call the API without a user login. Only the
+// SHA-256 hash of the key is stored, the prefix identifies the key in listings.
+// A key acts on behalf of the user who created it and is limited to its scopes
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primary_key"`
+	Name       string     `json:"name" gorm:"column:name;not null;size:255" example:"warehouse"`
+	Prefix     string     `json:"prefix" gorm:"column:prefix;unique;not null;size:16" example:"isk_Xk3f9QaB"`
+	KeyHash    string     `json:"-" gorm:"column:key_hash;unique;not null;size:64"`
+	Scopes     string     `json:"scopes" gorm:"column:scopes;not null;size:1024" example:"orders:read products:write"` // space separated permission names
+	OwnerID    uint       `json:"-" gorm:"column:owner_id;index"`
+	Owner      User       `json:"-" gorm:"foreignKey:OwnerID"`
+	ExpiresAt  *time.Time `json:"expires_at" gorm:"column:expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at" gorm:"column:last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at" gorm:"column:revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+func (apiKey *APIKey) BeforeCreate(tx *gorm.DB) (err error) {
+	apiKey.CreatedAt = time.Now()
+	return nil
+}
+
+// ScopeList returns the permission names the key is limited to
+func (apiKey *APIKey) ScopeList() []string {
+	return strings.Fields(apiKey.Scopes)
+}
+
+// IsActive reports whether the key is neither revoked nor expired
+func (apiKey *APIKey) IsActive() bool {
+	return apiKey.RevokedAt == nil && (apiKey.ExpiresAt == nil || time.Now().Before(*apiKey.ExpiresAt))
+}
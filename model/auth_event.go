@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// AuthEventType names what happened in an authentication event
+type AuthEventType string
+
+const (
+	SignupEvent          AuthEventType = "signup"
+	LoginSucceededEvent  AuthEventType = "login_succeeded"
+	LoginFailedEvent     AuthEventType = "login_failed"
+	LoginLockedOutEvent  AuthEventType = "login_locked_out" // refused because of too many failures
+	PasswordChangedEvent AuthEventType = "password_changed"
+	TokensRevokedEvent   AuthEventType = "tokens_revoked"
+	RoleChangedEvent     AuthEventType = "role_changed"
+
+	RolePermissionsChangedEvent AuthEventType = "role_permissions_changed" // a role was created or its permissions replaced
+)
+
+// AuthEvents are the event types that are recorded in the audit log
+var AuthEvents = []AuthEventType{
+	SignupEvent, LoginSucceededEvent, LoginFailedEvent, LoginLockedOutEvent,
+	PasswordChangedEvent, TokensRevokedEvent, RoleChangedEvent, RolePermissionsChangedEvent,
+}
+
+// AuthEvent is an entry of the authentication audit log. Users are referenced
+// by guid so that entries stay readable without joining the users table
+type AuthEvent struct {
+	ID        uint          `json:"id" gorm:"primary_key"`
+	Type      AuthEventType `json:"event_type" gorm:"column:event_type;not null;index;size:32" example:"login_failed"`
+	UserID    string        `json:"user_id" gorm:"column:user_guid;index;size:36"`   // the account the event is about, empty when no account matched
+	ActorID   string        `json:"actor_id" gorm:"column:actor_guid;index;size:36"` // who caused the event, e.g. the administrator who changed a role
+	Email     string        `json:"email" gorm:"column:email;index;size:255"`        // the email of the account, or the one given in a failed login
+	IPAddress string        `json:"ip_address" gorm:"column:ip_address;size:45"`
+	UserAgent string        `json:"user_agent" gorm:"column:user_agent;size:512"`
+	Details   string        `json:"details" gorm:"column:details;size:255" example:"password"`
+	CreatedAt time.Time     `json:"created_at" gorm:"column:created_at;index"`
+}
+
+func (event *AuthEvent) BeforeCreate(tx *gorm.DB) (err error) {
+	event.CreatedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Category groups products for navigation. Categories form a tree through
+// their parent and a product can be in several categories
+type Category struct {
+	ID          uint        `json:"-" gorm:"primary_key"`
+	Name        string      `json:"name" gorm:"column:name;not null;size:128" example:"Kettles"`
+	Slug        string      `json:"slug" gorm:"column:slug;unique;not null;size:64" example:"kettles"`
+	Description string      `json:"description" gorm:"column:description;size:255"`
+	ParentID    *uint       `json:"-" gorm:"column:parent_id;index"` // nil for top-level categories
+	ParentSlug  string      `json:"parent_slug,omitempty" gorm:"-" example:"kitchen"`
+	SortOrder   int         `json:"sort_order" gorm:"column:sort_order;not null"` // position among its siblings, lowest first
+	Children    []*Category `json:"children,omitempty" gorm:"-"`
+	CreatedAt   time.Time   `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt   time.Time   `json:"updated_at" gorm:"column:updated_at"`
+}
+
+func (category *Category) BeforeCreate(tx *gorm.DB) (err error) {
+	now := time.Now()
+	category.CreatedAt = now
+	category.UpdatedAt = now
+	return nil
+}
+
+func (category *Category) BeforeUpdate(tx *gorm.DB) (err error) {
+	category.UpdatedAt = time.Now()
+	return nil
+}
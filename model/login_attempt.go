@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// LoginAttempt tracks failed logins for a single throttling key, which is
+// either an account (by email) or a client IP address
+type LoginAttempt struct {
+	ID              uint       `json:"-" gorm:"primary_key"`
+	AttemptKey      string     `json:"attempt_key" gorm:"column:attempt_key;unique;not null;size:320"`
+	Failures        int        `json:"failures" gorm:"column:failures;not null;default:0"`
+	WindowStartedAt time.Time  `json:"window_started_at" gorm:"column:window_started_at"`
+	LastFailedAt    time.Time  `json:"last_failed_at" gorm:"column:last_failed_at"`
+	LockedUntil     *time.Time `json:"locked_until" gorm:"column:locked_until"`
+}
+
+// IsLocked reports whether the key is temporarily locked out
+func (loginAttempt *LoginAttempt) IsLocked() bool {
+	return loginAttempt.LockedUntil != nil && time.Now().Before(*loginAttempt.LockedUntil)
+}
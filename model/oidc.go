@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// OIDCLoginState remembers an authorization request sent to the OpenID
+// Connect provider until the provider redirects back. Only the SHA-256 hash
+// of the state parameter is stored and each state can be used once
+type OIDCLoginState struct {
+	ID           uint      `json:"-" gorm:"primary_key"`
+	StateHash    string    `json:"-" gorm:"column:state_hash;unique;not null;size:64"`
+	Nonce        string    `json:"-" gorm:"column:nonce;not null;size:64"`
+	CodeVerifier string    `json:"-" gorm:"column:code_verifier;not null;size:128"`
+	ExpiresAt    time.Time `json:"expires_at" gorm:"column:expires_at"`
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+func (OIDCLoginState) TableName() string {
+	return "oidc_login_states"
+}
+
+func (loginState *OIDCLoginState) BeforeCreate(tx *gorm.DB) (err error) {
+	loginState.CreatedAt = time.Now()
+	return nil
+}
+
+// IsExpired reports whether the provider took too long to redirect back
+func (loginState *OIDCLoginState) IsExpired() bool {
+	return time.Now().After(loginState.ExpiresAt)
+}
+
+// UserIdentity links a user to an account at an OpenID Connect provider,
+// identified by the issuer and the subject of its ID tokens
+type UserIdentity struct {
+	ID        uint      `json:"-" gorm:"primary_key"`
+	UserID    uint      `json:"-" gorm:"column:user_id;index"`
+	User      User      `json:"-" gorm:"foreignKey:UserID"`
+	Issuer    string    `json:"issuer" gorm:"column:issuer;not null;size:255;unique_index:idx_user_identities_issuer_subject"`
+	Subject   string    `json:"subject" gorm:"column:subject;not null;size:255;unique_index:idx_user_identities_issuer_subject"`
+	Email     string    `json:"email" gorm:"column:email;size:255"` // email claim at the time the identity was linked
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+func (identity *UserIdentity) BeforeCreate(tx *gorm.DB) (err error) {
+	identity.CreatedAt = time.Now()
+	return nil
+}
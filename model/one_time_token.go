@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Define a custom type for the purpose a one-time token was issued for
+type TokenPurpose string
+
+const (
+	PasswordResetPurpose      TokenPurpose = "password_reset"
+	EmailVerificationPurpose  TokenPurpose = "email_verification"
+	EmailChangePurpose        TokenPurpose = "email_change"
+	TwoFactorChallengePurpose TokenPurpose = "two_factor_challenge"
+)
+
+// OneTimeToken is a single-use, expiring token that is sent to a user by email
+// or, for two-factor challenges, returned by the password step of a login.
+// Only the SHA-256 hash of the token is stored
+type OneTimeToken struct {
+	ID        uint         `json:"-" gorm:"primary_key"`
+	UserID    uint         `json:"-" gorm:"column:user_id;index"`
+	User      User         `json:"-" gorm:"foreignKey:UserID"`
+	Purpose   TokenPurpose `json:"purpose" gorm:"column:purpose;not null;size:32"`
+	TokenHash string       `json:"-" gorm:"column:token_hash;unique;not null;size:64"`
+	Payload   string       `json:"-" gorm:"column:payload;size:255"` // purpose specific data, e.g. the new address for an email change
+	ExpiresAt time.Time    `json:"expires_at" gorm:"column:expires_at"`
+	UsedAt    *time.Time   `json:"used_at" gorm:"column:used_at"`
+	CreatedAt time.Time    `json:"created_at" gorm:"column:created_at"`
+}
+
+func (oneTimeToken *OneTimeToken) BeforeCreate(tx *gorm.DB) (err error) {
+	oneTimeToken.CreatedAt = time.Now()
+	return nil
+}
+
+// IsExpired reports whether the token can no longer be redeemed
+func (oneTimeToken *OneTimeToken) IsExpired() bool {
+	return time.Now().After(oneTimeToken.ExpiresAt)
+}
@@ -19,16 +19,19 @@ const (
 )
 
 type Order struct {
-	ID             uint            `json:"-" gorm:"primary_key"`
-	UserID         uint            `json:"-" gorm:"column:user_id;index"`
-	User           User            `json:"-" gorm:"foreignKey:UserID"`                                // Establish the relationship with User
-	Status         OrderStatus     `json:"order_status" gorm:"column:order_status" example:"Pending"` // Pending, Shipped, Delivered, Canceled
-	TotalPrice     decimal.Decimal `json:"total_price" gorm:"column:total_price;type:decimal(10,2)" example:"10.50"`
-	OrderReference string          `json:"order_reference" gorm:"column:order_reference;index" example:"order123"`
-	IsDeleted      bool            `json:"-" gorm:"column:is_deleted;default:false"`
-	Products       []Product       `json:"products" gorm:"many2many:order_products;"`
-	CreatedAt      time.Time       `json:"created_at" gorm:"column:created_at"`
-	UpdatedAt      time.Time       `json:"updated_at" gorm:"column:updated_at"`
+	ID              uint             `json:"-" gorm:"primary_key"`
+	UserID          uint             `json:"-" gorm:"column:user_id;index"`
+	User            User             `json:"-" gorm:"foreignKey:UserID"`                                // Establish the relationship with User
+	Status          OrderStatus      `json:"order_status" gorm:"column:order_status" example:"Pending"` // Pending, Shipped, Delivered, Canceled
+	TotalPrice      decimal.Decimal  `json:"total_price" gorm:"column:total_price;type:decimal(10,2)" example:"10.50"`
+	OrderReference  string           `json:"order_reference" gorm:"column:order_reference;index" example:"order123"`
+	IsDeleted       bool             `json:"-" gorm:"column:is_deleted;default:false"`
+	Products        []Product        `json:"products" gorm:"many2many:order_products;association_autoupdate:false;association_autocreate:false"`
+	Variants        []ProductVariant `json:"variants" gorm:"many2many:order_variants;association_autoupdate:false;association_autocreate:false"` // the variants ordered of products that have variants
+	ShippingAddress AddressSnapshot  `json:"shipping_address" gorm:"embedded;embedded_prefix:shipping_"`                                         // copied from the address book when the order is placed
+	BillingAddress  AddressSnapshot  `json:"billing_address" gorm:"embedded;embedded_prefix:billing_"`
+	CreatedAt       time.Time        `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt       time.Time        `json:"updated_at" gorm:"column:updated_at"`
 }
 
 func (order *Order) BeforeCreate(tx *gorm.DB) (err error) {
@@ -44,3 +47,11 @@ func (order *Order) BeforeUpdate(tx *gorm.DB) (err error) {
 	order.UpdatedAt = now
 	return nil
 }
+
+// OrderSummary aggregates the orders of a single user
+type OrderSummary struct {
+	TotalOrders    int                 `json:"total_orders"`
+	OrdersByStatus map[OrderStatus]int `json:"orders_by_status"`
+	TotalSpent     decimal.Decimal     `json:"total_spent" example:"120.50"` // excludes cancelled orders
+	LastOrderAt    *time.Time          `json:"last_order_at"`
+}
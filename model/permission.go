@@ -0,0 +1,78 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const SupportRole = "support"
+
+const (
+	OrdersReadPermission         = "orders:read"
+	OrdersUpdateStatusPermission = "orders:update_status"
+	ProductsWritePermission      = "products:write"
+	UsersManagePermission        = "users:manage"
+	APIKeysManagePermission      = "api_keys:manage"
+	AuditReadPermission          = "audit:read"
+)
+
+// DefaultPermissions lists the permissions known to the application with their descriptions
+var DefaultPermissions = map[string]string{
+	OrdersReadPermission:         "Read the orders of any user",
+	OrdersUpdateStatusPermission: "Change the status of orders",
+	ProductsWritePermission:      "Create, update and delete products and categories",
+	UsersManagePermission:        "Manage users, roles and permissions",
+	APIKeysManagePermission:      "Create and revoke API keys",
+	AuditReadPermission:          "Read the authentication audit log",
+}
+
+// DefaultRolePermissions are the permissions given to the built-in roles when
+// they are first created. The admin role always holds every permission
+var DefaultRolePermissions = map[string][]string{
+	AdminRole:   {},
+	SupportRole: {OrdersReadPermission, OrdersUpdateStatusPermission},
+	UserRole:    {},
+}
+
+// Permission is a named right that can be granted to roles
+type Permission struct {
+	ID          uint   `json:"-" gorm:"primary_key"`
+	Name        string `json:"name" gorm:"column:name;unique;not null;size:64" example:"orders:read"`
+	Description string `json:"description" gorm:"column:description;size:255"`
+}
+
+// RoleDefinition is a named set of permissions. Users reference a role by its name
+type RoleDefinition struct {
+	ID          uint         `json:"-" gorm:"primary_key"`
+	Name        string       `json:"name" gorm:"column:name;unique;not null;size:64" example:"support"`
+	Description string       `json:"description" gorm:"column:description;size:255"`
+	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions;jointable_foreignkey:role_id;association_jointable_foreignkey:permission_id"`
+	CreatedAt   time.Time    `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt   time.Time    `json:"updated_at" gorm:"column:updated_at"`
+}
+
+func (RoleDefinition) TableName() string {
+	return "roles"
+}
+
+func (role *RoleDefinition) BeforeCreate(tx *gorm.DB) (err error) {
+	now := time.Now()
+	role.CreatedAt = now
+	role.UpdatedAt = now
+	return nil
+}
+
+func (role *RoleDefinition) BeforeUpdate(tx *gorm.DB) (err error) {
+	role.UpdatedAt = time.Now()
+	return nil
+}
+
+// PermissionNames returns the names of the permissions granted to the role
+func (role *RoleDefinition) PermissionNames() []string {
+	names := make([]string, 0, len(role.Permissions))
+	for _, permission := range role.Permissions {
+		names = append(names, permission.Name)
+	}
+	return names
+}
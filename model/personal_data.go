@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// ERASED_EMAIL_DOMAIN is the domain of the placeholder address that replaces
+// the email of an erased user. The .invalid TLD can never receive mail
+const ERASED_EMAIL_DOMAIN = "erased.invalid"
+
+// PersonalDataExport is everything stored about a user, as handed out when
+// the user asks for a copy of their data
+type PersonalDataExport struct {
+	ExportedAt    time.Time      `json:"exported_at"`
+	User          *User          `json:"user"`
+	Orders        []Order        `json:"orders"`
+	Addresses     []Address      `json:"addresses"`
+	Sessions      []Session      `json:"sessions"`
+	Identities    []UserIdentity `json:"linked_identities"`
+	APIKeys       []APIKey       `json:"api_keys"`
+	AuthEvents    []AuthEvent    `json:"auth_events"`     // the events about the account in the audit log
+	TwoFactorAuth *TwoFactorAuth `json:"two_factor_auth"` // nil when the user never enrolled
+}
@@ -0,0 +1,11 @@
+package model
+
+// Principal is the caller of a request as resolved by the authentication
+// middleware: the user behind the token or API key and what they may do
+type Principal struct {
+	User        *User
+	Role        Role
+	Permissions []string
+	APIKeyID    uint // set when the request was made with an API key
+	MultiFactor bool // the user logged in with a second factor
+}
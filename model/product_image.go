@@ -0,0 +1,60 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// ProductImage is a picture of a product. The file and its thumbnails live
+// in the file storage under the image id, the URLs are filled in when the
+// image is returned. A product has at most one primary image, the one shown
+// in listings
+type ProductImage struct {
+	ID          uint              `json:"-" gorm:"primary_key"`
+	ImageID     string            `json:"image_id" gorm:"column:image_guid;not null;unique;size:36"`
+	ProductID   uint              `json:"-" gorm:"column:product_id;index"`
+	Position    int               `json:"position" gorm:"column:position;not null"`
+	IsPrimary   bool              `json:"is_primary" gorm:"column:is_primary;not null"`
+	ContentType string            `json:"content_type" gorm:"column:content_type;not null;size:32" example:"image/jpeg"`
+	Width       int               `json:"width" gorm:"column:width;not null" example:"1200"`
+	Height      int               `json:"height" gorm:"column:height;not null" example:"800"`
+	URL         string            `json:"url" gorm:"-" example:"/uploads/products/3f0c.../original.jpg"`
+	Thumbnails  map[string]string `json:"thumbnails" gorm:"-"` // URL by size: small, medium and large
+	CreatedAt   time.Time         `json:"created_at" gorm:"column:created_at"`
+}
+
+func (image *ProductImage) BeforeCreate(tx *gorm.DB) (err error) {
+	image.CreatedAt = time.Now()
+	if image.ImageID == "" {
+		image.ImageID = uuid.New().String()
+	}
+	return nil
+}
+
+// StorageKey is where the uploaded file of the image is stored
+func (image *ProductImage) StorageKey() string {
+	return "products/" + image.ImageID + "/original" + imageExtension(image.ContentType)
+}
+
+// ThumbnailKey is where the thumbnail of the given size is stored.
+// Thumbnails of JPEG images are JPEG, all others are PNG
+func (image *ProductImage) ThumbnailKey(size string) string {
+	extension := ".png"
+	if image.ContentType == "image/jpeg" {
+		extension = ".jpg"
+	}
+	return "products/" + image.ImageID + "/" + size + extension
+}
+
+func imageExtension(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	default:
+		return ".png"
+	}
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+// PriceChangeSource names what changed the price of a product
+type PriceChangeSource string
+
+const (
+	ManualPriceChange        PriceChangeSource = "manual"           // an update of the product
+	ImportPriceChange        PriceChangeSource = "import"           // a CSV import
+	ScheduleStartPriceChange PriceChangeSource = "schedule_started" // a price schedule took effect
+	ScheduleEndPriceChange   PriceChangeSource = "schedule_ended"   // a price schedule ended or was cancelled and the regular price came back
+)
+
+// PriceChange is an entry of the price history of a product. Users are
+// referenced by guid, like in the authentication audit log
+type PriceChange struct {
+	ID         uint              `json:"-" gorm:"primary_key"`
+	ProductID  uint              `json:"-" gorm:"column:product_id;index"`
+	OldPrice   decimal.Decimal   `json:"old_price" gorm:"column:old_price;type:decimal(10,2);not null"`
+	NewPrice   decimal.Decimal   `json:"new_price" gorm:"column:new_price;type:decimal(10,2);not null"`
+	Currency   string            `json:"currency" gorm:"column:currency;not null;size:3"`
+	Source     PriceChangeSource `json:"source" gorm:"column:source;not null;size:32" example:"manual"`
+	ChangedBy  string            `json:"changed_by,omitempty" gorm:"column:changed_by_guid;size:36"` // the user who made the change, empty when the scheduler made it
+	ScheduleID string            `json:"schedule_id,omitempty" gorm:"column:schedule_guid;size:36"`  // the price schedule behind the change, if any
+	CreatedAt  time.Time         `json:"changed_at" gorm:"column:created_at;index"`
+}
+
+func (change *PriceChange) BeforeCreate(tx *gorm.DB) (err error) {
+	change.CreatedAt = time.Now()
+	return nil
+}
+
+// PriceScheduleStatus is where a price schedule is in its life
+type PriceScheduleStatus string
+
+const (
+	PriceSchedulePending   PriceScheduleStatus = "pending"   // waiting for its start
+	PriceScheduleActive    PriceScheduleStatus = "active"    // the scheduled price is in effect until the end
+	PriceScheduleCompleted PriceScheduleStatus = "completed" // applied, and reverted when it has an end
+	PriceScheduleCancelled PriceScheduleStatus = "cancelled"
+	PriceScheduleExpired   PriceScheduleStatus = "expired" // the whole window passed before the scheduler could apply it
+)
+
+// PriceSchedule is a price that a product takes from StartsAt, e.g. for a
+// sale. When it has an end the regular price, the one the product had at the
+// start, comes back then. Schedules without an end change the price for good
+type PriceSchedule struct {
+	ID           uint                `json:"-" gorm:"primary_key"`
+	ScheduleID   string              `json:"schedule_id" gorm:"column:schedule_guid;not null;unique;size:36"`
+	ProductID    uint                `json:"-" gorm:"column:product_id;index"`
+	Price        decimal.Decimal     `json:"price" gorm:"column:price;type:decimal(10,2);not null"`
+	RegularPrice *decimal.Decimal    `json:"regular_price,omitempty" gorm:"column:regular_price;type:decimal(10,2)"` // filled in at the start
+	StartsAt     time.Time           `json:"starts_at" gorm:"column:starts_at;not null;index"`
+	EndsAt       *time.Time          `json:"ends_at,omitempty" gorm:"column:ends_at;index"`
+	Status       PriceScheduleStatus `json:"status" gorm:"column:status;not null;index;size:16" example:"pending"`
+	CreatedBy    string              `json:"created_by" gorm:"column:created_by_guid;size:36"`
+	CreatedAt    time.Time           `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt    time.Time           `json:"updated_at" gorm:"column:updated_at"`
+}
+
+func (schedule *PriceSchedule) BeforeCreate(tx *gorm.DB) (err error) {
+	now := time.Now()
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+	if schedule.ScheduleID == "" {
+		schedule.ScheduleID = uuid.New().String()
+	}
+	return nil
+}
+
+func (schedule *PriceSchedule) BeforeUpdate(tx *gorm.DB) (err error) {
+	schedule.UpdatedAt = time.Now()
+	return nil
+}
+
+// IsOpen reports whether the schedule has yet to start or to end
+func (schedule *PriceSchedule) IsOpen() bool {
+	return schedule.Status == PriceSchedulePending || schedule.Status == PriceScheduleActive
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+// ProductOption is a way in which the variants of a product differ, such as size or colour
+type ProductOption struct {
+	ID        uint   `json:"-" gorm:"primary_key"`
+	ProductID uint   `json:"-" gorm:"column:product_id;index"`
+	Name      string `json:"name" gorm:"column:name;not null;size:64" example:"size"`
+	Position  int    `json:"position" gorm:"column:position;not null"`
+}
+
+// ProductVariant is a version of a product with one value for each of its
+// options, e.g. the medium red shirt. It has its own SKU and stock and may
+// override the price of the product
+type ProductVariant struct {
+	ID        uint             `json:"-" gorm:"primary_key"`
+	ProductID uint             `json:"-" gorm:"column:product_id;index"`
+	SKU       string           `json:"sku" gorm:"column:sku;unique;not null;size:64" example:"SHIRT-RED-M"`
+	Price     *decimal.Decimal `json:"price,omitempty" gorm:"column:price;type:decimal(10,2)" example:"25.00"` // the price of the product applies when empty
+	Stock     uint             `json:"stock" gorm:"column:stock"`
+	IsDeleted bool             `json:"-" gorm:"column:is_deleted;not null"`
+	Options   []VariantOption  `json:"options" gorm:"foreignkey:VariantID;association_autoupdate:false;association_autocreate:false"`
+	CreatedAt time.Time        `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt time.Time        `json:"updated_at" gorm:"column:updated_at"`
+}
+
+func (variant *ProductVariant) BeforeCreate(tx *gorm.DB) (err error) {
+	now := time.Now()
+	variant.CreatedAt = now
+	variant.UpdatedAt = now
+	return nil
+}
+
+func (variant *ProductVariant) BeforeUpdate(tx *gorm.DB) (err error) {
+	variant.UpdatedAt = time.Now()
+	return nil
+}
+
+// EffectivePrice returns the price of the variant, which defaults to the price of its product
+func (variant *ProductVariant) EffectivePrice(productPrice decimal.Decimal) decimal.Decimal {
+	if variant.Price != nil {
+		return *variant.Price
+	}
+	return productPrice
+}
+
+// VariantOption is the value a variant has for one of the options of its product
+type VariantOption struct {
+	ID        uint   `json:"-" gorm:"primary_key"`
+	VariantID uint   `json:"-" gorm:"column:variant_id;index"`
+	Name      string `json:"name" gorm:"column:name;not null;size:64" example:"size"`
+	Value     string `json:"value" gorm:"column:value;not null;size:64" example:"M"`
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// RefreshToken is a long-lived token used to obtain new access tokens.
+// Only the SHA-256 hash of the token is stored. Every refresh rotates the
+// token, and all tokens descending from the same login share a FamilyID
+// so that the whole chain can be revoked when an old token is replayed
+type RefreshToken struct {
+	ID         uint       `json:"-" gorm:"primary_key"`
+	UserID     uint       `json:"-" gorm:"column:user_id;index"`
+	User       User       `json:"-" gorm:"foreignKey:UserID"`
+	TokenHash  string     `json:"-" gorm:"column:token_hash;unique;not null;size:64"`
+	FamilyID   string     `json:"-" gorm:"column:family_id;index;not null;size:36"`
+	ReplacedBy string     `json:"-" gorm:"column:replaced_by;size:64"` // hash of the token issued in exchange for this one
+	MFA        bool       `json:"-" gorm:"column:mfa;not null"`        // the login that started the family passed a second factor
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"column:expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at" gorm:"column:revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+func (refreshToken *RefreshToken) BeforeCreate(tx *gorm.DB) (err error) {
+	refreshToken.CreatedAt = time.Now()
+	return nil
+}
+
+// IsExpired reports whether the refresh token can no longer be exchanged
+func (refreshToken *RefreshToken) IsExpired() bool {
+	return time.Now().After(refreshToken.ExpiresAt)
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// RevokedToken is an entry in the access token denylist. Entries are keyed
+// by the token's jti claim and only need to be kept until the token expires
+type RevokedToken struct {
+	ID        uint      `json:"-" gorm:"primary_key"`
+	JTI       string    `json:"jti" gorm:"column:jti;unique;not null;size:36"`
+	UserID    string    `json:"user_id" gorm:"column:user_guid;index;not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"column:expires_at;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+func (revokedToken *RevokedToken) BeforeCreate(tx *gorm.DB) (err error) {
+	revokedToken.CreatedAt = time.Now()
+	return nil
+}
+
+// UserTokenRevocation records the last time a user logged out everywhere.
+// Any access token issued at or before RevokedAt is rejected. RevokedAt keeps
+// milliseconds so that tokens issued in the same second afterwards stay valid
+type UserTokenRevocation struct {
+	ID        uint      `json:"-" gorm:"primary_key"`
+	UserID    string    `json:"user_id" gorm:"column:user_guid;unique;not null"`
+	RevokedAt time.Time `json:"revoked_at" gorm:"column:revoked_at;type:datetime(3)"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Session is a login on one device. Its SessionID is the FamilyID of the
+// refresh tokens issued for the login and is carried by access tokens in the
+// sid claim, so revoking a session rejects both kinds of tokens
+type Session struct {
+	ID         uint       `json:"-" gorm:"primary_key"`
+	SessionID  string     `json:"session_id" gorm:"column:session_id;unique;not null;size:36"`
+	UserID     uint       `json:"-" gorm:"column:user_id;index"`
+	User       User       `json:"-" gorm:"foreignKey:UserID"`
+	UserAgent  string     `json:"user_agent" gorm:"column:user_agent;size:512"`
+	IPAddress  string     `json:"ip_address" gorm:"column:ip_address;size:45"` // address of the most recent request
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"column:expires_at"`         // moves forward whenever the refresh token is rotated
+	LastSeenAt time.Time  `json:"last_seen_at" gorm:"column:last_seen_at"`
+	RevokedAt  *time.Time `json:"-" gorm:"column:revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+func (session *Session) BeforeCreate(tx *gorm.DB) (err error) {
+	now := time.Now()
+	session.CreatedAt = now
+	session.LastSeenAt = now
+	return nil
+}
+
+// IsActive reports whether tokens of the session are still accepted
+func (session *Session) IsActive() bool {
+	return session.RevokedAt == nil && time.Now().Before(session.ExpiresAt)
+}
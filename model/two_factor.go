@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// TwoFactorAuth holds the TOTP secret of a user. The secret is pending until
+// the user confirms the enrollment with a valid code
+type TwoFactorAuth struct {
+	ID           uint       `json:"-" gorm:"primary_key"`
+	UserID       uint       `json:"-" gorm:"column:user_id;unique;not null"`
+	Secret       string     `json:"-" gorm:"column:secret;not null;size:64"`
+	EnabledAt    *time.Time `json:"enabled_at" gorm:"column:enabled_at"`
+	LastUsedStep int64      `json:"-" gorm:"column:last_used_step;not null"` // codes of this time step or earlier are rejected
+	CreatedAt    time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+func (twoFactorAuth *TwoFactorAuth) BeforeCreate(tx *gorm.DB) (err error) {
+	twoFactorAuth.CreatedAt = time.Now()
+	return nil
+}
+
+// IsEnabled reports whether the enrollment has been confirmed
+func (twoFactorAuth *TwoFactorAuth) IsEnabled() bool {
+	return twoFactorAuth.EnabledAt != nil
+}
+
+// RecoveryCode is a single-use code that replaces a TOTP code when the
+// authenticator is lost. Only the SHA-256 hash of the code is stored
+type RecoveryCode struct {
+	ID        uint       `json:"-" gorm:"primary_key"`
+	UserID    uint       `json:"-" gorm:"column:user_id;index"`
+	CodeHash  string     `json:"-" gorm:"column:code_hash;unique;not null;size:64"`
+	UsedAt    *time.Time `json:"used_at" gorm:"column:used_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+func (recoveryCode *RecoveryCode) BeforeCreate(tx *gorm.DB) (err error) {
+	recoveryCode.CreatedAt = time.Now()
+	return nil
+}
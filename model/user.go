@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,17 +16,18 @@ const (
 )
 
 type User struct {
-	ID        uint      `json:"-" gorm:"primary_key"`
-	Email     string    `gorm:"column:email;unique"`
-	Password  string    `json:"-" gorm:"column:password;not null"`
-	FirstName string    `json:"first_name" gorm:"column:first_name;not null;size:255"`
-	LastName  string    `json:"last_name" gorm:"column:last_name;not null;size:255"`
-	IsDeleted bool      `json:"-" gorm:"column:is_deleted;default:false"`
-	Currency  string    `json:"user_currency" gorm:"column:user_currency;not null;size:3"`
-	UserID    string    `json:"user_id" gorm:"column:user_guid;not null;unique"`
-	Role      Role      `json:"user_role" gorm:"column:role;not null"` // user or admin
-	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
-	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at"`
+	ID         uint       `json:"-" gorm:"primary_key"`
+	Email      string     `gorm:"column:email;unique"`
+	Password   string     `json:"-" gorm:"column:password;not null"`
+	FirstName  string     `json:"first_name" gorm:"column:first_name;not null;size:255"`
+	LastName   string     `json:"last_name" gorm:"column:last_name;not null;size:255"`
+	IsDeleted  bool       `json:"-" gorm:"column:is_deleted;default:false"`
+	Currency   string     `json:"user_currency" gorm:"column:user_currency;not null;size:3"`
+	UserID     string     `json:"user_id" gorm:"column:user_guid;not null;unique"`
+	Role       Role       `json:"user_role" gorm:"column:role;not null"` // user or admin
+	VerifiedAt *time.Time `json:"verified_at" gorm:"column:verified_at"` // set once the email address is confirmed
+	CreatedAt  time.Time  `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"column:updated_at"`
 }
 
 func (user *User) BeforeCreate(tx *gorm.DB) (err error) {
@@ -43,3 +45,13 @@ func (user *User) BeforeUpdate(tx *gorm.DB) (err error) {
 	user.UpdatedAt = now
 	return nil
 }
+
+// IsVerified reports whether the user has confirmed their email address
+func (user *User) IsVerified() bool {
+	return user.VerifiedAt != nil
+}
+
+// IsErased reports whether the personal data of the user has been erased
+func (user *User) IsErased() bool {
+	return strings.HasSuffix(user.Email, "@"+ERASED_EMAIL_DOMAIN)
+}
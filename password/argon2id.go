@@ -0,0 +1,113 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// The defaults follow the second recommended option of RFC 9106
+const (
+	DEFAULT_ARGON2_MEMORY      = 64 * 1024 // KiB
+	DEFAULT_ARGON2_ITERATIONS  = 3
+	DEFAULT_ARGON2_PARALLELISM = 2
+	ARGON2_SALT_LENGTH         = 16
+	ARGON2_KEY_LENGTH          = 32
+)
+
+// Argon2idHasher hashes passwords with argon2id into the PHC string format,
+// e.g. $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+type Argon2idHasher struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// NewArgon2idHasher returns an argon2id hasher with the default parameters
+func NewArgon2idHasher() *Argon2idHasher {
+	return &Argon2idHasher{
+		Memory:      DEFAULT_ARGON2_MEMORY,
+		Iterations:  DEFAULT_ARGON2_ITERATIONS,
+		Parallelism: DEFAULT_ARGON2_PARALLELISM,
+	}
+}
+
+// argon2idHash is a decoded argon2id hash
+type argon2idHash struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (hasher *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, ARGON2_SALT_LENGTH)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, hasher.Iterations, hasher.Memory, hasher.Parallelism, ARGON2_KEY_LENGTH)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", ARGON2ID_ALGORITHM, argon2.Version,
+		hasher.Memory, hasher.Iterations, hasher.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (hasher *Argon2idHasher) Verify(encodedHash, password string) (bool, error) {
+	decoded, err := decodeArgon2idHash(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	key := argon2.IDKey([]byte(password), decoded.salt, decoded.iterations, decoded.memory, decoded.parallelism, uint32(len(decoded.key)))
+	return subtle.ConstantTimeCompare(key, decoded.key) == 1, nil
+}
+
+func (hasher *Argon2idHasher) Identifies(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$"+ARGON2ID_ALGORITHM+"$")
+}
+
+func (hasher *Argon2idHasher) NeedsRehash(encodedHash string) bool {
+	decoded, err := decodeArgon2idHash(encodedHash)
+	if err != nil {
+		return true
+	}
+	return decoded.memory != hasher.Memory || decoded.iterations != hasher.Iterations ||
+		decoded.parallelism != hasher.Parallelism || len(decoded.key) != ARGON2_KEY_LENGTH
+}
+
+// decodeArgon2idHash parses a hash in the PHC string format
+func decodeArgon2idHash(encodedHash string) (*argon2idHash, error) {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != ARGON2ID_ALGORITHM {
+		return nil, ErrUnknownHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+
+	decoded := &argon2idHash{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &decoded.memory, &decoded.iterations, &decoded.parallelism); err != nil {
+		return nil, fmt.Errorf("invalid argon2 parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("invalid argon2 key: %w", err)
+	}
+
+	decoded.salt = salt
+	decoded.key = key
+	return decoded, nil
+}
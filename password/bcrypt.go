@@ -0,0 +1,56 @@
+package password
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	DEFAULT_BCRYPT_COST = bcrypt.DefaultCost
+	// BCRYPT_MAX_LENGTH is the number of bytes of a password bcrypt reads
+	BCRYPT_MAX_LENGTH = 72
+)
+
+// BcryptHasher hashes passwords with bcrypt, whose hashes carry their cost
+type BcryptHasher struct {
+	Cost int
+}
+
+// NewBcryptHasher returns a bcrypt hasher with the cost clamped to the range bcrypt accepts
+func NewBcryptHasher(cost int) *BcryptHasher {
+	cost = max(cost, bcrypt.MinCost)
+	cost = min(cost, bcrypt.MaxCost)
+	return &BcryptHasher{Cost: cost}
+}
+
+func (hasher *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), hasher.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (hasher *BcryptHasher) Verify(encodedHash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (hasher *BcryptHasher) Identifies(encodedHash string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(encodedHash, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (hasher *BcryptHasher) NeedsRehash(encodedHash string) bool {
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	return err != nil || cost != hasher.Cost
+}
@@ -0,0 +1,108 @@
+// Package password hashes user passwords and checks them against the
+// password policy. It does not depend on config or util because config hashes
+// the password of the seeded admin user and util depends on config
+package password
+
+import (
+	"errors"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+)
+
+const (
+	ARGON2ID_ALGORITHM = "argon2id"
+	BCRYPT_ALGORITHM   = "bcrypt"
+	// MAX_UINT32 is the largest uint32 environment value that also fits into an int
+	MAX_UINT32 = min(math.MaxUint32, math.MaxInt)
+)
+
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// Hasher turns passwords into encoded hashes that identify the algorithm and
+// the parameters they were made with
+type Hasher interface {
+	Hash(password string) (string, error)
+	Verify(encodedHash, password string) (bool, error)
+	// Identifies reports whether encodedHash was made by this algorithm
+	Identifies(encodedHash string) bool
+	// NeedsRehash reports whether encodedHash was made with other parameters than the hasher's
+	NeedsRehash(encodedHash string) bool
+}
+
+// NewHasherFromEnv returns the hasher selected by PASSWORD_HASH_ALGORITHM,
+// argon2id unless bcrypt is asked for
+func NewHasherFromEnv() Hasher {
+	algorithm := os.Getenv("PASSWORD_HASH_ALGORITHM")
+	switch algorithm {
+	case BCRYPT_ALGORITHM:
+		return NewBcryptHasher(intEnv("BCRYPT_COST", DEFAULT_BCRYPT_COST))
+	case "", ARGON2ID_ALGORITHM:
+		// argon2 needs at least one pass and 8 KiB of memory per lane
+		parallelism := boundedIntEnv("ARGON2_PARALLELISM", DEFAULT_ARGON2_PARALLELISM, 1, math.MaxUint8)
+		return &Argon2idHasher{
+			Memory:      uint32(boundedIntEnv("ARGON2_MEMORY", DEFAULT_ARGON2_MEMORY, 8*parallelism, MAX_UINT32)),
+			Iterations:  uint32(boundedIntEnv("ARGON2_ITERATIONS", DEFAULT_ARGON2_ITERATIONS, 1, MAX_UINT32)),
+			Parallelism: uint8(parallelism),
+		}
+	default:
+		log.Printf("Unknown password hash algorithm %q, using %s", algorithm, ARGON2ID_ALGORITHM)
+		return NewArgon2idHasher()
+	}
+}
+
+// defaultHasher is the hasher new passwords are hashed with
+var defaultHasher = sync.OnceValue(NewHasherFromEnv)
+
+// knownHashers verify hashes made by an algorithm that is no longer configured
+var knownHashers = []Hasher{NewArgon2idHasher(), NewBcryptHasher(DEFAULT_BCRYPT_COST)}
+
+// Hash hashes a password with the configured algorithm
+func Hash(password string) (string, error) {
+	return defaultHasher().Hash(password)
+}
+
+// Verify checks a password against an encoded hash of any supported
+// algorithm. needsRehash is set when the password matches but the hash was
+// made with another algorithm or other parameters than the configured ones,
+// so that the caller can store a fresh hash while it knows the password
+func Verify(encodedHash, password string) (matches, needsRehash bool) {
+	configured := defaultHasher()
+	for _, hasher := range append([]Hasher{configured}, knownHashers...) {
+		if !hasher.Identifies(encodedHash) {
+			continue
+		}
+
+		matches, err := hasher.Verify(encodedHash, password)
+		if err != nil || !matches {
+			return false, false
+		}
+		return true, !configured.Identifies(encodedHash) || configured.NeedsRehash(encodedHash)
+	}
+	return false, false
+}
+
+// intEnv reads a positive integer from the environment and falls back to the
+// provided default when it is not set or is invalid
+func intEnv(key string, fallback int) int {
+	return boundedIntEnv(key, fallback, 1, math.MaxInt)
+}
+
+// boundedIntEnv reads an integer from the environment and falls back to the
+// provided default when it is not set, is invalid or is outside of
+// [minimum, maximum]
+func boundedIntEnv(key string, fallback, minimum, maximum int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < minimum || parsed > maximum {
+		log.Printf("Invalid integer %q for environment variable %s, using %d", value, key, fallback)
+		return fallback
+	}
+	return parsed
+}
@@ -0,0 +1,116 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2idHasher keeps the tests fast
+func testArgon2idHasher() *Argon2idHasher {
+	return &Argon2idHasher{Memory: 1024, Iterations: 1, Parallelism: 1}
+}
+
+func TestArgon2idHasher(t *testing.T) {
+	hasher := testArgon2idHasher()
+
+	hash, err := hasher.Hash("correct-horse-battery")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"), "The hash should name its algorithm and parameters")
+	assert.True(t, hasher.Identifies(hash))
+
+	matches, err := hasher.Verify(hash, "correct-horse-battery")
+	assert.NoError(t, err)
+	assert.True(t, matches)
+
+	matches, err = hasher.Verify(hash, "wrong-password")
+	assert.NoError(t, err)
+	assert.False(t, matches)
+
+	other, err := hasher.Hash("correct-horse-battery")
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, other, "Hashes should be salted")
+
+	assert.False(t, hasher.NeedsRehash(hash))
+	assert.True(t, (&Argon2idHasher{Memory: 2048, Iterations: 1, Parallelism: 1}).NeedsRehash(hash))
+}
+
+func TestArgon2idHasherRejectsMalformedHashes(t *testing.T) {
+	hasher := testArgon2idHasher()
+
+	for _, hash := range []string{
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=x,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!!$a2V5",
+	} {
+		_, err := hasher.Verify(hash, "password")
+		assert.Error(t, err, hash)
+	}
+}
+
+func TestBcryptHasher(t *testing.T) {
+	hasher := NewBcryptHasher(bcrypt.MinCost)
+
+	hash, err := hasher.Hash("correct-horse-battery")
+	assert.NoError(t, err)
+	assert.True(t, hasher.Identifies(hash))
+	assert.False(t, testArgon2idHasher().Identifies(hash))
+
+	matches, err := hasher.Verify(hash, "correct-horse-battery")
+	assert.NoError(t, err)
+	assert.True(t, matches)
+
+	matches, err = hasher.Verify(hash, "wrong-password")
+	assert.NoError(t, err)
+	assert.False(t, matches)
+
+	assert.False(t, hasher.NeedsRehash(hash))
+	assert.True(t, NewBcryptHasher(bcrypt.MinCost+1).NeedsRehash(hash), "A changed cost should trigger a rehash")
+	assert.Equal(t, bcrypt.MinCost, NewBcryptHasher(1).Cost, "The cost should be clamped")
+}
+
+// Parameters argon2 cannot use, or that would not fit their type, fall back to the defaults
+func TestNewHasherFromEnvRejectsOutOfRangeArgon2Parameters(t *testing.T) {
+	t.Setenv("PASSWORD_HASH_ALGORITHM", ARGON2ID_ALGORITHM)
+	t.Setenv("ARGON2_PARALLELISM", "256")
+	t.Setenv("ARGON2_MEMORY", "4294967296")
+	t.Setenv("ARGON2_ITERATIONS", "0")
+
+	hasher := NewHasherFromEnv()
+	assert.Equal(t, NewArgon2idHasher(), hasher)
+
+	t.Setenv("ARGON2_PARALLELISM", "4")
+	t.Setenv("ARGON2_MEMORY", "16")
+	t.Setenv("ARGON2_ITERATIONS", "1")
+
+	hasher = NewHasherFromEnv()
+	assert.Equal(t, &Argon2idHasher{Memory: DEFAULT_ARGON2_MEMORY, Iterations: 1, Parallelism: 4}, hasher,
+		"The memory should cover 8 KiB per lane")
+}
+
+// Hashes of every supported algorithm verify, outdated ones ask for a rehash
+func TestVerify(t *testing.T) {
+	current, err := Hash("correct-horse-battery")
+	assert.NoError(t, err)
+
+	matches, needsRehash := Verify(current, "correct-horse-battery")
+	assert.True(t, matches)
+	assert.False(t, needsRehash)
+
+	legacy, err := NewBcryptHasher(bcrypt.MinCost).Hash("correct-horse-battery")
+	assert.NoError(t, err)
+
+	matches, needsRehash = Verify(legacy, "correct-horse-battery")
+	assert.True(t, matches)
+	assert.True(t, needsRehash, "bcrypt hashes should be replaced while argon2id is configured")
+
+	matches, needsRehash = Verify(legacy, "wrong-password")
+	assert.False(t, matches)
+	assert.False(t, needsRehash)
+
+	matches, _ = Verify("plaintext", "plaintext")
+	assert.False(t, matches, "Unknown formats should never match")
+}
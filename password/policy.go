@@ -0,0 +1,107 @@
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	DEFAULT_MIN_LENGTH = 8
+	DEFAULT_MAX_LENGTH = 128
+
+	COMMON_PASSWORD_ERROR = "This password is too common, please choose another one"
+)
+
+// commonPasswords is the list of common passwords that ships with the binary
+//
+//go:embed common_passwords.txt
+var commonPasswords string
+
+// Policy is what a new password has to satisfy
+type Policy struct {
+	MinLength int
+	MaxLength int
+	MaxBytes  int             // limit of the hashing algorithm, 0 when there is none
+	Blocklist map[string]bool // lower-cased passwords that are refused
+}
+
+// NewPolicyFromEnv builds the policy from PASSWORD_MIN_LENGTH,
+// PASSWORD_MAX_LENGTH and PASSWORD_BLOCKLIST_FILE. The file lists one
+// password per line and is added to the built-in list of common passwords.
+// Passwords are limited to 72 bytes when bcrypt is configured, because bcrypt
+// ignores everything after that
+func NewPolicyFromEnv() *Policy {
+	policy := &Policy{
+		MinLength: intEnv("PASSWORD_MIN_LENGTH", DEFAULT_MIN_LENGTH),
+		MaxLength: intEnv("PASSWORD_MAX_LENGTH", DEFAULT_MAX_LENGTH),
+		Blocklist: map[string]bool{},
+	}
+
+	if _, ok := defaultHasher().(*BcryptHasher); ok {
+		policy.MaxBytes = BCRYPT_MAX_LENGTH
+	}
+
+	policy.AddToBlocklist(strings.NewReader(commonPasswords))
+
+	if path := os.Getenv("PASSWORD_BLOCKLIST_FILE"); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Printf("Unable to open password blocklist %s: %v", path, err)
+			return policy
+		}
+		defer file.Close()
+
+		if err := policy.AddToBlocklist(file); err != nil {
+			log.Printf("Unable to read password blocklist %s: %v", path, err)
+		}
+	}
+	return policy
+}
+
+// AddToBlocklist refuses the passwords listed in reader, one per line
+func (policy *Policy) AddToBlocklist(reader io.Reader) error {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			policy.Blocklist[strings.ToLower(line)] = true
+		}
+	}
+	return scanner.Err()
+}
+
+// Validate returns an error describing why password is not acceptable
+func (policy *Policy) Validate(password string) error {
+	length := utf8.RuneCountInString(password)
+	if length < policy.MinLength {
+		return fmt.Errorf("Password must be at least %d characters long", policy.MinLength)
+	}
+
+	if length > policy.MaxLength {
+		return fmt.Errorf("Password must be at most %d characters long", policy.MaxLength)
+	}
+
+	if policy.MaxBytes > 0 && len(password) > policy.MaxBytes {
+		return fmt.Errorf("Password must be at most %d bytes long", policy.MaxBytes)
+	}
+
+	if policy.Blocklist[strings.ToLower(password)] {
+		return errors.New(COMMON_PASSWORD_ERROR)
+	}
+	return nil
+}
+
+// defaultPolicy is loaded on first use, reading the blocklist file only once
+var defaultPolicy = sync.OnceValue(NewPolicyFromEnv)
+
+// Validate checks a new password against the configured policy
+func Validate(password string) error {
+	return defaultPolicy().Validate(password)
+}
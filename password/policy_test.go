@@ -0,0 +1,33 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	policy := &Policy{MinLength: 8, MaxLength: 16, Blocklist: map[string]bool{}}
+	assert.NoError(t, policy.AddToBlocklist(strings.NewReader("# comment\nletmein123\n\n  Dragon123  \n")))
+
+	assert.NoError(t, policy.Validate("correct-horse"))
+	assert.NoError(t, policy.Validate("ñandú-ñandú"), "Length should be counted in characters")
+	assert.ErrorContains(t, policy.Validate("short"), "at least 8")
+	assert.ErrorContains(t, policy.Validate(strings.Repeat("a", 17)), "at most 16")
+	assert.EqualError(t, policy.Validate("LetMeIn123"), COMMON_PASSWORD_ERROR)
+	assert.EqualError(t, policy.Validate("dragon123"), COMMON_PASSWORD_ERROR)
+	assert.False(t, policy.Blocklist["# comment"], "Comments should be skipped")
+}
+
+func TestPolicyMaxBytes(t *testing.T) {
+	policy := &Policy{MinLength: 8, MaxLength: 128, MaxBytes: BCRYPT_MAX_LENGTH, Blocklist: map[string]bool{}}
+
+	assert.NoError(t, policy.Validate(strings.Repeat("a", 72)))
+	assert.ErrorContains(t, policy.Validate(strings.Repeat("é", 40)), "bytes", "bcrypt ignores bytes after the 72nd")
+}
+
+func TestDefaultBlocklist(t *testing.T) {
+	assert.EqualError(t, Validate("Password123"), COMMON_PASSWORD_ERROR)
+	assert.NoError(t, Validate("correct-horse-battery"))
+}
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"errors"
+	"log"
+
+	"github.com/hackdaemon2/instashop/config"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/jinzhu/gorm"
+)
+
+const DEFAULT_MAX_ADDRESSES_PER_USER = 20
+
+// MaxAddressesPerUser returns how many addresses a user may keep in their address book
+func MaxAddressesPerUser() int {
+	return config.GetIntEnv("USER_MAX_ADDRESSES", DEFAULT_MAX_ADDRESSES_PER_USER)
+}
+
+// ListAddresses returns the address book of a user in the order it was filled
+func ListAddresses(db *gorm.DB, user *model.User) ([]model.Address, error) {
+	var addresses []model.Address
+	err := db.Where("user_id = ?", user.ID).Order("id ASC").Find(&addresses).Error
+	return addresses, err
+}
+
+// FindAddress returns an address of the user by its guid
+func FindAddress(db *gorm.DB, user *model.User, addressID string) (*model.Address, error) {
+	var address model.Address
+	err := db.Where("address_guid = ? AND user_id = ?", addressID, user.ID).First(&address).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New(ADDRESS_NOT_FOUND_ERROR)
+		}
+		return nil, err
+	}
+	return &address, nil
+}
+
+// FindDefaultAddresses returns the default shipping and billing addresses of
+// the user. Either is nil when the user has not chosen one
+func FindDefaultAddresses(db *gorm.DB, user *model.User) (shipping, billing *model.Address, err error) {
+	var addresses []model.Address
+	err = db.Where("user_id = ? AND (is_default_shipping = ? OR is_default_billing = ?)", user.ID, true, true).
+		Find(&addresses).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range addresses {
+		if addresses[i].IsDefaultShipping {
+			shipping = &addresses[i]
+		}
+		if addresses[i].IsDefaultBilling {
+			billing = &addresses[i]
+		}
+	}
+	return shipping, billing, nil
+}
+
+// CreateAddress adds an address to the address book of the user. The first
+// address becomes the default shipping and billing address
+func CreateAddress(db *gorm.DB, user *model.User, address *model.Address) error {
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	var count int
+	if err := tx.Model(&model.Address{}).Where("user_id = ?", user.ID).Count(&count).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if count >= MaxAddressesPerUser() {
+		tx.Rollback()
+		return errors.New(ADDRESS_BOOK_FULL_ERROR)
+	}
+
+	address.UserID = user.ID
+	if count == 0 {
+		address.IsDefaultShipping = true
+		address.IsDefaultBilling = true
+	} else if err := clearOtherDefaults(tx, user, address); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Create(address).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return err
+	}
+	return nil
+}
+
+// UpdateAddress saves a changed address of the user. When it becomes a
+// default address the previous default loses that role
+func UpdateAddress(db *gorm.DB, user *model.User, address *model.Address) error {
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := clearOtherDefaults(tx, user, address); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Save(address).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return err
+	}
+	return nil
+}
+
+// DeleteAddress removes an address from the address book of the user. Orders
+// keep their own copy of the address. Deleting a default address leaves the
+// user without that default until they choose another one
+func DeleteAddress(db *gorm.DB, user *model.User, addressID string) error {
+	result := db.Where("address_guid = ? AND user_id = ?", addressID, user.ID).Delete(&model.Address{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New(ADDRESS_NOT_FOUND_ERROR)
+	}
+	return nil
+}
+
+// clearOtherDefaults takes the default roles of the address away from the
+// other addresses of the user
+func clearOtherDefaults(tx *gorm.DB, user *model.User, address *model.Address) error {
+	defaults := []struct {
+		column    string
+		isDefault bool
+	}{
+		{"is_default_shipping", address.IsDefaultShipping},
+		{"is_default_billing", address.IsDefaultBilling},
+	}
+
+	for _, role := range defaults {
+		if !role.isDefault {
+			continue
+		}
+
+		err := tx.Model(&model.Address{}).
+			Where("user_id = ? AND id <> ? AND "+role.column+" = ?", user.ID, address.ID, true).
+			UpdateColumn(role.column, false).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
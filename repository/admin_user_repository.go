@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ACTIVE_USERS      = "active"
+	DEACTIVATED_USERS = "deactivated"
+	ALL_USERS         = "all"
+)
+
+// UserFilter narrows down the users returned by ListUsers
+type UserFilter struct {
+	Search string // matched against the email, first name and last name
+	Role   string
+	Status string // active (default), deactivated or all
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// ListUsers returns a page of users matching filter together with the total number of matches
+func ListUsers(db *gorm.DB, filter UserFilter, page, limit int) ([]*model.User, int, error) {
+	var users []*model.User
+	var totalUsers int
+
+	query := db.Model(&model.User{})
+
+	switch filter.Status {
+	case ALL_USERS:
+	case DEACTIVATED_USERS:
+		query = query.Where("is_deleted = true")
+	default:
+		query = query.Where("is_deleted = false")
+	}
+
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		pattern := "%" + escapeLike(search) + "%"
+		query = query.Where("email LIKE ? OR first_name LIKE ? OR last_name LIKE ?", pattern, pattern, pattern)
+	}
+
+	if err := query.Count(&totalUsers).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	err := query.Order("id ASC").Limit(limit).Offset(offset).Find(&users).Error
+	return users, totalUsers, err
+}
+
+// FindAnyUser finds a user by their guid, including deactivated users
+func FindAnyUser(db *gorm.DB, userID string) (*model.User, error) {
+	var user model.User
+	if err := db.Where("user_guid = ?", userID).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserOrderSummary counts the orders of a user per status and sums what the user spent
+func GetUserOrderSummary(db *gorm.DB, user *model.User) (*model.OrderSummary, error) {
+	rows, err := db.Model(&model.Order{}).
+		Select("order_status, COUNT(*), COALESCE(SUM(total_price), 0), MAX(created_at)").
+		Where("user_id = ? AND is_deleted = false", user.ID).
+		Group("order_status").
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summary := &model.OrderSummary{OrdersByStatus: map[model.OrderStatus]int{}, TotalSpent: decimal.Zero}
+	for rows.Next() {
+		var status model.OrderStatus
+		var count int
+		var total decimal.Decimal
+		var lastOrderAt time.Time
+		if err := rows.Scan(&status, &count, &total, &lastOrderAt); err != nil {
+			return nil, err
+		}
+
+		summary.TotalOrders += count
+		summary.OrdersByStatus[status] = count
+		if status != model.Cancelled {
+			summary.TotalSpent = summary.TotalSpent.Add(total)
+		}
+		if summary.LastOrderAt == nil || lastOrderAt.After(*summary.LastOrderAt) {
+			summary.LastOrderAt = &lastOrderAt
+		}
+	}
+
+	return summary, rows.Err()
+}
+
+// SetUserRole changes the role of a user. Existing tokens carry the old role,
+// so every token of the user is revoked
+func SetUserRole(db *gorm.DB, user *model.User, role model.Role) error {
+	if err := db.Model(user).Update("role", role).Error; err != nil {
+		return err
+	}
+	InvalidateUser(user)
+	return RevokeAllUserTokens(db, user)
+}
+
+// SetUserActive deactivates or reactivates a user. Deactivated users are
+// logged out everywhere and can no longer log in. Erased users stay deactivated
+func SetUserActive(db *gorm.DB, user *model.User, active bool) error {
+	if active && user.IsErased() {
+		return errors.New(USER_ERASED_ERROR)
+	}
+
+	if err := db.Model(user).Update("is_deleted", !active).Error; err != nil {
+		return err
+	}
+	InvalidateUser(user)
+
+	if active {
+		return nil
+	}
+	return RevokeAllUserTokens(db, user)
+}
+
+// ForcePasswordReset replaces the password of a user with an unusable one,
+// logs the user out everywhere and issues a password reset token
+func ForcePasswordReset(db *gorm.DB, user *model.User, unusablePassword string) (string, error) {
+	if user.IsDeleted {
+		return "", errors.New(USER_DEACTIVATED_ERROR)
+	}
+
+	if err := db.Model(user).Update("password", unusablePassword).Error; err != nil {
+		return "", err
+	}
+	InvalidateUser(user)
+
+	if err := RevokeAllUserTokens(db, user); err != nil {
+		return "", err
+	}
+
+	return CreateOneTimeToken(db, user, model.PasswordResetPurpose, PasswordResetTTL(), "")
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	API_KEY_PREFIX = "isk_"
+
+	// the last use of a key is recorded at most once per interval so that
+	// busy integrations do not cause a write on every request
+	API_KEY_LAST_USED_INTERVAL = time.Minute
+)
+
+// CreateAPIKey issues a new API key owned by owner and returns the raw key,
+// which is never stored and cannot be retrieved again
+func CreateAPIKey(db *gorm.DB, owner *model.User, name string, scopes []string, expiresAt *time.Time) (string, *model.APIKey, error) {
+	prefix, err := util.GenerateSecureToken(6)
+	if err != nil {
+		return "", nil, err
+	}
+
+	secret, err := util.GenerateSecureToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	rawKey := API_KEY_PREFIX + prefix + "." + secret
+	apiKey := model.APIKey{
+		Name:      name,
+		Prefix:    API_KEY_PREFIX + prefix,
+		KeyHash:   util.HashToken(rawKey),
+		Scopes:    strings.Join(scopes, " "),
+		OwnerID:   owner.ID,
+		ExpiresAt: expiresAt,
+	}
+
+	if err := db.Create(&apiKey).Error; err != nil {
+		return "", nil, err
+	}
+	return rawKey, &apiKey, nil
+}
+
+// FindActiveAPIKey looks up an API key together with its owner. Unknown,
+// revoked and expired keys are reported as INVALID_API_KEY_ERROR
+func FindActiveAPIKey(db *gorm.DB, rawKey string) (*model.APIKey, error) {
+	var apiKey model.APIKey
+	err := db.Preload("Owner").Where("key_hash = ?", util.HashToken(rawKey)).First(&apiKey).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New(INVALID_API_KEY_ERROR)
+		}
+		return nil, err
+	}
+
+	if !apiKey.IsActive() || apiKey.Owner.IsDeleted {
+		return nil, errors.New(INVALID_API_KEY_ERROR)
+	}
+	return &apiKey, nil
+}
+
+// TouchAPIKey records that the key has just been used
+func TouchAPIKey(db *gorm.DB, apiKey *model.APIKey) error {
+	now := time.Now()
+	if apiKey.LastUsedAt != nil && now.Sub(*apiKey.LastUsedAt) < API_KEY_LAST_USED_INTERVAL {
+		return nil
+	}
+
+	apiKey.LastUsedAt = &now
+	return db.Model(&model.APIKey{}).Where("id = ?", apiKey.ID).UpdateColumn("last_used_at", now).Error
+}
+
+// ListAPIKeys returns every API key, newest first
+func ListAPIKeys(db *gorm.DB) ([]model.APIKey, error) {
+	var apiKeys []model.APIKey
+	err := db.Order("id DESC").Find(&apiKeys).Error
+	return apiKeys, err
+}
+
+// RevokeAPIKey revokes an API key so that it is no longer accepted
+func RevokeAPIKey(db *gorm.DB, id uint) (*model.APIKey, error) {
+	var apiKey model.APIKey
+	if err := db.Where("id = ?", id).First(&apiKey).Error; err != nil {
+		return nil, err
+	}
+
+	if apiKey.RevokedAt != nil {
+		return &apiKey, nil
+	}
+
+	now := time.Now()
+	if err := db.Model(&apiKey).UpdateColumn("revoked_at", now).Error; err != nil {
+		return nil, err
+	}
+	apiKey.RevokedAt = &now
+	return &apiKey, nil
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/jinzhu/gorm"
+)
+
+const MAX_AUTH_EVENT_DETAILS_LENGTH = 255
+
+// AuthEventFilter narrows down the audit log. Empty fields match everything
+type AuthEventFilter struct {
+	UserID string // events about the user or caused by them
+	Email  string // with UserID, also failed logins with this email that matched no account
+	Type   string
+	From   *time.Time
+	To     *time.Time // exclusive
+}
+
+// RecordAuthEvent appends an event to the audit log with the device it came from
+func RecordAuthEvent(db *gorm.DB, event model.AuthEvent, client ClientInfo) error {
+	event.IPAddress = client.IPAddress
+	event.UserAgent = truncateUserAgent(client.UserAgent)
+	if len(event.Details) > MAX_AUTH_EVENT_DETAILS_LENGTH {
+		event.Details = event.Details[:MAX_AUTH_EVENT_DETAILS_LENGTH] // the permissions of a role can be many
+	}
+	return db.Create(&event).Error
+}
+
+// ListAuthEvents returns a page of audit log events matching filter, newest
+// first, together with the total number of matches
+func ListAuthEvents(db *gorm.DB, filter AuthEventFilter, page, limit int) ([]model.AuthEvent, int, error) {
+	var events []model.AuthEvent
+	var totalEvents int
+
+	query := db.Model(&model.AuthEvent{})
+
+	if filter.UserID != "" {
+		if filter.Email != "" {
+			query = query.Where("user_guid = ? OR actor_guid = ? OR (user_guid = '' AND email = ?)", filter.UserID, filter.UserID, filter.Email)
+		} else {
+			query = query.Where("user_guid = ? OR actor_guid = ?", filter.UserID, filter.UserID)
+		}
+	}
+
+	if filter.Type != "" {
+		query = query.Where("event_type = ?", filter.Type)
+	}
+
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	if err := query.Count(&totalEvents).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&events).Error
+	return events, totalEvents, err
+}
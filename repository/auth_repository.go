@@ -4,12 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sync"
 
 	"github.com/hackdaemon2/instashop/model"
-	"github.com/hackdaemon2/instashop/util"
+	"github.com/hackdaemon2/instashop/password"
 
 	"github.com/jinzhu/gorm"
-	"golang.org/x/crypto/bcrypt"
 )
 
 func FindUserBy(db *gorm.DB, id string, value string) (*model.User, error) {
@@ -39,23 +39,62 @@ func RegisterUser(db *gorm.DB, user *model.User) (*model.User, error) {
 	return user, nil
 }
 
-func LoginUser(db *gorm.DB, email, password string) (*util.JwtData, error) {
+// dummyPasswordHash is compared against when no account matches the email,
+// so that unknown emails take as long to reject as wrong passwords
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := password.Hash("instashop-dummy-password")
+	if err != nil {
+		log.Println("unable to generate dummy password hash:", err)
+	}
+	return hash
+})
+
+// PasswordMatches reports whether plainPassword is the user's current password
+func PasswordMatches(user *model.User, plainPassword string) bool {
+	matches, _ := password.Verify(user.Password, plainPassword)
+	return matches
+}
+
+// AuthenticateUser checks the email and password of a user. Unknown emails and
+// wrong passwords do the same amount of work and return the same error. A
+// password hash made with outdated settings is replaced while the password is at hand
+func AuthenticateUser(db *gorm.DB, email, plainPassword string) (*model.User, error) {
 	existingUser, err := FindUserBy(db, "email", email)
-	if err != nil || existingUser == nil {
-		if existingUser == nil {
-			return nil, errors.New("invalid user credentials")
-		}
+	if isNotRecordNotFoundError(err) {
 		return nil, err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(existingUser.Password), []byte(password)); err != nil {
-		return nil, err
+	passwordHash := dummyPasswordHash()
+	if existingUser != nil {
+		passwordHash = existingUser.Password
+	}
+
+	matches, needsRehash := password.Verify(passwordHash, plainPassword)
+	if !matches || existingUser == nil {
+		return nil, errors.New(INVALID_CREDENTIALS_ERROR)
+	}
+
+	if needsRehash {
+		if err := rehashPassword(db, existingUser, plainPassword); err != nil {
+			log.Println("unable to rehash password:", err) // the old hash keeps working
+		}
 	}
 
-	jwtData, err := util.GenerateJWT(existingUser.UserID, existingUser.Role)
+	return existingUser, nil
+}
+
+// rehashPassword stores a hash of the password made with the current settings.
+// The password itself did not change, so tokens are left alone
+func rehashPassword(db *gorm.DB, user *model.User, plainPassword string) error {
+	hash, err := password.Hash(plainPassword)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return &jwtData, nil
+	if err := db.Model(user).UpdateColumn("password", hash).Error; err != nil {
+		return err
+	}
+	user.Password = hash
+	InvalidateUser(user)
+	return nil
 }
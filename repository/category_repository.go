@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"errors"
+	"log"
+	"slices"
+
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/jinzhu/gorm"
+)
+
+// categoryTree is the whole category tree, loaded at once. Catalogs have few
+// enough categories that walking the tree in memory beats recursive queries
+type categoryTree struct {
+	roots  []*model.Category
+	byID   map[uint]*model.Category
+	bySlug map[string]*model.Category
+}
+
+// loadCategoryTree reads every category and links children to their parents.
+// Siblings are ordered by sort order, then name
+func loadCategoryTree(db *gorm.DB) (*categoryTree, error) {
+	var categories []*model.Category
+	if err := db.Order("sort_order ASC, name ASC, id ASC").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	tree := &categoryTree{
+		byID:   make(map[uint]*model.Category, len(categories)),
+		bySlug: make(map[string]*model.Category, len(categories)),
+	}
+	for _, category := range categories {
+		tree.byID[category.ID] = category
+		tree.bySlug[category.Slug] = category
+	}
+
+	for _, category := range categories {
+		parent := tree.parent(category)
+		if parent == nil {
+			tree.roots = append(tree.roots, category)
+			continue
+		}
+		category.ParentSlug = parent.Slug
+		parent.Children = append(parent.Children, category)
+	}
+	return tree, nil
+}
+
+// parent returns the parent of category, nil for top-level categories
+func (tree *categoryTree) parent(category *model.Category) *model.Category {
+	if category.ParentID == nil {
+		return nil
+	}
+	return tree.byID[*category.ParentID]
+}
+
+// descendantIDs returns the ids of category and of all categories below it
+func descendantIDs(category *model.Category) []uint {
+	ids := []uint{category.ID}
+	for _, child := range category.Children {
+		ids = append(ids, descendantIDs(child)...)
+	}
+	return ids
+}
+
+// ListCategoryTree returns the top-level categories with their subcategories
+func ListCategoryTree(db *gorm.DB) ([]*model.Category, error) {
+	tree, err := loadCategoryTree(db)
+	if err != nil {
+		return nil, err
+	}
+	return tree.roots, nil
+}
+
+// FindCategory returns a category by its slug together with its subcategories
+func FindCategory(db *gorm.DB, slug string) (*model.Category, error) {
+	tree, err := loadCategoryTree(db)
+	if err != nil {
+		return nil, err
+	}
+
+	category, ok := tree.bySlug[slug]
+	if !ok {
+		return nil, errors.New(CATEGORY_NOT_FOUND_ERROR)
+	}
+	return category, nil
+}
+
+// CategoryProductFilter narrows filter down to the products of category and
+// of all its subcategories
+func CategoryProductFilter(category *model.Category, filter ProductFilter) ProductFilter {
+	filter.CategoryIDs = descendantIDs(category)
+	return filter
+}
+
+// SaveCategory creates a category or saves the changes made to an existing
+// one. The parent is given by category.ParentSlug, empty for a top-level
+// category. A category cannot be moved below one of its own subcategories
+func SaveCategory(db *gorm.DB, category *model.Category) error {
+	tree, err := loadCategoryTree(db)
+	if err != nil {
+		return err
+	}
+
+	if other, ok := tree.bySlug[category.Slug]; ok && other.ID != category.ID {
+		return errors.New(CATEGORY_SLUG_TAKEN_ERROR)
+	}
+
+	category.ParentID = nil
+	if category.ParentSlug != "" {
+		parent, ok := tree.bySlug[category.ParentSlug]
+		if !ok {
+			return errors.New(PARENT_CATEGORY_NOT_FOUND_ERROR)
+		}
+
+		for ancestor := parent; ancestor != nil; ancestor = tree.parent(ancestor) {
+			if category.ID != 0 && ancestor.ID == category.ID {
+				return errors.New(CATEGORY_CYCLE_ERROR)
+			}
+		}
+		category.ParentID = &parent.ID
+	}
+
+	return db.Save(category).Error
+}
+
+// DeleteCategory removes a category without subcategories. Its products stay
+// in the catalog and only lose the category
+func DeleteCategory(db *gorm.DB, category *model.Category) error {
+	if len(category.Children) > 0 {
+		return errors.New(CATEGORY_HAS_CHILDREN_ERROR)
+	}
+
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := tx.Exec("DELETE FROM product_categories WHERE category_id = ?", category.ID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Delete(category).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return err
+	}
+	return nil
+}
+
+// SetProductCategories replaces the categories of a product by the ones with
+// the given slugs. No slugs takes the product out of every category
+func SetProductCategories(db *gorm.DB, product *model.Product, slugs []string) error {
+	categories := []model.Category{}
+	if len(slugs) > 0 {
+		if err := db.Where("slug IN (?)", slugs).Order("sort_order ASC, name ASC, id ASC").Find(&categories).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, slug := range slugs {
+		if !slices.ContainsFunc(categories, func(category model.Category) bool { return category.Slug == slug }) {
+			return errors.New(UNKNOWN_CATEGORY_ERROR)
+		}
+	}
+
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := tx.Model(product).Association("Categories").Replace(categories).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return err
+	}
+
+	product.Categories = categories
+	return nil
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hackdaemon2/instashop/config"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	ACCOUNT_ATTEMPT_PREFIX = "account:"
+	IP_ATTEMPT_PREFIX      = "ip:"
+
+	DEFAULT_LOGIN_MAX_FAILURES    = 5
+	DEFAULT_LOGIN_MAX_IP_FAILURES = 20
+	DEFAULT_LOGIN_FAILURE_WINDOW  = 15 * time.Minute
+	DEFAULT_LOGIN_LOCKOUT         = 15 * time.Minute
+	DEFAULT_LOGIN_DELAY_BASE      = time.Second
+	DEFAULT_LOGIN_DELAY_MAX       = 30 * time.Second
+)
+
+// loginThrottlePolicy describes how many failures a key may accumulate
+// within a window before it is locked out
+type loginThrottlePolicy struct {
+	maxFailures int
+	window      time.Duration
+	lockout     time.Duration
+}
+
+func accountAttemptKey(email string) string {
+	return ACCOUNT_ATTEMPT_PREFIX + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipAttemptKey(ip string) string {
+	return IP_ATTEMPT_PREFIX + ip
+}
+
+func policyFor(key string) loginThrottlePolicy {
+	maxFailures := config.GetIntEnv("LOGIN_MAX_FAILURES", DEFAULT_LOGIN_MAX_FAILURES)
+	if strings.HasPrefix(key, IP_ATTEMPT_PREFIX) {
+		maxFailures = config.GetIntEnv("LOGIN_MAX_IP_FAILURES", DEFAULT_LOGIN_MAX_IP_FAILURES)
+	}
+
+	return loginThrottlePolicy{
+		maxFailures: maxFailures,
+		window:      config.GetDurationEnv("LOGIN_FAILURE_WINDOW", DEFAULT_LOGIN_FAILURE_WINDOW),
+		lockout:     config.GetDurationEnv("LOGIN_LOCKOUT_DURATION", DEFAULT_LOGIN_LOCKOUT),
+	}
+}
+
+// progressiveDelay returns how long a client has to wait after its latest
+// failure. The delay doubles with every failure up to LOGIN_DELAY_MAX
+func progressiveDelay(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+
+	base := config.GetDurationEnv("LOGIN_DELAY_BASE", DEFAULT_LOGIN_DELAY_BASE)
+	maxDelay := config.GetDurationEnv("LOGIN_DELAY_MAX", DEFAULT_LOGIN_DELAY_MAX)
+
+	delay := base
+	for i := 1; i < failures && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+// retryAfter returns how long the key has to wait before another attempt is accepted
+func retryAfter(loginAttempt model.LoginAttempt, now time.Time) time.Duration {
+	if loginAttempt.IsLocked() {
+		return loginAttempt.LockedUntil.Sub(now)
+	}
+
+	if now.Sub(loginAttempt.WindowStartedAt) > policyFor(loginAttempt.AttemptKey).window {
+		return 0 // the failures have aged out
+	}
+
+	nextAttempt := loginAttempt.LastFailedAt.Add(progressiveDelay(loginAttempt.Failures))
+	if now.Before(nextAttempt) {
+		return nextAttempt.Sub(now)
+	}
+	return 0
+}
+
+// CheckLoginAllowed returns an error together with the time the client has to
+// wait when the account or the client IP is locked out or is still inside the
+// progressive delay that follows a failed attempt
+func CheckLoginAllowed(db *gorm.DB, email, ip string) (time.Duration, error) {
+	var loginAttempts []model.LoginAttempt
+	keys := []string{accountAttemptKey(email), ipAttemptKey(ip)}
+	if err := db.Where("attempt_key IN (?)", keys).Find(&loginAttempts).Error; err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for _, loginAttempt := range loginAttempts {
+		wait = max(wait, retryAfter(loginAttempt, now))
+	}
+
+	if wait > 0 {
+		return wait, errors.New(TOO_MANY_LOGIN_ATTEMPTS_ERROR)
+	}
+	return 0, nil
+}
+
+// RecordLoginFailure counts a failed attempt against both the account and
+// the client IP and locks either of them once it exceeds its policy
+func RecordLoginFailure(db *gorm.DB, email, ip string) error {
+	for _, key := range []string{accountAttemptKey(email), ipAttemptKey(ip)} {
+		if err := recordFailure(db, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func recordFailure(db *gorm.DB, key string) error {
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	now := time.Now()
+	policy := policyFor(key)
+
+	var loginAttempt model.LoginAttempt
+	err := tx.Set("gorm:query_option", "FOR UPDATE").Where("attempt_key = ?", key).First(&loginAttempt).Error
+	if isNotRecordNotFoundError(err) {
+		tx.Rollback()
+		return err
+	}
+
+	if loginAttempt.ID == 0 || now.Sub(loginAttempt.WindowStartedAt) > policy.window {
+		loginAttempt.AttemptKey = key
+		loginAttempt.Failures = 0
+		loginAttempt.WindowStartedAt = now
+		loginAttempt.LockedUntil = nil
+	}
+
+	loginAttempt.Failures++
+	loginAttempt.LastFailedAt = now
+
+	if loginAttempt.Failures >= policy.maxFailures {
+		lockedUntil := now.Add(policy.lockout)
+		loginAttempt.LockedUntil = &lockedUntil
+		log.Printf("login locked for %s until %s after %d failures", key, lockedUntil.Format(time.RFC3339), loginAttempt.Failures)
+	}
+
+	if err := tx.Save(&loginAttempt).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// ClearLoginFailures forgets the failures of an account after a successful login.
+// IP failures are kept so that one valid account cannot reset the IP counter
+func ClearLoginFailures(db *gorm.DB, email string) error {
+	return db.Where("attempt_key = ?", accountAttemptKey(email)).Delete(&model.LoginAttempt{}).Error
+}
+
+// UnlockAccount lifts a lockout on an account and forgets its failed attempts
+func UnlockAccount(db *gorm.DB, user *model.User) error {
+	return ClearLoginFailures(db, user.Email)
+}
+
+func isNotRecordNotFoundError(err error) bool {
+	return err != nil && !gorm.IsRecordNotFoundError(err)
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/hackdaemon2/instashop/config"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	DEFAULT_OIDC_LOGIN_STATE_TTL = 10 * time.Minute
+	DEFAULT_OIDC_USER_CURRENCY   = "NGN"
+	OIDC_STATE_SIZE              = 32
+)
+
+// OIDCLoginStateTTL returns how long a user may take to log in at the provider
+func OIDCLoginStateTTL() time.Duration {
+	return config.GetDurationEnv("OIDC_LOGIN_STATE_TTL", DEFAULT_OIDC_LOGIN_STATE_TTL)
+}
+
+// OIDCUserCurrency returns the currency of users created by an OIDC login
+func OIDCUserCurrency() string {
+	if currency := os.Getenv("OIDC_DEFAULT_CURRENCY"); currency != "" {
+		return currency
+	}
+	return DEFAULT_OIDC_USER_CURRENCY
+}
+
+// CreateOIDCLoginState stores a new state, nonce and PKCE code verifier for an
+// authorization request and returns the raw state to send to the provider.
+// Expired states of abandoned logins are removed on the way
+func CreateOIDCLoginState(db *gorm.DB) (string, *model.OIDCLoginState, error) {
+	rawState, err := util.GenerateSecureToken(OIDC_STATE_SIZE)
+	if err != nil {
+		return "", nil, err
+	}
+
+	nonce, err := util.GenerateSecureToken(OIDC_STATE_SIZE)
+	if err != nil {
+		return "", nil, err
+	}
+
+	codeVerifier, err := util.GeneratePKCEVerifier()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := db.Where("expires_at < ?", time.Now()).Delete(&model.OIDCLoginState{}).Error; err != nil {
+		return "", nil, err
+	}
+
+	loginState := model.OIDCLoginState{
+		StateHash:    util.HashToken(rawState),
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(OIDCLoginStateTTL()),
+	}
+
+	if err := db.Create(&loginState).Error; err != nil {
+		return "", nil, err
+	}
+
+	return rawState, &loginState, nil
+}
+
+// ConsumeOIDCLoginState deletes and returns the login state matching the
+// state parameter of a callback. The conditional delete makes sure a state
+// can only ever be used once
+func ConsumeOIDCLoginState(db *gorm.DB, rawState string) (*model.OIDCLoginState, error) {
+	var loginState model.OIDCLoginState
+	err := db.Where("state_hash = ?", util.HashToken(rawState)).First(&loginState).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New(INVALID_OIDC_STATE_ERROR)
+		}
+		return nil, err
+	}
+
+	result := db.Where("id = ?", loginState.ID).Delete(&model.OIDCLoginState{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 || loginState.IsExpired() {
+		return nil, errors.New(INVALID_OIDC_STATE_ERROR)
+	}
+
+	return &loginState, nil
+}
+
+// FindOrCreateOIDCUser returns the user linked to a provider account. An
+// account that is not linked yet is linked to the user with the same verified
+// email address, or to a new user when nobody uses the address. New users get
+// the unusable password, so they can only log in through the provider until
+// they reset it
+func FindOrCreateOIDCUser(db *gorm.DB, claims *util.OIDCClaims, unusablePassword string) (*model.User, error) {
+	var identity model.UserIdentity
+	err := db.Preload("User").Where("issuer = ? AND subject = ?", claims.Issuer, claims.Subject).First(&identity).Error
+	if err == nil {
+		if identity.User.IsDeleted {
+			return nil, errors.New(USER_DEACTIVATED_ERROR)
+		}
+		return &identity.User, nil
+	}
+
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errors.New(OIDC_EMAIL_NOT_VERIFIED_ERROR)
+	}
+
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	user, err := findOrCreateUserByEmail(tx, claims, unusablePassword)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	identity = model.UserIdentity{UserID: user.ID, Issuer: claims.Issuer, Subject: claims.Subject, Email: claims.Email}
+	if err := tx.Create(&identity).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return nil, err
+	}
+	InvalidateUser(user)
+
+	return user, nil
+}
+
+// findOrCreateUserByEmail returns the user registered with the email claim,
+// marking the address as verified since the provider vouched for it, or
+// creates a new user for it
+func findOrCreateUserByEmail(tx *gorm.DB, claims *util.OIDCClaims, unusablePassword string) (*model.User, error) {
+	var user model.User
+	err := tx.Where("email = ?", claims.Email).First(&user).Error
+	if err == nil {
+		if user.IsDeleted {
+			return nil, errors.New(USER_DEACTIVATED_ERROR)
+		}
+
+		if !user.IsVerified() {
+			if err := tx.Model(&user).Update("verified_at", time.Now()).Error; err != nil {
+				return nil, err
+			}
+		}
+		return &user, nil
+	}
+
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+
+	now := time.Now()
+	user = model.User{
+		Email:      claims.Email,
+		Password:   unusablePassword,
+		FirstName:  claims.GivenName,
+		LastName:   claims.FamilyName,
+		Currency:   OIDCUserCurrency(),
+		Role:       model.UserRole,
+		VerifiedAt: &now,
+	}
+
+	if err := tx.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
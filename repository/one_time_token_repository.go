@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const ONE_TIME_TOKEN_SIZE = 32
+
+// CreateOneTimeToken issues a new token for the given purpose and invalidates
+// any token previously issued to the user for the same purpose. The payload is
+// stored with the token and returned when it is redeemed
+func CreateOneTimeToken(db *gorm.DB, user *model.User, purpose model.TokenPurpose, ttl time.Duration, payload string) (string, error) {
+	rawToken, err := util.GenerateSecureToken(ONE_TIME_TOKEN_SIZE)
+	if err != nil {
+		return "", err
+	}
+
+	err = db.Model(&model.OneTimeToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, purpose).
+		Update("used_at", time.Now()).Error
+	if err != nil {
+		return "", err
+	}
+
+	oneTimeToken := model.OneTimeToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: util.HashToken(rawToken),
+		Payload:   payload,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	if err := db.Create(&oneTimeToken).Error; err != nil {
+		return "", err
+	}
+
+	return rawToken, nil
+}
+
+// RedeemOneTimeToken marks an unused, unexpired token as used and returns it.
+// The conditional update makes sure a token can only ever be redeemed once
+func RedeemOneTimeToken(db *gorm.DB, rawToken string, purpose model.TokenPurpose) (*model.OneTimeToken, error) {
+	var oneTimeToken model.OneTimeToken
+	err := db.Where("token_hash = ? AND purpose = ?", util.HashToken(rawToken), purpose).First(&oneTimeToken).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New(INVALID_ONE_TIME_TOKEN_ERROR)
+		}
+		return nil, err
+	}
+
+	if oneTimeToken.UsedAt != nil || oneTimeToken.IsExpired() {
+		return nil, errors.New(INVALID_ONE_TIME_TOKEN_ERROR)
+	}
+
+	result := db.Model(&model.OneTimeToken{}).
+		Where("id = ? AND used_at IS NULL", oneTimeToken.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, errors.New(INVALID_ONE_TIME_TOKEN_ERROR)
+	}
+
+	return &oneTimeToken, nil
+}
@@ -1,26 +1,86 @@
 package repository
 
 import (
+	"errors"
+	"log"
+
 	"github.com/hackdaemon2/instashop/model"
 	"github.com/jinzhu/gorm"
 )
 
 const IS_DELETED_CLAUSE = "is_deleted = ?"
 
+// preloadOrderItems loads the products and variants of orders. Deleted
+// variants are loaded too, an order has to tell which variant was bought
+func preloadOrderItems(db *gorm.DB) *gorm.DB {
+	return db.Preload("Products", IS_DELETED_CLAUSE, false).Preload("Variants.Options")
+}
+
 func FindOrder(db *gorm.DB, orderReference string) (*model.Order, error) {
 	var order model.Order
 	query := "order_reference = ? AND is_deleted = false"
-	err := db.Where(query, orderReference).Preload("Products", IS_DELETED_CLAUSE, false).Find(&order).Error
+	err := preloadOrderItems(db.Where(query, orderReference)).Find(&order).Error
 	return &order, err
 }
 
-func CreateOrder(db *gorm.DB, order model.Order) (*model.Order, error) {
-	if err := db.Create(&order).Error; err != nil { // Create the order
+// StockReservation is the quantity of a product, or of one of its variants,
+// taken out of stock by an order
+type StockReservation struct {
+	ProductID uint
+	VariantID uint // zero for products without variants
+	Quantity  uint
+}
+
+// CreateOrder saves order and takes its items out of stock in one
+// transaction. Stock is only taken while enough is left, so the order fails
+// with OUT_OF_STOCK_ERROR when an item sold out since it was checked
+func CreateOrder(db *gorm.DB, order model.Order, reservations []StockReservation) (*model.Order, error) {
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	for _, reservation := range reservations {
+		if err := reserveStock(tx, reservation); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Create(&order).Error; err != nil { // Create the order
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
 		return nil, err
 	}
 	return &order, nil
 }
 
+// reserveStock takes the quantity of reservation out of stock, or fails
+// with OUT_OF_STOCK_ERROR when not enough is left
+func reserveStock(tx *gorm.DB, reservation StockReservation) error {
+	query := tx.Model(&model.Product{}).Where("id = ? AND stock >= ?", reservation.ProductID, reservation.Quantity)
+	if reservation.VariantID != 0 {
+		query = tx.Model(&model.ProductVariant{}).Where("id = ? AND stock >= ?", reservation.VariantID, reservation.Quantity)
+	}
+
+	result := query.UpdateColumn("stock", gorm.Expr("stock - ?", reservation.Quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New(OUT_OF_STOCK_ERROR)
+	}
+	return nil
+}
+
 func UpdateOrder(db *gorm.DB, order model.Order) (*model.Order, error) {
 	// Update order fields
 	tx := db.Begin()
@@ -31,7 +91,7 @@ func UpdateOrder(db *gorm.DB, order model.Order) (*model.Order, error) {
 		}
 	}()
 
-	if err := tx.Model(&order).Preload("Products", IS_DELETED_CLAUSE, false).Save(&order).Error; err != nil {
+	if err := preloadOrderItems(tx.Model(&order)).Save(&order).Error; err != nil {
 		tx.Rollback()
 		return nil, err
 	}
@@ -48,7 +108,7 @@ func UpdateOrder(db *gorm.DB, order model.Order) (*model.Order, error) {
 func GetUserOrder(db *gorm.DB, userID, orderReference string) (*model.Order, error) {
 	var orders model.Order
 	query := "user_id = ? AND order_reference = ? AND is_deleted = false"
-	err := db.Where(query, userID, orderReference).Preload("Products", IS_DELETED_CLAUSE, false).Find(&orders).Error
+	err := preloadOrderItems(db.Where(query, userID, orderReference)).Find(&orders).Error
 	return &orders, err
 }
 
@@ -68,7 +128,7 @@ func GetUserOrders(db *gorm.DB, userID, orderStatus string, page, limit int) ([]
 	}
 
 	offset := (page - 1) * limit
-	err = query.Preload("Products", IS_DELETED_CLAUSE, false).Limit(limit).Offset(offset).Find(&orders).Error
+	err = preloadOrderItems(query).Limit(limit).Offset(offset).Find(&orders).Error
 
 	return orders, totalOrders, err
 }
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/hackdaemon2/instashop/config"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/jinzhu/gorm"
+)
+
+const DEFAULT_PASSWORD_RESET_TTL = time.Hour
+
+// PasswordResetTTL returns how long a password reset token stays valid
+func PasswordResetTTL() time.Duration {
+	return config.GetDurationEnv("PASSWORD_RESET_TOKEN_TTL", DEFAULT_PASSWORD_RESET_TTL)
+}
+
+// CreatePasswordResetToken issues a reset token for the account registered
+// with email. A nil user is returned without error when no such account exists
+func CreatePasswordResetToken(db *gorm.DB, email string) (*model.User, string, error) {
+	user, err := FindUserBy(db, "email", email)
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+
+	rawToken, err := CreateOneTimeToken(db, user, model.PasswordResetPurpose, PasswordResetTTL(), "")
+	if err != nil {
+		return nil, "", err
+	}
+
+	return user, rawToken, nil
+}
+
+// ResetPassword redeems a reset token, stores the new password hash and
+// revokes every token issued to the user before the reset
+func ResetPassword(db *gorm.DB, rawToken, hashedPassword string) (*model.User, error) {
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	oneTimeToken, err := RedeemOneTimeToken(tx, rawToken, model.PasswordResetPurpose)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	user, err := FindUserBy(tx, "id", strconv.Itoa(int(oneTimeToken.UserID)))
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Model(user).Update("password", hashedPassword).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return nil, err
+	}
+	InvalidateUser(user)
+
+	if err := RevokeAllUserTokens(db, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
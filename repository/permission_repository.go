@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/hackdaemon2/instashop/config"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const DEFAULT_PERMISSION_CACHE_TTL = time.Minute
+
+// rolePermissionCache remembers the permissions of each role so that
+// authorizing a request does not need a database round trip
+var rolePermissionCache = util.NewCache[string, []string]()
+
+func permissionCacheTTL() time.Duration {
+	return config.GetDurationEnv("PERMISSION_CACHE_TTL", DEFAULT_PERMISSION_CACHE_TTL)
+}
+
+// RolePermissions returns the names of the permissions granted to a role.
+// Unknown roles have no permissions
+func RolePermissions(db *gorm.DB, role string) ([]string, error) {
+	if permissions, ok := rolePermissionCache.Get(role); ok {
+		return permissions, nil
+	}
+
+	var permissions []string
+	err := db.Table("permissions").
+		Joins("INNER JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("INNER JOIN roles ON roles.id = role_permissions.role_id").
+		Where("roles.name = ?", role).
+		Pluck("permissions.name", &permissions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	rolePermissionCache.Set(role, permissions, permissionCacheTTL())
+	return permissions, nil
+}
+
+// HasPermissions reports whether every required permission is among the granted ones
+func HasPermissions(granted []string, required ...string) bool {
+	for _, permission := range required {
+		if !slices.Contains(granted, permission) {
+			return false
+		}
+	}
+	return true
+}
+
+// InvalidateRolePermissions drops the cached permissions of a role
+func InvalidateRolePermissions(role string) {
+	rolePermissionCache.Delete(role)
+}
+
+// ListPermissions returns every permission known to the application
+func ListPermissions(db *gorm.DB) ([]model.Permission, error) {
+	var permissions []model.Permission
+	err := db.Order("name ASC").Find(&permissions).Error
+	return permissions, err
+}
+
+// ListRoles returns every role with its permissions
+func ListRoles(db *gorm.DB) ([]model.RoleDefinition, error) {
+	var roles []model.RoleDefinition
+	err := db.Preload("Permissions").Order("name ASC").Find(&roles).Error
+	return roles, err
+}
+
+// FindRole finds a role by its name
+func FindRole(db *gorm.DB, name string) (*model.RoleDefinition, error) {
+	var role model.RoleDefinition
+	if err := db.Preload("Permissions").Where("name = ?", name).First(&role).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// SaveRole creates a role or replaces the description and permissions of an existing one
+func SaveRole(db *gorm.DB, name, description string, permissionNames []string) (*model.RoleDefinition, error) {
+	var permissions []model.Permission
+	if len(permissionNames) > 0 {
+		if err := db.Where("name IN (?)", permissionNames).Find(&permissions).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	for _, permissionName := range permissionNames {
+		if !slices.ContainsFunc(permissions, func(permission model.Permission) bool { return permission.Name == permissionName }) {
+			return nil, errors.New(UNKNOWN_PERMISSION_ERROR)
+		}
+	}
+
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	var role model.RoleDefinition
+	err := tx.Where("name = ?", name).First(&role).Error
+	if isNotRecordNotFoundError(err) {
+		tx.Rollback()
+		return nil, err
+	}
+
+	role.Name = name
+	role.Description = description
+	if err := tx.Save(&role).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Model(&role).Association("Permissions").Replace(permissions).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	InvalidateRolePermissions(name)
+	role.Permissions = permissions
+	return &role, nil
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/jinzhu/gorm"
+)
+
+// personalRecord is a table holding data that belongs to a single user
+type personalRecord struct {
+	model  any
+	column string // column referencing users.id
+}
+
+// personalRecords are deleted when a user is erased. Orders are not listed:
+// they are financial records and stay linked to the anonymized user, only
+// their address copies are cleared
+var personalRecords = []personalRecord{
+	{&model.Session{}, "user_id"},
+	{&model.RefreshToken{}, "user_id"},
+	{&model.OneTimeToken{}, "user_id"},
+	{&model.TwoFactorAuth{}, "user_id"},
+	{&model.RecoveryCode{}, "user_id"},
+	{&model.UserIdentity{}, "user_id"},
+	{&model.Address{}, "user_id"},
+	{&model.APIKey{}, "owner_id"},
+}
+
+// ExportPersonalData collects the user together with every record that
+// belongs to them. Secrets such as password and key hashes are left out by
+// the JSON encoding of the models
+func ExportPersonalData(db *gorm.DB, user *model.User) (*model.PersonalDataExport, error) {
+	export := &model.PersonalDataExport{
+		ExportedAt: time.Now(),
+		User:       user,
+		Orders:     []model.Order{},
+		Addresses:  []model.Address{},
+		Sessions:   []model.Session{},
+		Identities: []model.UserIdentity{},
+		APIKeys:    []model.APIKey{},
+		AuthEvents: []model.AuthEvent{},
+	}
+
+	if err := preloadOrderItems(db.Where("user_id = ?", user.ID)).Order("id ASC").Find(&export.Orders).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Where("user_id = ?", user.ID).Order("id ASC").Find(&export.Addresses).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Where("user_id = ?", user.ID).Order("id ASC").Find(&export.Sessions).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Where("user_id = ?", user.ID).Order("id ASC").Find(&export.Identities).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Where("owner_id = ?", user.ID).Order("id ASC").Find(&export.APIKeys).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Where("user_guid = ?", user.UserID).Order("id ASC").Find(&export.AuthEvents).Error; err != nil {
+		return nil, err
+	}
+
+	twoFactorAuth, err := FindTwoFactorAuth(db, user)
+	if err != nil {
+		return nil, err
+	}
+	export.TwoFactorAuth = twoFactorAuth
+
+	return export, nil
+}
+
+// erasedOrderAddresses blanks the address copies of orders. The countries
+// are kept, they are needed for tax reporting and do not identify anyone
+func erasedOrderAddresses() map[string]any {
+	columns := map[string]any{}
+	for _, prefix := range []string{"shipping_", "billing_"} {
+		for _, column := range []string{"full_name", "line1", "line2", "city", "region", "postal_code", "phone"} {
+			columns[prefix+column] = ""
+		}
+	}
+	return columns
+}
+
+// ErasePersonalData anonymizes the user and deletes the records that only
+// exist for them. The account is deactivated and keeps its id and guid so
+// that its orders stay intact for accounting. The unusable password replaces
+// the password hash
+func ErasePersonalData(db *gorm.DB, user *model.User, unusablePassword string) error {
+	if user.IsErased() {
+		return errors.New(USER_ERASED_ERROR)
+	}
+
+	originalEmail := user.Email
+
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	updates := map[string]any{
+		"email":       user.UserID + "@" + model.ERASED_EMAIL_DOMAIN,
+		"first_name":  "",
+		"last_name":   "",
+		"password":    unusablePassword,
+		"verified_at": nil,
+		"is_deleted":  true,
+	}
+	if err := tx.Model(user).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Model(&model.Order{}).Where("user_id = ?", user.ID).UpdateColumns(erasedOrderAddresses()).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, record := range personalRecords {
+		if err := tx.Where(record.column+" = ?", user.ID).Delete(record.model).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	// failed logins are tracked by email address
+	if err := tx.Where("attempt_key = ?", accountAttemptKey(originalEmail)).Delete(&model.LoginAttempt{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// the audit log keeps what happened to the account but not who they were
+	// or where they came from, including failed logins with their email
+	err := tx.Model(&model.AuthEvent{}).
+		Where("user_guid = ? OR (user_guid = '' AND email = ?)", user.UserID, originalEmail).
+		UpdateColumns(map[string]any{"email": "", "ip_address": "", "user_agent": ""}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return err
+	}
+	InvalidateUser(user)
+
+	// access tokens that are still out there must stop working as well
+	return RevokeAllUserTokens(db, user)
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/hackdaemon2/instashop/config"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const DEFAULT_PRINCIPAL_CACHE_TTL = 30 * time.Second
+
+// principalCache remembers the active users by guid so that authenticating a
+// request does not need a database round trip. Changes made through the
+// repository drop the entry right away, changes made by other instances are
+// picked up once it expires
+var principalCache = util.NewCache[string, model.User]()
+
+func principalCacheTTL() time.Duration {
+	return config.GetDurationEnv("PRINCIPAL_CACHE_TTL", DEFAULT_PRINCIPAL_CACHE_TTL)
+}
+
+// FindActiveUser returns the user with the guid unless it has been
+// deactivated or erased. Every call returns a copy that the caller may change
+func FindActiveUser(db *gorm.DB, userID string) (*model.User, error) {
+	if user, ok := principalCache.Get(userID); ok {
+		return &user, nil
+	}
+
+	user, err := FindUserBy(db, "user_guid", userID)
+	if err != nil {
+		return nil, err
+	}
+
+	principalCache.Set(userID, *user, principalCacheTTL())
+	return user, nil
+}
+
+// InvalidateUser drops the cached user, it must be called whenever a user is changed
+func InvalidateUser(user *model.User) {
+	principalCache.Delete(user.UserID)
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"errors"
+	"log"
+
+	"github.com/hackdaemon2/instashop/config"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/jinzhu/gorm"
+)
+
+const DEFAULT_MAX_IMAGES_PER_PRODUCT = 10
+
+// MaxImagesPerProduct returns how many images a product may have
+func MaxImagesPerProduct() int {
+	return config.GetIntEnv("PRODUCT_MAX_IMAGES", DEFAULT_MAX_IMAGES_PER_PRODUCT)
+}
+
+// LoadProductImages fills in the images of the products, in their display
+// order, with a single query for the whole list
+func LoadProductImages(db *gorm.DB, products []*model.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	byID := make(map[uint]*model.Product, len(products))
+	productIDs := make([]uint, 0, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+		productIDs = append(productIDs, product.ID)
+		product.Images = nil
+	}
+
+	var images []model.ProductImage
+	if err := db.Where("product_id IN (?)", productIDs).Order("position ASC, id ASC").Find(&images).Error; err != nil {
+		return err
+	}
+
+	for _, image := range images {
+		if product, ok := byID[image.ProductID]; ok {
+			product.Images = append(product.Images, image)
+		}
+	}
+	return nil
+}
+
+// FindProductImage returns an image of a product by its guid
+func FindProductImage(db *gorm.DB, product *model.Product, imageID string) (*model.ProductImage, error) {
+	var image model.ProductImage
+	err := db.Where("image_guid = ? AND product_id = ?", imageID, product.ID).First(&image).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New(IMAGE_NOT_FOUND_ERROR)
+		}
+		return nil, err
+	}
+	return &image, nil
+}
+
+// AddProductImage adds an image after the other images of a product. The
+// first image of a product becomes its primary image
+func AddProductImage(db *gorm.DB, product *model.Product, image *model.ProductImage) error {
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	var count int
+	if err := tx.Model(&model.ProductImage{}).Where("product_id = ?", product.ID).Count(&count).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if count >= MaxImagesPerProduct() {
+		tx.Rollback()
+		return errors.New(PRODUCT_IMAGES_FULL_ERROR)
+	}
+
+	image.ProductID = product.ID
+	image.Position = count
+	image.IsPrimary = count == 0
+	if err := tx.Create(image).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return err
+	}
+	return nil
+}
+
+// SetPrimaryImage makes an image the primary image of its product in place of the previous one
+func SetPrimaryImage(db *gorm.DB, product *model.Product, image *model.ProductImage) error {
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	err := tx.Model(&model.ProductImage{}).
+		Where("product_id = ? AND id <> ? AND is_primary = ?", product.ID, image.ID, true).
+		Update("is_primary", false).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Model(image).Update("is_primary", true).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return err
+	}
+	return nil
+}
+
+// ReorderProductImages puts the images of a product in the order of
+// imageIDs, which must list each of them once
+func ReorderProductImages(db *gorm.DB, product *model.Product, imageIDs []string) error {
+	if err := LoadProductImages(db, []*model.Product{product}); err != nil {
+		return err
+	}
+
+	if len(imageIDs) != len(product.Images) {
+		return errors.New(INVALID_IMAGE_ORDER_ERROR)
+	}
+
+	byID := make(map[string]*model.ProductImage, len(product.Images))
+	for i := range product.Images {
+		byID[product.Images[i].ImageID] = &product.Images[i]
+	}
+
+	ordered := make([]model.ProductImage, 0, len(imageIDs))
+	for _, imageID := range imageIDs {
+		image, ok := byID[imageID]
+		if !ok {
+			return errors.New(INVALID_IMAGE_ORDER_ERROR)
+		}
+		delete(byID, imageID) // a repeated id is not found the second time
+		ordered = append(ordered, *image)
+	}
+
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := renumberImages(tx, ordered); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return err
+	}
+
+	product.Images = ordered
+	return nil
+}
+
+// DeleteProductImage removes an image from its product and closes the gap it
+// leaves in the order. When it was the primary image the first of the
+// remaining images takes its place. The files are left to the caller
+func DeleteProductImage(db *gorm.DB, product *model.Product, image *model.ProductImage) error {
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := tx.Delete(image).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var remaining []model.ProductImage
+	if err := tx.Where("product_id = ?", product.ID).Order("position ASC, id ASC").Find(&remaining).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := renumberImages(tx, remaining); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if image.IsPrimary && len(remaining) > 0 {
+		if err := tx.Model(&remaining[0]).Update("is_primary", true).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return err
+	}
+	return nil
+}
+
+// renumberImages saves the position of each image where it is out of order
+func renumberImages(tx *gorm.DB, images []model.ProductImage) error {
+	for position := range images {
+		if images[position].Position == position {
+			continue
+		}
+
+		if err := tx.Model(&images[position]).Update("position", position).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,329 @@
+package repository
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/hackdaemon2/instashop/config"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+const DEFAULT_PRICE_SCHEDULER_INTERVAL = time.Minute
+
+// priceScheduleStep is what happens to a price schedule and to the price of
+// its product when the schedule moves on. A nil price leaves the price alone
+type priceScheduleStep struct {
+	status       model.PriceScheduleStatus
+	price        *decimal.Decimal
+	regularPrice *decimal.Decimal
+	source       model.PriceChangeSource
+}
+
+// NewPriceChange describes the change of the price of product from oldPrice
+// to its current price. It returns nil when the price stayed the same
+func NewPriceChange(product *model.Product, oldPrice decimal.Decimal, source model.PriceChangeSource, changedBy string) *model.PriceChange {
+	if product.Price.Equal(oldPrice) {
+		return nil
+	}
+
+	return &model.PriceChange{
+		ProductID: product.ID,
+		OldPrice:  oldPrice,
+		NewPrice:  product.Price,
+		Currency:  product.Currency,
+		Source:    source,
+		ChangedBy: changedBy,
+	}
+}
+
+// ListPriceChanges returns a page of the price history of a product, newest
+// first, together with the total number of changes
+func ListPriceChanges(db *gorm.DB, product *model.Product, page, limit int) ([]model.PriceChange, int, error) {
+	var changes []model.PriceChange
+	var totalChanges int
+
+	query := db.Model(&model.PriceChange{}).Where("product_id = ?", product.ID)
+	if err := query.Count(&totalChanges).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&changes).Error
+	return changes, totalChanges, err
+}
+
+// ListPriceSchedules returns a page of the price schedules of a product, the
+// latest start first, together with the total number of schedules
+func ListPriceSchedules(db *gorm.DB, product *model.Product, page, limit int) ([]model.PriceSchedule, int, error) {
+	var schedules []model.PriceSchedule
+	var totalSchedules int
+
+	query := db.Model(&model.PriceSchedule{}).Where("product_id = ?", product.ID)
+	if err := query.Count(&totalSchedules).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	err := query.Order("starts_at DESC, id DESC").Limit(limit).Offset(offset).Find(&schedules).Error
+	return schedules, totalSchedules, err
+}
+
+// FindPriceSchedule returns a price schedule of a product by its guid
+func FindPriceSchedule(db *gorm.DB, product *model.Product, scheduleID string) (*model.PriceSchedule, error) {
+	var schedule model.PriceSchedule
+	err := db.Where("schedule_guid = ? AND product_id = ?", scheduleID, product.ID).First(&schedule).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New(PRICE_SCHEDULE_NOT_FOUND_ERROR)
+		}
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// CreatePriceSchedule schedules a price for a product. Schedules with an end
+// may not overlap the open schedules with an end of the same product, as
+// each of them brings back the price it started from. Schedules without an
+// end take effect at a single moment and never overlap
+func CreatePriceSchedule(db *gorm.DB, product *model.Product, schedule *model.PriceSchedule) error {
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if schedule.EndsAt != nil {
+		var overlapping int
+		err := tx.Model(&model.PriceSchedule{}).
+			Where("product_id = ? AND status IN (?)", product.ID, []model.PriceScheduleStatus{model.PriceSchedulePending, model.PriceScheduleActive}).
+			Where("ends_at IS NOT NULL AND ends_at > ? AND starts_at < ?", schedule.StartsAt, *schedule.EndsAt).
+			Count(&overlapping).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if overlapping > 0 {
+			tx.Rollback()
+			return errors.New(PRICE_SCHEDULE_OVERLAP_ERROR)
+		}
+	}
+
+	schedule.ProductID = product.ID
+	schedule.Status = model.PriceSchedulePending
+	if err := tx.Create(schedule).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return err
+	}
+	return nil
+}
+
+// CancelPriceSchedule stops a schedule. A schedule in effect ends at once and
+// the regular price comes back, as it would have at the end
+func CancelPriceSchedule(db *gorm.DB, schedule *model.PriceSchedule, cancelledBy string) error {
+	if !schedule.IsOpen() {
+		return errors.New(PRICE_SCHEDULE_CLOSED_ERROR)
+	}
+
+	return stepPriceSchedule(db, schedule, cancelledBy, func(product *model.Product) priceScheduleStep {
+		step := priceScheduleStep{status: model.PriceScheduleCancelled}
+		if schedule.Status == model.PriceScheduleActive {
+			step.price = revertedPrice(schedule, product)
+			step.source = model.ScheduleEndPriceChange
+		}
+		return step
+	})
+}
+
+// ApplyPriceSchedules starts the pending schedules whose start has come and
+// ends the active ones whose end has come, in the order they were due. A
+// schedule that cannot be moved on is logged and tried again next time
+func ApplyPriceSchedules(db *gorm.DB, now time.Time) error {
+	var due []model.PriceSchedule
+	err := db.Where("(status = ? AND starts_at <= ?) OR (status = ? AND ends_at <= ?)",
+		model.PriceSchedulePending, now, model.PriceScheduleActive, now).Find(&due).Error
+	if err != nil {
+		return err
+	}
+
+	// when one sale ends as the next one starts, the first has to bring back
+	// the regular price before the second remembers it, so at the same time
+	// ends go before starts whatever order the schedules were created in
+	slices.SortStableFunc(due, func(a, b model.PriceSchedule) int {
+		if byTime := dueAt(&a).Compare(dueAt(&b)); byTime != 0 {
+			return byTime
+		}
+		if a.Status != b.Status {
+			if a.Status == model.PriceScheduleActive {
+				return -1
+			}
+			return 1
+		}
+		return int(a.ID) - int(b.ID)
+	})
+
+	for i := range due {
+		schedule := &due[i]
+
+		var err error
+		if schedule.Status == model.PriceSchedulePending {
+			err = startPriceSchedule(db, schedule, now)
+		} else {
+			err = endPriceSchedule(db, schedule)
+		}
+
+		// another instance of the scheduler got to it first
+		if err != nil && err.Error() != PRICE_SCHEDULE_CLOSED_ERROR {
+			log.Println("unable to apply price schedule", schedule.ScheduleID, err)
+		}
+	}
+	return nil
+}
+
+// StartPriceScheduler applies the price schedules every
+// PRICE_SCHEDULER_INTERVAL in the background. An interval of 0 turns the
+// scheduler off
+func StartPriceScheduler(db *gorm.DB) {
+	interval := config.GetDurationEnv("PRICE_SCHEDULER_INTERVAL", DEFAULT_PRICE_SCHEDULER_INTERVAL)
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		for now := range time.Tick(interval) {
+			if err := ApplyPriceSchedules(db, now); err != nil {
+				log.Println("unable to apply price schedules:", err)
+			}
+		}
+	}()
+}
+
+// dueAt returns when a schedule has to be moved on next
+func dueAt(schedule *model.PriceSchedule) time.Time {
+	if schedule.Status == model.PriceScheduleActive && schedule.EndsAt != nil {
+		return *schedule.EndsAt
+	}
+	return schedule.StartsAt
+}
+
+// startPriceSchedule puts the scheduled price in effect and remembers the
+// regular price. A schedule whose end passed before it could start, or
+// whose product was deleted, changes nothing
+func startPriceSchedule(db *gorm.DB, schedule *model.PriceSchedule, now time.Time) error {
+	return stepPriceSchedule(db, schedule, "", func(product *model.Product) priceScheduleStep {
+		switch {
+		case product.IsDeleted:
+			return priceScheduleStep{status: model.PriceScheduleCancelled}
+		case schedule.EndsAt != nil && !schedule.EndsAt.After(now):
+			return priceScheduleStep{status: model.PriceScheduleExpired}
+		}
+
+		regularPrice := product.Price
+		step := priceScheduleStep{
+			status:       model.PriceScheduleActive,
+			price:        &schedule.Price,
+			regularPrice: &regularPrice,
+			source:       model.ScheduleStartPriceChange,
+		}
+		if schedule.EndsAt == nil {
+			step.status = model.PriceScheduleCompleted
+		}
+		return step
+	})
+}
+
+// endPriceSchedule brings back the regular price at the end of a schedule
+func endPriceSchedule(db *gorm.DB, schedule *model.PriceSchedule) error {
+	return stepPriceSchedule(db, schedule, "", func(product *model.Product) priceScheduleStep {
+		return priceScheduleStep{
+			status: model.PriceScheduleCompleted,
+			price:  revertedPrice(schedule, product),
+			source: model.ScheduleEndPriceChange,
+		}
+	})
+}
+
+// revertedPrice returns the price a product goes back to at the end of a
+// schedule. A price changed while the schedule was in effect is left alone,
+// as whoever changed it meant to replace the scheduled price
+func revertedPrice(schedule *model.PriceSchedule, product *model.Product) *decimal.Decimal {
+	if product.IsDeleted || schedule.RegularPrice == nil || !product.Price.Equal(schedule.Price) {
+		return nil
+	}
+	return schedule.RegularPrice
+}
+
+// stepPriceSchedule moves a schedule on to the step decided by next for its
+// product, in a single transaction. The status only changes when nobody has
+// moved the schedule on in the meantime, so that running several schedulers
+// at once never applies a schedule twice
+func stepPriceSchedule(db *gorm.DB, schedule *model.PriceSchedule, changedBy string, next func(product *model.Product) priceScheduleStep) error {
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	var product model.Product
+	if err := tx.Where("id = ?", schedule.ProductID).First(&product).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	step := next(&product)
+	fields := map[string]any{"status": step.status}
+	if step.regularPrice != nil {
+		fields["regular_price"] = *step.regularPrice
+	}
+
+	claimed := tx.Model(&model.PriceSchedule{}).Where("id = ? AND status = ?", schedule.ID, schedule.Status).Updates(fields)
+	if claimed.Error != nil {
+		tx.Rollback()
+		return claimed.Error
+	}
+
+	if claimed.RowsAffected == 0 {
+		tx.Rollback()
+		return errors.New(PRICE_SCHEDULE_CLOSED_ERROR)
+	}
+
+	if step.price != nil && !step.price.Equal(product.Price) {
+		oldPrice := product.Price
+		if err := tx.Model(&product).Update("price", *step.price).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		change := NewPriceChange(&product, oldPrice, step.source, changedBy)
+		change.ScheduleID = schedule.ScheduleID
+		if err := tx.Create(change).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return err
+	}
+
+	schedule.Status = step.status
+	if step.regularPrice != nil {
+		regularPrice := *step.regularPrice
+		schedule.RegularPrice = &regularPrice
+	}
+	return nil
+}
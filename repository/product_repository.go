@@ -3,11 +3,156 @@ package repository
 import (
 	"errors"
 	"fmt"
+	"log"
+	"strings"
 
 	"github.com/hackdaemon2/instashop/model"
 	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
 )
 
+const (
+	SORT_ASCENDING          = "asc"
+	SORT_DESCENDING         = "desc"
+	PRODUCT_CODE_BATCH_SIZE = 500
+
+	// ACTIVE_VARIANTS_QUERY selects the variants of a product that are for sale
+	ACTIVE_VARIANTS_QUERY = "SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id AND product_variants.is_deleted = false"
+)
+
+// productSortColumns maps the sort keys of the catalog to their columns
+var productSortColumns = map[string]string{
+	"price":      "price",
+	"name":       "product_name",
+	"created_at": "created_at",
+}
+
+// ProductFilter narrows down and orders the products returned by ListProducts.
+// Empty fields match everything
+type ProductFilter struct {
+	MinPrice    *decimal.Decimal
+	MaxPrice    *decimal.Decimal
+	Currency    string
+	InStock     bool
+	CategoryIDs []uint // products in any of these categories, see CategoryProductFilter
+	Sort        string // price, name or created_at (default)
+	Order       string // asc or desc, newest first when sorting by created_at and ascending otherwise
+}
+
+// IsProductSort reports whether products can be sorted by key
+func IsProductSort(key string) bool {
+	_, ok := productSortColumns[key]
+	return ok
+}
+
+// productOrder returns the ORDER BY clause of filter. Products with equal
+// sort values keep a stable order across pages
+func productOrder(filter ProductFilter) string {
+	column, ok := productSortColumns[filter.Sort]
+	if !ok {
+		column = productSortColumns["created_at"]
+	}
+
+	direction := filter.Order
+	if direction != SORT_ASCENDING && direction != SORT_DESCENDING {
+		direction = SORT_ASCENDING
+		if column == "created_at" {
+			direction = SORT_DESCENDING
+		}
+	}
+	return fmt.Sprintf("%s %s, id %s", column, direction, direction)
+}
+
+// priceRange returns the condition that column lies in the price range of
+// filter, with its arguments
+func priceRange(column string, filter ProductFilter) (string, []any) {
+	var conditions []string
+	var args []any
+	if filter.MinPrice != nil {
+		conditions = append(conditions, column+" >= ?")
+		args = append(args, *filter.MinPrice)
+	}
+
+	if filter.MaxPrice != nil {
+		conditions = append(conditions, column+" <= ?")
+		args = append(args, *filter.MaxPrice)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// filterProducts returns the query for the products matching filter.
+// Deleted products never match. Products with variants are priced and
+// stocked by variant, so they match the price range when one of their
+// variants does and are in stock when one of their variants is
+func filterProducts(db *gorm.DB, filter ProductFilter) *gorm.DB {
+	query := db.Model(&model.Product{}).Where("is_deleted = false")
+
+	if filter.MinPrice != nil || filter.MaxPrice != nil {
+		productCondition, productArgs := priceRange("products.price", filter)
+		variantCondition, variantArgs := priceRange("COALESCE(product_variants.price, products.price)", filter)
+		query = query.Where(
+			fmt.Sprintf("(NOT EXISTS (%s) AND %s) OR EXISTS (%s AND %s)", ACTIVE_VARIANTS_QUERY, productCondition, ACTIVE_VARIANTS_QUERY, variantCondition),
+			append(productArgs, variantArgs...)...)
+	}
+
+	if filter.Currency != "" {
+		query = query.Where("currency = ?", filter.Currency)
+	}
+
+	if filter.InStock {
+		query = query.Where(fmt.Sprintf("(NOT EXISTS (%s) AND products.stock > 0) OR EXISTS (%s AND product_variants.stock > 0)", ACTIVE_VARIANTS_QUERY, ACTIVE_VARIANTS_QUERY))
+	}
+
+	if len(filter.CategoryIDs) > 0 {
+		query = query.Where("id IN (SELECT product_id FROM product_categories WHERE category_id IN (?))", filter.CategoryIDs)
+	}
+	return query
+}
+
+// ListProducts returns a page of the catalog matching filter together with
+// the total number of matches. Deleted products are never listed
+func ListProducts(db *gorm.DB, filter ProductFilter, page, limit int) ([]*model.Product, int, error) {
+	var products []*model.Product
+	var totalProducts int
+
+	query := filterProducts(db, filter)
+	if err := query.Count(&totalProducts).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	err := query.Order(productOrder(filter)).Limit(limit).Offset(offset).Find(&products).Error
+	return products, totalProducts, err
+}
+
+// EachProduct walks through the products matching filter in the order they
+// were created, batchSize at a time, so that the whole catalog never has to
+// be held in memory. The sort of the filter does not apply. Walking stops at
+// the first error returned by fn
+func EachProduct(db *gorm.DB, filter ProductFilter, batchSize int, fn func(products []*model.Product) error) error {
+	var lastID uint
+	for {
+		var products []*model.Product
+		err := filterProducts(db, filter).Where("id > ?", lastID).Order("id ASC").Limit(batchSize).Find(&products).Error
+		if err != nil {
+			return err
+		}
+
+		if len(products) == 0 {
+			return nil
+		}
+
+		if err := fn(products); err != nil {
+			return err
+		}
+
+		if len(products) < batchSize {
+			return nil
+		}
+		lastID = products[len(products)-1].ID
+	}
+}
+
 // Helper function to find a product by its product code
 func findByProductCode(db *gorm.DB, productCode string) (*model.Product, error) {
 	var product model.Product
@@ -29,8 +174,9 @@ func GetProduct(db *gorm.DB, productCode string) (*model.Product, error) {
 	return product, nil
 }
 
-// UpdateProduct updates an existing product
-func UpdateProduct(db *gorm.DB, product *model.Product) (*model.Product, error) {
+// UpdateProduct updates an existing product and records priceChange, when
+// not nil, in its price history
+func UpdateProduct(db *gorm.DB, product *model.Product, priceChange *model.PriceChange) (*model.Product, error) {
 	tx := db.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -45,13 +191,20 @@ func UpdateProduct(db *gorm.DB, product *model.Product) (*model.Product, error)
 		return nil, err
 	}
 
-	err := tx.Commit().Error
-	if err != nil {
-		tx.Rollback()
-		return product, nil
+	if priceChange != nil {
+		if err := tx.Create(priceChange).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return nil, err
 	}
 
-	return nil, err
+	indexProduct(product)
+	return product, nil
 }
 
 func DeleteProduct(db *gorm.DB, product *model.Product) error {
@@ -59,6 +212,7 @@ func DeleteProduct(db *gorm.DB, product *model.Product) error {
 		fmt.Printf("error: %v", err)
 		return err
 	}
+	indexProduct(product)
 	return nil
 }
 
@@ -67,5 +221,84 @@ func CreateProduct(db *gorm.DB, product model.Product) (*model.Product, error) {
 	if err := db.Create(&product).Error; err != nil { // Create the product
 		return nil, err
 	}
+	indexProduct(&product)
 	return &product, nil
 }
+
+// FindProductsByCode returns the products with the given codes by code,
+// deleted ones included as their codes stay taken
+func FindProductsByCode(db *gorm.DB, codes []string) (map[string]*model.Product, error) {
+	products := make(map[string]*model.Product, len(codes))
+	for start := 0; start < len(codes); start += PRODUCT_CODE_BATCH_SIZE {
+		var batch []*model.Product
+		end := min(start+PRODUCT_CODE_BATCH_SIZE, len(codes))
+		if err := db.Where("product_code IN (?)", codes[start:end]).Find(&batch).Error; err != nil {
+			return nil, err
+		}
+
+		for _, product := range batch {
+			products[product.ProductCode] = product
+		}
+	}
+	return products, nil
+}
+
+// ProductUpdate is a change of some columns of a product. Product is the
+// product with the change applied, Columns holds the changed columns only, so
+// that the stock taken by orders and the prices set by the scheduler in the
+// meantime are kept
+type ProductUpdate struct {
+	Product *model.Product
+	Columns map[string]any
+}
+
+// ImportProducts creates and updates products in a single transaction, so
+// that either all of them are saved or none, along with the price changes of
+// the updated ones
+func ImportProducts(db *gorm.DB, created []*model.Product, updated []ProductUpdate, priceChanges []*model.PriceChange) error {
+	if len(created) == 0 && len(updated) == 0 {
+		return nil
+	}
+
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	for _, product := range created {
+		if err := tx.Create(product).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	for _, update := range updated {
+		if err := tx.Model(&model.Product{}).Where("id = ?", update.Product.ID).Updates(update.Columns).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	for _, priceChange := range priceChanges {
+		if err := tx.Create(priceChange).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return err
+	}
+
+	for _, product := range created {
+		indexProduct(product)
+	}
+	for _, update := range updated {
+		indexProduct(update.Product)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hackdaemon2/instashop/config"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/search"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	PRODUCT_NAME_FIELD        = "product_name"
+	PRODUCT_DESCRIPTION_FIELD = "product_description"
+
+	productIndexBatchSize = 1000
+)
+
+// productIndex is the full-text index of the catalog, keyed by product code.
+// It lives in memory: it is built from the database on startup, kept in sync
+// by CreateProduct, UpdateProduct and DeleteProduct, and rebuilt every
+// SEARCH_REINDEX_INTERVAL to pick up changes made by other instances
+var productIndex atomic.Pointer[search.Index]
+
+var (
+	// productIndexRebuild lets a single rebuild of the index run at a time
+	productIndexRebuild sync.Mutex
+
+	// productIndexUpdates collects the products indexed while the index is
+	// rebuilt, to replay them on the new index. It is nil when no rebuild runs
+	productIndexUpdates      []model.Product
+	productIndexUpdatesMutex sync.Mutex
+)
+
+func init() {
+	productIndex.Store(newProductIndex())
+}
+
+func newProductIndex() *search.Index {
+	return search.NewIndex(
+		search.Field{Name: PRODUCT_NAME_FIELD, Weight: 2},
+		search.Field{Name: PRODUCT_DESCRIPTION_FIELD, Weight: 1},
+	)
+}
+
+func addToIndex(index *search.Index, product *model.Product) {
+	index.Add(product.ProductCode, map[string]string{
+		PRODUCT_NAME_FIELD:        product.Name,
+		PRODUCT_DESCRIPTION_FIELD: product.Description,
+	})
+}
+
+// updateIndex brings the entry of a product in index up to date
+func updateIndex(index *search.Index, product *model.Product) {
+	if product.IsDeleted {
+		index.Remove(product.ProductCode)
+		return
+	}
+	addToIndex(index, product)
+}
+
+// indexProduct brings the index entry of a product up to date, and of the
+// index being rebuilt if any
+func indexProduct(product *model.Product) {
+	productIndexUpdatesMutex.Lock()
+	defer productIndexUpdatesMutex.Unlock()
+
+	updateIndex(productIndex.Load(), product)
+	if productIndexUpdates != nil {
+		productIndexUpdates = append(productIndexUpdates, *product)
+	}
+}
+
+// RebuildProductIndex indexes every product of the catalog and replaces the
+// current index once done, so searches keep working in the meantime. Products
+// indexed during the rebuild are indexed again on the new index, as the
+// rebuild may have read them before they changed
+func RebuildProductIndex(db *gorm.DB) error {
+	productIndexRebuild.Lock()
+	defer productIndexRebuild.Unlock()
+
+	productIndexUpdatesMutex.Lock()
+	productIndexUpdates = []model.Product{}
+	productIndexUpdatesMutex.Unlock()
+
+	defer func() {
+		productIndexUpdatesMutex.Lock()
+		productIndexUpdates = nil
+		productIndexUpdatesMutex.Unlock()
+	}()
+
+	index := newProductIndex()
+
+	var lastID uint
+	for {
+		var products []model.Product
+		err := db.Where("id > ? AND is_deleted = false", lastID).Order("id ASC").Limit(productIndexBatchSize).Find(&products).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range products {
+			addToIndex(index, &products[i])
+		}
+
+		if len(products) < productIndexBatchSize {
+			break
+		}
+		lastID = products[len(products)-1].ID
+	}
+
+	productIndexUpdatesMutex.Lock()
+	defer productIndexUpdatesMutex.Unlock()
+
+	for i := range productIndexUpdates {
+		updateIndex(index, &productIndexUpdates[i])
+	}
+	productIndex.Store(index)
+	return nil
+}
+
+// StartProductIndexRefresh rebuilds the index every SEARCH_REINDEX_INTERVAL
+// in the background. An interval of 0 turns the refresh off
+func StartProductIndexRefresh(db *gorm.DB) {
+	interval := config.GetDurationEnv("SEARCH_REINDEX_INTERVAL", 10*time.Minute)
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		for range time.Tick(interval) {
+			if err := RebuildProductIndex(db); err != nil {
+				log.Println("unable to rebuild the product search index:", err)
+			}
+		}
+	}()
+}
+
+// ProductMatch is a product found by SearchProducts
+type ProductMatch struct {
+	Product    *model.Product
+	Score      float64
+	Highlights map[string]string
+}
+
+// SearchProducts returns a page of the products matching query, the most
+// relevant first, together with the total number of matches. The total is the
+// one of the index: products deleted by another instance since the last
+// rebuild are left out of the page but still counted until the next rebuild
+func SearchProducts(db *gorm.DB, query string, page, limit int) ([]ProductMatch, int, error) {
+	hits, total := productIndex.Load().Search(query, (page-1)*limit, limit)
+	if len(hits) == 0 {
+		return []ProductMatch{}, total, nil
+	}
+
+	codes := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		codes = append(codes, hit.ID)
+	}
+
+	var products []*model.Product
+	if err := db.Where("product_code IN (?) AND is_deleted = false", codes).Find(&products).Error; err != nil {
+		return nil, 0, err
+	}
+
+	byCode := make(map[string]*model.Product, len(products))
+	for _, product := range products {
+		byCode[product.ProductCode] = product
+	}
+
+	// products deleted by another instance since the last rebuild are left out
+	matches := make([]ProductMatch, 0, len(hits))
+	for _, hit := range hits {
+		if product, ok := byCode[hit.ID]; ok {
+			matches = append(matches, ProductMatch{Product: product, Score: hit.Score, Highlights: hit.Highlights})
+		}
+	}
+	return matches, total, nil
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/jinzhu/gorm"
+)
+
+// LoadProductVariants fills in the options and the variants of a product
+func LoadProductVariants(db *gorm.DB, product *model.Product) error {
+	if err := db.Where("product_id = ?", product.ID).Order("position ASC").Find(&product.Options).Error; err != nil {
+		return err
+	}
+
+	return db.Where("product_id = ? AND is_deleted = false", product.ID).
+		Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Order("id ASC").Find(&product.Variants).Error
+}
+
+// CountVariants returns how many variants of a product can be ordered
+func CountVariants(db *gorm.DB, product *model.Product) (int, error) {
+	var count int
+	err := db.Model(&model.ProductVariant{}).Where("product_id = ? AND is_deleted = false", product.ID).Count(&count).Error
+	return count, err
+}
+
+// FindVariant returns a variant of a product by its SKU
+func FindVariant(db *gorm.DB, product *model.Product, sku string) (*model.ProductVariant, error) {
+	var variant model.ProductVariant
+	err := db.Where("sku = ? AND product_id = ? AND is_deleted = false", sku, product.ID).
+		Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		First(&variant).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New(VARIANT_NOT_FOUND_ERROR)
+		}
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// SetProductOptions replaces the options of a product, in the given order.
+// Options cannot change while the product has variants, as every variant has
+// a value for each option
+func SetProductOptions(db *gorm.DB, product *model.Product, names []string) error {
+	count, err := CountVariants(db, product)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return errors.New(PRODUCT_HAS_VARIANTS_ERROR)
+	}
+
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := tx.Where("product_id = ?", product.ID).Delete(&model.ProductOption{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	options := make([]model.ProductOption, 0, len(names))
+	for position, name := range names {
+		option := model.ProductOption{ProductID: product.ID, Name: name, Position: position}
+		if err := tx.Create(&option).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		options = append(options, option)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return err
+	}
+
+	product.Options = options
+	return nil
+}
+
+// variantKey identifies the combination of option values of a variant
+func variantKey(options []model.VariantOption) string {
+	values := make([]string, 0, len(options))
+	for _, option := range options {
+		values = append(values, option.Name+"="+strings.ToLower(option.Value))
+	}
+	slices.Sort(values)
+	return strings.Join(values, "\x00")
+}
+
+// SaveVariant creates a variant of a product or saves the changes made to an
+// existing one. The variant must have one value for each option of the
+// product, a combination no other variant has, and a SKU of its own
+func SaveVariant(db *gorm.DB, product *model.Product, variant *model.ProductVariant) error {
+	if err := LoadProductVariants(db, product); err != nil {
+		return err
+	}
+
+	if len(variant.Options) != len(product.Options) {
+		return errors.New(VARIANT_OPTIONS_ERROR)
+	}
+	for _, option := range product.Options {
+		if !slices.ContainsFunc(variant.Options, func(value model.VariantOption) bool { return value.Name == option.Name }) {
+			return errors.New(VARIANT_OPTIONS_ERROR)
+		}
+	}
+
+	key := variantKey(variant.Options)
+	for _, other := range product.Variants {
+		if other.ID != variant.ID && variantKey(other.Options) == key {
+			return errors.New(DUPLICATE_VARIANT_ERROR)
+		}
+	}
+
+	// deleted variants keep their SKU, as orders refer to them
+	var count int
+	if err := db.Model(&model.ProductVariant{}).Where("sku = ? AND id <> ?", variant.SKU, variant.ID).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return errors.New(SKU_TAKEN_ERROR)
+	}
+
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	variant.ProductID = product.ID
+	if err := tx.Save(variant).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Where("variant_id = ?", variant.ID).Delete(&model.VariantOption{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for i := range variant.Options {
+		variant.Options[i].ID = 0
+		variant.Options[i].VariantID = variant.ID
+		if err := tx.Create(&variant.Options[i]).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return err
+	}
+	return nil
+}
+
+// DeleteVariant stops a variant from being sold. Orders keep referring to it
+func DeleteVariant(db *gorm.DB, variant *model.ProductVariant) error {
+	return db.Model(variant).Update("is_deleted", true).Error
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/jinzhu/gorm"
+)
+
+// UpdateUserProfile changes the editable profile fields of a user
+func UpdateUserProfile(db *gorm.DB, user *model.User, firstName, lastName, currency string) (*model.User, error) {
+	updates := map[string]any{"first_name": firstName, "last_name": lastName, "user_currency": currency}
+	if err := db.Model(user).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	InvalidateUser(user)
+	return user, nil
+}
+
+// ChangeUserPassword stores a new password hash and revokes every token
+// issued before the change, so all clients have to log in again
+func ChangeUserPassword(db *gorm.DB, user *model.User, hashedPassword string) error {
+	if err := db.Model(user).Update("password", hashedPassword).Error; err != nil {
+		return err
+	}
+	InvalidateUser(user)
+
+	return RevokeAllUserTokens(db, user)
+}
+
+// isEmailTaken reports whether any account, including deactivated ones, uses email
+func isEmailTaken(db *gorm.DB, email string) (bool, error) {
+	var count int
+	if err := db.Model(&model.User{}).Where("email = ?", email).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// RequestEmailChange issues a token that switches the user's email to newEmail
+// once it is confirmed from the new address
+func RequestEmailChange(db *gorm.DB, user *model.User, newEmail string) (string, error) {
+	taken, err := isEmailTaken(db, newEmail)
+	if err != nil {
+		return "", err
+	}
+
+	if taken {
+		return "", errors.New(EMAIL_ALREADY_IN_USE_ERROR)
+	}
+
+	return CreateOneTimeToken(db, user, model.EmailChangePurpose, EmailVerificationTTL(), newEmail)
+}
+
+// ConfirmEmailChange redeems an email change token and switches the user's
+// email to the confirmed address
+func ConfirmEmailChange(db *gorm.DB, rawToken string) (*model.User, error) {
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	oneTimeToken, err := RedeemOneTimeToken(tx, rawToken, model.EmailChangePurpose)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	user, err := FindUserBy(tx, "id", strconv.Itoa(int(oneTimeToken.UserID)))
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// the address may have been registered since the change was requested
+	taken, err := isEmailTaken(tx, oneTimeToken.Payload)
+	if err != nil || taken {
+		tx.Rollback()
+		if taken {
+			return nil, errors.New(EMAIL_ALREADY_IN_USE_ERROR)
+		}
+		return nil, err
+	}
+
+	updates := map[string]any{"email": oneTimeToken.Payload, "verified_at": time.Now()}
+	if err := tx.Model(user).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return nil, err
+	}
+	InvalidateUser(user)
+
+	return user, nil
+}
@@ -3,4 +3,53 @@ package repository
 const (
 	TRANSACTION_COMMIT_ERROR = "Error committing transaction: "
 	PRODUCT_NOT_FOUND_ERROR  = "Product not found"
+
+	CATEGORY_NOT_FOUND_ERROR        = "Category not found"
+	PARENT_CATEGORY_NOT_FOUND_ERROR = "Parent category not found"
+	CATEGORY_SLUG_TAKEN_ERROR       = "A category with this slug already exists"
+	CATEGORY_CYCLE_ERROR            = "A category cannot be moved below itself or one of its subcategories"
+	CATEGORY_HAS_CHILDREN_ERROR     = "Move or delete the subcategories first"
+	UNKNOWN_CATEGORY_ERROR          = "Unknown category"
+
+	VARIANT_NOT_FOUND_ERROR    = "Variant not found"
+	SKU_TAKEN_ERROR            = "A variant with this SKU already exists"
+	VARIANT_OPTIONS_ERROR      = "A variant needs exactly one value for each option of its product"
+	DUPLICATE_VARIANT_ERROR    = "Another variant of the product has the same options"
+	PRODUCT_HAS_VARIANTS_ERROR = "Delete the variants of the product before changing its options"
+
+	IMAGE_NOT_FOUND_ERROR     = "Image not found"
+	PRODUCT_IMAGES_FULL_ERROR = "The product has the most images allowed, delete an image first"
+	INVALID_IMAGE_ORDER_ERROR = "The new order must list every image of the product exactly once"
+
+	PRICE_SCHEDULE_NOT_FOUND_ERROR = "Price schedule not found"
+	PRICE_SCHEDULE_OVERLAP_ERROR   = "The product already has a price scheduled for part of this time"
+	PRICE_SCHEDULE_CLOSED_ERROR    = "The price schedule has already ended or been cancelled"
+
+	INVALID_REFRESH_TOKEN_ERROR = "Invalid or expired refresh token"
+	REFRESH_TOKEN_REUSED_ERROR  = "Refresh token has already been used"
+	SESSION_NOT_FOUND_ERROR     = "Session not found"
+
+	ADDRESS_NOT_FOUND_ERROR = "Address not found"
+	ADDRESS_BOOK_FULL_ERROR = "The address book is full, delete an address first"
+
+	OUT_OF_STOCK_ERROR = "An ordered product is no longer in stock"
+
+	INVALID_ONE_TIME_TOKEN_ERROR = "Invalid or expired token"
+
+	INVALID_CREDENTIALS_ERROR     = "invalid user credentials"
+	TOO_MANY_LOGIN_ATTEMPTS_ERROR = "Too many failed login attempts, try again later"
+
+	EMAIL_ALREADY_IN_USE_ERROR = "Email address is already in use"
+	USER_DEACTIVATED_ERROR     = "User account is deactivated"
+	USER_ERASED_ERROR          = "The personal data of this user has been erased"
+
+	UNKNOWN_PERMISSION_ERROR = "Unknown permission"
+	INVALID_API_KEY_ERROR    = "Invalid, expired or revoked API key"
+
+	TWO_FACTOR_ALREADY_ENABLED_ERROR = "Two-factor authentication is already enabled"
+	TWO_FACTOR_NOT_ENROLLED_ERROR    = "Two-factor authentication is not enabled"
+	INVALID_TWO_FACTOR_CODE_ERROR    = "Invalid two-factor authentication code"
+
+	INVALID_OIDC_STATE_ERROR      = "Invalid or expired login state"
+	OIDC_EMAIL_NOT_VERIFIED_ERROR = "The identity provider did not confirm an email address for this account"
 )
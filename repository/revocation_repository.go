@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/hackdaemon2/instashop/config"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const DEFAULT_REVOCATION_CACHE_TTL = 30 * time.Second
+
+// revokedTokenCache remembers denylist lookups by jti so that most requests
+// never reach the database. Revoked entries are kept until the token expires,
+// lookups that found nothing are only kept for the revocation cache TTL so
+// that revocations made by other instances are picked up quickly
+var revokedTokenCache = util.NewCache[string, bool]()
+
+// userRevocationCache remembers the last "log out everywhere" time per user guid
+var userRevocationCache = util.NewCache[string, time.Time]()
+
+func revocationCacheTTL() time.Duration {
+	return config.GetDurationEnv("REVOCATION_CACHE_TTL", DEFAULT_REVOCATION_CACHE_TTL)
+}
+
+// RevokeAccessToken adds the token identified by jti to the denylist
+func RevokeAccessToken(db *gorm.DB, jti, userID string, expiresAt time.Time) error {
+	revokedToken := model.RevokedToken{JTI: jti, UserID: userID, ExpiresAt: expiresAt}
+	if err := db.Where(model.RevokedToken{JTI: jti}).FirstOrCreate(&revokedToken).Error; err != nil {
+		return err
+	}
+
+	revokedTokenCache.Set(jti, true, time.Until(expiresAt))
+
+	// expired entries can no longer be used, so there is no need to keep them
+	return db.Where("expires_at < ?", time.Now()).Delete(&model.RevokedToken{}).Error
+}
+
+// RevokeAllUserTokens invalidates every access token issued to the user up to
+// now and revokes all of the user's refresh tokens and sessions
+func RevokeAllUserTokens(db *gorm.DB, user *model.User) error {
+	// truncated, not rounded by the database, so that no token issued after
+	// the revocation ends up before it
+	now := time.Now().Truncate(time.Millisecond)
+
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	var revocation model.UserTokenRevocation
+	err := tx.Where(model.UserTokenRevocation{UserID: user.UserID}).
+		Assign(model.UserTokenRevocation{RevokedAt: now}).
+		FirstOrCreate(&revocation).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", user.ID).
+		Update("revoked_at", now).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Model(&model.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", user.ID).
+		UpdateColumn("revoked_at", now).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	userRevocationCache.Set(user.UserID, now, revocationCacheTTL())
+	return nil
+}
+
+// IsAccessTokenRevoked reports whether a token has been revoked, either on
+// its own through its jti or because the user logged out everywhere after
+// the token was issued. Tokens without a jti can only be revoked per user.
+// issuedAt is compared in milliseconds, see util.ISSUED_AT_MILLIS_CLAIM
+func IsAccessTokenRevoked(db *gorm.DB, jti, userID string, issuedAt time.Time) (bool, error) {
+	if jti != "" {
+		revoked, err := isJTIRevoked(db, jti)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	revokedAt, err := userRevokedAt(db, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return !revokedAt.IsZero() && !issuedAt.After(revokedAt), nil
+}
+
+func isJTIRevoked(db *gorm.DB, jti string) (bool, error) {
+	if revoked, ok := revokedTokenCache.Get(jti); ok {
+		return revoked, nil
+	}
+
+	var revokedToken model.RevokedToken
+	err := db.Where("jti = ?", jti).First(&revokedToken).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			revokedTokenCache.Set(jti, false, revocationCacheTTL())
+			return false, nil
+		}
+		return false, err
+	}
+
+	revokedTokenCache.Set(jti, true, time.Until(revokedToken.ExpiresAt))
+	return true, nil
+}
+
+func userRevokedAt(db *gorm.DB, userID string) (time.Time, error) {
+	if revokedAt, ok := userRevocationCache.Get(userID); ok {
+		return revokedAt, nil
+	}
+
+	var revocation model.UserTokenRevocation
+	err := db.Where("user_guid = ?", userID).First(&revocation).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return time.Time{}, err
+	}
+
+	userRevocationCache.Set(userID, revocation.RevokedAt, revocationCacheTTL())
+	return revocation.RevokedAt, nil
+}
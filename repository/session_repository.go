@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	SESSION_LAST_SEEN_INTERVAL = time.Minute
+	MAX_USER_AGENT_LENGTH      = 512
+)
+
+// ClientInfo describes the device a login came from
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// revokedSessionCache remembers session lookups by session id, like
+// revokedTokenCache does for access tokens
+var revokedSessionCache = util.NewCache[string, bool]()
+
+// sessionActivityCache remembers sessions whose last activity was recorded
+// recently, so that busy sessions do not write on every request
+var sessionActivityCache = util.NewCache[string, bool]()
+
+// truncateUserAgent cuts a user agent down to the size of the user_agent columns
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) > MAX_USER_AGENT_LENGTH {
+		return userAgent[:MAX_USER_AGENT_LENGTH]
+	}
+	return userAgent
+}
+
+// createSession records a new login of the user that lasts as long as its
+// refresh tokens
+func createSession(db *gorm.DB, user *model.User, sessionID string, client ClientInfo) error {
+	session := model.Session{
+		SessionID: sessionID,
+		UserID:    user.ID,
+		UserAgent: truncateUserAgent(client.UserAgent),
+		IPAddress: client.IPAddress,
+		ExpiresAt: time.Now().Add(RefreshTokenTTL()),
+	}
+	return db.Create(&session).Error
+}
+
+// extendSession keeps a session alive after its refresh token was rotated
+func extendSession(db *gorm.DB, sessionID string) error {
+	now := time.Now()
+	return db.Model(&model.Session{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		UpdateColumns(map[string]any{"expires_at": now.Add(RefreshTokenTTL()), "last_seen_at": now}).Error
+}
+
+// ListSessions returns the active sessions of a user, most recently used first
+func ListSessions(db *gorm.DB, user *model.User) ([]model.Session, error) {
+	var sessions []model.Session
+	err := db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.ID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// RevokeSession ends an active session of the user together with its refresh tokens
+func RevokeSession(db *gorm.DB, user *model.User, sessionID string) error {
+	var session model.Session
+	err := db.Where("session_id = ? AND user_id = ?", sessionID, user.ID).First(&session).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return errors.New(SESSION_NOT_FOUND_ERROR)
+		}
+		return err
+	}
+
+	if !session.IsActive() {
+		return errors.New(SESSION_NOT_FOUND_ERROR)
+	}
+	return RevokeTokenFamily(db, sessionID)
+}
+
+// IsSessionRevoked reports whether the session an access token belongs to
+// has been revoked or no longer exists
+func IsSessionRevoked(db *gorm.DB, sessionID string) (bool, error) {
+	if revoked, ok := revokedSessionCache.Get(sessionID); ok {
+		return revoked, nil
+	}
+
+	var session model.Session
+	err := db.Where("session_id = ?", sessionID).First(&session).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return false, err
+	}
+
+	revoked := err != nil || session.RevokedAt != nil
+	if revoked {
+		revokedSessionCache.Set(sessionID, true, util.AccessTokenTTL())
+	} else {
+		revokedSessionCache.Set(sessionID, false, revocationCacheTTL())
+	}
+	return revoked, nil
+}
+
+// TouchSession records that the session has just been used from ipAddress
+func TouchSession(db *gorm.DB, sessionID, ipAddress string) error {
+	if _, ok := sessionActivityCache.Get(sessionID); ok {
+		return nil
+	}
+
+	err := db.Model(&model.Session{}).
+		Where("session_id = ?", sessionID).
+		UpdateColumns(map[string]any{"last_seen_at": time.Now(), "ip_address": ipAddress}).Error
+	if err != nil {
+		return err
+	}
+
+	sessionActivityCache.Set(sessionID, true, SESSION_LAST_SEEN_INTERVAL)
+	return nil
+}
@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/hackdaemon2/instashop/config"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	DEFAULT_REFRESH_TOKEN_TTL = 30 * 24 * time.Hour
+	REFRESH_TOKEN_SIZE        = 32
+)
+
+// RefreshTokenTTL returns how long a refresh token stays valid
+func RefreshTokenTTL() time.Duration {
+	return config.GetDurationEnv("REFRESH_TOKEN_TTL", DEFAULT_REFRESH_TOKEN_TTL)
+}
+
+// IssueTokens starts a new session for the user and returns an access token
+// together with the first refresh token of the session. mfa records whether
+// the login passed a second factor, which refreshed tokens inherit
+func IssueTokens(db *gorm.DB, user *model.User, mfa bool, client ClientInfo) (*util.JwtData, error) {
+	sessionID := uuid.New().String()
+
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := createSession(tx, user, sessionID, client); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	jwtData, err := issueTokens(tx, user, sessionID, mfa)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return nil, err
+	}
+
+	return jwtData, nil
+}
+
+// issueTokens creates an access token and a refresh token that belongs to
+// familyID, which is also the id of the session
+func issueTokens(db *gorm.DB, user *model.User, familyID string, mfa bool) (*util.JwtData, error) {
+	claims := jwt.MapClaims{"amr": util.AuthMethods(mfa), "sid": familyID}
+	jwtData, err := util.GenerateJWTWithClaims(user.UserID, user.Role, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	rawToken, refreshToken, err := newRefreshToken(user.ID, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken.MFA = mfa
+
+	if err := db.Create(refreshToken).Error; err != nil {
+		return nil, err
+	}
+
+	jwtData.RefreshToken = rawToken
+	jwtData.RefreshExpiry = refreshToken.ExpiresAt.Unix()
+	jwtData.SessionID = familyID
+	return &jwtData, nil
+}
+
+func newRefreshToken(userID uint, familyID string) (string, *model.RefreshToken, error) {
+	rawToken, err := util.GenerateSecureToken(REFRESH_TOKEN_SIZE)
+	if err != nil {
+		return "", nil, err
+	}
+
+	refreshToken := &model.RefreshToken{
+		UserID:    userID,
+		TokenHash: util.HashToken(rawToken),
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(RefreshTokenTTL()),
+	}
+	return rawToken, refreshToken, nil
+}
+
+// RefreshTokens exchanges a refresh token for a new access token and a new
+// refresh token. Presenting a token that has already been exchanged is treated
+// as theft: the whole token family is revoked and the caller must log in again
+func RefreshTokens(db *gorm.DB, rawToken string, client ClientInfo) (*util.JwtData, error) {
+	var refreshToken model.RefreshToken
+	err := db.Where("token_hash = ?", util.HashToken(rawToken)).First(&refreshToken).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New(INVALID_REFRESH_TOKEN_ERROR)
+		}
+		return nil, err
+	}
+
+	if refreshToken.RevokedAt != nil {
+		return nil, handleRefreshTokenReuse(db, &refreshToken, client)
+	}
+
+	if refreshToken.IsExpired() {
+		return nil, errors.New(INVALID_REFRESH_TOKEN_ERROR)
+	}
+
+	user, err := FindUserBy(db, "id", strconv.Itoa(int(refreshToken.UserID)))
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New(INVALID_REFRESH_TOKEN_ERROR)
+		}
+		return nil, err
+	}
+
+	return rotateRefreshToken(db, &refreshToken, user, client)
+}
+
+// rotateRefreshToken revokes the presented token and issues its successor in one
+// transaction. The conditional update guarantees that two concurrent requests
+// presenting the same token cannot both succeed
+func rotateRefreshToken(db *gorm.DB, refreshToken *model.RefreshToken, user *model.User, client ClientInfo) (*util.JwtData, error) {
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	jwtData, err := issueTokens(tx, user, refreshToken.FamilyID, refreshToken.MFA)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	result := tx.Model(&model.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", refreshToken.ID).
+		Updates(map[string]any{"revoked_at": time.Now(), "replaced_by": util.HashToken(jwtData.RefreshToken)})
+	if result.Error != nil {
+		tx.Rollback()
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return nil, handleRefreshTokenReuse(db, refreshToken, client)
+	}
+
+	if err := extendSession(tx, refreshToken.FamilyID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return nil, err
+	}
+
+	return jwtData, nil
+}
+
+// handleRefreshTokenReuse revokes the family of a reused refresh token and
+// records the revocation in the audit log for the owner of the token
+func handleRefreshTokenReuse(db *gorm.DB, refreshToken *model.RefreshToken, client ClientInfo) error {
+	log.Printf("refresh token reuse detected, revoking token family %s", refreshToken.FamilyID)
+	if err := RevokeTokenFamily(db, refreshToken.FamilyID); err != nil {
+		return err
+	}
+
+	var user model.User
+	if err := db.Where("id = ?", refreshToken.UserID).First(&user).Error; err != nil {
+		log.Println("unable to record auth event:", err)
+		return errors.New(REFRESH_TOKEN_REUSED_ERROR)
+	}
+
+	event := model.AuthEvent{Type: model.TokensRevokedEvent, UserID: user.UserID, ActorID: user.UserID, Email: user.Email, Details: "refresh token reuse"}
+	if err := RecordAuthEvent(db, event, client); err != nil {
+		log.Println("unable to record auth event:", err)
+	}
+	return errors.New(REFRESH_TOKEN_REUSED_ERROR)
+}
+
+// RevokeTokenFamily revokes every active refresh token in a family and ends
+// the session of the family, which rejects its access tokens as well
+func RevokeTokenFamily(db *gorm.DB, familyID string) error {
+	now := time.Now()
+	err := db.Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return err
+	}
+
+	err = db.Model(&model.Session{}).
+		Where("session_id = ? AND revoked_at IS NULL", familyID).
+		UpdateColumn("revoked_at", now).Error
+	if err != nil {
+		return err
+	}
+
+	revokedSessionCache.Set(familyID, true, util.AccessTokenTTL())
+	return nil
+}
+
+// RevokeRefreshToken revokes the family of a refresh token owned by the user.
+// Unknown tokens are ignored so that logging out never fails because of them
+func RevokeRefreshToken(db *gorm.DB, rawToken string, userID uint) error {
+	var refreshToken model.RefreshToken
+	err := db.Where("token_hash = ? AND user_id = ?", util.HashToken(rawToken), userID).First(&refreshToken).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil
+		}
+		return err
+	}
+	return RevokeTokenFamily(db, refreshToken.FamilyID)
+}
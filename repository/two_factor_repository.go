@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hackdaemon2/instashop/config"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/hackdaemon2/instashop/util"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	DEFAULT_TWO_FACTOR_CHALLENGE_TTL = 5 * time.Minute
+	DEFAULT_TOTP_ISSUER              = "Instashop"
+	TOTP_ALLOWED_SKEW                = 1 // accept the codes of the previous and the next time step
+	RECOVERY_CODE_COUNT              = 10
+	RECOVERY_CODE_LENGTH             = 10
+)
+
+// TwoFactorChallengeTTL returns how long the second step of a login may take
+func TwoFactorChallengeTTL() time.Duration {
+	return config.GetDurationEnv("TWO_FACTOR_CHALLENGE_TTL", DEFAULT_TWO_FACTOR_CHALLENGE_TTL)
+}
+
+// TOTPIssuer returns the issuer shown by authenticator apps
+func TOTPIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return DEFAULT_TOTP_ISSUER
+}
+
+// FindTwoFactorAuth returns the TOTP enrollment of a user, or nil when the
+// user never started one
+func FindTwoFactorAuth(db *gorm.DB, user *model.User) (*model.TwoFactorAuth, error) {
+	var twoFactorAuth model.TwoFactorAuth
+	err := db.Where("user_id = ?", user.ID).First(&twoFactorAuth).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &twoFactorAuth, nil
+}
+
+// IsTwoFactorEnabled reports whether a user has a confirmed TOTP enrollment
+func IsTwoFactorEnabled(db *gorm.DB, user *model.User) (bool, error) {
+	twoFactorAuth, err := FindTwoFactorAuth(db, user)
+	if err != nil {
+		return false, err
+	}
+	return twoFactorAuth != nil && twoFactorAuth.IsEnabled(), nil
+}
+
+// StartTwoFactorEnrollment generates a new pending TOTP secret for a user
+// and returns it with its provisioning URI
+func StartTwoFactorEnrollment(db *gorm.DB, user *model.User) (string, string, error) {
+	twoFactorAuth, err := FindTwoFactorAuth(db, user)
+	if err != nil {
+		return "", "", err
+	}
+
+	if twoFactorAuth != nil && twoFactorAuth.IsEnabled() {
+		return "", "", errors.New(TWO_FACTOR_ALREADY_ENABLED_ERROR)
+	}
+
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	if twoFactorAuth == nil {
+		twoFactorAuth = &model.TwoFactorAuth{UserID: user.ID}
+	}
+	twoFactorAuth.Secret = secret
+	twoFactorAuth.LastUsedStep = 0
+
+	if err := db.Save(twoFactorAuth).Error; err != nil {
+		return "", "", err
+	}
+
+	return secret, util.TOTPProvisioningURI(TOTPIssuer(), user.Email, secret), nil
+}
+
+// ConfirmTwoFactorEnrollment enables two-factor authentication once the user
+// proves their authenticator works and returns a fresh set of recovery codes
+func ConfirmTwoFactorEnrollment(db *gorm.DB, user *model.User, code string) ([]string, error) {
+	twoFactorAuth, err := FindTwoFactorAuth(db, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if twoFactorAuth == nil {
+		return nil, errors.New(TWO_FACTOR_NOT_ENROLLED_ERROR)
+	}
+
+	if twoFactorAuth.IsEnabled() {
+		return nil, errors.New(TWO_FACTOR_ALREADY_ENABLED_ERROR)
+	}
+
+	step, ok := util.ValidateTOTP(twoFactorAuth.Secret, code, time.Now(), TOTP_ALLOWED_SKEW)
+	if !ok {
+		return nil, errors.New(INVALID_TWO_FACTOR_CODE_ERROR)
+	}
+
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	updates := map[string]any{"enabled_at": time.Now(), "last_used_step": step}
+	if err := tx.Model(twoFactorAuth).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	recoveryCodes, err := replaceRecoveryCodes(tx, user)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return recoveryCodes, nil
+}
+
+// VerifyTwoFactorCode accepts either a TOTP code or an unused recovery code.
+// A TOTP code is only accepted once and a recovery code is used up
+func VerifyTwoFactorCode(db *gorm.DB, user *model.User, code string) error {
+	twoFactorAuth, err := FindTwoFactorAuth(db, user)
+	if err != nil {
+		return err
+	}
+
+	if twoFactorAuth == nil || !twoFactorAuth.IsEnabled() {
+		return errors.New(TWO_FACTOR_NOT_ENROLLED_ERROR)
+	}
+
+	if step, ok := util.ValidateTOTP(twoFactorAuth.Secret, code, time.Now(), TOTP_ALLOWED_SKEW); ok {
+		result := db.Model(&model.TwoFactorAuth{}).
+			Where("id = ? AND last_used_step < ?", twoFactorAuth.ID, step).
+			UpdateColumn("last_used_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return errors.New(INVALID_TWO_FACTOR_CODE_ERROR) // the code was already used
+		}
+		return nil
+	}
+
+	return useRecoveryCode(db, user, code)
+}
+
+func useRecoveryCode(db *gorm.DB, user *model.User, code string) error {
+	result := db.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, util.HashToken(normalizeRecoveryCode(code))).
+		UpdateColumn("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New(INVALID_TWO_FACTOR_CODE_ERROR)
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of a user
+func RegenerateRecoveryCodes(db *gorm.DB, user *model.User) ([]string, error) {
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	recoveryCodes, err := replaceRecoveryCodes(tx, user)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return recoveryCodes, nil
+}
+
+// DisableTwoFactor removes the TOTP secret and the recovery codes of a user
+func DisableTwoFactor(db *gorm.DB, user *model.User) error {
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := tx.Where("user_id = ?", user.ID).Delete(&model.TwoFactorAuth{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Where("user_id = ?", user.ID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// CreateTwoFactorChallenge issues the token that links the password step of
+// a login to its second step
+func CreateTwoFactorChallenge(db *gorm.DB, user *model.User) (string, error) {
+	return CreateOneTimeToken(db, user, model.TwoFactorChallengePurpose, TwoFactorChallengeTTL(), "")
+}
+
+// FindTwoFactorChallenge looks up an unused, unexpired challenge and its user
+// without using it up, so that a mistyped code can be retried
+func FindTwoFactorChallenge(db *gorm.DB, rawToken string) (*model.OneTimeToken, *model.User, error) {
+	var challenge model.OneTimeToken
+	err := db.Where("token_hash = ? AND purpose = ?", util.HashToken(rawToken), model.TwoFactorChallengePurpose).First(&challenge).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil, errors.New(INVALID_ONE_TIME_TOKEN_ERROR)
+		}
+		return nil, nil, err
+	}
+
+	if challenge.UsedAt != nil || challenge.IsExpired() {
+		return nil, nil, errors.New(INVALID_ONE_TIME_TOKEN_ERROR)
+	}
+
+	user, err := FindUserBy(db, "id", strconv.Itoa(int(challenge.UserID)))
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil, errors.New(INVALID_ONE_TIME_TOKEN_ERROR)
+		}
+		return nil, nil, err
+	}
+	return &challenge, user, nil
+}
+
+// replaceRecoveryCodes deletes the recovery codes of a user and creates new ones
+func replaceRecoveryCodes(db *gorm.DB, user *model.User) ([]string, error) {
+	if err := db.Where("user_id = ?", user.ID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	recoveryCodes := make([]string, 0, RECOVERY_CODE_COUNT)
+	for range RECOVERY_CODE_COUNT {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		recoveryCode := model.RecoveryCode{UserID: user.ID, CodeHash: util.HashToken(normalizeRecoveryCode(code))}
+		if err := db.Create(&recoveryCode).Error; err != nil {
+			return nil, err
+		}
+		recoveryCodes = append(recoveryCodes, code)
+	}
+	return recoveryCodes, nil
+}
+
+// generateRecoveryCode returns a code such as "k7mq2-x4pta"
+func generateRecoveryCode() (string, error) {
+	random := make([]byte, RECOVERY_CODE_LENGTH)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(random))[:RECOVERY_CODE_LENGTH]
+	return code[:RECOVERY_CODE_LENGTH/2] + "-" + code[RECOVERY_CODE_LENGTH/2:], nil
+}
+
+// normalizeRecoveryCode ignores case, spaces and dashes in a recovery code
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/hackdaemon2/instashop/config"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/jinzhu/gorm"
+)
+
+const DEFAULT_EMAIL_VERIFICATION_TTL = 24 * time.Hour
+
+// EmailVerificationTTL returns how long an email verification token stays valid
+func EmailVerificationTTL() time.Duration {
+	return config.GetDurationEnv("EMAIL_VERIFICATION_TOKEN_TTL", DEFAULT_EMAIL_VERIFICATION_TTL)
+}
+
+// CreateEmailVerificationToken issues a token that confirms the user's email address
+func CreateEmailVerificationToken(db *gorm.DB, user *model.User) (string, error) {
+	return CreateOneTimeToken(db, user, model.EmailVerificationPurpose, EmailVerificationTTL(), "")
+}
+
+// FindUnverifiedUser returns the account registered with email if its address
+// has not been confirmed yet. A nil user is returned without error otherwise
+func FindUnverifiedUser(db *gorm.DB, email string) (*model.User, error) {
+	user, err := FindUserBy(db, "email", email)
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if user.IsVerified() {
+		return nil, nil
+	}
+	return user, nil
+}
+
+// VerifyEmail redeems a verification token and marks the user's email as verified
+func VerifyEmail(db *gorm.DB, rawToken string) (*model.User, error) {
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	oneTimeToken, err := RedeemOneTimeToken(tx, rawToken, model.EmailVerificationPurpose)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	user, err := FindUserBy(tx, "id", strconv.Itoa(int(oneTimeToken.UserID)))
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if !user.IsVerified() {
+		if err := tx.Model(user).Update("verified_at", time.Now()).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Println(TRANSACTION_COMMIT_ERROR, err)
+		return nil, err
+	}
+	InvalidateUser(user)
+
+	return user, nil
+}
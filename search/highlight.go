@@ -0,0 +1,33 @@
+package search
+
+import (
+	"html"
+	"strings"
+)
+
+const (
+	HIGHLIGHT_START = "<mark>"
+	HIGHLIGHT_END   = "</mark>"
+)
+
+// Highlight wraps the words of text whose terms are in terms with <mark>
+// tags. The rest of the text is HTML escaped, so the result can be shown as is
+func Highlight(text string, terms map[string]bool) string {
+	var builder strings.Builder
+	last := 0
+
+	for _, token := range Tokenize(text) {
+		if !terms[token.Term] {
+			continue
+		}
+
+		builder.WriteString(html.EscapeString(text[last:token.Start]))
+		builder.WriteString(HIGHLIGHT_START)
+		builder.WriteString(html.EscapeString(text[token.Start:token.End]))
+		builder.WriteString(HIGHLIGHT_END)
+		last = token.End
+	}
+
+	builder.WriteString(html.EscapeString(text[last:]))
+	return builder.String()
+}
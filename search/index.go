@@ -0,0 +1,275 @@
+package search
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	MAX_QUERY_TERMS   = 10
+	MIN_PREFIX_LENGTH = 2 // the last word of a query also matches longer words starting with it
+
+	PREFIX_MATCH_WEIGHT = 0.8 // a word that was only partly typed
+	TYPO_PENALTY        = 0.3 // subtracted from the weight of a match for every typo
+
+	// BM25 parameters: term frequency saturation and field length normalization
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Field is a searchable field of the documents. Matches in fields with a
+// higher weight rank higher
+type Field struct {
+	Name   string
+	Weight float64
+}
+
+// Hit is a document matching a search
+type Hit struct {
+	ID         string
+	Score      float64
+	Highlights map[string]string // the fields with the matching words highlighted, see Highlight
+}
+
+type document struct {
+	fields  map[string]string
+	lengths map[string]int // number of words per field
+	terms   []string
+}
+
+// posting counts how often a term appears in each field of a document
+type posting map[string]int
+
+// candidate is a term of the index that a word of a query may stand for
+type candidate struct {
+	term   string
+	weight float64
+}
+
+// Index is an in-memory inverted index. Queries are matched word by word,
+// tolerating typos, and ranked with BM25. It is safe for concurrent use
+type Index struct {
+	mutex        sync.RWMutex
+	fields       []Field
+	documents    map[string]*document
+	postings     map[string]map[string]posting // term => document id => frequencies
+	totalLengths map[string]int                // sum of the field lengths, for the average length
+}
+
+func NewIndex(fields ...Field) *Index {
+	return &Index{
+		fields:       fields,
+		documents:    make(map[string]*document),
+		postings:     make(map[string]map[string]posting),
+		totalLengths: make(map[string]int),
+	}
+}
+
+// Len returns the number of indexed documents
+func (index *Index) Len() int {
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+	return len(index.documents)
+}
+
+// Add indexes the fields of a document, replacing the document if it was
+// already indexed. Fields the index does not know are ignored
+func (index *Index) Add(id string, fields map[string]string) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	index.remove(id)
+
+	doc := &document{fields: make(map[string]string), lengths: make(map[string]int)}
+	for _, field := range index.fields {
+		text := fields[field.Name]
+		tokens := Tokenize(text)
+		doc.fields[field.Name] = text
+		doc.lengths[field.Name] = len(tokens)
+		index.totalLengths[field.Name] += len(tokens)
+
+		for _, token := range tokens {
+			documents, ok := index.postings[token.Term]
+			if !ok {
+				documents = make(map[string]posting)
+				index.postings[token.Term] = documents
+			}
+
+			if _, ok := documents[id]; !ok {
+				documents[id] = posting{}
+				doc.terms = append(doc.terms, token.Term)
+			}
+			documents[id][field.Name]++
+		}
+	}
+	index.documents[id] = doc
+}
+
+// Remove drops a document from the index
+func (index *Index) Remove(id string) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	index.remove(id)
+}
+
+// remove drops a document, the caller must hold the write lock
+func (index *Index) remove(id string) {
+	doc, ok := index.documents[id]
+	if !ok {
+		return
+	}
+
+	for _, term := range doc.terms {
+		delete(index.postings[term], id)
+		if len(index.postings[term]) == 0 {
+			delete(index.postings, term)
+		}
+	}
+
+	for name, length := range doc.lengths {
+		index.totalLengths[name] -= length
+	}
+	delete(index.documents, id)
+}
+
+// queryTerms returns the distinct terms of a query
+func queryTerms(query string) []string {
+	var terms []string
+	for _, token := range Tokenize(query) {
+		if !slices.Contains(terms, token.Term) {
+			terms = append(terms, token.Term)
+		}
+	}
+	return terms[:min(len(terms), MAX_QUERY_TERMS)]
+}
+
+// candidates returns the terms of the index that a word of a query may stand
+// for: the word itself, words with a few typos and, for the word being typed
+// last, the words starting with it. The caller must hold the read lock
+func (index *Index) candidates(word string, isLast bool) []candidate {
+	var candidates []candidate
+	if _, ok := index.postings[word]; ok {
+		candidates = append(candidates, candidate{term: word, weight: 1})
+	}
+
+	typos := allowedTypos(word)
+	prefix := isLast && len([]rune(word)) >= MIN_PREFIX_LENGTH
+	if typos == 0 && !prefix {
+		return candidates
+	}
+
+	for term := range index.postings {
+		if term == word {
+			continue
+		}
+
+		if prefix && strings.HasPrefix(term, word) {
+			candidates = append(candidates, candidate{term: term, weight: PREFIX_MATCH_WEIGHT})
+			continue
+		}
+
+		if typos > 0 {
+			if distance := editDistance(word, term, typos); distance <= typos {
+				candidates = append(candidates, candidate{term: term, weight: 1 - TYPO_PENALTY*float64(distance)})
+			}
+		}
+	}
+	return candidates
+}
+
+// score is the BM25 score of a term in a document, summed over the weighted
+// fields. The caller must hold the read lock
+func (index *Index) score(id string, frequencies posting, documentFrequency int) float64 {
+	total := float64(len(index.documents))
+	idf := math.Log(1 + (total-float64(documentFrequency)+0.5)/(float64(documentFrequency)+0.5))
+	doc := index.documents[id]
+
+	score := 0.0
+	for _, field := range index.fields {
+		frequency := float64(frequencies[field.Name])
+		if frequency == 0 {
+			continue
+		}
+
+		averageLength := float64(index.totalLengths[field.Name]) / total
+		normalization := 1 - bm25B + bm25B*float64(doc.lengths[field.Name])/averageLength
+		score += field.Weight * idf * frequency * (bm25K1 + 1) / (frequency + bm25K1*normalization)
+	}
+	return score
+}
+
+// Search returns the page of documents that match every word of query, the
+// best matches first, together with the total number of matches
+func (index *Index) Search(query string, offset, limit int) ([]Hit, int) {
+	terms := queryTerms(query)
+	if len(terms) == 0 {
+		return nil, 0
+	}
+
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+
+	var scores map[string]float64
+	matched := make(map[string]map[string]bool) // document id => index terms to highlight
+
+	for i, word := range terms {
+		best := make(map[string]float64)
+		for _, candidate := range index.candidates(word, i == len(terms)-1) {
+			documents := index.postings[candidate.term]
+			for id, frequencies := range documents {
+				if scores != nil {
+					if _, ok := scores[id]; !ok {
+						continue // already missed an earlier word
+					}
+				}
+
+				best[id] = max(best[id], candidate.weight*index.score(id, frequencies, len(documents)))
+				if matched[id] == nil {
+					matched[id] = make(map[string]bool)
+				}
+				matched[id][candidate.term] = true
+			}
+		}
+
+		if scores == nil {
+			scores = best
+			continue
+		}
+
+		for id := range scores {
+			if score, ok := best[id]; ok {
+				scores[id] += score
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+
+	slices.SortFunc(hits, func(a, b Hit) int {
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	total := len(hits)
+	hits = hits[min(offset, total):min(offset+limit, total)]
+	for i := range hits {
+		doc := index.documents[hits[i].ID]
+		hits[i].Highlights = make(map[string]string, len(doc.fields))
+		for name, text := range doc.fields {
+			hits[i].Highlights[name] = Highlight(text, matched[hits[i].ID])
+		}
+	}
+	return hits, total
+}
@@ -0,0 +1,117 @@
+package search
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestIndex() *Index {
+	index := NewIndex(Field{Name: "name", Weight: 2}, Field{Name: "description", Weight: 1})
+	index.Add("kettle", map[string]string{"name": "Electric Kettle", "description": "Boils water in two minutes"})
+	index.Add("teapot", map[string]string{"name": "Ceramic Teapot", "description": "Pairs well with an electric kettle"})
+	index.Add("toaster", map[string]string{"name": "Toaster", "description": "Two slice toaster with a crumb tray"})
+	return index
+}
+
+func hitIDs(hits []Hit) []string {
+	ids := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids
+}
+
+// Matches in the name rank above matches in the description
+func TestSearchRanksByField(t *testing.T) {
+	hits, total := newTestIndex().Search("kettle", 0, 10)
+
+	assert.Equal(t, 2, total)
+	assert.Equal(t, []string{"kettle", "teapot"}, hitIDs(hits))
+	assert.Greater(t, hits[0].Score, hits[1].Score)
+	assert.Equal(t, "Electric <mark>Kettle</mark>", hits[0].Highlights["name"])
+	assert.Equal(t, "Pairs well with an electric <mark>kettle</mark>", hits[1].Highlights["description"])
+}
+
+func TestSearchMatchesEveryWord(t *testing.T) {
+	hits, _ := newTestIndex().Search("electric teapot", 0, 10)
+	assert.Equal(t, []string{"teapot"}, hitIDs(hits))
+
+	hits, total := newTestIndex().Search("electric toaster", 0, 10)
+	assert.Empty(t, hits)
+	assert.Zero(t, total)
+}
+
+func TestSearchToleratesTypos(t *testing.T) {
+	index := newTestIndex()
+
+	hits, _ := index.Search("ketle", 0, 10)
+	assert.Equal(t, []string{"kettle", "teapot"}, hitIDs(hits))
+	assert.Equal(t, "Electric <mark>Kettle</mark>", hits[0].Highlights["name"])
+
+	exact, _ := index.Search("kettle", 0, 10)
+	assert.Less(t, hits[0].Score, exact[0].Score, "Typos should rank below exact matches")
+
+	hits, _ = index.Search("tost", 0, 10)
+	assert.Empty(t, hits, "Short words should not tolerate typos")
+}
+
+// The last word may still be being typed
+func TestSearchMatchesPrefixOfLastWord(t *testing.T) {
+	hits, _ := newTestIndex().Search("ceramic te", 0, 10)
+	assert.Equal(t, []string{"teapot"}, hitIDs(hits))
+
+	hits, _ = newTestIndex().Search("te ceramic", 0, 10)
+	assert.Empty(t, hits, "Only the last word is a prefix")
+}
+
+func TestSearchIgnoresCaseAndAccents(t *testing.T) {
+	index := newTestIndex()
+	index.Add("cafe", map[string]string{"name": "Café Crème Beans"})
+
+	hits, _ := index.Search("CAFE creme", 0, 10)
+	assert.Equal(t, []string{"cafe"}, hitIDs(hits))
+	assert.Equal(t, "<mark>Café</mark> <mark>Crème</mark> Beans", hits[0].Highlights["name"])
+}
+
+func TestSearchPagination(t *testing.T) {
+	index := NewIndex(Field{Name: "name", Weight: 1})
+	for i := range 5 {
+		index.Add(fmt.Sprintf("mug-%d", i), map[string]string{"name": "Coffee mug"})
+	}
+
+	hits, total := index.Search("mug", 2, 2)
+	assert.Equal(t, 5, total)
+	assert.Equal(t, []string{"mug-2", "mug-3"}, hitIDs(hits), "Equal scores should keep a stable order")
+
+	hits, total = index.Search("mug", 10, 2)
+	assert.Empty(t, hits)
+	assert.Equal(t, 5, total)
+}
+
+// Updating or removing a document replaces what was indexed before
+func TestIndexAddAndRemove(t *testing.T) {
+	index := newTestIndex()
+
+	index.Add("kettle", map[string]string{"name": "Stovetop Whistle", "description": "For gas stoves"})
+	hits, _ := index.Search("electric", 0, 10)
+	assert.Equal(t, []string{"teapot"}, hitIDs(hits))
+
+	hits, _ = index.Search("whistle", 0, 10)
+	assert.Equal(t, []string{"kettle"}, hitIDs(hits))
+
+	index.Remove("kettle")
+	hits, _ = index.Search("whistle", 0, 10)
+	assert.Empty(t, hits)
+	assert.Equal(t, 2, index.Len())
+
+	index.Remove("unknown")
+	assert.Equal(t, 2, index.Len())
+}
+
+func TestSearchEmptyQuery(t *testing.T) {
+	hits, total := newTestIndex().Search(" ?! ", 0, 10)
+	assert.Nil(t, hits)
+	assert.Zero(t, total)
+}
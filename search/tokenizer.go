@@ -0,0 +1,57 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Token is a normalized word of a text together with where it appears
+type Token struct {
+	Term  string
+	Start int // byte offset of the word in the text
+	End   int
+}
+
+// normalize lower-cases a word and strips its diacritics, so that "Café"
+// and "cafe" are the same term
+func normalize(word string) string {
+	var builder strings.Builder
+	for _, r := range norm.NFD.String(word) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		builder.WriteRune(unicode.ToLower(r))
+	}
+	return builder.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+// Tokenize splits text into words made of letters and digits
+func Tokenize(text string) []Token {
+	var tokens []Token
+	start := -1
+
+	for i, r := range text {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+
+		if start >= 0 {
+			tokens = append(tokens, Token{Term: normalize(text[start:i]), Start: start, End: i})
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		tokens = append(tokens, Token{Term: normalize(text[start:]), Start: start, End: len(text)})
+	}
+	return tokens
+}
@@ -0,0 +1,39 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens := Tokenize("Crème Brûlée, 2-pack!")
+
+	assert.Equal(t, []Token{
+		{Term: "creme", Start: 0, End: 6},
+		{Term: "brulee", Start: 7, End: 15},
+		{Term: "2", Start: 17, End: 18},
+		{Term: "pack", Start: 19, End: 23},
+	}, tokens)
+	assert.Empty(t, Tokenize(" -- "))
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("kettle", "kettle", 2))
+	assert.Equal(t, 1, editDistance("ketle", "kettle", 2), "insertion")
+	assert.Equal(t, 1, editDistance("kettel", "kettle", 2), "swapped letters count once")
+	assert.Equal(t, 2, editDistance("kitle", "kettle", 2))
+	assert.Equal(t, 3, editDistance("kettle", "toaster", 2), "distances over the limit stop counting")
+	assert.Equal(t, 1, editDistance("naïve", "naive", 1), "letters are compared, not bytes")
+}
+
+func TestAllowedTypos(t *testing.T) {
+	assert.Equal(t, 0, allowedTypos("mug"))
+	assert.Equal(t, 1, allowedTypos("kettle"))
+	assert.Equal(t, 2, allowedTypos("headphones"))
+}
+
+func TestHighlight(t *testing.T) {
+	highlighted := Highlight("Electric <Kettle> & kettle-stand", map[string]bool{"kettle": true})
+	assert.Equal(t, "Electric &lt;<mark>Kettle</mark>&gt; &amp; <mark>kettle</mark>-stand", highlighted)
+}
@@ -0,0 +1,71 @@
+package search
+
+import "unicode/utf8"
+
+const (
+	ONE_TYPO_MIN_LENGTH  = 5 // shorter words must match exactly
+	TWO_TYPOS_MIN_LENGTH = 9
+)
+
+// allowedTypos is the number of typos tolerated in a query word. Short words
+// tolerate none, otherwise "cat" would match "car", "hat" and "cut"
+func allowedTypos(term string) int {
+	switch length := utf8.RuneCountInString(term); {
+	case length >= TWO_TYPOS_MIN_LENGTH:
+		return 2
+	case length >= ONE_TYPO_MIN_LENGTH:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// editDistance counts the insertions, deletions, substitutions and swaps of
+// adjacent letters that turn a into b. Counting stops once it exceeds max,
+// in which case max+1 is returned
+func editDistance(a, b string, max int) int {
+	source, target := []rune(a), []rune(b)
+	if abs(len(source)-len(target)) > max {
+		return max + 1
+	}
+
+	// three rows of the optimal string alignment matrix
+	previous2 := make([]int, len(target)+1)
+	previous := make([]int, len(target)+1)
+	current := make([]int, len(target)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(source); i++ {
+		current[0] = i
+		rowMin := current[0]
+
+		for j := 1; j <= len(target); j++ {
+			cost := 1
+			if source[i-1] == target[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && source[i-1] == target[j-2] && source[i-2] == target[j-1] {
+				current[j] = min(current[j], previous2[j-2]+1)
+			}
+			rowMin = min(rowMin, current[j])
+		}
+
+		if rowMin > max {
+			return max + 1
+		}
+		previous2, previous, current = previous, current, previous2
+	}
+
+	return min(previous[len(target)], max+1)
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,106 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// countryCodes are the officially assigned ISO 3166-1 alpha-2 codes
+var countryCodes = toSet(strings.Fields(`
+	AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ BA BB BD BE BF BG BH BI BJ BL
+	BM BN BO BQ BR BS BT BV BW BY BZ CA CC CD CF CG CH CI CK CL CM CN CO CR CU CV
+	CW CX CY CZ DE DJ DK DM DO DZ EC EE EG EH ER ES ET FI FJ FK FM FO FR GA GB GD
+	GE GF GG GH GI GL GM GN GP GQ GR GS GT GU GW GY HK HM HN HR HT HU ID IE IL IM
+	IN IO IQ IR IS IT JE JM JO JP KE KG KH KI KM KN KP KR KW KY KZ LA LB LC LI LK
+	LR LS LT LU LV LY MA MC MD ME MF MG MH MK ML MM MN MO MP MQ MR MS MT MU MV MW
+	MX MY MZ NA NC NE NF NG NI NL NO NP NR NU NZ OM PA PE PF PG PH PK PL PM PN PR
+	PS PT PW PY QA RE RO RS RU RW SA SB SC SD SE SG SH SI SJ SK SL SM SN SO SR SS
+	ST SV SX SY SZ TC TD TF TG TH TJ TK TL TM TN TO TR TT TV TW TZ UA UG UM US UY
+	UZ VA VC VE VG VI VN VU WF WS YE YT ZA ZM ZW`))
+
+// countriesWithoutPostalCodes do not use postal codes, addresses there may leave it empty
+var countriesWithoutPostalCodes = toSet(strings.Fields(`
+	AE AG AO AW BF BI BJ BO BS BW BZ CD CF CG CI CK CM DJ DM ER FJ GA GD GH GM GQ
+	GY HK KI KM KN KP LY ML MO MR MW NR NU QA RW SB SC SL SO SR SS ST SY TD TF TG
+	TK TL TO TT TV UG VU YE ZW`))
+
+// postalCodePatterns are the postal code formats of the countries we ship to
+// most. Other countries accept any short code of letters, digits, spaces and
+// dashes
+var postalCodePatterns = map[string]*regexp.Regexp{
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"BE": regexp.MustCompile(`^\d{4}$`),
+	"BR": regexp.MustCompile(`^\d{5}-?\d{3}$`),
+	"CA": regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`),
+	"CH": regexp.MustCompile(`^\d{4}$`),
+	"CN": regexp.MustCompile(`^\d{6}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"DK": regexp.MustCompile(`^\d{4}$`),
+	"EG": regexp.MustCompile(`^\d{5}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+	"IE": regexp.MustCompile(`^[A-Z]\d[\dW] ?[A-Z\d]{4}$`),
+	"IN": regexp.MustCompile(`^\d{6}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+	"KE": regexp.MustCompile(`^\d{5}$`),
+	"MA": regexp.MustCompile(`^\d{5}$`),
+	"MX": regexp.MustCompile(`^\d{5}$`),
+	"NG": regexp.MustCompile(`^\d{6}$`),
+	"NL": regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`),
+	"NO": regexp.MustCompile(`^\d{4}$`),
+	"PL": regexp.MustCompile(`^\d{2}-\d{3}$`),
+	"PT": regexp.MustCompile(`^\d{4}-\d{3}$`),
+	"SE": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+	"ZA": regexp.MustCompile(`^\d{4}$`),
+}
+
+var defaultPostalCodePattern = regexp.MustCompile(`^[A-Z\d][A-Z\d -]{1,9}$`)
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+// NormalizeCountryCode upper-cases a country code and trims surrounding spaces
+func NormalizeCountryCode(country string) string {
+	return strings.ToUpper(strings.TrimSpace(country))
+}
+
+// NormalizePostalCode upper-cases a postal code and collapses its whitespace
+func NormalizePostalCode(postalCode string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(postalCode), " "))
+}
+
+// IsValidCountryCode reports whether country is an ISO 3166-1 alpha-2 code.
+// The code is expected to be normalized
+func IsValidCountryCode(country string) bool {
+	return countryCodes[country]
+}
+
+// ValidatePostalCode checks a normalized postal code against the format of
+// the country. The postal code may only be empty in countries without postal codes
+func ValidatePostalCode(country, postalCode string) error {
+	if postalCode == "" {
+		if countriesWithoutPostalCodes[country] {
+			return nil
+		}
+		return fmt.Errorf("A postal code is required for addresses in %s", country)
+	}
+
+	pattern, ok := postalCodePatterns[country]
+	if !ok {
+		pattern = defaultPostalCodePattern
+	}
+
+	if !pattern.MatchString(postalCode) {
+		return fmt.Errorf("Invalid postal code %s for country %s", postalCode, country)
+	}
+	return nil
+}
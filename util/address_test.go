@@ -0,0 +1,45 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsValidCountryCode(t *testing.T) {
+	assert.True(t, IsValidCountryCode("NG"))
+	assert.True(t, IsValidCountryCode(NormalizeCountryCode(" gb ")))
+	assert.False(t, IsValidCountryCode("UK"), "UK is not an ISO 3166-1 code")
+	assert.False(t, IsValidCountryCode("NGA"), "Alpha-3 codes are not accepted")
+}
+
+func TestValidatePostalCode(t *testing.T) {
+	valid := [][2]string{
+		{"US", "94103"},
+		{"US", "94103-1234"},
+		{"GB", "SW1A 1AA"},
+		{"CA", "K1A 0B1"},
+		{"NG", "106104"},
+		{"NL", "1012 AB"},
+		{"AR", "C1425"}, // no specific format, any short code is accepted
+		{"AE", ""},      // no postal codes
+	}
+	for _, address := range valid {
+		assert.NoError(t, ValidatePostalCode(address[0], NormalizePostalCode(address[1])), "%s %s", address[0], address[1])
+	}
+
+	invalid := [][2]string{
+		{"US", "9410"},
+		{"GB", "12345"},
+		{"DE", "1234A"},
+		{"NG", ""},
+		{"AR", "C1425!"},
+	}
+	for _, address := range invalid {
+		assert.Error(t, ValidatePostalCode(address[0], NormalizePostalCode(address[1])), "%s %s", address[0], address[1])
+	}
+}
+
+func TestNormalizePostalCode(t *testing.T) {
+	assert.Equal(t, "SW1A 1AA", NormalizePostalCode("  sw1a   1aa "))
+}
@@ -0,0 +1,91 @@
+package util
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// CACHE_SWEEP_THRESHOLD is the number of entries after which expired
+	// entries are swept from a cache on write. It is also the most entries a
+	// cache holds: when the sweep leaves it full, the CACHE_EVICTION_BATCH
+	// entries closest to expiry are evicted to make room
+	CACHE_SWEEP_THRESHOLD = 10000
+	CACHE_EVICTION_BATCH  = CACHE_SWEEP_THRESHOLD / 10
+)
+
+type cacheEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// Cache is a small thread-safe in-memory cache whose entries expire after a TTL
+type Cache[K comparable, V any] struct {
+	mutex   sync.RWMutex
+	entries map[K]cacheEntry[V]
+}
+
+func NewCache[K comparable, V any]() *Cache[K, V] {
+	return &Cache[K, V]{entries: make(map[K]cacheEntry[V])}
+}
+
+// Get returns the cached value for key if it exists and has not expired
+func (cache *Cache[K, V]) Get(key K) (V, bool) {
+	cache.mutex.RLock()
+	entry, ok := cache.entries[key]
+	cache.mutex.RUnlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
+}
+
+// Set stores value under key for the duration of ttl
+func (cache *Cache[K, V]) Set(key K, value V, ttl time.Duration) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if _, ok := cache.entries[key]; !ok && len(cache.entries) >= CACHE_SWEEP_THRESHOLD {
+		cache.sweep()
+		if len(cache.entries) >= CACHE_SWEEP_THRESHOLD {
+			cache.evict(len(cache.entries) - CACHE_SWEEP_THRESHOLD + CACHE_EVICTION_BATCH)
+		}
+	}
+	cache.entries[key] = cacheEntry[V]{value: value, expiresAt: time.Now().Add(ttl)}
+}
+
+// Delete removes key from the cache
+func (cache *Cache[K, V]) Delete(key K) {
+	cache.mutex.Lock()
+	delete(cache.entries, key)
+	cache.mutex.Unlock()
+}
+
+// sweep removes expired entries, the caller must hold the write lock
+func (cache *Cache[K, V]) sweep() {
+	now := time.Now()
+	for key, entry := range cache.entries {
+		if now.After(entry.expiresAt) {
+			delete(cache.entries, key)
+		}
+	}
+}
+
+// evict removes the count entries closest to expiry, the caller must hold the
+// write lock
+func (cache *Cache[K, V]) evict(count int) {
+	keys := make([]K, 0, len(cache.entries))
+	for key := range cache.entries {
+		keys = append(keys, key)
+	}
+
+	slices.SortFunc(keys, func(a, b K) int {
+		return cache.entries[a].expiresAt.Compare(cache.entries[b].expiresAt)
+	})
+	for _, key := range keys[:min(count, len(keys))] {
+		delete(cache.entries, key)
+	}
+}
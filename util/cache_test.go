@@ -0,0 +1,58 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheGetAndSet(t *testing.T) {
+	cache := NewCache[string, bool]()
+	cache.Set("jti", true, time.Minute)
+
+	value, ok := cache.Get("jti")
+	assert.True(t, ok, "Expected cached entry to be found")
+	assert.True(t, value)
+
+	_, ok = cache.Get("missing")
+	assert.False(t, ok, "Expected missing entry not to be found")
+}
+
+func TestCacheEntryExpires(t *testing.T) {
+	cache := NewCache[string, int]()
+	cache.Set("key", 1, -time.Second)
+
+	_, ok := cache.Get("key")
+	assert.False(t, ok, "Expected expired entry not to be returned")
+}
+
+func TestCacheDelete(t *testing.T) {
+	cache := NewCache[string, int]()
+	cache.Set("key", 1, time.Minute)
+	cache.Delete("key")
+
+	_, ok := cache.Get("key")
+	assert.False(t, ok, "Expected deleted entry not to be returned")
+}
+
+// A cache full of live entries makes room by evicting those closest to expiry
+func TestCacheEvictsEntriesClosestToExpiry(t *testing.T) {
+	cache := NewCache[int, int]()
+	for i := range CACHE_SWEEP_THRESHOLD {
+		cache.Set(i, i, time.Hour+time.Duration(i)*time.Second)
+	}
+
+	cache.Set(CACHE_SWEEP_THRESHOLD, 0, time.Hour)
+	assert.Len(t, cache.entries, CACHE_SWEEP_THRESHOLD-CACHE_EVICTION_BATCH+1)
+
+	_, ok := cache.Get(0)
+	assert.False(t, ok, "Expected the entry closest to expiry to be evicted")
+	_, ok = cache.Get(CACHE_SWEEP_THRESHOLD - 1)
+	assert.True(t, ok, "Expected the entry furthest from expiry to be kept")
+	_, ok = cache.Get(CACHE_SWEEP_THRESHOLD)
+	assert.True(t, ok, "Expected the new entry to be stored")
+
+	cache.Set(CACHE_SWEEP_THRESHOLD-1, 1, time.Hour)
+	assert.Len(t, cache.entries, CACHE_SWEEP_THRESHOLD-CACHE_EVICTION_BATCH+1, "Updating an entry should not evict others")
+}
@@ -1,40 +1,117 @@
 package util
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
 	"github.com/hackdaemon2/instashop/config"
 	"github.com/hackdaemon2/instashop/model"
 )
 
+const (
+	DEFAULT_ACCESS_TOKEN_TTL = 15 * time.Minute
+	TOKEN_ISSUER             = "instashop"
+	// ISSUED_AT_MILLIS_CLAIM carries the issue time in milliseconds, because
+	// iat only has whole seconds and a token issued right after a revocation
+	// would be taken for one issued before it
+	ISSUED_AT_MILLIS_CLAIM = "iat_ms"
+)
+
 type JwtData struct {
-	Issuer     string `json:"issuer"`
-	Token      string `json:"token"`
-	Expiry     int64  `json:"expires"`
-	DateIssued int64  `json:"issued"`
-	UserID     string `json:"user_id"`
+	Issuer        string `json:"issuer"`
+	Token         string `json:"token"`
+	Expiry        int64  `json:"expires"`
+	DateIssued    int64  `json:"issued"`
+	UserID        string `json:"user_id"`
+	RefreshToken  string `json:"refresh_token,omitempty"`
+	RefreshExpiry int64  `json:"refresh_expires,omitempty"`
+	SessionID     string `json:"session_id,omitempty"`
 }
 
 func newJwtData(strToken, userID string, exp int64, iss time.Time) JwtData {
 	return JwtData{
 		Token:      strToken,
 		Expiry:     exp,
-		Issuer:     "instashop",
+		Issuer:     TOKEN_ISSUER,
 		DateIssued: iss.Unix(),
 		UserID:     userID,
 	}
 }
 
+// AccessTokenTTL returns how long an access token stays valid
+func AccessTokenTTL() time.Duration {
+	return config.GetDurationEnv("ACCESS_TOKEN_TTL", DEFAULT_ACCESS_TOKEN_TTL)
+}
+
+// Authentication methods carried in the amr claim, see RFC 8176
+const (
+	PASSWORD_AUTH_METHOD     = "pwd"
+	OTP_AUTH_METHOD          = "otp"
+	MULTI_FACTOR_AUTH_METHOD = "mfa"
+)
+
+// AuthMethods returns the amr claim of a login, which lists the
+// second factor when one was used
+func AuthMethods(mfa bool) []string {
+	if mfa {
+		return []string{PASSWORD_AUTH_METHOD, OTP_AUTH_METHOD, MULTI_FACTOR_AUTH_METHOD}
+	}
+	return []string{PASSWORD_AUTH_METHOD}
+}
+
 func GenerateJWT(userID string, role model.Role) (JwtData, error) {
+	return GenerateJWTWithClaims(userID, role, nil)
+}
+
+// GenerateJWTWithClaims issues an access token carrying additional claims.
+// The registered claims cannot be overridden
+func GenerateJWTWithClaims(userID string, role model.Role, extra jwt.MapClaims) (JwtData, error) {
 	iss := time.Now()
-	exp := iss.Add(time.Hour * 24).Unix()
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     exp,
-		"role":    role,
+	exp := iss.Add(AccessTokenTTL()).Unix()
+	claims := jwt.MapClaims{}
+	for name, value := range extra {
+		claims[name] = value
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	strToken, err := token.SignedString([]byte(config.GetEnv("SECRET_KEY")))
+
+	claims["iss"] = TOKEN_ISSUER
+	claims["user_id"] = userID
+	claims["exp"] = exp
+	claims["iat"] = iss.Unix()
+	claims[ISSUED_AT_MILLIS_CLAIM] = iss.UnixMilli()
+	claims["jti"] = uuid.New().String()
+	claims["role"] = role
+
+	strToken, err := signClaims(claims)
 	return newJwtData(strToken, userID, exp, iss), err
 }
+
+// signClaims signs claims with the active key of the keyring
+func signClaims(claims jwt.MapClaims) (string, error) {
+	signingKey := Keys().ActiveKey()
+	token := jwt.NewWithClaims(signingKey.method(), claims)
+	if signingKey.ID != "" {
+		token.Header["kid"] = signingKey.ID
+	}
+	return token.SignedString(signingKey.signingKey())
+}
+
+// ParseJWT verifies a token issued by GenerateJWT. The key is selected by the
+// kid header and the token is rejected unless its alg header matches that key,
+// which rules out "none" and algorithm substitution attacks
+func ParseJWT(tokenString string) (*jwt.Token, error) {
+	keyring := Keys()
+	return jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		signingKey, err := keyring.VerificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+
+		if token.Method.Alg() != signingKey.Algorithm {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return signingKey.verificationKey(), nil
+	})
+}
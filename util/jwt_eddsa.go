@@ -0,0 +1,50 @@
+package util
+
+import (
+	"crypto/ed25519"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// SigningMethodEd25519 implements the EdDSA signing method (RFC 8037) with
+// Ed25519 keys, which jwt-go v3 does not ship with
+type SigningMethodEd25519 struct{}
+
+var SigningMethodEdDSA = &SigningMethodEd25519{}
+
+func init() {
+	jwt.RegisterSigningMethod(SigningMethodEdDSA.Alg(), func() jwt.SigningMethod {
+		return SigningMethodEdDSA
+	})
+}
+
+func (method *SigningMethodEd25519) Alg() string {
+	return EDDSA_ALGORITHM
+}
+
+// Sign signs signingString with an ed25519.PrivateKey
+func (method *SigningMethodEd25519) Sign(signingString string, key any) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok || len(privateKey) != ed25519.PrivateKeySize {
+		return "", jwt.ErrInvalidKeyType
+	}
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
+
+// Verify checks signature against signingString with an ed25519.PublicKey
+func (method *SigningMethodEd25519) Verify(signingString, signature string, key any) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok || len(publicKey) != ed25519.PublicKeySize {
+		return jwt.ErrInvalidKeyType
+	}
+
+	decodedSignature, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(publicKey, []byte(signingString), decodedSignature) {
+		return jwt.ErrSignatureInvalid
+	}
+	return nil
+}
@@ -0,0 +1,391 @@
+package util
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/hackdaemon2/instashop/config"
+)
+
+const (
+	HS256_ALGORITHM = "HS256"
+	RS256_ALGORITHM = "RS256"
+	EDDSA_ALGORITHM = "EdDSA"
+
+	DEFAULT_KEY_GRACE_PERIOD = 24 * time.Hour
+	RSA_KEY_BITS             = 2048
+)
+
+// SigningKey is a key used to sign access tokens. HS256 keys only carry a
+// shared secret, RS256 and EdDSA keys carry a private key whose public half
+// is published on the JWKS endpoint
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	Secret     []byte
+	PrivateKey crypto.Signer
+	RetiredAt  *time.Time // set once the key no longer signs new tokens
+}
+
+// JSONWebKey is the public representation of a signing key (RFC 7517)
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Modulus   string `json:"n,omitempty"`
+	Exponent  string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// Keyring holds the key that signs new tokens together with the keys that
+// are still accepted when verifying them. Retired keys keep verifying tokens
+// for the grace period so that tokens signed before a rotation stay valid
+type Keyring struct {
+	mutex       sync.RWMutex
+	active      *SigningKey
+	keys        map[string]*SigningKey
+	gracePeriod time.Duration
+}
+
+var (
+	keyring      *Keyring
+	keyringMutex sync.Mutex
+)
+
+// NewKeyring creates a keyring that signs with active and also verifies with others
+func NewKeyring(active *SigningKey, gracePeriod time.Duration, others ...*SigningKey) *Keyring {
+	keys := map[string]*SigningKey{active.ID: active}
+	for _, key := range others {
+		keys[key.ID] = key
+	}
+	return &Keyring{active: active, keys: keys, gracePeriod: gracePeriod}
+}
+
+// Keys returns the keyring used by the application, loading it from the
+// environment the first time it is needed
+func Keys() *Keyring {
+	keyringMutex.Lock()
+	defer keyringMutex.Unlock()
+
+	if keyring == nil {
+		loaded, err := LoadKeyring()
+		if err != nil {
+			log.Fatalf("Error loading JWT signing keys: %v", err)
+		}
+		keyring = loaded
+	}
+	return keyring
+}
+
+// SetKeyring replaces the keyring used by the application
+func SetKeyring(newKeyring *Keyring) {
+	keyringMutex.Lock()
+	keyring = newKeyring
+	keyringMutex.Unlock()
+}
+
+// ActiveKey returns the key that signs new tokens
+func (keyring *Keyring) ActiveKey() *SigningKey {
+	keyring.mutex.RLock()
+	defer keyring.mutex.RUnlock()
+	return keyring.active
+}
+
+// Rotate makes next the signing key. The previous key is retired and keeps
+// verifying tokens for the grace period
+func (keyring *Keyring) Rotate(next *SigningKey) {
+	keyring.mutex.Lock()
+	defer keyring.mutex.Unlock()
+
+	now := time.Now()
+	keyring.active.RetiredAt = &now
+	keyring.active = next
+	keyring.keys[next.ID] = next
+}
+
+// isUsable reports whether key may still verify tokens
+func (keyring *Keyring) isUsable(key *SigningKey, now time.Time) bool {
+	return key.RetiredAt == nil || now.Before(key.RetiredAt.Add(keyring.gracePeriod))
+}
+
+// VerificationKey returns the key a token with the given kid header has to be
+// verified with: the active key, a key published ahead of a rotation or a
+// retired key within its grace period. Tokens without a kid are only accepted
+// for shared-secret keys
+func (keyring *Keyring) VerificationKey(kid string) (*SigningKey, error) {
+	keyring.mutex.RLock()
+	defer keyring.mutex.RUnlock()
+
+	if kid == "" {
+		if keyring.active.Algorithm == HS256_ALGORITHM {
+			return keyring.active, nil
+		}
+		return nil, errors.New("token has no key id")
+	}
+
+	key, ok := keyring.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if !keyring.isUsable(key, time.Now()) {
+		return nil, fmt.Errorf("signing key %q has been retired", kid)
+	}
+	return key, nil
+}
+
+// JWKS returns the public keys that verifiers should currently accept.
+// Shared secrets are never published
+func (keyring *Keyring) JWKS() JSONWebKeySet {
+	keyring.mutex.RLock()
+	defer keyring.mutex.RUnlock()
+
+	now := time.Now()
+	keySet := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, key := range keyring.keys {
+		if key.PrivateKey == nil || !keyring.isUsable(key, now) {
+			continue
+		}
+		keySet.Keys = append(keySet.Keys, key.publicJWK())
+	}
+	return keySet
+}
+
+// signingKey returns the value jwt-go signs with for this key
+func (key *SigningKey) signingKey() any {
+	if key.Algorithm == HS256_ALGORITHM {
+		return key.Secret
+	}
+	return key.PrivateKey
+}
+
+// verificationKey returns the value jwt-go verifies with for this key
+func (key *SigningKey) verificationKey() any {
+	if key.Algorithm == HS256_ALGORITHM {
+		return key.Secret
+	}
+	return key.PrivateKey.Public()
+}
+
+func (key *SigningKey) method() jwt.SigningMethod {
+	switch key.Algorithm {
+	case RS256_ALGORITHM:
+		return jwt.SigningMethodRS256
+	case EDDSA_ALGORITHM:
+		return SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+func (key *SigningKey) publicJWK() JSONWebKey {
+	jwk := JSONWebKey{KeyID: key.ID, Use: "sig", Algorithm: key.Algorithm}
+
+	switch publicKey := key.PrivateKey.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.Modulus = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.Exponent = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	}
+	return jwk
+}
+
+// NewSigningKey wraps a private key and derives its algorithm from the key type
+func NewSigningKey(kid string, privateKey crypto.Signer) (*SigningKey, error) {
+	switch privateKey.(type) {
+	case *rsa.PrivateKey:
+		return &SigningKey{ID: kid, Algorithm: RS256_ALGORITHM, PrivateKey: privateKey}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{ID: kid, Algorithm: EDDSA_ALGORITHM, PrivateKey: privateKey}, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T for key %q", privateKey, kid)
+	}
+}
+
+// GenerateSigningKey creates a fresh key for the given asymmetric algorithm
+func GenerateSigningKey(kid, algorithm string) (*SigningKey, error) {
+	var privateKey crypto.Signer
+	var err error
+
+	switch algorithm {
+	case RS256_ALGORITHM:
+		privateKey, err = rsa.GenerateKey(rand.Reader, RSA_KEY_BITS)
+	case EDDSA_ALGORITHM:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("cannot generate a key for algorithm %q", algorithm)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return NewSigningKey(kid, privateKey)
+}
+
+// parsePrivateKey reads a PEM encoded PKCS#8 or PKCS#1 private key
+func parsePrivateKey(pemBytes []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+// loadSigningKeys reads every <kid>.pem file in directory
+func loadSigningKeys(directory string) (map[string]*SigningKey, error) {
+	files, err := filepath.Glob(filepath.Join(directory, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*SigningKey, len(files))
+	for _, file := range files {
+		pemBytes, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		privateKey, err := parsePrivateKey(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", kid, err)
+		}
+
+		if keys[kid], err = NewSigningKey(kid, privateKey); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// parseRetiredKeys parses a list such as "kid1=2024-05-01T00:00:00Z,kid2=..."
+// giving the time each key stopped signing tokens
+func parseRetiredKeys(value string) (map[string]time.Time, error) {
+	retired := make(map[string]time.Time)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kid, retiredAt, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid retired key entry %q", entry)
+		}
+
+		parsed, err := time.Parse(time.RFC3339, retiredAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retirement time for key %q: %w", kid, err)
+		}
+		retired[kid] = parsed
+	}
+	return retired, nil
+}
+
+// LoadKeyring builds the keyring described by the environment.
+//
+// With JWT_SIGNING_ALGORITHM=HS256 (the default) tokens are signed with SECRET_KEY.
+// With RS256 or EdDSA every <kid>.pem file in JWT_KEYS_DIR is loaded, the key named
+// by JWT_ACTIVE_KEY_ID signs new tokens and the keys listed in JWT_RETIRED_KEYS are
+// accepted for JWT_KEY_GRACE_PERIOD after their retirement time. Any other key in
+// the directory is published ahead of a rotation. It does not sign tokens yet but
+// already verifies them, so that during a rolling rotation the instances still
+// on the old configuration accept the tokens of those already switched over.
+// Without JWT_KEYS_DIR startup fails, unless JWT_ALLOW_EPHEMERAL_KEY=true asks
+// for a key generated at startup, which is only fit for a single development instance
+func LoadKeyring() (*Keyring, error) {
+	algorithm := os.Getenv("JWT_SIGNING_ALGORITHM")
+	if algorithm == "" {
+		algorithm = HS256_ALGORITHM
+	}
+	gracePeriod := config.GetDurationEnv("JWT_KEY_GRACE_PERIOD", DEFAULT_KEY_GRACE_PERIOD)
+
+	switch algorithm {
+	case HS256_ALGORITHM:
+		return NewKeyring(&SigningKey{Algorithm: HS256_ALGORITHM, Secret: []byte(config.GetEnv("SECRET_KEY"))}, gracePeriod), nil
+	case RS256_ALGORITHM, EDDSA_ALGORITHM:
+	default:
+		return nil, fmt.Errorf("unsupported JWT signing algorithm %q", algorithm)
+	}
+
+	directory := os.Getenv("JWT_KEYS_DIR")
+	if directory == "" {
+		// every restart and every other instance would sign with a key of its
+		// own and reject the tokens issued before, so this has to be asked for
+		if !config.GetBoolEnv("JWT_ALLOW_EPHEMERAL_KEY", false) {
+			return nil, fmt.Errorf("JWT_KEYS_DIR is required for %s, set JWT_ALLOW_EPHEMERAL_KEY=true to sign with a throwaway key instead", algorithm)
+		}
+
+		log.Printf("JWT_KEYS_DIR not set, generating an ephemeral %s signing key", algorithm)
+		active, err := GenerateSigningKey(fmt.Sprintf("ephemeral-%d", time.Now().Unix()), algorithm)
+		if err != nil {
+			return nil, err
+		}
+		return NewKeyring(active, gracePeriod), nil
+	}
+
+	keys, err := loadSigningKeys(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	activeID := config.GetEnv("JWT_ACTIVE_KEY_ID")
+	active, ok := keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active signing key %q not found in %s", activeID, directory)
+	}
+
+	if active.Algorithm != algorithm {
+		return nil, fmt.Errorf("active signing key %q is a %s key, expected %s", activeID, active.Algorithm, algorithm)
+	}
+
+	retired, err := parseRetiredKeys(os.Getenv("JWT_RETIRED_KEYS"))
+	if err != nil {
+		return nil, err
+	}
+
+	others := make([]*SigningKey, 0, len(keys))
+	for kid, key := range keys {
+		if kid == activeID {
+			continue
+		}
+		if retiredAt, ok := retired[kid]; ok {
+			key.RetiredAt = &retiredAt
+		}
+		others = append(others, key)
+	}
+
+	return NewKeyring(active, gracePeriod, others...), nil
+}
@@ -0,0 +1,161 @@
+package util
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/hackdaemon2/instashop/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// useKeyring swaps the application keyring for the duration of a test
+func useKeyring(t *testing.T, testKeyring *Keyring) {
+	previous := Keys()
+	SetKeyring(testKeyring)
+	t.Cleanup(func() { SetKeyring(previous) })
+}
+
+func generateTestKey(t *testing.T, kid, algorithm string) *SigningKey {
+	key, err := GenerateSigningKey(kid, algorithm)
+	if err != nil {
+		t.Fatalf("Error generating %s key: %v", algorithm, err)
+	}
+	return key
+}
+
+func TestGenerateAndParseJWTWithAsymmetricKeys(t *testing.T) {
+	for _, algorithm := range []string{RS256_ALGORITHM, EDDSA_ALGORITHM} {
+		t.Run(algorithm, func(t *testing.T) {
+			useKeyring(t, NewKeyring(generateTestKey(t, "key-1", algorithm), time.Hour))
+
+			jwtData, err := GenerateJWT("user123", model.Role("user"))
+			assert.NoError(t, err)
+
+			token, err := ParseJWT(jwtData.Token)
+			assert.NoError(t, err, "Expected token to verify with the active key")
+			assert.True(t, token.Valid)
+			assert.Equal(t, "key-1", token.Header["kid"])
+			assert.Equal(t, algorithm, token.Header["alg"])
+		})
+	}
+}
+
+func TestParseJWTAfterRotation(t *testing.T) {
+	testKeyring := NewKeyring(generateTestKey(t, "old", EDDSA_ALGORITHM), time.Hour)
+	useKeyring(t, testKeyring)
+
+	jwtData, err := GenerateJWT("user123", model.Role("user"))
+	assert.NoError(t, err)
+
+	testKeyring.Rotate(generateTestKey(t, "new", EDDSA_ALGORITHM))
+
+	_, err = ParseJWT(jwtData.Token)
+	assert.NoError(t, err, "Tokens signed with a retired key should verify during the grace period")
+	assert.Len(t, testKeyring.JWKS().Keys, 2, "Both keys should be published during the grace period")
+
+	retiredAt := time.Now().Add(-2 * time.Hour)
+	testKeyring.keys["old"].RetiredAt = &retiredAt
+
+	_, err = ParseJWT(jwtData.Token)
+	assert.Error(t, err, "Tokens signed with a key past its grace period should be rejected")
+	assert.Len(t, testKeyring.JWKS().Keys, 1, "Expired keys should not be published")
+}
+
+func TestParseJWTRejectsAlgorithmMismatch(t *testing.T) {
+	rsaKey := generateTestKey(t, "rsa", RS256_ALGORITHM)
+	useKeyring(t, NewKeyring(rsaKey, time.Hour))
+
+	// sign an HS256 token with the public key as the secret and the RSA key id
+	publicKey, _ := x509.MarshalPKIXPublicKey(rsaKey.PrivateKey.Public())
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": "user123"})
+	token.Header["kid"] = "rsa"
+	forged, err := token.SignedString(publicKey)
+	assert.NoError(t, err)
+
+	_, err = ParseJWT(forged)
+	assert.Error(t, err, "Tokens whose alg does not match the key should be rejected")
+}
+
+func TestParseJWTRejectsMissingKeyID(t *testing.T) {
+	useKeyring(t, NewKeyring(generateTestKey(t, "ed", EDDSA_ALGORITHM), time.Hour))
+
+	token := jwt.NewWithClaims(SigningMethodEdDSA, jwt.MapClaims{"user_id": "user123"})
+	signed, err := token.SignedString(Keys().ActiveKey().PrivateKey)
+	assert.NoError(t, err)
+
+	_, err = ParseJWT(signed)
+	assert.Error(t, err, "Asymmetric tokens without a kid should be rejected")
+}
+
+func TestJWKSDoesNotPublishSharedSecrets(t *testing.T) {
+	testKeyring := NewKeyring(&SigningKey{Algorithm: HS256_ALGORITHM, Secret: []byte("secret")}, time.Hour)
+	assert.Empty(t, testKeyring.JWKS().Keys)
+}
+
+func TestJWKSPublicKeyFormat(t *testing.T) {
+	testKeyring := NewKeyring(generateTestKey(t, "rsa", RS256_ALGORITHM), time.Hour, generateTestKey(t, "ed", EDDSA_ALGORITHM))
+
+	keys := map[string]JSONWebKey{}
+	for _, key := range testKeyring.JWKS().Keys {
+		keys[key.KeyID] = key
+	}
+
+	assert.Equal(t, "RSA", keys["rsa"].KeyType)
+	assert.Equal(t, "AQAB", keys["rsa"].Exponent)
+	assert.NotEmpty(t, keys["rsa"].Modulus)
+	assert.Equal(t, "OKP", keys["ed"].KeyType)
+	assert.Equal(t, "Ed25519", keys["ed"].Curve)
+	assert.NotEmpty(t, keys["ed"].X)
+}
+
+func TestLoadKeyringFromDirectory(t *testing.T) {
+	directory := t.TempDir()
+	for _, kid := range []string{"2024-01", "2024-02"} {
+		key := generateTestKey(t, kid, EDDSA_ALGORITHM)
+		der, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+		assert.NoError(t, err)
+		pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		assert.NoError(t, os.WriteFile(filepath.Join(directory, kid+".pem"), pemBytes, 0o600))
+	}
+
+	t.Setenv("JWT_SIGNING_ALGORITHM", EDDSA_ALGORITHM)
+	t.Setenv("JWT_KEYS_DIR", directory)
+	t.Setenv("JWT_ACTIVE_KEY_ID", "2024-02")
+	t.Setenv("JWT_RETIRED_KEYS", "2024-01="+time.Now().Format(time.RFC3339))
+
+	loaded, err := LoadKeyring()
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-02", loaded.ActiveKey().ID)
+
+	previous, err := loaded.VerificationKey("2024-01")
+	assert.NoError(t, err, "The retired key should still verify tokens")
+	assert.NotNil(t, previous.RetiredAt)
+}
+
+func TestLoadKeyringUnknownActiveKey(t *testing.T) {
+	t.Setenv("JWT_SIGNING_ALGORITHM", RS256_ALGORITHM)
+	t.Setenv("JWT_KEYS_DIR", t.TempDir())
+	t.Setenv("JWT_ACTIVE_KEY_ID", "missing")
+
+	_, err := LoadKeyring()
+	assert.Error(t, err)
+}
+
+// A key generated at startup changes with every restart, so it has to be asked for
+func TestLoadKeyringWithoutKeysDirectory(t *testing.T) {
+	t.Setenv("JWT_SIGNING_ALGORITHM", EDDSA_ALGORITHM)
+	t.Setenv("JWT_KEYS_DIR", "")
+
+	_, err := LoadKeyring()
+	assert.ErrorContains(t, err, "JWT_ALLOW_EPHEMERAL_KEY")
+
+	t.Setenv("JWT_ALLOW_EPHEMERAL_KEY", "true")
+	loaded, err := LoadKeyring()
+	assert.NoError(t, err)
+	assert.Equal(t, EDDSA_ALGORITHM, loaded.ActiveKey().Algorithm)
+}
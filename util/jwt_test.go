@@ -46,6 +46,8 @@ func TestGenerateJWT(t *testing.T) {
 	assert.Equal(t, userID, claims["user_id"], "User ID should be in claims")
 	assert.Equal(t, float64(jwtData.Expiry), claims["exp"], "Expiration time in claims should match")
 	assert.Equal(t, string(role), claims["role"], "Role in claims should match")
+	assert.NotEmpty(t, claims["jti"], "Token should carry a jti claim")
+	assert.Equal(t, float64(jwtData.DateIssued), claims["iat"], "Issued at in claims should match")
 }
 
 func TestGenerateJWTExpiration(t *testing.T) {
@@ -54,7 +56,7 @@ func TestGenerateJWTExpiration(t *testing.T) {
 	jwtData, err := GenerateJWT(userID, role)
 	assert.NoError(t, err)
 	expiration := time.Unix(jwtData.Expiry, 0)
-	assert.WithinDuration(t, time.Now().Add(24*time.Hour), expiration, time.Second, "Expiration should be 24 hours from now")
+	assert.WithinDuration(t, time.Now().Add(DEFAULT_ACCESS_TOKEN_TTL), expiration, time.Second, "Expiration should match the access token TTL")
 }
 
 func TestGenerateJWTInvalidRole(t *testing.T) {
@@ -0,0 +1,115 @@
+package util
+
+import (
+	"fmt"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hackdaemon2/instashop/config"
+)
+
+const (
+	SMTP_MAIL_DRIVER      = "smtp"
+	OUTBOX_MAIL_DRIVER    = "outbox"
+	DEFAULT_MAIL_FROM     = "no-reply@instashop.local"
+	DEFAULT_OUTBOX_FOLDER = "outbox"
+)
+
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends transactional emails such as password reset links
+type Mailer interface {
+	Send(message MailMessage) error
+}
+
+// SMTPMailer delivers messages through an SMTP server
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// OutboxMailer writes every message to a file in Directory instead of
+// delivering it. It is meant for tests and local development
+type OutboxMailer struct {
+	Directory string
+	From      string
+}
+
+// NewMailer builds the mailer selected by the MAIL_DRIVER environment variable.
+// Messages go to the local outbox unless the smtp driver is configured
+func NewMailer() Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = DEFAULT_MAIL_FROM
+	}
+
+	if os.Getenv("MAIL_DRIVER") == SMTP_MAIL_DRIVER {
+		return &SMTPMailer{
+			Host:     config.GetEnv("SMTP_HOST"),
+			Port:     config.GetEnv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	}
+
+	directory := os.Getenv("MAIL_OUTBOX_DIR")
+	if directory == "" {
+		directory = DEFAULT_OUTBOX_FOLDER
+	}
+	return &OutboxMailer{Directory: directory, From: from}
+}
+
+func (mailer *SMTPMailer) Send(message MailMessage) error {
+	var auth smtp.Auth
+	if mailer.Username != "" {
+		auth = smtp.PlainAuth("", mailer.Username, mailer.Password, mailer.Host)
+	}
+
+	address := fmt.Sprintf("%s:%s", mailer.Host, mailer.Port)
+	return smtp.SendMail(address, auth, mailer.From, []string{message.To}, formatMailMessage(mailer.From, message))
+}
+
+func (mailer *OutboxMailer) Send(message MailMessage) error {
+	if err := os.MkdirAll(mailer.Directory, 0o755); err != nil {
+		return err
+	}
+
+	suffix, err := GenerateSecureToken(6)
+	if err != nil {
+		return err
+	}
+
+	fileName := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), suffix)
+	return os.WriteFile(filepath.Join(mailer.Directory, fileName), formatMailMessage(mailer.From, message), 0o600)
+}
+
+// sanitizeHeader strips line breaks so that user supplied values
+// cannot inject additional mail headers
+func sanitizeHeader(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
+
+// formatMailMessage renders a plain text RFC 5322 message
+func formatMailMessage(from string, message MailMessage) []byte {
+	var builder strings.Builder
+	builder.WriteString("From: " + sanitizeHeader(from) + "\r\n")
+	builder.WriteString("To: " + sanitizeHeader(message.To) + "\r\n")
+	builder.WriteString("Subject: " + sanitizeHeader(message.Subject) + "\r\n")
+	builder.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return []byte(builder.String())
+}
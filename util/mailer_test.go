@@ -0,0 +1,37 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboxMailerWritesMessage(t *testing.T) {
+	directory := t.TempDir()
+	mailer := &OutboxMailer{Directory: directory, From: DEFAULT_MAIL_FROM}
+
+	err := mailer.Send(MailMessage{To: "test@test.com", Subject: "Reset your password", Body: "token: abc"})
+	assert.NoError(t, err, "Expected no error while writing to the outbox")
+
+	files, err := filepath.Glob(filepath.Join(directory, "*.eml"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1, "Expected exactly one message in the outbox")
+
+	content, err := os.ReadFile(files[0])
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "To: test@test.com")
+	assert.Contains(t, string(content), "Subject: Reset your password")
+	assert.Contains(t, string(content), "token: abc")
+}
+
+func TestFormatMailMessageStripsHeaderInjection(t *testing.T) {
+	message := formatMailMessage(DEFAULT_MAIL_FROM, MailMessage{
+		To:      "test@test.com\r\nBcc: attacker@test.com",
+		Subject: "Hello",
+		Body:    "Body",
+	})
+
+	assert.NotContains(t, string(message), "\r\nBcc:", "Line breaks in headers should be removed")
+}
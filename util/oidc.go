@@ -0,0 +1,353 @@
+package util
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	OIDC_DISCOVERY_PATH    = "/.well-known/openid-configuration"
+	DEFAULT_OIDC_SCOPES    = "openid email profile"
+	PKCE_CHALLENGE_METHOD  = "S256"
+	PKCE_VERIFIER_SIZE     = 32
+	OIDC_HTTP_TIMEOUT      = 10 * time.Second
+	OIDC_MAX_RESPONSE_SIZE = 1 << 20
+)
+
+// OIDCConfig describes the OpenID Connect provider and the client registered with it
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string // empty for public clients, which rely on PKCE alone
+	RedirectURL  string
+	Scopes       []string
+}
+
+// OIDCDiscovery holds the parts of the provider metadata the login flow needs
+type OIDCDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCClaims are the identity claims read from a verified ID token
+type OIDCClaims struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+}
+
+// OIDCProvider runs the authorization code flow with PKCE against a single
+// provider. The provider metadata and signing keys are fetched on first use
+// and the keys are fetched again when a token names an unknown key
+type OIDCProvider struct {
+	Config     OIDCConfig
+	HTTPClient *http.Client
+
+	mutex     sync.Mutex
+	discovery *OIDCDiscovery
+	keys      map[string]any
+}
+
+type oidcTokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// NewOIDCProvider creates a provider client for the given configuration
+func NewOIDCProvider(config OIDCConfig) *OIDCProvider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = strings.Fields(DEFAULT_OIDC_SCOPES)
+	}
+	config.Issuer = strings.TrimSuffix(config.Issuer, "/")
+	return &OIDCProvider{Config: config, HTTPClient: &http.Client{Timeout: OIDC_HTTP_TIMEOUT}}
+}
+
+// NewOIDCProviderFromEnv creates the provider configured by the OIDC_*
+// environment variables, or returns nil when OIDC_ISSUER is not set
+func NewOIDCProviderFromEnv() *OIDCProvider {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return nil
+	}
+
+	scopes := os.Getenv("OIDC_SCOPES")
+	if scopes == "" {
+		scopes = DEFAULT_OIDC_SCOPES
+	}
+
+	return NewOIDCProvider(OIDCConfig{
+		Issuer:       issuer,
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:       strings.Fields(scopes),
+	})
+}
+
+// GeneratePKCEVerifier returns a random code verifier (RFC 7636)
+func GeneratePKCEVerifier() (string, error) {
+	return GenerateSecureToken(PKCE_VERIFIER_SIZE)
+}
+
+// PKCEChallenge derives the S256 code challenge sent with the authorization request
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Discovery returns the provider metadata, fetching it on first use
+func (provider *OIDCProvider) Discovery() (*OIDCDiscovery, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if provider.discovery != nil {
+		return provider.discovery, nil
+	}
+
+	var discovery OIDCDiscovery
+	if err := provider.getJSON(provider.Config.Issuer+OIDC_DISCOVERY_PATH, &discovery); err != nil {
+		return nil, fmt.Errorf("unable to load provider metadata: %w", err)
+	}
+
+	if strings.TrimSuffix(discovery.Issuer, "/") != provider.Config.Issuer {
+		return nil, fmt.Errorf("provider metadata is for issuer %q", discovery.Issuer)
+	}
+
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("provider metadata is missing an endpoint")
+	}
+
+	provider.discovery = &discovery
+	return provider.discovery, nil
+}
+
+// AuthorizationURL builds the URL the user is redirected to in order to log in at the provider
+func (provider *OIDCProvider) AuthorizationURL(state, nonce, codeVerifier string) (string, error) {
+	discovery, err := provider.Discovery()
+	if err != nil {
+		return "", err
+	}
+
+	authorizationURL, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	query := authorizationURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", provider.Config.ClientID)
+	query.Set("redirect_uri", provider.Config.RedirectURL)
+	query.Set("scope", strings.Join(provider.Config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", PKCEChallenge(codeVerifier))
+	query.Set("code_challenge_method", PKCE_CHALLENGE_METHOD)
+	authorizationURL.RawQuery = query.Encode()
+
+	return authorizationURL.String(), nil
+}
+
+// Exchange redeems an authorization code at the token endpoint and returns the raw ID token
+func (provider *OIDCProvider) Exchange(code, codeVerifier string) (string, error) {
+	discovery, err := provider.Discovery()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {provider.Config.RedirectURL},
+		"client_id":     {provider.Config.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	if provider.Config.ClientSecret != "" {
+		form.Set("client_secret", provider.Config.ClientSecret)
+	}
+
+	response, err := provider.HTTPClient.PostForm(discovery.TokenEndpoint, form)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	var tokenResponse oidcTokenResponse
+	if err := json.NewDecoder(io.LimitReader(response.Body, OIDC_MAX_RESPONSE_SIZE)).Decode(&tokenResponse); err != nil {
+		return "", fmt.Errorf("unable to read token response: %w", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed with status %d: %s %s", response.StatusCode,
+			tokenResponse.Error, tokenResponse.ErrorDescription)
+	}
+
+	if tokenResponse.IDToken == "" {
+		return "", errors.New("token response does not contain an id_token")
+	}
+	return tokenResponse.IDToken, nil
+}
+
+// VerifyIDToken checks the signature, issuer, audience, expiry and nonce of
+// an ID token and returns its identity claims
+func (provider *OIDCProvider) VerifyIDToken(rawIDToken, nonce string) (*OIDCClaims, error) {
+	discovery, err := provider.Discovery()
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &jwt.Parser{ValidMethods: []string{RS256_ALGORITHM, EDDSA_ALGORITHM}}
+	token, err := parser.Parse(rawIDToken, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return provider.verificationKey(kid)
+	})
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("invalid id token: %v", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid id token claims")
+	}
+
+	if issuer, _ := claims["iss"].(string); issuer != discovery.Issuer {
+		return nil, fmt.Errorf("id token was issued by %q", issuer)
+	}
+
+	if !claims.VerifyAudience(provider.Config.ClientID, true) && !audienceContains(claims["aud"], provider.Config.ClientID) {
+		return nil, errors.New("id token was issued for another client")
+	}
+
+	// jwt-go rejects expired tokens but accepts tokens without an expiry
+	if _, ok := claims["exp"].(float64); !ok {
+		return nil, errors.New("id token has no expiry")
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce == "" || tokenNonce != nonce {
+		return nil, errors.New("id token nonce does not match")
+	}
+
+	oidcClaims := &OIDCClaims{Issuer: discovery.Issuer, EmailVerified: isTrueClaim(claims["email_verified"])}
+	oidcClaims.Subject, _ = claims["sub"].(string)
+	oidcClaims.Email, _ = claims["email"].(string)
+	oidcClaims.GivenName, _ = claims["given_name"].(string)
+	oidcClaims.FamilyName, _ = claims["family_name"].(string)
+
+	if oidcClaims.Subject == "" {
+		return nil, errors.New("id token has no subject")
+	}
+	return oidcClaims, nil
+}
+
+// verificationKey returns the provider key with the given id, reloading the
+// key set once when the key is not known yet
+func (provider *OIDCProvider) verificationKey(kid string) (any, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if key, ok := provider.keys[kid]; ok {
+		return key, nil
+	}
+
+	var keySet JSONWebKeySet
+	if err := provider.getJSON(provider.discovery.JWKSURI, &keySet); err != nil {
+		return nil, fmt.Errorf("unable to load provider keys: %w", err)
+	}
+
+	keys := map[string]any{}
+	for _, jwk := range keySet.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.KeyID] = key
+		}
+	}
+	provider.keys = keys
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown provider key %q", kid)
+}
+
+func (provider *OIDCProvider) getJSON(address string, target any) error {
+	response, err := provider.HTTPClient.Get(address)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned status %d", address, response.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(response.Body, OIDC_MAX_RESPONSE_SIZE)).Decode(target)
+}
+
+// publicKey converts an RSA or Ed25519 JSON web key into the value jwt-go verifies with
+func (jwk JSONWebKey) publicKey() (any, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		modulus, err := base64.RawURLEncoding.DecodeString(jwk.Modulus)
+		if err != nil {
+			return nil, err
+		}
+		exponent, err := base64.RawURLEncoding.DecodeString(jwk.Exponent)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(modulus), E: int(new(big.Int).SetBytes(exponent).Int64())}, nil
+	case "OKP":
+		publicKey, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || jwk.Curve != "Ed25519" || len(publicKey) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(publicKey), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.KeyType)
+	}
+}
+
+// audienceContains handles an aud claim that lists several audiences, which
+// jwt-go v3 does not understand
+func audienceContains(audience any, clientID string) bool {
+	audiences, _ := audience.([]any)
+	for _, value := range audiences {
+		if value == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// isTrueClaim accepts boolean claims that some providers send as strings
+func isTrueClaim(value any) bool {
+	switch claim := value.(type) {
+	case bool:
+		return claim
+	case string:
+		return claim == "true"
+	default:
+		return false
+	}
+}
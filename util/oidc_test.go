@@ -0,0 +1,191 @@
+package util
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	TEST_OIDC_CLIENT_ID = "instashop"
+	TEST_OIDC_CODE      = "authorization-code"
+	TEST_OIDC_NONCE     = "nonce"
+)
+
+// testOIDCServer is a local stand-in for an OpenID Connect provider. It only
+// redeems TEST_OIDC_CODE with the code verifier matching codeChallenge
+type testOIDCServer struct {
+	*httptest.Server
+	key           *SigningKey
+	codeChallenge string
+	claims        jwt.MapClaims
+}
+
+func newTestOIDCServer(t *testing.T) *testOIDCServer {
+	server := &testOIDCServer{key: generateTestKey(t, "provider-key", RS256_ALGORITHM)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(OIDC_DISCOVERY_PATH, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(OIDCDiscovery{
+			Issuer:                server.URL,
+			AuthorizationEndpoint: server.URL + "/authorize",
+			TokenEndpoint:         server.URL + "/token",
+			JWKSURI:               server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(NewKeyring(server.key, time.Hour).JWKS())
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("code") != TEST_OIDC_CODE || r.PostFormValue("client_id") != TEST_OIDC_CLIENT_ID ||
+			PKCEChallenge(r.PostFormValue("code_verifier")) != server.codeChallenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": server.sign(t, server.claims)})
+	})
+
+	server.Server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	server.claims = jwt.MapClaims{
+		"iss":            server.URL,
+		"sub":            "subject-1",
+		"aud":            TEST_OIDC_CLIENT_ID,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          TEST_OIDC_NONCE,
+		"email":          "jane@example.com",
+		"email_verified": true,
+		"given_name":     "Jane",
+		"family_name":    "Doe",
+	}
+	return server
+}
+
+func (server *testOIDCServer) sign(t *testing.T, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = server.key.ID
+	signed, err := token.SignedString(server.key.PrivateKey)
+	if err != nil {
+		t.Fatalf("Error signing id token: %v", err)
+	}
+	return signed
+}
+
+func (server *testOIDCServer) provider() *OIDCProvider {
+	return NewOIDCProvider(OIDCConfig{
+		Issuer:      server.URL,
+		ClientID:    TEST_OIDC_CLIENT_ID,
+		RedirectURL: "http://localhost:3000/api/v1/user/oidc/callback",
+	})
+}
+
+func TestOIDCAuthorizationURLUsesPKCE(t *testing.T) {
+	server := newTestOIDCServer(t)
+
+	authorizationURL, err := server.provider().AuthorizationURL("state", TEST_OIDC_NONCE, "verifier")
+	assert.NoError(t, err)
+
+	parsed, err := url.Parse(authorizationURL)
+	assert.NoError(t, err)
+	query := parsed.Query()
+
+	assert.Equal(t, server.URL+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, TEST_OIDC_CLIENT_ID, query.Get("client_id"))
+	assert.Equal(t, DEFAULT_OIDC_SCOPES, query.Get("scope"))
+	assert.Equal(t, "state", query.Get("state"))
+	assert.Equal(t, TEST_OIDC_NONCE, query.Get("nonce"))
+	assert.Equal(t, PKCEChallenge("verifier"), query.Get("code_challenge"))
+	assert.Equal(t, PKCE_CHALLENGE_METHOD, query.Get("code_challenge_method"))
+}
+
+func TestPKCEChallengeMatchesRFC7636(t *testing.T) {
+	// test vector from RFC 7636 appendix B
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", PKCEChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+}
+
+func TestOIDCExchangeAndVerifyIDToken(t *testing.T) {
+	server := newTestOIDCServer(t)
+	server.codeChallenge = PKCEChallenge("verifier")
+	provider := server.provider()
+
+	rawIDToken, err := provider.Exchange(TEST_OIDC_CODE, "verifier")
+	assert.NoError(t, err)
+
+	claims, err := provider.VerifyIDToken(rawIDToken, TEST_OIDC_NONCE)
+	assert.NoError(t, err)
+	assert.Equal(t, &OIDCClaims{
+		Issuer:        server.URL,
+		Subject:       "subject-1",
+		Email:         "jane@example.com",
+		EmailVerified: true,
+		GivenName:     "Jane",
+		FamilyName:    "Doe",
+	}, claims)
+}
+
+func TestOIDCExchangeRejectsWrongCodeVerifier(t *testing.T) {
+	server := newTestOIDCServer(t)
+	server.codeChallenge = PKCEChallenge("verifier")
+
+	_, err := server.provider().Exchange(TEST_OIDC_CODE, "another-verifier")
+	assert.ErrorContains(t, err, "invalid_grant")
+}
+
+func TestOIDCVerifyIDTokenRejectsInvalidTokens(t *testing.T) {
+	server := newTestOIDCServer(t)
+	otherKey := generateTestKey(t, "provider-key", RS256_ALGORITHM)
+
+	withClaim := func(name string, value any) jwt.MapClaims {
+		claims := jwt.MapClaims{}
+		for key, claim := range server.claims {
+			claims[key] = claim
+		}
+		claims[name] = value
+		return claims
+	}
+
+	forgedToken := jwt.NewWithClaims(jwt.SigningMethodRS256, server.claims)
+	forgedToken.Header["kid"] = server.key.ID
+	forged, _ := forgedToken.SignedString(otherKey.PrivateKey)
+
+	hmacToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, server.claims).SignedString([]byte("secret"))
+
+	testCases := map[string]string{
+		"wrong nonce":    server.sign(t, withClaim("nonce", "another-nonce")),
+		"wrong audience": server.sign(t, withClaim("aud", "another-client")),
+		"wrong issuer":   server.sign(t, withClaim("iss", "https://attacker.example")),
+		"expired":        server.sign(t, withClaim("exp", time.Now().Add(-time.Hour).Unix())),
+		"no expiry":      server.sign(t, withClaim("exp", nil)),
+		"no subject":     server.sign(t, withClaim("sub", "")),
+		"forged":         forged,
+		"shared secret":  hmacToken,
+	}
+
+	provider := server.provider()
+	for name, rawIDToken := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := provider.VerifyIDToken(rawIDToken, TEST_OIDC_NONCE)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestOIDCVerifyIDTokenAcceptsAudienceList(t *testing.T) {
+	server := newTestOIDCServer(t)
+	server.claims["aud"] = []string{"another-client", TEST_OIDC_CLIENT_ID}
+	server.claims["email_verified"] = "true"
+
+	claims, err := server.provider().VerifyIDToken(server.sign(t, server.claims), TEST_OIDC_NONCE)
+	assert.NoError(t, err)
+	assert.True(t, claims.EmailVerified)
+}
@@ -0,0 +1,191 @@
+package util
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	S3_REQUEST_TIMEOUT = 30 * time.Second
+	S3_SIGNING_SERVICE = "s3"
+	S3_AMZ_DATE_FORMAT = "20060102T150405Z"
+)
+
+// S3Storage keeps files in a bucket of an S3 compatible object store, such as
+// AWS S3 or MinIO. Requests use path style addressing, {Endpoint}/{Bucket}/{key},
+// and are signed with AWS Signature Version 4. Files are downloaded from
+// BaseURL, a CDN or public bucket URL, or straight from the bucket when empty
+type S3Storage struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	BaseURL         string
+	Client          *http.Client
+	now             func() time.Time
+}
+
+func (storage *S3Storage) Put(key string, content []byte, contentType string) error {
+	request, err := storage.newRequest(http.MethodPut, key, content)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", contentType)
+	return storage.do(request, content)
+}
+
+// Delete removes a file. Buckets report success for keys they do not have
+func (storage *S3Storage) Delete(key string) error {
+	request, err := storage.newRequest(http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	return storage.do(request, nil)
+}
+
+func (storage *S3Storage) URL(key string) string {
+	if storage.BaseURL != "" {
+		return strings.TrimRight(storage.BaseURL, "/") + "/" + key
+	}
+	return storage.objectURL(key)
+}
+
+// objectURL is the path style address of a key in the bucket
+func (storage *S3Storage) objectURL(key string) string {
+	return strings.TrimRight(storage.Endpoint, "/") + "/" + storage.Bucket + "/" + s3EscapePath(key)
+}
+
+func (storage *S3Storage) newRequest(method, key string, content []byte) (*http.Request, error) {
+	if !validStorageKey(key) {
+		return nil, errors.New(INVALID_STORAGE_KEY_ERROR)
+	}
+
+	request, err := http.NewRequest(method, storage.objectURL(key), bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	request.ContentLength = int64(len(content))
+	return request, nil
+}
+
+// do signs and sends a request and turns error responses into errors
+func (storage *S3Storage) do(request *http.Request, content []byte) error {
+	now := time.Now
+	if storage.now != nil {
+		now = storage.now
+	}
+
+	payloadHash := sha256.Sum256(content)
+	request.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	request.Header.Set("X-Amz-Date", now().UTC().Format(S3_AMZ_DATE_FORMAT))
+	signS3Request(request, storage.AccessKeyID, storage.SecretAccessKey, storage.Region)
+
+	client := storage.Client
+	if client == nil {
+		client = &http.Client{Timeout: S3_REQUEST_TIMEOUT}
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("object storage responded to %s %s with %s: %s", request.Method, request.URL.Path, response.Status, body)
+	}
+	return nil
+}
+
+// signS3Request adds the AWS Signature Version 4 Authorization header to a
+// request that already carries its X-Amz-Date and X-Amz-Content-Sha256
+// headers. Every header of the request is signed, along with the host
+func signS3Request(request *http.Request, accessKeyID, secretAccessKey, region string) {
+	amzDate := request.Header.Get("X-Amz-Date")
+	scope := strings.Join([]string{amzDate[:8], region, S3_SIGNING_SERVICE, "aws4_request"}, "/")
+
+	headers := map[string]string{"host": request.URL.Host}
+	for name, values := range request.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		canonicalS3Query(request.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		request.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(canonicalHash[:])}, "\n")
+
+	key := []byte("AWS4" + secretAccessKey)
+	for _, part := range []string{amzDate[:8], region, S3_SIGNING_SERVICE, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s,SignedHeaders=%s,Signature=%s",
+		accessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalS3Query sorts the query parameters and escapes them the way
+// Signature Version 4 expects
+func canonicalS3Query(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, s3Escape(name)+"="+s3Escape(value))
+		}
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// s3Escape percent-encodes everything but the unreserved characters of RFC 3986
+func s3Escape(value string) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
+
+// s3EscapePath escapes each segment of a key and keeps the slashes between them
+func s3EscapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = s3Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,140 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hackdaemon2/instashop/config"
+)
+
+const (
+	LOCAL_STORAGE_DRIVER      = "local"
+	S3_STORAGE_DRIVER         = "s3"
+	DEFAULT_STORAGE_FOLDER    = "uploads"
+	DEFAULT_STORAGE_URL       = "/uploads"
+	DEFAULT_S3_REGION         = "us-east-1"
+	INVALID_STORAGE_KEY_ERROR = "invalid storage key"
+)
+
+// Storage keeps uploaded files, such as product images, and tells where they
+// can be downloaded from. Keys are slash separated paths like "products/1/a.jpg"
+type Storage interface {
+	Put(key string, content []byte, contentType string) error
+	Delete(key string) error
+	URL(key string) string
+}
+
+// LocalStorage keeps files in Directory. The application serves them itself
+// under BaseURL when it is a path, otherwise a web server or CDN in front of
+// it is expected to
+type LocalStorage struct {
+	Directory string
+	BaseURL   string
+}
+
+// NewStorage builds the storage selected by the STORAGE_DRIVER environment
+// variable. Files go to the local disk unless the s3 driver is configured
+func NewStorage() Storage {
+	baseURL := os.Getenv("STORAGE_BASE_URL")
+
+	if os.Getenv("STORAGE_DRIVER") == S3_STORAGE_DRIVER {
+		region := os.Getenv("S3_REGION")
+		if region == "" {
+			region = DEFAULT_S3_REGION
+		}
+
+		return &S3Storage{
+			Endpoint:        config.GetEnv("S3_ENDPOINT"),
+			Region:          region,
+			Bucket:          config.GetEnv("S3_BUCKET"),
+			AccessKeyID:     config.GetEnv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: config.GetEnv("S3_SECRET_ACCESS_KEY"),
+			BaseURL:         baseURL,
+		}
+	}
+
+	directory := os.Getenv("STORAGE_LOCAL_DIR")
+	if directory == "" {
+		directory = DEFAULT_STORAGE_FOLDER
+	}
+
+	if baseURL == "" {
+		baseURL = DEFAULT_STORAGE_URL
+	}
+	return &LocalStorage{Directory: directory, BaseURL: baseURL}
+}
+
+// validStorageKey rejects keys that could escape the storage, such as "../x"
+func validStorageKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// path returns the file of a key inside the directory of the storage
+func (storage *LocalStorage) path(key string) (string, error) {
+	if !validStorageKey(key) {
+		return "", errors.New(INVALID_STORAGE_KEY_ERROR)
+	}
+	return filepath.Join(storage.Directory, filepath.FromSlash(key)), nil
+}
+
+func (storage *LocalStorage) Put(key string, content []byte, contentType string) error {
+	path, err := storage.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that readers never see half a file
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := bytes.NewReader(content).WriteTo(file); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err := os.Chmod(file.Name(), 0o644); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func (storage *LocalStorage) Delete(key string) error {
+	path, err := storage.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (storage *LocalStorage) URL(key string) string {
+	return strings.TrimRight(storage.BaseURL, "/") + "/" + key
+}
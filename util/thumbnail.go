@@ -0,0 +1,160 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif" // registers the GIF decoder with image.Decode
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	MAX_IMAGE_PIXELS        = 40_000_000 // larger images are refused before they are decoded
+	THUMBNAIL_JPEG_QUALITY  = 85
+	UNSUPPORTED_IMAGE_ERROR = "Images must be JPEG, PNG or GIF files"
+	IMAGE_TOO_LARGE_ERROR   = "Images may have at most 40 megapixels"
+)
+
+// ThumbnailSize is a named box that thumbnails are scaled down to fit in
+type ThumbnailSize struct {
+	Name string
+	Max  int
+}
+
+// ThumbnailSizes are the sizes generated for every uploaded image, smallest first
+var ThumbnailSizes = []ThumbnailSize{
+	{Name: "small", Max: 160},
+	{Name: "medium", Max: 480},
+	{Name: "large", Max: 1024},
+}
+
+// ProcessedImage is a validated upload and the thumbnails made from it
+type ProcessedImage struct {
+	ContentType string
+	Width       int
+	Height      int
+	Thumbnails  map[string][]byte // by size name, encoded as ThumbnailContentType
+}
+
+// ThumbnailContentType is the format of the thumbnails of an image. Photos
+// stay JPEG while PNG and GIF images keep their transparency as PNG
+func ThumbnailContentType(contentType string) string {
+	if contentType == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+// ProcessImage checks that content is a JPEG, PNG or GIF image of a
+// reasonable size and renders its thumbnails. The format is told from the
+// content itself, never from the file name or the declared type
+func ProcessImage(content []byte) (*ProcessedImage, error) {
+	contentType := http.DetectContentType(content)
+	if contentType != "image/jpeg" && contentType != "image/png" && contentType != "image/gif" {
+		return nil, errors.New(UNSUPPORTED_IMAGE_ERROR)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, errors.New(UNSUPPORTED_IMAGE_ERROR)
+	}
+
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MAX_IMAGE_PIXELS {
+		return nil, errors.New(IMAGE_TOO_LARGE_ERROR)
+	}
+
+	source, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, errors.New(UNSUPPORTED_IMAGE_ERROR)
+	}
+
+	processed := &ProcessedImage{
+		ContentType: contentType,
+		Width:       config.Width,
+		Height:      config.Height,
+		Thumbnails:  make(map[string][]byte, len(ThumbnailSizes)),
+	}
+
+	rgba := toRGBA(source)
+	for _, size := range ThumbnailSizes {
+		thumbnail, err := encodeThumbnail(resizeToFit(rgba, size.Max), ThumbnailContentType(contentType))
+		if err != nil {
+			return nil, err
+		}
+		processed.Thumbnails[size.Name] = thumbnail
+	}
+	return processed, nil
+}
+
+// toRGBA copies an image into non-premultiplied RGBA pixels starting at 0,0
+func toRGBA(source image.Image) *image.NRGBA {
+	bounds := source.Bounds()
+	rgba := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), source, bounds.Min, draw.Src)
+	return rgba
+}
+
+// resizeToFit scales an image down, keeping its aspect ratio, so that neither
+// side is longer than longest. Smaller images are left as they are. Each pixel of
+// the thumbnail is the average of the source pixels it covers, which keeps
+// fine detail from turning into noise
+func resizeToFit(source *image.NRGBA, longest int) *image.NRGBA {
+	width, height := source.Rect.Dx(), source.Rect.Dy()
+	if width <= longest && height <= longest {
+		return source
+	}
+
+	targetWidth, targetHeight := longest, longest
+	if width > height {
+		targetHeight = max(1, height*longest/width)
+	} else {
+		targetWidth = max(1, width*longest/height)
+	}
+
+	target := image.NewNRGBA(image.Rect(0, 0, targetWidth, targetHeight))
+	for y := range targetHeight {
+		y0, y1 := y*height/targetHeight, max((y+1)*height/targetHeight, y*height/targetHeight+1)
+		for x := range targetWidth {
+			x0, x1 := x*width/targetWidth, max((x+1)*width/targetWidth, x*width/targetWidth+1)
+
+			// weigh colours by their opacity so transparent pixels do not darken the edges
+			var r, g, b, a, count uint64
+			for sy := y0; sy < y1; sy++ {
+				offset := sy*source.Stride + x0*4
+				for sx := x0; sx < x1; sx++ {
+					pixel := source.Pix[offset : offset+4 : offset+4]
+					alpha := uint64(pixel[3])
+					r += uint64(pixel[0]) * alpha
+					g += uint64(pixel[1]) * alpha
+					b += uint64(pixel[2]) * alpha
+					a += alpha
+					count++
+					offset += 4
+				}
+			}
+
+			offset := y*target.Stride + x*4
+			if a > 0 {
+				target.Pix[offset] = uint8(r / a)
+				target.Pix[offset+1] = uint8(g / a)
+				target.Pix[offset+2] = uint8(b / a)
+			}
+			target.Pix[offset+3] = uint8(a / count)
+		}
+	}
+	return target
+}
+
+func encodeThumbnail(thumbnail image.Image, contentType string) ([]byte, error) {
+	var buffer bytes.Buffer
+	var err error
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buffer, thumbnail, &jpeg.Options{Quality: THUMBNAIL_JPEG_QUALITY})
+	} else {
+		err = png.Encode(&buffer, thumbnail)
+	}
+	return buffer.Bytes(), err
+}
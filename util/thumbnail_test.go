@@ -0,0 +1,77 @@
+package util
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeTestPNG(t *testing.T, width, height int) []byte {
+	source := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			source.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+
+	var buffer bytes.Buffer
+	assert.NoError(t, png.Encode(&buffer, source))
+	return buffer.Bytes()
+}
+
+func TestProcessImageRendersThumbnails(t *testing.T) {
+	processed, err := ProcessImage(encodeTestPNG(t, 2000, 1000))
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", processed.ContentType)
+	assert.Equal(t, 2000, processed.Width)
+	assert.Equal(t, 1000, processed.Height)
+
+	for _, size := range ThumbnailSizes {
+		thumbnail, err := png.DecodeConfig(bytes.NewReader(processed.Thumbnails[size.Name]))
+		assert.NoError(t, err, size.Name)
+		assert.Equal(t, size.Max, thumbnail.Width, "The longer side should fit the %s size", size.Name)
+		assert.Equal(t, size.Max/2, thumbnail.Height, "The aspect ratio should be kept")
+	}
+}
+
+func TestProcessImageKeepsSmallImagesAndJPEG(t *testing.T) {
+	var buffer bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buffer, image.NewRGBA(image.Rect(0, 0, 300, 200)), nil))
+
+	processed, err := ProcessImage(buffer.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, "image/jpeg", processed.ContentType)
+
+	small, err := jpeg.DecodeConfig(bytes.NewReader(processed.Thumbnails["small"]))
+	assert.NoError(t, err)
+	assert.Equal(t, 160, small.Width)
+
+	large, err := jpeg.DecodeConfig(bytes.NewReader(processed.Thumbnails["large"]))
+	assert.NoError(t, err)
+	assert.Equal(t, 300, large.Width, "Images should never be scaled up")
+}
+
+func TestProcessImageRejectsOtherFiles(t *testing.T) {
+	_, err := ProcessImage([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"))
+	assert.EqualError(t, err, UNSUPPORTED_IMAGE_ERROR)
+
+	truncated := encodeTestPNG(t, 10, 10)[:40]
+	_, err = ProcessImage(truncated)
+	assert.EqualError(t, err, UNSUPPORTED_IMAGE_ERROR)
+}
+
+func TestResizeToFitAveragesPixels(t *testing.T) {
+	source := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	source.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	source.Set(1, 0, color.NRGBA{R: 255, A: 255})
+	source.Set(0, 1, color.NRGBA{B: 255, A: 255})
+	source.Set(1, 1, color.NRGBA{}) // transparent pixels do not count towards the colour
+
+	resized := resizeToFit(source, 1)
+	assert.Equal(t, color.NRGBA{R: 170, B: 85, A: 191}, resized.NRGBAAt(0, 0))
+}
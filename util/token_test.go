@@ -0,0 +1,25 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateSecureToken(t *testing.T) {
+	first, err := GenerateSecureToken(32)
+	assert.NoError(t, err)
+	second, err := GenerateSecureToken(32)
+	assert.NoError(t, err)
+
+	assert.Len(t, first, 43, "32 random bytes should encode to 43 base64url characters")
+	assert.NotEqual(t, first, second, "Tokens should be random")
+}
+
+func TestHashToken(t *testing.T) {
+	hash := HashToken("token")
+
+	assert.Len(t, hash, 64, "SHA-256 hex digest should be 64 characters")
+	assert.Equal(t, hash, HashToken("token"), "Hashing should be deterministic")
+	assert.NotEqual(t, hash, HashToken("other"), "Different tokens should hash differently")
+}